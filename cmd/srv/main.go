@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -22,6 +23,8 @@ var (
 	flagVersion = flag.Bool("version", false, "print version and exit")
 	flagRestore = flag.String("restore", "", "restore database from backup file and exit")
 	flagBackup  = flag.String("backup", "", "create a one-time backup to the given directory and exit")
+	flagConfig  = flag.String("config", "", "path to a YAML config file (or GORSS_CONFIG); environment variables override it")
+	flagMigrate = flag.String("migrate-user", "", "move this user's feeds, categories and read state to the user ID given as argument, and exit")
 )
 
 func main() {
@@ -40,46 +43,23 @@ func run() error {
 		return nil
 	}
 
+	if err := loadConfigFile(); err != nil {
+		return err
+	}
+
 	// Determine DB path
 	dbPath := "db.sqlite3"
 	if envPath := os.Getenv("GORSS_DB_PATH"); envPath != "" {
 		dbPath = envPath
 	}
 
-	// Restore command
-	if *flagRestore != "" {
-		fmt.Printf("Restoring database from: %s\n", *flagRestore)
-		fmt.Printf("Target database: %s\n", dbPath)
-		fmt.Println("")
-		fmt.Println("WARNING: This will replace the existing database.")
-		fmt.Println("Make sure the GoRSS server is stopped before restoring.")
-		fmt.Print("Continue? [y/N] ")
-		var answer string
-		_, _ = fmt.Scanln(&answer)
-		if answer != "y" && answer != "Y" {
-			fmt.Println("Restore cancelled.")
-			return nil
-		}
-		if err := db.Restore(*flagRestore, dbPath); err != nil {
-			return fmt.Errorf("restore failed: %w", err)
-		}
-		fmt.Println("Restore complete. You can start the server now.")
-		return nil
-	}
-
-	// One-time backup command
-	if *flagBackup != "" {
-		srcDB, err := db.Open(dbPath)
-		if err != nil {
-			return fmt.Errorf("open database: %w", err)
-		}
-		defer srcDB.Close() //nolint:errcheck
-		path, err := db.Backup(srcDB, *flagBackup)
-		if err != nil {
-			return fmt.Errorf("backup failed: %w", err)
-		}
-		fmt.Printf("Backup saved to: %s\n", path)
-		return nil
+	switch {
+	case *flagRestore != "":
+		return runRestore(dbPath)
+	case *flagBackup != "":
+		return runBackup(dbPath)
+	case *flagMigrate != "":
+		return runMigrateUser(dbPath)
 	}
 
 	slog.Info("gorss starting",
@@ -99,6 +79,86 @@ func run() error {
 	return server.Serve(*flagPort)
 }
 
+// runRestore replaces the database at dbPath with the -restore backup,
+// after confirmation.
+func runRestore(dbPath string) error {
+	fmt.Printf("Restoring database from: %s\n", *flagRestore)
+	fmt.Printf("Target database: %s\n", dbPath)
+	fmt.Println("")
+	fmt.Println("WARNING: This will replace the existing database.")
+	fmt.Println("Make sure the GoRSS server is stopped before restoring.")
+	fmt.Print("Continue? [y/N] ")
+	var answer string
+	_, _ = fmt.Scanln(&answer)
+	if answer != "y" && answer != "Y" {
+		fmt.Println("Restore cancelled.")
+		return nil
+	}
+	if err := db.Restore(*flagRestore, dbPath); err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	fmt.Println("Restore complete. You can start the server now.")
+	return nil
+}
+
+// runBackup writes a one-time backup of dbPath to the -backup directory.
+func runBackup(dbPath string) error {
+	srcDB, err := db.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer srcDB.Close() //nolint:errcheck
+	path, err := db.Backup(srcDB, *flagBackup)
+	if err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	fmt.Printf("Backup saved to: %s\n", path)
+	return nil
+}
+
+// runMigrateUser reassigns the -migrate-user user's data to the user ID
+// given as argument.
+func runMigrateUser(dbPath string) error {
+	if flag.NArg() != 1 {
+		return fmt.Errorf("usage: gorss -migrate-user <fromUserID> <toUserID>")
+	}
+	sqlDB, err := db.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer sqlDB.Close() //nolint:errcheck
+	if err := db.RunMigrations(sqlDB); err != nil {
+		return fmt.Errorf("migrate database: %w", err)
+	}
+	res, err := db.MigrateUser(context.Background(), sqlDB, *flagMigrate, flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Moved %d feeds, %d categories and %d article states from %q to %q.\n",
+		res.Feeds, res.Categories, res.ArticleStates, *flagMigrate, flag.Arg(0))
+	if res.Skipped > 0 {
+		fmt.Printf("%d feeds were already subscribed by %q and were left in place.\n", res.Skipped, flag.Arg(0))
+	}
+	return nil
+}
+
+// loadConfigFile applies the -config (or GORSS_CONFIG) file, whose values
+// fill in environment variables that aren't already set.
+func loadConfigFile() error {
+	path := *flagConfig
+	if path == "" {
+		path = os.Getenv("GORSS_CONFIG")
+	}
+	if path == "" {
+		return nil
+	}
+	cfg, err := srv.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	return cfg.ApplyEnv()
+}
+
 func usage() {
 	fmt.Fprintf(os.Stderr, `GoRSS - A self-hosted RSS/Atom feed reader
 
@@ -117,12 +177,57 @@ Environment Variables:
   GORSS_PORT                Port to listen on (default: 8080)
   GORSS_DB_PATH             Path to SQLite database (default: ./db.sqlite3)
   GORSS_AUTH_MODE           Authentication mode: none, password, proxy (default: none)
+  GORSS_ADMIN_GROUP         Proxy group required for /api/admin/ endpoints (unset: closed in proxy mode)
   GORSS_PASSWORD            Password for "password" auth mode
   GORSS_REFRESH_INTERVAL    Feed refresh interval, e.g. 30m, 1h, 2h (default: 1h)
   GORSS_PURGE_DAYS          Auto-purge read articles older than N days, 0 to disable (default: 30)
   GORSS_BACKUP_DIR          Directory for periodic backups (disabled if unset)
   GORSS_BACKUP_INTERVAL     Backup interval, e.g. 12h, 24h (default: 24h)
   GORSS_BACKUP_KEEP         Number of backup files to keep (default: 7)
+  GORSS_HTTP_PROXY          Proxy URL for feed fetches (http, https or socks5); defaults to HTTPS_PROXY/HTTP_PROXY/ALL_PROXY, honors NO_PROXY
+  GORSS_STRICT_CONTENT_TYPE Set to 1 to reject feed responses with a non-feed Content-Type (default: warn only)
+  GORSS_COOKIE_NAME         Session cookie name (default: gorss_session)
+  GORSS_COOKIE_SAMESITE     Session cookie SameSite: lax, strict, none (default: lax)
+  GORSS_COOKIE_SECURE       Force cookie Secure flag (default: auto from TLS)
+  GORSS_EMPTY_FEED_WARN_AFTER Empty refreshes before a feed is flagged (default: 5, 0 = off)
+  GORSS_DNS_FAIL_DISABLE_AFTER "No such host" failures before a feed is flagged dead (default: 5, 0 = off)
+  GORSS_FRESH_HOURS         Hours an unread article counts as fresh (default: 24, 0 = off)
+  GORSS_USER_SEEN_INTERVAL  Min interval between per-user last_seen writes (default: 5m)
+  GORSS_READ_PROPAGATION_WINDOW Mark same-URL articles read within this window (e.g. 72h; default: off)
+  GORSS_ALLOW_INSECURE_TLS  Set to 1 to allow per-feed insecure_skip_verify (admins only)
+  GORSS_BACKUP_OPML         Set to 1 to also export per-user OPML with each backup
+  GORSS_CAPTURE_FEED_BODIES Directory to save unparseable feed bodies (debugging)
+  GORSS_MAX_SESSIONS        Maximum stored login sessions (default: 1000)
+  GORSS_CONFIG              Path to a YAML config file (same as -config)
+  GORSS_FETCH_RETRIES       Retries after a timeout, refused/reset connection or 5xx (default: 3, max 5)
+  GORSS_USER_AGENT          User-Agent for outgoing requests (default: GoRSS/1.0 (feed reader))
+  GORSS_HTTP_MAX_IDLE_CONNS Idle connections kept for feed fetching (default: 100)
+  GORSS_HTTP_MAX_IDLE_CONNS_PER_HOST
+                            Idle connections kept per host (default: 10)
+  GORSS_HTTP_IDLE_CONN_TIMEOUT
+                            Idle connection lifetime (default: 90s)
+  GORSS_REFRESH_QUIET_HOURS Pause background refresh in this local-time window, e.g. 23-06
+  GORSS_REFRESH_ORDER       Feed order per refresh cycle: stale, active or random (default: stale)
+  GORSS_DISCOVERY_URL       Feed discovery backend URL ({q} = query; disabled if unset)
+  GORSS_DISCOVERY_KEY       Bearer token for the discovery backend
+  GORSS_MAX_TITLE_LEN       Max stored title length in characters (default: 500, 0 = no limit)
+  GORSS_MAX_SUMMARY_LEN     Max stored summary length in characters (default: 5000, 0 = no limit)
+  GORSS_EXCERPT_LEN         Summary generated from content when a feed has none (default: 200, 0 = off)
+  GORSS_REFRESH_ON_START    Refresh all feeds at startup (default: true)
+  GORSS_STARTUP_DELAY       Wait before the first purge and backup (default: 30s)
+  GORSS_READONLY            set to 1 to reject API writes (read-only demo)
+  GORSS_DEFAULT_USER        user ID for requests without one (default anonymous)
+  GORSS_REQUIRE_USER_ID     set to 1 to reject blank user IDs (proxy mode only)
+  GORSS_COMPRESS_CONTENT    set to 1 to gzip article content at rest
+  GORSS_COUNTS_CACHE_TTL    cache /api/counts per user for this long, e.g. 5s
+  GORSS_MAX_STREAMS         cap on concurrent streaming responses (default: 0 = unlimited)
+  GORSS_MAX_STREAMS_PER_USER per-user cap (default: a quarter of GORSS_MAX_STREAMS)
+  GORSS_DEFAULT_THEME       theme without a saved choice: auto, light or dark (default: auto)
+  GORSS_DEDUP_BY_URL        collapse same-URL articles in lists: oldest or newest (default: off)
+  GORSS_WEBHOOK_URL         POST new articles as JSON to this URL after each refresh
+  GORSS_UNREAD_ON_UPDATE    1 to mark edited articles unread again (default: off)
+  GORSS_MAX_IMPORT_MB       largest OPML upload in MB, 0 = no limit (default: 10)
+  GORSS_MAX_IMPORT_FEEDS    most feeds per OPML import, 0 = no limit (default: 5000)
   TZ                        Timezone (default: UTC)
 
 Examples:
@@ -130,6 +235,7 @@ Examples:
   gorss -port 3000                         # Listen on port 3000
   gorss -backup /backups                   # Create a one-time backup
   gorss -restore /backups/gorss-2026-02-16-030000.db  # Restore from backup
+  gorss -migrate-user anonymous alice      # Move anonymous data to user alice
   GORSS_BACKUP_DIR=/backups gorss          # Enable periodic backup every 24h
   GORSS_AUTH_MODE=password GORSS_PASSWORD=secret gorss
   GORSS_DB_PATH=/data/gorss.db GORSS_REFRESH_INTERVAL=30m gorss
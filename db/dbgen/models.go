@@ -9,16 +9,26 @@ import (
 )
 
 type Article struct {
-	ID          int64      `json:"id"`
-	FeedID      int64      `json:"feed_id"`
-	Guid        string     `json:"guid"`
-	Url         string     `json:"url"`
-	Title       string     `json:"title"`
-	Author      string     `json:"author"`
-	Content     string     `json:"content"`
-	Summary     string     `json:"summary"`
-	PublishedAt *time.Time `json:"published_at"`
-	CreatedAt   time.Time  `json:"created_at"`
+	ID                int64      `json:"id"`
+	FeedID            int64      `json:"feed_id"`
+	Guid              string     `json:"guid"`
+	Url               string     `json:"url"`
+	Title             string     `json:"title"`
+	Author            string     `json:"author"`
+	Content           string     `json:"content"`
+	Summary           string     `json:"summary"`
+	PublishedAt       *time.Time `json:"published_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+	DurationSeconds   *int64     `json:"duration_seconds"`
+	Episode           *int64     `json:"episode"`
+	Season            *int64     `json:"season"`
+	EpisodeType       *string    `json:"episode_type"`
+	ContentCompressed int64      `json:"content_compressed"`
+	EnclosureUrl      *string    `json:"enclosure_url"`
+	EnclosureType     *string    `json:"enclosure_type"`
+	ContentHash       string     `json:"content_hash"`
+	Inserted          int64      `json:"inserted"`
 }
 
 type ArticleState struct {
@@ -28,31 +38,60 @@ type ArticleState struct {
 	IsStarred int64      `json:"is_starred"`
 	ReadAt    *time.Time `json:"read_at"`
 	StarredAt *time.Time `json:"starred_at"`
+	IsHidden  int64      `json:"is_hidden"`
 }
 
 type Category struct {
-	ID        int64     `json:"id"`
+	ID                     int64     `json:"id"`
+	UserID                 string    `json:"user_id"`
+	Title                  string    `json:"title"`
+	CreatedAt              time.Time `json:"created_at"`
+	SortOrder              int64     `json:"sort_order"`
+	RefreshIntervalMinutes *int64    `json:"refresh_interval_minutes"`
+}
+
+type Feed struct {
+	ID                     int64      `json:"id"`
+	UserID                 string     `json:"user_id"`
+	CategoryID             *int64     `json:"category_id"`
+	Url                    string     `json:"url"`
+	Title                  string     `json:"title"`
+	SiteUrl                string     `json:"site_url"`
+	Description            string     `json:"description"`
+	LastUpdated            *time.Time `json:"last_updated"`
+	LastError              *string    `json:"last_error"`
+	CreatedAt              time.Time  `json:"created_at"`
+	SortOrder              int64      `json:"sort_order"`
+	Etag                   string     `json:"etag"`
+	LastModified           string     `json:"last_modified"`
+	ErrorCount             int64      `json:"error_count"`
+	AutoReadAfterDays      int64      `json:"auto_read_after_days"`
+	EmptyCount             int64      `json:"empty_count"`
+	LastWarning            *string    `json:"last_warning"`
+	RefreshIntervalMinutes *int64     `json:"refresh_interval_minutes"`
+	DedupTitles            int64      `json:"dedup_titles"`
+	InsecureSkipVerify     int64      `json:"insecure_skip_verify"`
+	LastSuccessAt          *time.Time `json:"last_success_at"`
+	LastFetchAttempts      int64      `json:"last_fetch_attempts"`
+	FetchAttempts          int64      `json:"fetch_attempts"`
+	DateSource             string     `json:"date_source"`
+	KeepOnlyPattern        string     `json:"keep_only_pattern"`
+	GuidUnstable           int64      `json:"guid_unstable"`
+	DnsFailCount           int64      `json:"dns_fail_count"`
+	Dead                   int64      `json:"dead"`
+	LogoUrl                string     `json:"logo_url"`
+}
+
+type FeedToken struct {
 	UserID    string    `json:"user_id"`
-	Title     string    `json:"title"`
+	Token     string    `json:"token"`
 	CreatedAt time.Time `json:"created_at"`
-	SortOrder int64     `json:"sort_order"`
 }
 
-type Feed struct {
-	ID           int64      `json:"id"`
-	UserID       string     `json:"user_id"`
-	CategoryID   *int64     `json:"category_id"`
-	Url          string     `json:"url"`
-	Title        string     `json:"title"`
-	SiteUrl      string     `json:"site_url"`
-	Description  string     `json:"description"`
-	LastUpdated  *time.Time `json:"last_updated"`
-	LastError    *string    `json:"last_error"`
-	CreatedAt    time.Time  `json:"created_at"`
-	SortOrder    int64      `json:"sort_order"`
-	Etag         string     `json:"etag"`
-	LastModified string     `json:"last_modified"`
-	ErrorCount   int64      `json:"error_count"`
+type FeverKey struct {
+	ApiKey    string    `json:"api_key"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Migration struct {
@@ -61,9 +100,21 @@ type Migration struct {
 	ExecutedAt      time.Time `json:"executed_at"`
 }
 
+type ReadUndo struct {
+	Token     string    `json:"token"`
+	UserID    string    `json:"user_id"`
+	ArticleID int64     `json:"article_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type User struct {
 	ID        string    `json:"id"`
 	Email     *string   `json:"email"`
 	CreatedAt time.Time `json:"created_at"`
 	LastSeen  time.Time `json:"last_seen"`
 }
+
+type UserPref struct {
+	UserID            string `json:"user_id"`
+	DefaultCategoryID *int64 `json:"default_category_id"`
+}
@@ -8,9 +8,55 @@ package dbgen
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 )
 
+const autoReadStaleArticles = `-- name: AutoReadStaleArticles :execresult
+INSERT INTO article_states (user_id, article_id, is_read, read_at)
+SELECT f.user_id, a.id, 1, ?
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE a.feed_id = ?
+  AND (s.is_read IS NULL OR s.is_read = 0)
+  AND COALESCE(s.is_starred, 0) = 0
+  AND COALESCE(a.published_at, a.created_at) < ?3
+ON CONFLICT (user_id, article_id) DO UPDATE SET
+  is_read = 1,
+  read_at = excluded.read_at
+`
+
+type AutoReadStaleArticlesParams struct {
+	ReadAt *time.Time `json:"read_at"`
+	FeedID int64      `json:"feed_id"`
+	Cutoff *time.Time `json:"cutoff"`
+}
+
+func (q *Queries) AutoReadStaleArticles(ctx context.Context, arg AutoReadStaleArticlesParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, autoReadStaleArticles, arg.ReadAt, arg.FeedID, arg.Cutoff)
+}
+
+const clearReadIfEdited = `-- name: ClearReadIfEdited :execresult
+UPDATE article_states SET is_read = 0, read_at = NULL
+WHERE is_read = 1 AND article_id = (
+  SELECT id FROM articles
+  WHERE feed_id = ? AND guid = ? AND content_hash != '' AND content_hash != ?
+)
+`
+
+type ClearReadIfEditedParams struct {
+	FeedID      int64  `json:"feed_id"`
+	Guid        string `json:"guid"`
+	ContentHash string `json:"content_hash"`
+}
+
+// Marks an article unread for whoever had read it when its stored hash
+// differs from content_hash. Articles stored before hashing have none.
+func (q *Queries) ClearReadIfEdited(ctx context.Context, arg ClearReadIfEditedParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, clearReadIfEdited, arg.FeedID, arg.Guid, arg.ContentHash)
+}
+
 const countOldReadArticles = `-- name: CountOldReadArticles :one
 SELECT COUNT(*) as count
 FROM articles a
@@ -28,9 +74,27 @@ func (q *Queries) CountOldReadArticles(ctx context.Context, publishedAt *time.Ti
 	return count, err
 }
 
+const countReadUndo = `-- name: CountReadUndo :one
+SELECT COUNT(*) FROM read_undo
+WHERE token = ? AND user_id = ? AND created_at > datetime('now', CAST(?3 AS TEXT))
+`
+
+type CountReadUndoParams struct {
+	Token  string `json:"token"`
+	UserID string `json:"user_id"`
+	MaxAge string `json:"max_age"`
+}
+
+func (q *Queries) CountReadUndo(ctx context.Context, arg CountReadUndoParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countReadUndo, arg.Token, arg.UserID, arg.MaxAge)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createCategory = `-- name: CreateCategory :one
 
-INSERT INTO categories (user_id, title) VALUES (?, ?) RETURNING id, user_id, title, created_at, sort_order
+INSERT INTO categories (user_id, title) VALUES (?, ?) RETURNING id, user_id, title, created_at, sort_order, refresh_interval_minutes
 `
 
 type CreateCategoryParams struct {
@@ -48,14 +112,15 @@ func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams)
 		&i.Title,
 		&i.CreatedAt,
 		&i.SortOrder,
+		&i.RefreshIntervalMinutes,
 	)
 	return i, err
 }
 
 const createFeed = `-- name: CreateFeed :one
 
-INSERT INTO feeds (user_id, category_id, url, title, site_url, description)
-VALUES (?, ?, ?, ?, ?, ?) RETURNING id, user_id, category_id, url, title, site_url, description, last_updated, last_error, created_at, sort_order, etag, last_modified, error_count
+INSERT INTO feeds (user_id, category_id, url, title, site_url, description, logo_url)
+VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id, user_id, category_id, url, title, site_url, description, last_updated, last_error, created_at, sort_order, etag, last_modified, error_count, auto_read_after_days, empty_count, last_warning, refresh_interval_minutes, dedup_titles, insecure_skip_verify, last_success_at, last_fetch_attempts, fetch_attempts, date_source, keep_only_pattern, guid_unstable, dns_fail_count, dead, logo_url
 `
 
 type CreateFeedParams struct {
@@ -65,6 +130,7 @@ type CreateFeedParams struct {
 	Title       string `json:"title"`
 	SiteUrl     string `json:"site_url"`
 	Description string `json:"description"`
+	LogoUrl     string `json:"logo_url"`
 }
 
 // Feed queries
@@ -76,6 +142,7 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Title,
 		arg.SiteUrl,
 		arg.Description,
+		arg.LogoUrl,
 	)
 	var i Feed
 	err := row.Scan(
@@ -93,6 +160,21 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Etag,
 		&i.LastModified,
 		&i.ErrorCount,
+		&i.AutoReadAfterDays,
+		&i.EmptyCount,
+		&i.LastWarning,
+		&i.RefreshIntervalMinutes,
+		&i.DedupTitles,
+		&i.InsecureSkipVerify,
+		&i.LastSuccessAt,
+		&i.LastFetchAttempts,
+		&i.FetchAttempts,
+		&i.DateSource,
+		&i.KeepOnlyPattern,
+		&i.GuidUnstable,
+		&i.DnsFailCount,
+		&i.Dead,
+		&i.LogoUrl,
 	)
 	return i, err
 }
@@ -125,8 +207,31 @@ func (q *Queries) DeleteFeed(ctx context.Context, arg DeleteFeedParams) error {
 	return err
 }
 
+const deleteFeverKeys = `-- name: DeleteFeverKeys :exec
+DELETE FROM fever_keys WHERE user_id = ?
+`
+
+func (q *Queries) DeleteFeverKeys(ctx context.Context, userID string) error {
+	_, err := q.db.ExecContext(ctx, deleteFeverKeys, userID)
+	return err
+}
+
+const deleteReadUndo = `-- name: DeleteReadUndo :exec
+DELETE FROM read_undo WHERE token = ? AND user_id = ?
+`
+
+type DeleteReadUndoParams struct {
+	Token  string `json:"token"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) DeleteReadUndo(ctx context.Context, arg DeleteReadUndoParams) error {
+	_, err := q.db.ExecContext(ctx, deleteReadUndo, arg.Token, arg.UserID)
+	return err
+}
+
 const getAllFeedsForRefresh = `-- name: GetAllFeedsForRefresh :many
-SELECT id, user_id, category_id, url, title, site_url, description, last_updated, last_error, created_at, sort_order, etag, last_modified, error_count FROM feeds ORDER BY last_updated ASC NULLS FIRST LIMIT ?
+SELECT id, user_id, category_id, url, title, site_url, description, last_updated, last_error, created_at, sort_order, etag, last_modified, error_count, auto_read_after_days, empty_count, last_warning, refresh_interval_minutes, dedup_titles, insecure_skip_verify, last_success_at, last_fetch_attempts, fetch_attempts, date_source, keep_only_pattern, guid_unstable, dns_fail_count, dead, logo_url FROM feeds ORDER BY last_updated ASC NULLS FIRST LIMIT ?
 `
 
 func (q *Queries) GetAllFeedsForRefresh(ctx context.Context, limit int64) ([]Feed, error) {
@@ -153,6 +258,21 @@ func (q *Queries) GetAllFeedsForRefresh(ctx context.Context, limit int64) ([]Fee
 			&i.Etag,
 			&i.LastModified,
 			&i.ErrorCount,
+			&i.AutoReadAfterDays,
+			&i.EmptyCount,
+			&i.LastWarning,
+			&i.RefreshIntervalMinutes,
+			&i.DedupTitles,
+			&i.InsecureSkipVerify,
+			&i.LastSuccessAt,
+			&i.LastFetchAttempts,
+			&i.FetchAttempts,
+			&i.DateSource,
+			&i.KeepOnlyPattern,
+			&i.GuidUnstable,
+			&i.DnsFailCount,
+			&i.Dead,
+			&i.LogoUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -168,7 +288,7 @@ func (q *Queries) GetAllFeedsForRefresh(ctx context.Context, limit int64) ([]Fee
 }
 
 const getArticle = `-- name: GetArticle :one
-SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, f.title as feed_title, f.site_url as feed_site_url,
+SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, a.updated_at, a.duration_seconds, a.episode, a.season, a.episode_type, a.content_compressed, a.enclosure_url, a.enclosure_type, a.content_hash, a.inserted, f.title as feed_title, f.site_url as feed_site_url,
   COALESCE(s.is_read, 0) as is_read,
   COALESCE(s.is_starred, 0) as is_starred
 FROM articles a
@@ -184,20 +304,30 @@ type GetArticleParams struct {
 }
 
 type GetArticleRow struct {
-	ID          int64      `json:"id"`
-	FeedID      int64      `json:"feed_id"`
-	Guid        string     `json:"guid"`
-	Url         string     `json:"url"`
-	Title       string     `json:"title"`
-	Author      string     `json:"author"`
-	Content     string     `json:"content"`
-	Summary     string     `json:"summary"`
-	PublishedAt *time.Time `json:"published_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	FeedTitle   string     `json:"feed_title"`
-	FeedSiteUrl string     `json:"feed_site_url"`
-	IsRead      int64      `json:"is_read"`
-	IsStarred   int64      `json:"is_starred"`
+	ID                int64      `json:"id"`
+	FeedID            int64      `json:"feed_id"`
+	Guid              string     `json:"guid"`
+	Url               string     `json:"url"`
+	Title             string     `json:"title"`
+	Author            string     `json:"author"`
+	Content           string     `json:"content"`
+	Summary           string     `json:"summary"`
+	PublishedAt       *time.Time `json:"published_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+	DurationSeconds   *int64     `json:"duration_seconds"`
+	Episode           *int64     `json:"episode"`
+	Season            *int64     `json:"season"`
+	EpisodeType       *string    `json:"episode_type"`
+	ContentCompressed int64      `json:"content_compressed"`
+	EnclosureUrl      *string    `json:"enclosure_url"`
+	EnclosureType     *string    `json:"enclosure_type"`
+	ContentHash       string     `json:"content_hash"`
+	Inserted          int64      `json:"inserted"`
+	FeedTitle         string     `json:"feed_title"`
+	FeedSiteUrl       string     `json:"feed_site_url"`
+	IsRead            int64      `json:"is_read"`
+	IsStarred         int64      `json:"is_starred"`
 }
 
 func (q *Queries) GetArticle(ctx context.Context, arg GetArticleParams) (GetArticleRow, error) {
@@ -214,6 +344,16 @@ func (q *Queries) GetArticle(ctx context.Context, arg GetArticleParams) (GetArti
 		&i.Summary,
 		&i.PublishedAt,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DurationSeconds,
+		&i.Episode,
+		&i.Season,
+		&i.EpisodeType,
+		&i.ContentCompressed,
+		&i.EnclosureUrl,
+		&i.EnclosureType,
+		&i.ContentHash,
+		&i.Inserted,
 		&i.FeedTitle,
 		&i.FeedSiteUrl,
 		&i.IsRead,
@@ -222,10 +362,134 @@ func (q *Queries) GetArticle(ctx context.Context, arg GetArticleParams) (GetArti
 	return i, err
 }
 
+const getArticleContentBatch = `-- name: GetArticleContentBatch :many
+SELECT a.id, a.title, a.content, a.content_compressed, a.summary
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+WHERE f.user_id = ? AND a.id > ?
+ORDER BY a.id
+LIMIT ?
+`
+
+type GetArticleContentBatchParams struct {
+	UserID string `json:"user_id"`
+	ID     int64  `json:"id"`
+	Limit  int64  `json:"limit"`
+}
+
+type GetArticleContentBatchRow struct {
+	ID                int64  `json:"id"`
+	Title             string `json:"title"`
+	Content           string `json:"content"`
+	ContentCompressed int64  `json:"content_compressed"`
+	Summary           string `json:"summary"`
+}
+
+func (q *Queries) GetArticleContentBatch(ctx context.Context, arg GetArticleContentBatchParams) ([]GetArticleContentBatchRow, error) {
+	rows, err := q.db.QueryContext(ctx, getArticleContentBatch, arg.UserID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetArticleContentBatchRow{}
+	for rows.Next() {
+		var i GetArticleContentBatchRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Content,
+			&i.ContentCompressed,
+			&i.Summary,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getArticleEnclosure = `-- name: GetArticleEnclosure :one
+SELECT a.enclosure_url FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+WHERE a.id = ? AND f.user_id = ?
+`
+
+type GetArticleEnclosureParams struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) GetArticleEnclosure(ctx context.Context, arg GetArticleEnclosureParams) (*string, error) {
+	row := q.db.QueryRowContext(ctx, getArticleEnclosure, arg.ID, arg.UserID)
+	var enclosure_url *string
+	err := row.Scan(&enclosure_url)
+	return enclosure_url, err
+}
+
+const getArticleURLs = `-- name: GetArticleURLs :many
+
+SELECT a.id, a.url
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+WHERE f.user_id = ? AND a.id IN (/*SLICE:ids*/?)
+`
+
+type GetArticleURLsParams struct {
+	UserID string  `json:"user_id"`
+	Ids    []int64 `json:"ids"`
+}
+
+type GetArticleURLsRow struct {
+	ID  int64  `json:"id"`
+	Url string `json:"url"`
+}
+
+// Read propagation queries
+func (q *Queries) GetArticleURLs(ctx context.Context, arg GetArticleURLsParams) ([]GetArticleURLsRow, error) {
+	query := getArticleURLs
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.UserID)
+	if len(arg.Ids) > 0 {
+		for _, v := range arg.Ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(arg.Ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetArticleURLsRow{}
+	for rows.Next() {
+		var i GetArticleURLsRow
+		if err := rows.Scan(&i.ID, &i.Url); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getArticles = `-- name: GetArticles :many
-SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, f.title as feed_title, f.site_url as feed_site_url,
+SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, a.updated_at, a.duration_seconds, a.episode, a.season, a.episode_type, a.content_compressed, a.enclosure_url, a.enclosure_type, a.content_hash, a.inserted, f.title as feed_title, f.site_url as feed_site_url,
   COALESCE(s.is_read, 0) as is_read,
-  COALESCE(s.is_starred, 0) as is_starred
+  COALESCE(s.is_starred, 0) as is_starred,
+  s.read_at
 FROM articles a
 JOIN feeds f ON a.feed_id = f.id
 LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = ?
@@ -242,20 +506,31 @@ type GetArticlesParams struct {
 }
 
 type GetArticlesRow struct {
-	ID          int64      `json:"id"`
-	FeedID      int64      `json:"feed_id"`
-	Guid        string     `json:"guid"`
-	Url         string     `json:"url"`
-	Title       string     `json:"title"`
-	Author      string     `json:"author"`
-	Content     string     `json:"content"`
-	Summary     string     `json:"summary"`
-	PublishedAt *time.Time `json:"published_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	FeedTitle   string     `json:"feed_title"`
-	FeedSiteUrl string     `json:"feed_site_url"`
-	IsRead      int64      `json:"is_read"`
-	IsStarred   int64      `json:"is_starred"`
+	ID                int64      `json:"id"`
+	FeedID            int64      `json:"feed_id"`
+	Guid              string     `json:"guid"`
+	Url               string     `json:"url"`
+	Title             string     `json:"title"`
+	Author            string     `json:"author"`
+	Content           string     `json:"content"`
+	Summary           string     `json:"summary"`
+	PublishedAt       *time.Time `json:"published_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+	DurationSeconds   *int64     `json:"duration_seconds"`
+	Episode           *int64     `json:"episode"`
+	Season            *int64     `json:"season"`
+	EpisodeType       *string    `json:"episode_type"`
+	ContentCompressed int64      `json:"content_compressed"`
+	EnclosureUrl      *string    `json:"enclosure_url"`
+	EnclosureType     *string    `json:"enclosure_type"`
+	ContentHash       string     `json:"content_hash"`
+	Inserted          int64      `json:"inserted"`
+	FeedTitle         string     `json:"feed_title"`
+	FeedSiteUrl       string     `json:"feed_site_url"`
+	IsRead            int64      `json:"is_read"`
+	IsStarred         int64      `json:"is_starred"`
+	ReadAt            *time.Time `json:"read_at"`
 }
 
 func (q *Queries) GetArticles(ctx context.Context, arg GetArticlesParams) ([]GetArticlesRow, error) {
@@ -283,10 +558,21 @@ func (q *Queries) GetArticles(ctx context.Context, arg GetArticlesParams) ([]Get
 			&i.Summary,
 			&i.PublishedAt,
 			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DurationSeconds,
+			&i.Episode,
+			&i.Season,
+			&i.EpisodeType,
+			&i.ContentCompressed,
+			&i.EnclosureUrl,
+			&i.EnclosureType,
+			&i.ContentHash,
+			&i.Inserted,
 			&i.FeedTitle,
 			&i.FeedSiteUrl,
 			&i.IsRead,
 			&i.IsStarred,
+			&i.ReadAt,
 		); err != nil {
 			return nil, err
 		}
@@ -302,7 +588,7 @@ func (q *Queries) GetArticles(ctx context.Context, arg GetArticlesParams) ([]Get
 }
 
 const getArticlesByCategory = `-- name: GetArticlesByCategory :many
-SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, f.title as feed_title, f.site_url as feed_site_url,
+SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, a.updated_at, a.duration_seconds, a.episode, a.season, a.episode_type, a.content_compressed, a.enclosure_url, a.enclosure_type, a.content_hash, a.inserted, f.title as feed_title, f.site_url as feed_site_url,
   COALESCE(s.is_read, 0) as is_read,
   COALESCE(s.is_starred, 0) as is_starred
 FROM articles a
@@ -322,20 +608,30 @@ type GetArticlesByCategoryParams struct {
 }
 
 type GetArticlesByCategoryRow struct {
-	ID          int64      `json:"id"`
-	FeedID      int64      `json:"feed_id"`
-	Guid        string     `json:"guid"`
-	Url         string     `json:"url"`
-	Title       string     `json:"title"`
-	Author      string     `json:"author"`
-	Content     string     `json:"content"`
-	Summary     string     `json:"summary"`
-	PublishedAt *time.Time `json:"published_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	FeedTitle   string     `json:"feed_title"`
-	FeedSiteUrl string     `json:"feed_site_url"`
-	IsRead      int64      `json:"is_read"`
-	IsStarred   int64      `json:"is_starred"`
+	ID                int64      `json:"id"`
+	FeedID            int64      `json:"feed_id"`
+	Guid              string     `json:"guid"`
+	Url               string     `json:"url"`
+	Title             string     `json:"title"`
+	Author            string     `json:"author"`
+	Content           string     `json:"content"`
+	Summary           string     `json:"summary"`
+	PublishedAt       *time.Time `json:"published_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+	DurationSeconds   *int64     `json:"duration_seconds"`
+	Episode           *int64     `json:"episode"`
+	Season            *int64     `json:"season"`
+	EpisodeType       *string    `json:"episode_type"`
+	ContentCompressed int64      `json:"content_compressed"`
+	EnclosureUrl      *string    `json:"enclosure_url"`
+	EnclosureType     *string    `json:"enclosure_type"`
+	ContentHash       string     `json:"content_hash"`
+	Inserted          int64      `json:"inserted"`
+	FeedTitle         string     `json:"feed_title"`
+	FeedSiteUrl       string     `json:"feed_site_url"`
+	IsRead            int64      `json:"is_read"`
+	IsStarred         int64      `json:"is_starred"`
 }
 
 func (q *Queries) GetArticlesByCategory(ctx context.Context, arg GetArticlesByCategoryParams) ([]GetArticlesByCategoryRow, error) {
@@ -364,6 +660,16 @@ func (q *Queries) GetArticlesByCategory(ctx context.Context, arg GetArticlesByCa
 			&i.Summary,
 			&i.PublishedAt,
 			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DurationSeconds,
+			&i.Episode,
+			&i.Season,
+			&i.EpisodeType,
+			&i.ContentCompressed,
+			&i.EnclosureUrl,
+			&i.EnclosureType,
+			&i.ContentHash,
+			&i.Inserted,
 			&i.FeedTitle,
 			&i.FeedSiteUrl,
 			&i.IsRead,
@@ -383,7 +689,7 @@ func (q *Queries) GetArticlesByCategory(ctx context.Context, arg GetArticlesByCa
 }
 
 const getArticlesByFeed = `-- name: GetArticlesByFeed :many
-SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, f.title as feed_title, f.site_url as feed_site_url,
+SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, a.updated_at, a.duration_seconds, a.episode, a.season, a.episode_type, a.content_compressed, a.enclosure_url, a.enclosure_type, a.content_hash, a.inserted, f.title as feed_title, f.site_url as feed_site_url,
   COALESCE(s.is_read, 0) as is_read,
   COALESCE(s.is_starred, 0) as is_starred
 FROM articles a
@@ -403,20 +709,30 @@ type GetArticlesByFeedParams struct {
 }
 
 type GetArticlesByFeedRow struct {
-	ID          int64      `json:"id"`
-	FeedID      int64      `json:"feed_id"`
-	Guid        string     `json:"guid"`
-	Url         string     `json:"url"`
-	Title       string     `json:"title"`
-	Author      string     `json:"author"`
-	Content     string     `json:"content"`
-	Summary     string     `json:"summary"`
-	PublishedAt *time.Time `json:"published_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	FeedTitle   string     `json:"feed_title"`
-	FeedSiteUrl string     `json:"feed_site_url"`
-	IsRead      int64      `json:"is_read"`
-	IsStarred   int64      `json:"is_starred"`
+	ID                int64      `json:"id"`
+	FeedID            int64      `json:"feed_id"`
+	Guid              string     `json:"guid"`
+	Url               string     `json:"url"`
+	Title             string     `json:"title"`
+	Author            string     `json:"author"`
+	Content           string     `json:"content"`
+	Summary           string     `json:"summary"`
+	PublishedAt       *time.Time `json:"published_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+	DurationSeconds   *int64     `json:"duration_seconds"`
+	Episode           *int64     `json:"episode"`
+	Season            *int64     `json:"season"`
+	EpisodeType       *string    `json:"episode_type"`
+	ContentCompressed int64      `json:"content_compressed"`
+	EnclosureUrl      *string    `json:"enclosure_url"`
+	EnclosureType     *string    `json:"enclosure_type"`
+	ContentHash       string     `json:"content_hash"`
+	Inserted          int64      `json:"inserted"`
+	FeedTitle         string     `json:"feed_title"`
+	FeedSiteUrl       string     `json:"feed_site_url"`
+	IsRead            int64      `json:"is_read"`
+	IsStarred         int64      `json:"is_starred"`
 }
 
 func (q *Queries) GetArticlesByFeed(ctx context.Context, arg GetArticlesByFeedParams) ([]GetArticlesByFeedRow, error) {
@@ -445,6 +761,16 @@ func (q *Queries) GetArticlesByFeed(ctx context.Context, arg GetArticlesByFeedPa
 			&i.Summary,
 			&i.PublishedAt,
 			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DurationSeconds,
+			&i.Episode,
+			&i.Season,
+			&i.EpisodeType,
+			&i.ContentCompressed,
+			&i.EnclosureUrl,
+			&i.EnclosureType,
+			&i.ContentHash,
+			&i.Inserted,
 			&i.FeedTitle,
 			&i.FeedSiteUrl,
 			&i.IsRead,
@@ -464,7 +790,7 @@ func (q *Queries) GetArticlesByFeed(ctx context.Context, arg GetArticlesByFeedPa
 }
 
 const getCategories = `-- name: GetCategories :many
-SELECT id, user_id, title, created_at, sort_order FROM categories WHERE user_id = ? ORDER BY title
+SELECT id, user_id, title, created_at, sort_order, refresh_interval_minutes FROM categories WHERE user_id = ? ORDER BY title
 `
 
 func (q *Queries) GetCategories(ctx context.Context, userID string) ([]Category, error) {
@@ -482,6 +808,7 @@ func (q *Queries) GetCategories(ctx context.Context, userID string) ([]Category,
 			&i.Title,
 			&i.CreatedAt,
 			&i.SortOrder,
+			&i.RefreshIntervalMinutes,
 		); err != nil {
 			return nil, err
 		}
@@ -497,7 +824,7 @@ func (q *Queries) GetCategories(ctx context.Context, userID string) ([]Category,
 }
 
 const getCategoriesOrdered = `-- name: GetCategoriesOrdered :many
-SELECT id, user_id, title, created_at, sort_order FROM categories WHERE user_id = ? ORDER BY sort_order ASC, title ASC
+SELECT id, user_id, title, created_at, sort_order, refresh_interval_minutes FROM categories WHERE user_id = ? ORDER BY sort_order ASC, title ASC
 `
 
 func (q *Queries) GetCategoriesOrdered(ctx context.Context, userID string) ([]Category, error) {
@@ -515,6 +842,7 @@ func (q *Queries) GetCategoriesOrdered(ctx context.Context, userID string) ([]Ca
 			&i.Title,
 			&i.CreatedAt,
 			&i.SortOrder,
+			&i.RefreshIntervalMinutes,
 		); err != nil {
 			return nil, err
 		}
@@ -530,7 +858,7 @@ func (q *Queries) GetCategoriesOrdered(ctx context.Context, userID string) ([]Ca
 }
 
 const getCategory = `-- name: GetCategory :one
-SELECT id, user_id, title, created_at, sort_order FROM categories WHERE id = ? AND user_id = ?
+SELECT id, user_id, title, created_at, sort_order, refresh_interval_minutes FROM categories WHERE id = ? AND user_id = ?
 `
 
 type GetCategoryParams struct {
@@ -547,12 +875,82 @@ func (q *Queries) GetCategory(ctx context.Context, arg GetCategoryParams) (Categ
 		&i.Title,
 		&i.CreatedAt,
 		&i.SortOrder,
+		&i.RefreshIntervalMinutes,
 	)
 	return i, err
 }
 
+const getCategoryRefreshIntervals = `-- name: GetCategoryRefreshIntervals :many
+SELECT id, refresh_interval_minutes FROM categories WHERE refresh_interval_minutes IS NOT NULL
+`
+
+type GetCategoryRefreshIntervalsRow struct {
+	ID                     int64  `json:"id"`
+	RefreshIntervalMinutes *int64 `json:"refresh_interval_minutes"`
+}
+
+func (q *Queries) GetCategoryRefreshIntervals(ctx context.Context) ([]GetCategoryRefreshIntervalsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCategoryRefreshIntervals)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetCategoryRefreshIntervalsRow{}
+	for rows.Next() {
+		var i GetCategoryRefreshIntervalsRow
+		if err := rows.Scan(&i.ID, &i.RefreshIntervalMinutes); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCategoryUnreadCounts = `-- name: GetCategoryUnreadCounts :many
+SELECT COALESCE(f.category_id, 0) AS category_id, COUNT(*) AS unread_count
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE f.user_id = ? AND (s.is_read IS NULL OR (s.is_read = 0 AND s.is_hidden = 0))
+GROUP BY COALESCE(f.category_id, 0)
+`
+
+type GetCategoryUnreadCountsRow struct {
+	CategoryID  int64 `json:"category_id"`
+	UnreadCount int64 `json:"unread_count"`
+}
+
+func (q *Queries) GetCategoryUnreadCounts(ctx context.Context, userID string) ([]GetCategoryUnreadCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCategoryUnreadCounts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetCategoryUnreadCountsRow{}
+	for rows.Next() {
+		var i GetCategoryUnreadCountsRow
+		if err := rows.Scan(&i.CategoryID, &i.UnreadCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeed = `-- name: GetFeed :one
-SELECT f.id, f.user_id, f.category_id, f.url, f.title, f.site_url, f.description, f.last_updated, f.last_error, f.created_at, f.sort_order, f.etag, f.last_modified, f.error_count, c.title as category_title
+SELECT f.id, f.user_id, f.category_id, f.url, f.title, f.site_url, f.description, f.last_updated, f.last_error, f.created_at, f.sort_order, f.etag, f.last_modified, f.error_count, f.auto_read_after_days, f.empty_count, f.last_warning, f.refresh_interval_minutes, f.dedup_titles, f.insecure_skip_verify, f.last_success_at, f.last_fetch_attempts, f.fetch_attempts, f.date_source, f.keep_only_pattern, f.guid_unstable, f.dns_fail_count, f.dead, f.logo_url, c.title as category_title
 FROM feeds f
 LEFT JOIN categories c ON f.category_id = c.id
 WHERE f.id = ? AND f.user_id = ?
@@ -564,21 +962,36 @@ type GetFeedParams struct {
 }
 
 type GetFeedRow struct {
-	ID            int64      `json:"id"`
-	UserID        string     `json:"user_id"`
-	CategoryID    *int64     `json:"category_id"`
-	Url           string     `json:"url"`
-	Title         string     `json:"title"`
-	SiteUrl       string     `json:"site_url"`
-	Description   string     `json:"description"`
-	LastUpdated   *time.Time `json:"last_updated"`
-	LastError     *string    `json:"last_error"`
-	CreatedAt     time.Time  `json:"created_at"`
-	SortOrder     int64      `json:"sort_order"`
-	Etag          string     `json:"etag"`
-	LastModified  string     `json:"last_modified"`
-	ErrorCount    int64      `json:"error_count"`
-	CategoryTitle *string    `json:"category_title"`
+	ID                     int64      `json:"id"`
+	UserID                 string     `json:"user_id"`
+	CategoryID             *int64     `json:"category_id"`
+	Url                    string     `json:"url"`
+	Title                  string     `json:"title"`
+	SiteUrl                string     `json:"site_url"`
+	Description            string     `json:"description"`
+	LastUpdated            *time.Time `json:"last_updated"`
+	LastError              *string    `json:"last_error"`
+	CreatedAt              time.Time  `json:"created_at"`
+	SortOrder              int64      `json:"sort_order"`
+	Etag                   string     `json:"etag"`
+	LastModified           string     `json:"last_modified"`
+	ErrorCount             int64      `json:"error_count"`
+	AutoReadAfterDays      int64      `json:"auto_read_after_days"`
+	EmptyCount             int64      `json:"empty_count"`
+	LastWarning            *string    `json:"last_warning"`
+	RefreshIntervalMinutes *int64     `json:"refresh_interval_minutes"`
+	DedupTitles            int64      `json:"dedup_titles"`
+	InsecureSkipVerify     int64      `json:"insecure_skip_verify"`
+	LastSuccessAt          *time.Time `json:"last_success_at"`
+	LastFetchAttempts      int64      `json:"last_fetch_attempts"`
+	FetchAttempts          int64      `json:"fetch_attempts"`
+	DateSource             string     `json:"date_source"`
+	KeepOnlyPattern        string     `json:"keep_only_pattern"`
+	GuidUnstable           int64      `json:"guid_unstable"`
+	DnsFailCount           int64      `json:"dns_fail_count"`
+	Dead                   int64      `json:"dead"`
+	LogoUrl                string     `json:"logo_url"`
+	CategoryTitle          *string    `json:"category_title"`
 }
 
 func (q *Queries) GetFeed(ctx context.Context, arg GetFeedParams) (GetFeedRow, error) {
@@ -599,13 +1012,62 @@ func (q *Queries) GetFeed(ctx context.Context, arg GetFeedParams) (GetFeedRow, e
 		&i.Etag,
 		&i.LastModified,
 		&i.ErrorCount,
+		&i.AutoReadAfterDays,
+		&i.EmptyCount,
+		&i.LastWarning,
+		&i.RefreshIntervalMinutes,
+		&i.DedupTitles,
+		&i.InsecureSkipVerify,
+		&i.LastSuccessAt,
+		&i.LastFetchAttempts,
+		&i.FetchAttempts,
+		&i.DateSource,
+		&i.KeepOnlyPattern,
+		&i.GuidUnstable,
+		&i.DnsFailCount,
+		&i.Dead,
+		&i.LogoUrl,
 		&i.CategoryTitle,
 	)
 	return i, err
 }
 
+const getFeedActivity = `-- name: GetFeedActivity :many
+SELECT feed_id, COUNT(*) AS article_count FROM articles
+WHERE created_at >= datetime('now', CAST(?1 AS TEXT))
+GROUP BY feed_id
+`
+
+type GetFeedActivityRow struct {
+	FeedID       int64 `json:"feed_id"`
+	ArticleCount int64 `json:"article_count"`
+}
+
+func (q *Queries) GetFeedActivity(ctx context.Context, maxAge string) ([]GetFeedActivityRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedActivity, maxAge)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetFeedActivityRow{}
+	for rows.Next() {
+		var i GetFeedActivityRow
+		if err := rows.Scan(&i.FeedID, &i.ArticleCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, user_id, category_id, url, title, site_url, description, last_updated, last_error, created_at, sort_order, etag, last_modified, error_count FROM feeds WHERE user_id = ? AND url = ?
+SELECT id, user_id, category_id, url, title, site_url, description, last_updated, last_error, created_at, sort_order, etag, last_modified, error_count, auto_read_after_days, empty_count, last_warning, refresh_interval_minutes, dedup_titles, insecure_skip_verify, last_success_at, last_fetch_attempts, fetch_attempts, date_source, keep_only_pattern, guid_unstable, dns_fail_count, dead, logo_url FROM feeds WHERE user_id = ? AND url = ?
 `
 
 type GetFeedByURLParams struct {
@@ -631,15 +1093,107 @@ func (q *Queries) GetFeedByURL(ctx context.Context, arg GetFeedByURLParams) (Fee
 		&i.Etag,
 		&i.LastModified,
 		&i.ErrorCount,
+		&i.AutoReadAfterDays,
+		&i.EmptyCount,
+		&i.LastWarning,
+		&i.RefreshIntervalMinutes,
+		&i.DedupTitles,
+		&i.InsecureSkipVerify,
+		&i.LastSuccessAt,
+		&i.LastFetchAttempts,
+		&i.FetchAttempts,
+		&i.DateSource,
+		&i.KeepOnlyPattern,
+		&i.GuidUnstable,
+		&i.DnsFailCount,
+		&i.Dead,
+		&i.LogoUrl,
 	)
 	return i, err
 }
 
+const getFeedReadCounts = `-- name: GetFeedReadCounts :many
+SELECT a.feed_id, COUNT(*) as count
+FROM article_states s
+JOIN articles a ON s.article_id = a.id
+JOIN feeds f ON a.feed_id = f.id AND f.user_id = s.user_id
+WHERE s.user_id = ? AND s.is_read = 1
+GROUP BY a.feed_id
+`
+
+type GetFeedReadCountsRow struct {
+	FeedID int64 `json:"feed_id"`
+	Count  int64 `json:"count"`
+}
+
+func (q *Queries) GetFeedReadCounts(ctx context.Context, userID string) ([]GetFeedReadCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedReadCounts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetFeedReadCountsRow{}
+	for rows.Next() {
+		var i GetFeedReadCountsRow
+		if err := rows.Scan(&i.FeedID, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedToken = `-- name: GetFeedToken :one
+
+SELECT token FROM feed_tokens WHERE user_id = ?
+`
+
+// Feed token queries
+func (q *Queries) GetFeedToken(ctx context.Context, userID string) (string, error) {
+	row := q.db.QueryRowContext(ctx, getFeedToken, userID)
+	var token string
+	err := row.Scan(&token)
+	return token, err
+}
+
+const getFeedUserIDs = `-- name: GetFeedUserIDs :many
+SELECT DISTINCT user_id FROM feeds ORDER BY user_id
+`
+
+func (q *Queries) GetFeedUserIDs(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedUserIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var user_id string
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeeds = `-- name: GetFeeds :many
-SELECT f.id, f.user_id, f.category_id, f.url, f.title, f.site_url, f.description, f.last_updated, f.last_error, f.created_at, f.sort_order, f.etag, f.last_modified, f.error_count, c.title as category_title,
+SELECT f.id, f.user_id, f.category_id, f.url, f.title, f.site_url, f.description, f.last_updated, f.last_error, f.created_at, f.sort_order, f.etag, f.last_modified, f.error_count, f.auto_read_after_days, f.empty_count, f.last_warning, f.refresh_interval_minutes, f.dedup_titles, f.insecure_skip_verify, f.last_success_at, f.last_fetch_attempts, f.fetch_attempts, f.date_source, f.keep_only_pattern, f.guid_unstable, f.dns_fail_count, f.dead, f.logo_url, c.title as category_title,
   (SELECT COUNT(*) FROM articles a 
    LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
-   WHERE a.feed_id = f.id AND (s.is_read IS NULL OR s.is_read = 0)) as unread_count
+   WHERE a.feed_id = f.id AND (s.is_read IS NULL OR (s.is_read = 0 AND s.is_hidden = 0))) as unread_count
 FROM feeds f
 LEFT JOIN categories c ON f.category_id = c.id
 WHERE f.user_id = ?
@@ -647,22 +1201,37 @@ ORDER BY f.title
 `
 
 type GetFeedsRow struct {
-	ID            int64      `json:"id"`
-	UserID        string     `json:"user_id"`
-	CategoryID    *int64     `json:"category_id"`
-	Url           string     `json:"url"`
-	Title         string     `json:"title"`
-	SiteUrl       string     `json:"site_url"`
-	Description   string     `json:"description"`
-	LastUpdated   *time.Time `json:"last_updated"`
-	LastError     *string    `json:"last_error"`
-	CreatedAt     time.Time  `json:"created_at"`
-	SortOrder     int64      `json:"sort_order"`
-	Etag          string     `json:"etag"`
-	LastModified  string     `json:"last_modified"`
-	ErrorCount    int64      `json:"error_count"`
-	CategoryTitle *string    `json:"category_title"`
-	UnreadCount   int64      `json:"unread_count"`
+	ID                     int64      `json:"id"`
+	UserID                 string     `json:"user_id"`
+	CategoryID             *int64     `json:"category_id"`
+	Url                    string     `json:"url"`
+	Title                  string     `json:"title"`
+	SiteUrl                string     `json:"site_url"`
+	Description            string     `json:"description"`
+	LastUpdated            *time.Time `json:"last_updated"`
+	LastError              *string    `json:"last_error"`
+	CreatedAt              time.Time  `json:"created_at"`
+	SortOrder              int64      `json:"sort_order"`
+	Etag                   string     `json:"etag"`
+	LastModified           string     `json:"last_modified"`
+	ErrorCount             int64      `json:"error_count"`
+	AutoReadAfterDays      int64      `json:"auto_read_after_days"`
+	EmptyCount             int64      `json:"empty_count"`
+	LastWarning            *string    `json:"last_warning"`
+	RefreshIntervalMinutes *int64     `json:"refresh_interval_minutes"`
+	DedupTitles            int64      `json:"dedup_titles"`
+	InsecureSkipVerify     int64      `json:"insecure_skip_verify"`
+	LastSuccessAt          *time.Time `json:"last_success_at"`
+	LastFetchAttempts      int64      `json:"last_fetch_attempts"`
+	FetchAttempts          int64      `json:"fetch_attempts"`
+	DateSource             string     `json:"date_source"`
+	KeepOnlyPattern        string     `json:"keep_only_pattern"`
+	GuidUnstable           int64      `json:"guid_unstable"`
+	DnsFailCount           int64      `json:"dns_fail_count"`
+	Dead                   int64      `json:"dead"`
+	LogoUrl                string     `json:"logo_url"`
+	CategoryTitle          *string    `json:"category_title"`
+	UnreadCount            int64      `json:"unread_count"`
 }
 
 func (q *Queries) GetFeeds(ctx context.Context, userID string) ([]GetFeedsRow, error) {
@@ -689,6 +1258,21 @@ func (q *Queries) GetFeeds(ctx context.Context, userID string) ([]GetFeedsRow, e
 			&i.Etag,
 			&i.LastModified,
 			&i.ErrorCount,
+			&i.AutoReadAfterDays,
+			&i.EmptyCount,
+			&i.LastWarning,
+			&i.RefreshIntervalMinutes,
+			&i.DedupTitles,
+			&i.InsecureSkipVerify,
+			&i.LastSuccessAt,
+			&i.LastFetchAttempts,
+			&i.FetchAttempts,
+			&i.DateSource,
+			&i.KeepOnlyPattern,
+			&i.GuidUnstable,
+			&i.DnsFailCount,
+			&i.Dead,
+			&i.LogoUrl,
 			&i.CategoryTitle,
 			&i.UnreadCount,
 		); err != nil {
@@ -706,7 +1290,7 @@ func (q *Queries) GetFeeds(ctx context.Context, userID string) ([]GetFeedsRow, e
 }
 
 const getFeedsOrdered = `-- name: GetFeedsOrdered :many
-SELECT id, user_id, category_id, url, title, site_url, description, last_updated, last_error, created_at, sort_order, etag, last_modified, error_count FROM feeds WHERE user_id = ? ORDER BY sort_order ASC, title ASC
+SELECT id, user_id, category_id, url, title, site_url, description, last_updated, last_error, created_at, sort_order, etag, last_modified, error_count, auto_read_after_days, empty_count, last_warning, refresh_interval_minutes, dedup_titles, insecure_skip_verify, last_success_at, last_fetch_attempts, fetch_attempts, date_source, keep_only_pattern, guid_unstable, dns_fail_count, dead, logo_url FROM feeds WHERE user_id = ? ORDER BY sort_order ASC, title ASC
 `
 
 func (q *Queries) GetFeedsOrdered(ctx context.Context, userID string) ([]Feed, error) {
@@ -733,6 +1317,21 @@ func (q *Queries) GetFeedsOrdered(ctx context.Context, userID string) ([]Feed, e
 			&i.Etag,
 			&i.LastModified,
 			&i.ErrorCount,
+			&i.AutoReadAfterDays,
+			&i.EmptyCount,
+			&i.LastWarning,
+			&i.RefreshIntervalMinutes,
+			&i.DedupTitles,
+			&i.InsecureSkipVerify,
+			&i.LastSuccessAt,
+			&i.LastFetchAttempts,
+			&i.FetchAttempts,
+			&i.DateSource,
+			&i.KeepOnlyPattern,
+			&i.GuidUnstable,
+			&i.DnsFailCount,
+			&i.Dead,
+			&i.LogoUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -747,16 +1346,178 @@ func (q *Queries) GetFeedsOrdered(ctx context.Context, userID string) ([]Feed, e
 	return items, nil
 }
 
-const getStarredArticles = `-- name: GetStarredArticles :many
-SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, f.title as feed_title, f.site_url as feed_site_url,
-  COALESCE(s.is_read, 0) as is_read,
-  COALESCE(s.is_starred, 0) as is_starred
+const getFreshCount = `-- name: GetFreshCount :one
+SELECT COUNT(*) as count
 FROM articles a
 JOIN feeds f ON a.feed_id = f.id
-JOIN article_states s ON s.article_id = a.id AND s.user_id = ?
-WHERE f.user_id = ? AND s.is_starred = 1
-ORDER BY s.starred_at DESC
-LIMIT ? OFFSET ?
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE f.user_id = ? AND (s.is_read IS NULL OR (s.is_read = 0 AND s.is_hidden = 0))
+  AND COALESCE(a.published_at, a.created_at) >= ?2
+`
+
+type GetFreshCountParams struct {
+	UserID string     `json:"user_id"`
+	Since  *time.Time `json:"since"`
+}
+
+func (q *Queries) GetFreshCount(ctx context.Context, arg GetFreshCountParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getFreshCount, arg.UserID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getReadCount = `-- name: GetReadCount :one
+SELECT COUNT(*) as count
+FROM article_states s
+JOIN articles a ON s.article_id = a.id
+JOIN feeds f ON a.feed_id = f.id
+WHERE s.user_id = ? AND s.is_read = 1
+`
+
+func (q *Queries) GetReadCount(ctx context.Context, userID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getReadCount, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getRecentArticleTitles = `-- name: GetRecentArticleTitles :many
+SELECT guid, title FROM articles
+WHERE feed_id = ? AND created_at >= datetime('now', CAST(?2 AS TEXT))
+`
+
+type GetRecentArticleTitlesParams struct {
+	FeedID int64  `json:"feed_id"`
+	MaxAge string `json:"max_age"`
+}
+
+type GetRecentArticleTitlesRow struct {
+	Guid  string `json:"guid"`
+	Title string `json:"title"`
+}
+
+func (q *Queries) GetRecentArticleTitles(ctx context.Context, arg GetRecentArticleTitlesParams) ([]GetRecentArticleTitlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentArticleTitles, arg.FeedID, arg.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetRecentArticleTitlesRow{}
+	for rows.Next() {
+		var i GetRecentArticleTitlesRow
+		if err := rows.Scan(&i.Guid, &i.Title); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentUnreadArticleURLs = `-- name: GetRecentUnreadArticleURLs :many
+SELECT a.id, a.url
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE f.user_id = ? AND (s.is_read IS NULL OR s.is_read = 0)
+  AND a.created_at >= datetime('now', CAST(?2 AS TEXT))
+`
+
+type GetRecentUnreadArticleURLsParams struct {
+	UserID string `json:"user_id"`
+	MaxAge string `json:"max_age"`
+}
+
+type GetRecentUnreadArticleURLsRow struct {
+	ID  int64  `json:"id"`
+	Url string `json:"url"`
+}
+
+func (q *Queries) GetRecentUnreadArticleURLs(ctx context.Context, arg GetRecentUnreadArticleURLsParams) ([]GetRecentUnreadArticleURLsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentUnreadArticleURLs, arg.UserID, arg.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetRecentUnreadArticleURLsRow{}
+	for rows.Next() {
+		var i GetRecentUnreadArticleURLsRow
+		if err := rows.Scan(&i.ID, &i.Url); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStarredArticleRefs = `-- name: GetStarredArticleRefs :many
+SELECT a.feed_id, a.guid, a.url, a.title, s.starred_at
+FROM article_states s
+JOIN articles a ON s.article_id = a.id
+JOIN feeds f ON a.feed_id = f.id AND f.user_id = s.user_id
+WHERE s.user_id = ? AND s.is_starred = 1
+ORDER BY s.starred_at DESC
+`
+
+type GetStarredArticleRefsRow struct {
+	FeedID    int64      `json:"feed_id"`
+	Guid      string     `json:"guid"`
+	Url       string     `json:"url"`
+	Title     string     `json:"title"`
+	StarredAt *time.Time `json:"starred_at"`
+}
+
+func (q *Queries) GetStarredArticleRefs(ctx context.Context, userID string) ([]GetStarredArticleRefsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStarredArticleRefs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetStarredArticleRefsRow{}
+	for rows.Next() {
+		var i GetStarredArticleRefsRow
+		if err := rows.Scan(
+			&i.FeedID,
+			&i.Guid,
+			&i.Url,
+			&i.Title,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStarredArticles = `-- name: GetStarredArticles :many
+SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, a.updated_at, a.duration_seconds, a.episode, a.season, a.episode_type, a.content_compressed, a.enclosure_url, a.enclosure_type, a.content_hash, a.inserted, f.title as feed_title, f.site_url as feed_site_url,
+  COALESCE(s.is_read, 0) as is_read,
+  COALESCE(s.is_starred, 0) as is_starred
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+JOIN article_states s ON s.article_id = a.id AND s.user_id = ?
+WHERE f.user_id = ? AND s.is_starred = 1
+ORDER BY s.starred_at DESC
+LIMIT ? OFFSET ?
 `
 
 type GetStarredArticlesParams struct {
@@ -767,20 +1528,30 @@ type GetStarredArticlesParams struct {
 }
 
 type GetStarredArticlesRow struct {
-	ID          int64      `json:"id"`
-	FeedID      int64      `json:"feed_id"`
-	Guid        string     `json:"guid"`
-	Url         string     `json:"url"`
-	Title       string     `json:"title"`
-	Author      string     `json:"author"`
-	Content     string     `json:"content"`
-	Summary     string     `json:"summary"`
-	PublishedAt *time.Time `json:"published_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	FeedTitle   string     `json:"feed_title"`
-	FeedSiteUrl string     `json:"feed_site_url"`
-	IsRead      int64      `json:"is_read"`
-	IsStarred   int64      `json:"is_starred"`
+	ID                int64      `json:"id"`
+	FeedID            int64      `json:"feed_id"`
+	Guid              string     `json:"guid"`
+	Url               string     `json:"url"`
+	Title             string     `json:"title"`
+	Author            string     `json:"author"`
+	Content           string     `json:"content"`
+	Summary           string     `json:"summary"`
+	PublishedAt       *time.Time `json:"published_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+	DurationSeconds   *int64     `json:"duration_seconds"`
+	Episode           *int64     `json:"episode"`
+	Season            *int64     `json:"season"`
+	EpisodeType       *string    `json:"episode_type"`
+	ContentCompressed int64      `json:"content_compressed"`
+	EnclosureUrl      *string    `json:"enclosure_url"`
+	EnclosureType     *string    `json:"enclosure_type"`
+	ContentHash       string     `json:"content_hash"`
+	Inserted          int64      `json:"inserted"`
+	FeedTitle         string     `json:"feed_title"`
+	FeedSiteUrl       string     `json:"feed_site_url"`
+	IsRead            int64      `json:"is_read"`
+	IsStarred         int64      `json:"is_starred"`
 }
 
 func (q *Queries) GetStarredArticles(ctx context.Context, arg GetStarredArticlesParams) ([]GetStarredArticlesRow, error) {
@@ -808,6 +1579,16 @@ func (q *Queries) GetStarredArticles(ctx context.Context, arg GetStarredArticles
 			&i.Summary,
 			&i.PublishedAt,
 			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DurationSeconds,
+			&i.Episode,
+			&i.Season,
+			&i.EpisodeType,
+			&i.ContentCompressed,
+			&i.EnclosureUrl,
+			&i.EnclosureType,
+			&i.ContentHash,
+			&i.Inserted,
 			&i.FeedTitle,
 			&i.FeedSiteUrl,
 			&i.IsRead,
@@ -856,7 +1637,7 @@ func (q *Queries) GetTotalArticleCount(ctx context.Context, userID string) (int6
 }
 
 const getUnreadArticles = `-- name: GetUnreadArticles :many
-SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, f.title as feed_title, f.site_url as feed_site_url,
+SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, a.updated_at, a.duration_seconds, a.episode, a.season, a.episode_type, a.content_compressed, a.enclosure_url, a.enclosure_type, a.content_hash, a.inserted, f.title as feed_title, f.site_url as feed_site_url,
   COALESCE(s.is_read, 0) as is_read,
   COALESCE(s.is_starred, 0) as is_starred
 FROM articles a
@@ -875,20 +1656,30 @@ type GetUnreadArticlesParams struct {
 }
 
 type GetUnreadArticlesRow struct {
-	ID          int64      `json:"id"`
-	FeedID      int64      `json:"feed_id"`
-	Guid        string     `json:"guid"`
-	Url         string     `json:"url"`
-	Title       string     `json:"title"`
-	Author      string     `json:"author"`
-	Content     string     `json:"content"`
-	Summary     string     `json:"summary"`
-	PublishedAt *time.Time `json:"published_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	FeedTitle   string     `json:"feed_title"`
-	FeedSiteUrl string     `json:"feed_site_url"`
-	IsRead      int64      `json:"is_read"`
-	IsStarred   int64      `json:"is_starred"`
+	ID                int64      `json:"id"`
+	FeedID            int64      `json:"feed_id"`
+	Guid              string     `json:"guid"`
+	Url               string     `json:"url"`
+	Title             string     `json:"title"`
+	Author            string     `json:"author"`
+	Content           string     `json:"content"`
+	Summary           string     `json:"summary"`
+	PublishedAt       *time.Time `json:"published_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+	DurationSeconds   *int64     `json:"duration_seconds"`
+	Episode           *int64     `json:"episode"`
+	Season            *int64     `json:"season"`
+	EpisodeType       *string    `json:"episode_type"`
+	ContentCompressed int64      `json:"content_compressed"`
+	EnclosureUrl      *string    `json:"enclosure_url"`
+	EnclosureType     *string    `json:"enclosure_type"`
+	ContentHash       string     `json:"content_hash"`
+	Inserted          int64      `json:"inserted"`
+	FeedTitle         string     `json:"feed_title"`
+	FeedSiteUrl       string     `json:"feed_site_url"`
+	IsRead            int64      `json:"is_read"`
+	IsStarred         int64      `json:"is_starred"`
 }
 
 func (q *Queries) GetUnreadArticles(ctx context.Context, arg GetUnreadArticlesParams) ([]GetUnreadArticlesRow, error) {
@@ -916,6 +1707,16 @@ func (q *Queries) GetUnreadArticles(ctx context.Context, arg GetUnreadArticlesPa
 			&i.Summary,
 			&i.PublishedAt,
 			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DurationSeconds,
+			&i.Episode,
+			&i.Season,
+			&i.EpisodeType,
+			&i.ContentCompressed,
+			&i.EnclosureUrl,
+			&i.EnclosureType,
+			&i.ContentHash,
+			&i.Inserted,
 			&i.FeedTitle,
 			&i.FeedSiteUrl,
 			&i.IsRead,
@@ -940,7 +1741,7 @@ SELECT COUNT(*) as count
 FROM articles a
 JOIN feeds f ON a.feed_id = f.id
 LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
-WHERE f.user_id = ? AND (s.is_read IS NULL OR s.is_read = 0)
+WHERE f.user_id = ? AND (s.is_read IS NULL OR (s.is_read = 0 AND s.is_hidden = 0))
 `
 
 // Stats queries
@@ -967,15 +1768,106 @@ func (q *Queries) GetUser(ctx context.Context, id string) (User, error) {
 	return i, err
 }
 
-const markAllRead = `-- name: MarkAllRead :exec
+const getUserByFeedToken = `-- name: GetUserByFeedToken :one
+SELECT user_id FROM feed_tokens WHERE token = ?
+`
+
+func (q *Queries) GetUserByFeedToken(ctx context.Context, token string) (string, error) {
+	row := q.db.QueryRowContext(ctx, getUserByFeedToken, token)
+	var user_id string
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const getUserByFeverKey = `-- name: GetUserByFeverKey :one
+SELECT user_id FROM fever_keys WHERE api_key = ?
+`
+
+func (q *Queries) GetUserByFeverKey(ctx context.Context, apiKey string) (string, error) {
+	row := q.db.QueryRowContext(ctx, getUserByFeverKey, apiKey)
+	var user_id string
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const getUserPrefs = `-- name: GetUserPrefs :one
+
+SELECT user_id, default_category_id FROM user_prefs WHERE user_id = ?
+`
+
+// User preference queries
+func (q *Queries) GetUserPrefs(ctx context.Context, userID string) (UserPref, error) {
+	row := q.db.QueryRowContext(ctx, getUserPrefs, userID)
+	var i UserPref
+	err := row.Scan(&i.UserID, &i.DefaultCategoryID)
+	return i, err
+}
+
+const indexArticleContent = `-- name: IndexArticleContent :exec
+UPDATE articles_fts SET title = ?, content = ?, summary = ? WHERE rowid = ?
+`
+
+type IndexArticleContentParams struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Summary string `json:"summary"`
+	Rowid   int64  `json:"rowid"`
+}
+
+// Sets an article's search index entry, for content stored compressed,
+// which the articles_fts triggers index as empty.
+func (q *Queries) IndexArticleContent(ctx context.Context, arg IndexArticleContentParams) error {
+	_, err := q.db.ExecContext(ctx, indexArticleContent,
+		arg.Title,
+		arg.Content,
+		arg.Summary,
+		arg.Rowid,
+	)
+	return err
+}
+
+const insertFeverKey = `-- name: InsertFeverKey :exec
+INSERT INTO fever_keys (api_key, user_id) VALUES (?, ?)
+`
+
+type InsertFeverKeyParams struct {
+	ApiKey string `json:"api_key"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) InsertFeverKey(ctx context.Context, arg InsertFeverKeyParams) error {
+	_, err := q.db.ExecContext(ctx, insertFeverKey, arg.ApiKey, arg.UserID)
+	return err
+}
+
+const insertReadUndo = `-- name: InsertReadUndo :exec
+
+INSERT INTO read_undo (token, user_id, article_id) VALUES (?, ?, ?)
+`
+
+type InsertReadUndoParams struct {
+	Token     string `json:"token"`
+	UserID    string `json:"user_id"`
+	ArticleID int64  `json:"article_id"`
+}
+
+// Read undo log
+func (q *Queries) InsertReadUndo(ctx context.Context, arg InsertReadUndoParams) error {
+	_, err := q.db.ExecContext(ctx, insertReadUndo, arg.Token, arg.UserID, arg.ArticleID)
+	return err
+}
+
+const markAllRead = `-- name: MarkAllRead :many
 INSERT INTO article_states (user_id, article_id, is_read, read_at)
 SELECT ?, a.id, 1, ?
 FROM articles a
 JOIN feeds f ON a.feed_id = f.id
-WHERE f.user_id = ?
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE f.user_id = ? AND (s.is_read IS NULL OR s.is_read = 0)
 ON CONFLICT (user_id, article_id) DO UPDATE SET
   is_read = 1,
   read_at = excluded.read_at
+RETURNING article_id
 `
 
 type MarkAllReadParams struct {
@@ -984,29 +1876,86 @@ type MarkAllReadParams struct {
 	UserID_2 string     `json:"user_id_2"`
 }
 
-func (q *Queries) MarkAllRead(ctx context.Context, arg MarkAllReadParams) error {
-	_, err := q.db.ExecContext(ctx, markAllRead, arg.UserID, arg.ReadAt, arg.UserID_2)
-	return err
+func (q *Queries) MarkAllRead(ctx context.Context, arg MarkAllReadParams) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, markAllRead, arg.UserID, arg.ReadAt, arg.UserID_2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var article_id int64
+		if err := rows.Scan(&article_id); err != nil {
+			return nil, err
+		}
+		items = append(items, article_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const markFeedRead = `-- name: MarkFeedRead :exec
+const markFeedRead = `-- name: MarkFeedRead :many
+
 INSERT INTO article_states (user_id, article_id, is_read, read_at)
 SELECT ?, a.id, 1, ?
 FROM articles a
-WHERE a.feed_id = ?
+JOIN feeds f ON a.feed_id = f.id
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE a.feed_id = ? AND f.user_id = ? AND (s.is_read IS NULL OR s.is_read = 0)
 ON CONFLICT (user_id, article_id) DO UPDATE SET
   is_read = 1,
   read_at = excluded.read_at
+RETURNING article_id
 `
 
 type MarkFeedReadParams struct {
-	UserID string     `json:"user_id"`
-	ReadAt *time.Time `json:"read_at"`
-	FeedID int64      `json:"feed_id"`
+	UserID   string     `json:"user_id"`
+	ReadAt   *time.Time `json:"read_at"`
+	FeedID   int64      `json:"feed_id"`
+	UserID_2 string     `json:"user_id_2"`
 }
 
-func (q *Queries) MarkFeedRead(ctx context.Context, arg MarkFeedReadParams) error {
-	_, err := q.db.ExecContext(ctx, markFeedRead, arg.UserID, arg.ReadAt, arg.FeedID)
+// Only currently-unread articles are touched; the returned ids are exactly
+// the articles that were flipped to read (used for counts and undo).
+func (q *Queries) MarkFeedRead(ctx context.Context, arg MarkFeedReadParams) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, markFeedRead,
+		arg.UserID,
+		arg.ReadAt,
+		arg.FeedID,
+		arg.UserID_2,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var article_id int64
+		if err := rows.Scan(&article_id); err != nil {
+			return nil, err
+		}
+		items = append(items, article_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeExpiredReadUndo = `-- name: PurgeExpiredReadUndo :exec
+DELETE FROM read_undo WHERE created_at <= datetime('now', CAST(?1 AS TEXT))
+`
+
+func (q *Queries) PurgeExpiredReadUndo(ctx context.Context, maxAge string) error {
+	_, err := q.db.ExecContext(ctx, purgeExpiredReadUndo, maxAge)
 	return err
 }
 
@@ -1026,89 +1975,42 @@ func (q *Queries) PurgeOldReadArticles(ctx context.Context, publishedAt *time.Ti
 	return q.db.ExecContext(ctx, purgeOldReadArticles, publishedAt)
 }
 
-const searchArticles = `-- name: SearchArticles :many
-SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at, f.title as feed_title, f.site_url as feed_site_url,
-  COALESCE(s.is_read, 0) as is_read,
-  COALESCE(s.is_starred, 0) as is_starred
-FROM articles a
-JOIN feeds f ON a.feed_id = f.id
-LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = ?
-WHERE f.user_id = ? AND (a.title LIKE ? OR a.content LIKE ? OR a.summary LIKE ?)
-ORDER BY a.published_at DESC
-LIMIT ? OFFSET ?
+const setArticleHidden = `-- name: SetArticleHidden :exec
+INSERT INTO article_states (user_id, article_id, is_hidden)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, article_id) DO UPDATE SET
+  is_hidden = excluded.is_hidden
 `
 
-type SearchArticlesParams struct {
-	UserID   string `json:"user_id"`
-	UserID_2 string `json:"user_id_2"`
-	Title    string `json:"title"`
-	Content  string `json:"content"`
-	Summary  string `json:"summary"`
-	Limit    int64  `json:"limit"`
-	Offset   int64  `json:"offset"`
+type SetArticleHiddenParams struct {
+	UserID    string `json:"user_id"`
+	ArticleID int64  `json:"article_id"`
+	IsHidden  int64  `json:"is_hidden"`
 }
 
-type SearchArticlesRow struct {
-	ID          int64      `json:"id"`
-	FeedID      int64      `json:"feed_id"`
-	Guid        string     `json:"guid"`
-	Url         string     `json:"url"`
-	Title       string     `json:"title"`
-	Author      string     `json:"author"`
-	Content     string     `json:"content"`
-	Summary     string     `json:"summary"`
-	PublishedAt *time.Time `json:"published_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	FeedTitle   string     `json:"feed_title"`
-	FeedSiteUrl string     `json:"feed_site_url"`
-	IsRead      int64      `json:"is_read"`
-	IsStarred   int64      `json:"is_starred"`
-}
-
-func (q *Queries) SearchArticles(ctx context.Context, arg SearchArticlesParams) ([]SearchArticlesRow, error) {
-	rows, err := q.db.QueryContext(ctx, searchArticles,
-		arg.UserID,
-		arg.UserID_2,
-		arg.Title,
-		arg.Content,
-		arg.Summary,
-		arg.Limit,
-		arg.Offset,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []SearchArticlesRow{}
-	for rows.Next() {
-		var i SearchArticlesRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.FeedID,
-			&i.Guid,
-			&i.Url,
-			&i.Title,
-			&i.Author,
-			&i.Content,
-			&i.Summary,
-			&i.PublishedAt,
-			&i.CreatedAt,
-			&i.FeedTitle,
-			&i.FeedSiteUrl,
-			&i.IsRead,
-			&i.IsStarred,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) SetArticleHidden(ctx context.Context, arg SetArticleHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, setArticleHidden, arg.UserID, arg.ArticleID, arg.IsHidden)
+	return err
+}
+
+const markArticleReadOnce = `-- name: MarkArticleReadOnce :exec
+INSERT INTO article_states (user_id, article_id, is_read, read_at)
+VALUES (?, ?, 1, ?)
+ON CONFLICT (user_id, article_id) DO UPDATE SET
+  is_read = 1,
+  read_at = CASE WHEN article_states.is_read = 1 THEN article_states.read_at ELSE excluded.read_at END
+`
+
+type MarkArticleReadOnceParams struct {
+	UserID    string     `json:"user_id"`
+	ArticleID int64      `json:"article_id"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+// Like SetArticleRead, but an article that is already read keeps its read_at.
+func (q *Queries) MarkArticleReadOnce(ctx context.Context, arg MarkArticleReadOnceParams) error {
+	_, err := q.db.ExecContext(ctx, markArticleReadOnce, arg.UserID, arg.ArticleID, arg.ReadAt)
+	return err
 }
 
 const setArticleRead = `-- name: SetArticleRead :exec
@@ -1187,6 +2089,119 @@ func (q *Queries) SetArticleUnstarred(ctx context.Context, arg SetArticleUnstarr
 	return err
 }
 
+const setDefaultCategory = `-- name: SetDefaultCategory :exec
+INSERT INTO user_prefs (user_id, default_category_id) VALUES (?, ?)
+ON CONFLICT (user_id) DO UPDATE SET
+  default_category_id = excluded.default_category_id
+`
+
+type SetDefaultCategoryParams struct {
+	UserID            string `json:"user_id"`
+	DefaultCategoryID *int64 `json:"default_category_id"`
+}
+
+func (q *Queries) SetDefaultCategory(ctx context.Context, arg SetDefaultCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, setDefaultCategory, arg.UserID, arg.DefaultCategoryID)
+	return err
+}
+
+const setFeedToken = `-- name: SetFeedToken :exec
+INSERT INTO feed_tokens (user_id, token) VALUES (?, ?)
+ON CONFLICT (user_id) DO UPDATE SET
+  token = excluded.token,
+  created_at = CURRENT_TIMESTAMP
+`
+
+type SetFeedTokenParams struct {
+	UserID string `json:"user_id"`
+	Token  string `json:"token"`
+}
+
+func (q *Queries) SetFeedToken(ctx context.Context, arg SetFeedTokenParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedToken, arg.UserID, arg.Token)
+	return err
+}
+
+const starArticleByGUID = `-- name: StarArticleByGUID :exec
+INSERT INTO article_states (user_id, article_id, is_starred, starred_at)
+SELECT f.user_id, a.id, 1, ?
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+WHERE a.feed_id = ? AND a.guid = ?
+ON CONFLICT (user_id, article_id) DO UPDATE SET
+  is_starred = 1,
+  starred_at = excluded.starred_at
+`
+
+type StarArticleByGUIDParams struct {
+	StarredAt *time.Time `json:"starred_at"`
+	FeedID    int64      `json:"feed_id"`
+	Guid      string     `json:"guid"`
+}
+
+// Stars a feed's article by GUID for the feed's owner, if it is stored.
+func (q *Queries) StarArticleByGUID(ctx context.Context, arg StarArticleByGUIDParams) error {
+	_, err := q.db.ExecContext(ctx, starArticleByGUID, arg.StarredAt, arg.FeedID, arg.Guid)
+	return err
+}
+
+const undoRead = `-- name: UndoRead :execresult
+UPDATE article_states SET is_read = 0, read_at = NULL
+WHERE article_states.is_read = 1 AND EXISTS (
+  SELECT 1 FROM read_undo u
+  WHERE u.token = ?1 AND u.user_id = article_states.user_id
+    AND u.article_id = article_states.article_id
+    AND u.created_at > datetime('now', CAST(?2 AS TEXT))
+) AND article_states.user_id = ?3
+`
+
+type UndoReadParams struct {
+	Token  string `json:"token"`
+	MaxAge string `json:"max_age"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) UndoRead(ctx context.Context, arg UndoReadParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, undoRead, arg.Token, arg.MaxAge, arg.UserID)
+}
+
+const uncategorizeFeeds = `-- name: UncategorizeFeeds :exec
+UPDATE feeds SET category_id = NULL WHERE category_id = ? AND user_id = ?
+`
+
+type UncategorizeFeedsParams struct {
+	CategoryID *int64 `json:"category_id"`
+	UserID     string `json:"user_id"`
+}
+
+func (q *Queries) UncategorizeFeeds(ctx context.Context, arg UncategorizeFeedsParams) error {
+	_, err := q.db.ExecContext(ctx, uncategorizeFeeds, arg.CategoryID, arg.UserID)
+	return err
+}
+
+const updateArticleContent = `-- name: UpdateArticleContent :exec
+UPDATE articles SET title = ?, content = ?, content_compressed = ?, summary = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+`
+
+type UpdateArticleContentParams struct {
+	Title             string `json:"title"`
+	Content           string `json:"content"`
+	ContentCompressed int64  `json:"content_compressed"`
+	Summary           string `json:"summary"`
+	ID                int64  `json:"id"`
+}
+
+func (q *Queries) UpdateArticleContent(ctx context.Context, arg UpdateArticleContentParams) error {
+	_, err := q.db.ExecContext(ctx, updateArticleContent,
+		arg.Title,
+		arg.Content,
+		arg.ContentCompressed,
+		arg.Summary,
+		arg.ID,
+	)
+	return err
+}
+
 const updateCategory = `-- name: UpdateCategory :exec
 UPDATE categories SET title = ? WHERE id = ? AND user_id = ?
 `
@@ -1202,6 +2217,21 @@ func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams)
 	return err
 }
 
+const updateCategoryRefreshInterval = `-- name: UpdateCategoryRefreshInterval :exec
+UPDATE categories SET refresh_interval_minutes = ? WHERE id = ? AND user_id = ?
+`
+
+type UpdateCategoryRefreshIntervalParams struct {
+	RefreshIntervalMinutes *int64 `json:"refresh_interval_minutes"`
+	ID                     int64  `json:"id"`
+	UserID                 string `json:"user_id"`
+}
+
+func (q *Queries) UpdateCategoryRefreshInterval(ctx context.Context, arg UpdateCategoryRefreshIntervalParams) error {
+	_, err := q.db.ExecContext(ctx, updateCategoryRefreshInterval, arg.RefreshIntervalMinutes, arg.ID, arg.UserID)
+	return err
+}
+
 const updateCategorySortOrder = `-- name: UpdateCategorySortOrder :exec
 UPDATE categories SET sort_order = ? WHERE id = ? AND user_id = ?
 `
@@ -1251,6 +2281,21 @@ func (q *Queries) UpdateFeed(ctx context.Context, arg UpdateFeedParams) error {
 	return err
 }
 
+const updateFeedAutoRead = `-- name: UpdateFeedAutoRead :exec
+UPDATE feeds SET auto_read_after_days = ? WHERE id = ? AND user_id = ?
+`
+
+type UpdateFeedAutoReadParams struct {
+	AutoReadAfterDays int64  `json:"auto_read_after_days"`
+	ID                int64  `json:"id"`
+	UserID            string `json:"user_id"`
+}
+
+func (q *Queries) UpdateFeedAutoRead(ctx context.Context, arg UpdateFeedAutoReadParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedAutoRead, arg.AutoReadAfterDays, arg.ID, arg.UserID)
+	return err
+}
+
 const updateFeedCategory = `-- name: UpdateFeedCategory :exec
 UPDATE feeds SET category_id = ?, sort_order = ? WHERE id = ? AND user_id = ?
 `
@@ -1272,6 +2317,66 @@ func (q *Queries) UpdateFeedCategory(ctx context.Context, arg UpdateFeedCategory
 	return err
 }
 
+const updateFeedDNSFailures = `-- name: UpdateFeedDNSFailures :exec
+UPDATE feeds SET dns_fail_count = ?, dead = ? WHERE id = ?
+`
+
+type UpdateFeedDNSFailuresParams struct {
+	DnsFailCount int64 `json:"dns_fail_count"`
+	Dead         int64 `json:"dead"`
+	ID           int64 `json:"id"`
+}
+
+func (q *Queries) UpdateFeedDNSFailures(ctx context.Context, arg UpdateFeedDNSFailuresParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedDNSFailures, arg.DnsFailCount, arg.Dead, arg.ID)
+	return err
+}
+
+const updateFeedDateSource = `-- name: UpdateFeedDateSource :exec
+UPDATE feeds SET date_source = ? WHERE id = ? AND user_id = ?
+`
+
+type UpdateFeedDateSourceParams struct {
+	DateSource string `json:"date_source"`
+	ID         int64  `json:"id"`
+	UserID     string `json:"user_id"`
+}
+
+func (q *Queries) UpdateFeedDateSource(ctx context.Context, arg UpdateFeedDateSourceParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedDateSource, arg.DateSource, arg.ID, arg.UserID)
+	return err
+}
+
+const updateFeedDead = `-- name: UpdateFeedDead :exec
+UPDATE feeds SET dead = ?, dns_fail_count = 0 WHERE id = ? AND user_id = ?
+`
+
+type UpdateFeedDeadParams struct {
+	Dead   int64  `json:"dead"`
+	ID     int64  `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) UpdateFeedDead(ctx context.Context, arg UpdateFeedDeadParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedDead, arg.Dead, arg.ID, arg.UserID)
+	return err
+}
+
+const updateFeedDedupTitles = `-- name: UpdateFeedDedupTitles :exec
+UPDATE feeds SET dedup_titles = ? WHERE id = ? AND user_id = ?
+`
+
+type UpdateFeedDedupTitlesParams struct {
+	DedupTitles int64  `json:"dedup_titles"`
+	ID          int64  `json:"id"`
+	UserID      string `json:"user_id"`
+}
+
+func (q *Queries) UpdateFeedDedupTitles(ctx context.Context, arg UpdateFeedDedupTitlesParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedDedupTitles, arg.DedupTitles, arg.ID, arg.UserID)
+	return err
+}
+
 const updateFeedDetails = `-- name: UpdateFeedDetails :exec
 UPDATE feeds SET title = ?, url = ? WHERE id = ? AND user_id = ?
 `
@@ -1293,11 +2398,101 @@ func (q *Queries) UpdateFeedDetails(ctx context.Context, arg UpdateFeedDetailsPa
 	return err
 }
 
+const updateFeedEmptyStatus = `-- name: UpdateFeedEmptyStatus :exec
+UPDATE feeds SET empty_count = ?, last_warning = ? WHERE id = ?
+`
+
+type UpdateFeedEmptyStatusParams struct {
+	EmptyCount  int64   `json:"empty_count"`
+	LastWarning *string `json:"last_warning"`
+	ID          int64   `json:"id"`
+}
+
+func (q *Queries) UpdateFeedEmptyStatus(ctx context.Context, arg UpdateFeedEmptyStatusParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedEmptyStatus, arg.EmptyCount, arg.LastWarning, arg.ID)
+	return err
+}
+
+const updateFeedFetchAttempts = `-- name: UpdateFeedFetchAttempts :exec
+UPDATE feeds SET last_fetch_attempts = ?, fetch_attempts = fetch_attempts + ? WHERE id = ?
+`
+
+type UpdateFeedFetchAttemptsParams struct {
+	LastFetchAttempts int64 `json:"last_fetch_attempts"`
+	FetchAttempts     int64 `json:"fetch_attempts"`
+	ID                int64 `json:"id"`
+}
+
+func (q *Queries) UpdateFeedFetchAttempts(ctx context.Context, arg UpdateFeedFetchAttemptsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedFetchAttempts, arg.LastFetchAttempts, arg.FetchAttempts, arg.ID)
+	return err
+}
+
+const updateFeedGuidUnstable = `-- name: UpdateFeedGuidUnstable :exec
+UPDATE feeds SET guid_unstable = ? WHERE id = ? AND user_id = ?
+`
+
+type UpdateFeedGuidUnstableParams struct {
+	GuidUnstable int64  `json:"guid_unstable"`
+	ID           int64  `json:"id"`
+	UserID       string `json:"user_id"`
+}
+
+func (q *Queries) UpdateFeedGuidUnstable(ctx context.Context, arg UpdateFeedGuidUnstableParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedGuidUnstable, arg.GuidUnstable, arg.ID, arg.UserID)
+	return err
+}
+
+const updateFeedInsecureSkipVerify = `-- name: UpdateFeedInsecureSkipVerify :exec
+UPDATE feeds SET insecure_skip_verify = ? WHERE id = ? AND user_id = ?
+`
+
+type UpdateFeedInsecureSkipVerifyParams struct {
+	InsecureSkipVerify int64  `json:"insecure_skip_verify"`
+	ID                 int64  `json:"id"`
+	UserID             string `json:"user_id"`
+}
+
+func (q *Queries) UpdateFeedInsecureSkipVerify(ctx context.Context, arg UpdateFeedInsecureSkipVerifyParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedInsecureSkipVerify, arg.InsecureSkipVerify, arg.ID, arg.UserID)
+	return err
+}
+
+const updateFeedKeepOnly = `-- name: UpdateFeedKeepOnly :exec
+UPDATE feeds SET keep_only_pattern = ? WHERE id = ? AND user_id = ?
+`
+
+type UpdateFeedKeepOnlyParams struct {
+	KeepOnlyPattern string `json:"keep_only_pattern"`
+	ID              int64  `json:"id"`
+	UserID          string `json:"user_id"`
+}
+
+func (q *Queries) UpdateFeedKeepOnly(ctx context.Context, arg UpdateFeedKeepOnlyParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedKeepOnly, arg.KeepOnlyPattern, arg.ID, arg.UserID)
+	return err
+}
+
+const updateFeedLastSuccess = `-- name: UpdateFeedLastSuccess :exec
+UPDATE feeds SET last_success_at = ? WHERE id = ?
+`
+
+type UpdateFeedLastSuccessParams struct {
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	ID            int64      `json:"id"`
+}
+
+func (q *Queries) UpdateFeedLastSuccess(ctx context.Context, arg UpdateFeedLastSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedLastSuccess, arg.LastSuccessAt, arg.ID)
+	return err
+}
+
 const updateFeedMeta = `-- name: UpdateFeedMeta :exec
 UPDATE feeds SET
   title = ?,
   site_url = ?,
   description = ?,
+  logo_url = ?,
   last_updated = ?,
   last_error = ?,
   etag = ?,
@@ -1310,6 +2505,7 @@ type UpdateFeedMetaParams struct {
 	Title        string     `json:"title"`
 	SiteUrl      string     `json:"site_url"`
 	Description  string     `json:"description"`
+	LogoUrl      string     `json:"logo_url"`
 	LastUpdated  *time.Time `json:"last_updated"`
 	LastError    *string    `json:"last_error"`
 	Etag         string     `json:"etag"`
@@ -1323,6 +2519,7 @@ func (q *Queries) UpdateFeedMeta(ctx context.Context, arg UpdateFeedMetaParams)
 		arg.Title,
 		arg.SiteUrl,
 		arg.Description,
+		arg.LogoUrl,
 		arg.LastUpdated,
 		arg.LastError,
 		arg.Etag,
@@ -1333,6 +2530,21 @@ func (q *Queries) UpdateFeedMeta(ctx context.Context, arg UpdateFeedMetaParams)
 	return err
 }
 
+const updateFeedRefreshInterval = `-- name: UpdateFeedRefreshInterval :exec
+UPDATE feeds SET refresh_interval_minutes = ? WHERE id = ? AND user_id = ?
+`
+
+type UpdateFeedRefreshIntervalParams struct {
+	RefreshIntervalMinutes *int64 `json:"refresh_interval_minutes"`
+	ID                     int64  `json:"id"`
+	UserID                 string `json:"user_id"`
+}
+
+func (q *Queries) UpdateFeedRefreshInterval(ctx context.Context, arg UpdateFeedRefreshIntervalParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedRefreshInterval, arg.RefreshIntervalMinutes, arg.ID, arg.UserID)
+	return err
+}
+
 const updateFeedSortOrder = `-- name: UpdateFeedSortOrder :exec
 UPDATE feeds SET sort_order = ? WHERE id = ? AND user_id = ?
 `
@@ -1350,27 +2562,50 @@ func (q *Queries) UpdateFeedSortOrder(ctx context.Context, arg UpdateFeedSortOrd
 
 const upsertArticle = `-- name: UpsertArticle :one
 
-INSERT INTO articles (feed_id, guid, url, title, author, content, summary, published_at)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+INSERT INTO articles (feed_id, guid, url, title, author, content, content_compressed, summary, published_at,
+  duration_seconds, episode, season, episode_type, enclosure_url, enclosure_type, content_hash, updated_at, inserted)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, 1)
 ON CONFLICT (feed_id, guid) DO UPDATE SET
   url = excluded.url,
   title = excluded.title,
   author = excluded.author,
   content = excluded.content,
+  content_compressed = excluded.content_compressed,
   summary = excluded.summary,
-  published_at = excluded.published_at
-RETURNING id, feed_id, guid, url, title, author, content, summary, published_at, created_at
+  published_at = excluded.published_at,
+  duration_seconds = excluded.duration_seconds,
+  episode = excluded.episode,
+  season = excluded.season,
+  episode_type = excluded.episode_type,
+  enclosure_url = excluded.enclosure_url,
+  enclosure_type = excluded.enclosure_type,
+  content_hash = excluded.content_hash,
+  updated_at = CASE
+    WHEN articles.url IS NOT excluded.url OR articles.title IS NOT excluded.title
+      OR articles.author IS NOT excluded.author OR articles.content IS NOT excluded.content
+      OR articles.summary IS NOT excluded.summary
+    THEN CURRENT_TIMESTAMP ELSE articles.updated_at END,
+  inserted = 0
+RETURNING id, feed_id, guid, url, title, author, content, summary, published_at, created_at, updated_at, duration_seconds, episode, season, episode_type, content_compressed, enclosure_url, enclosure_type, content_hash, inserted
 `
 
 type UpsertArticleParams struct {
-	FeedID      int64      `json:"feed_id"`
-	Guid        string     `json:"guid"`
-	Url         string     `json:"url"`
-	Title       string     `json:"title"`
-	Author      string     `json:"author"`
-	Content     string     `json:"content"`
-	Summary     string     `json:"summary"`
-	PublishedAt *time.Time `json:"published_at"`
+	FeedID            int64      `json:"feed_id"`
+	Guid              string     `json:"guid"`
+	Url               string     `json:"url"`
+	Title             string     `json:"title"`
+	Author            string     `json:"author"`
+	Content           string     `json:"content"`
+	ContentCompressed int64      `json:"content_compressed"`
+	Summary           string     `json:"summary"`
+	PublishedAt       *time.Time `json:"published_at"`
+	DurationSeconds   *int64     `json:"duration_seconds"`
+	Episode           *int64     `json:"episode"`
+	Season            *int64     `json:"season"`
+	EpisodeType       *string    `json:"episode_type"`
+	EnclosureUrl      *string    `json:"enclosure_url"`
+	EnclosureType     *string    `json:"enclosure_type"`
+	ContentHash       string     `json:"content_hash"`
 }
 
 // Article queries
@@ -1382,8 +2617,16 @@ func (q *Queries) UpsertArticle(ctx context.Context, arg UpsertArticleParams) (A
 		arg.Title,
 		arg.Author,
 		arg.Content,
+		arg.ContentCompressed,
 		arg.Summary,
 		arg.PublishedAt,
+		arg.DurationSeconds,
+		arg.Episode,
+		arg.Season,
+		arg.EpisodeType,
+		arg.EnclosureUrl,
+		arg.EnclosureType,
+		arg.ContentHash,
 	)
 	var i Article
 	err := row.Scan(
@@ -1397,6 +2640,16 @@ func (q *Queries) UpsertArticle(ctx context.Context, arg UpsertArticleParams) (A
 		&i.Summary,
 		&i.PublishedAt,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DurationSeconds,
+		&i.Episode,
+		&i.Season,
+		&i.EpisodeType,
+		&i.ContentCompressed,
+		&i.EnclosureUrl,
+		&i.EnclosureType,
+		&i.ContentHash,
+		&i.Inserted,
 	)
 	return i, err
 }
@@ -0,0 +1,15 @@
+-- Short-lived log of articles flipped to read by bulk mark-read operations,
+-- keyed by an undo token so the operation can be reverted
+CREATE TABLE IF NOT EXISTS read_undo (
+    token TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (token, article_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_read_undo_created ON read_undo(created_at);
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (004, '004-read-undo');
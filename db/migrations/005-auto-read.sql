@@ -0,0 +1,6 @@
+-- Per-feed rule to auto-mark unread articles read once they reach a given age (0 = disabled)
+ALTER TABLE feeds ADD COLUMN auto_read_after_days INTEGER NOT NULL DEFAULT 0;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (005, '005-auto-read');
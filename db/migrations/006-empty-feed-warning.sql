@@ -0,0 +1,7 @@
+-- Track consecutive refreshes that returned no items, and a warning shown once past the threshold
+ALTER TABLE feeds ADD COLUMN empty_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN last_warning TEXT;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (006, '006-empty-feed-warning');
@@ -0,0 +1,10 @@
+-- Per-user token for cookie-less access to republished Atom feeds
+CREATE TABLE IF NOT EXISTS feed_tokens (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (007, '007-feed-tokens');
@@ -0,0 +1,8 @@
+-- Optional refresh intervals: a feed override takes precedence over its
+-- category's interval, which takes precedence over the global default
+ALTER TABLE feeds ADD COLUMN refresh_interval_minutes INTEGER;
+ALTER TABLE categories ADD COLUMN refresh_interval_minutes INTEGER;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (008, '008-refresh-intervals');
@@ -0,0 +1,6 @@
+-- Per-feed opt-in to collapse recent articles with the same normalized title
+ALTER TABLE feeds ADD COLUMN dedup_titles INTEGER NOT NULL DEFAULT 0;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (009, '009-title-dedup');
@@ -0,0 +1,6 @@
+-- Per-feed opt-out of TLS certificate verification (self-signed internal feeds)
+ALTER TABLE feeds ADD COLUMN insecure_skip_verify INTEGER NOT NULL DEFAULT 0;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (010, '010-insecure-tls');
@@ -0,0 +1,6 @@
+-- Time of the last successful (200/304) fetch; last_updated is the last attempt
+ALTER TABLE feeds ADD COLUMN last_success_at TIMESTAMP;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (011, '011-last-success');
@@ -0,0 +1,7 @@
+-- Fetch attempts made by the most recent refresh, and a running total
+ALTER TABLE feeds ADD COLUMN last_fetch_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN fetch_attempts INTEGER NOT NULL DEFAULT 0;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (012, '012-fetch-attempts');
@@ -0,0 +1,9 @@
+-- Per-user preferences
+CREATE TABLE IF NOT EXISTS user_prefs (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    default_category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL
+);
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (013, '013-user-prefs');
@@ -0,0 +1,9 @@
+-- When an article's stored content last changed, for incremental sync and
+-- offline caching. Existing rows start at their created_at.
+ALTER TABLE articles ADD COLUMN updated_at TIMESTAMP;
+UPDATE articles SET updated_at = created_at;
+CREATE INDEX IF NOT EXISTS idx_articles_feed_updated ON articles(feed_id, updated_at);
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (014, '014-article-updated-at');
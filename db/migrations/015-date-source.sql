@@ -0,0 +1,7 @@
+-- Per-feed choice of which item timestamp becomes the article date:
+-- prefer_published (published, falling back to updated), published or updated
+ALTER TABLE feeds ADD COLUMN date_source TEXT NOT NULL DEFAULT 'prefer_published';
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (015, '015-date-source');
@@ -0,0 +1,9 @@
+-- iTunes podcast episode metadata (NULL for non-podcast feeds)
+ALTER TABLE articles ADD COLUMN duration_seconds INTEGER;
+ALTER TABLE articles ADD COLUMN episode INTEGER;
+ALTER TABLE articles ADD COLUMN season INTEGER;
+ALTER TABLE articles ADD COLUMN episode_type TEXT;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (016, '016-podcast-metadata');
@@ -0,0 +1,6 @@
+-- 1 when articles.content holds gzip-compressed HTML (GORSS_COMPRESS_CONTENT)
+ALTER TABLE articles ADD COLUMN content_compressed INTEGER NOT NULL DEFAULT 0;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (017, '017-content-compression');
@@ -0,0 +1,7 @@
+-- First audio/video enclosure of an article, for media proxying (NULL if none)
+ALTER TABLE articles ADD COLUMN enclosure_url TEXT;
+ALTER TABLE articles ADD COLUMN enclosure_type TEXT;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (018, '018-enclosures');
@@ -0,0 +1,7 @@
+-- Per-feed allow filter: when set, only items whose title or summary match
+-- this case-insensitive regular expression are stored ('' = keep everything)
+ALTER TABLE feeds ADD COLUMN keep_only_pattern TEXT NOT NULL DEFAULT '';
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (019, '019-keep-only');
@@ -0,0 +1,7 @@
+-- Per-feed flag for feeds whose GUIDs/links can't be trusted to identify an
+-- item: articles are keyed by a hash of title, date and content instead
+ALTER TABLE feeds ADD COLUMN guid_unstable INTEGER NOT NULL DEFAULT 0;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (020, '020-guid-unstable');
@@ -0,0 +1,32 @@
+-- Full-text index of article titles, content and summaries for search.
+-- Contentless: the text lives only in articles, and rowid is the article id.
+-- Compressed content is indexed as '' here; the app indexes its text
+-- separately (indexCompressedContent).
+CREATE VIRTUAL TABLE IF NOT EXISTS articles_fts USING fts5(
+    title, content, summary,
+    content='', contentless_delete=1
+);
+
+INSERT INTO articles_fts (rowid, title, content, summary)
+SELECT id, title, CASE WHEN content_compressed = 0 THEN content ELSE '' END, summary
+FROM articles;
+
+-- Keep the index in sync with every write to articles
+CREATE TRIGGER IF NOT EXISTS articles_fts_insert AFTER INSERT ON articles BEGIN
+    INSERT INTO articles_fts (rowid, title, content, summary)
+    VALUES (new.id, new.title, CASE WHEN new.content_compressed = 0 THEN new.content ELSE '' END, new.summary);
+END;
+
+CREATE TRIGGER IF NOT EXISTS articles_fts_delete AFTER DELETE ON articles BEGIN
+    DELETE FROM articles_fts WHERE rowid = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS articles_fts_update AFTER UPDATE OF title, content, content_compressed, summary ON articles BEGIN
+    DELETE FROM articles_fts WHERE rowid = old.id;
+    INSERT INTO articles_fts (rowid, title, content, summary)
+    VALUES (new.id, new.title, CASE WHEN new.content_compressed = 0 THEN new.content ELSE '' END, new.summary);
+END;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (021, '021-article-search');
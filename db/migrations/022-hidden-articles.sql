@@ -0,0 +1,7 @@
+-- Per-user "hide" flag: hidden articles are left out of every list and of
+-- unread counts, without being marked read
+ALTER TABLE article_states ADD COLUMN is_hidden INTEGER NOT NULL DEFAULT 0;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (022, '022-hidden-articles');
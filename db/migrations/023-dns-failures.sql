@@ -0,0 +1,9 @@
+-- Consecutive "no such host" failures per feed; after
+-- GORSS_DNS_FAIL_DISABLE_AFTER of them the feed is flagged dead and left out
+-- of refresh cycles until the user edits it or a forced refresh succeeds
+ALTER TABLE feeds ADD COLUMN dns_fail_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN dead INTEGER NOT NULL DEFAULT 0;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (023, '023-dns-failures');
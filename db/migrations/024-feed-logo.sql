@@ -0,0 +1,7 @@
+-- Channel image declared by the feed (RSS <image>, Atom <logo>, JSON Feed
+-- icon), shown as the feed's logo; empty when the feed has none
+ALTER TABLE feeds ADD COLUMN logo_url TEXT NOT NULL DEFAULT '';
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (024, '024-feed-logo');
@@ -0,0 +1,7 @@
+-- Hash of an article's normalized text, so an edit can be told apart from
+-- a re-fetch of the same content; empty for articles stored before this
+ALTER TABLE articles ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (025, '025-content-hash');
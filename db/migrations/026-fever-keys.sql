@@ -0,0 +1,13 @@
+-- Fever API credentials, apart from the feed token since that one ends up
+-- in ?token= URLs. api_key is what a client sends, md5("<username>:<password>"),
+-- with one row per username (user ID or email) the password was issued for
+CREATE TABLE IF NOT EXISTS fever_keys (
+    api_key TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_fever_keys_user ON fever_keys(user_id);
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (026, '026-fever-keys');
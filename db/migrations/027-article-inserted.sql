@@ -0,0 +1,7 @@
+-- Set to 1 by UpsertArticle's INSERT and to 0 by its update path, so the
+-- RETURNING row tells a new article apart from a re-fetched one
+ALTER TABLE articles ADD COLUMN inserted INTEGER NOT NULL DEFAULT 0;
+
+-- Record execution of this migration
+INSERT OR IGNORE INTO migrations (migration_number, migration_name)
+VALUES (027, '027-article-inserted');
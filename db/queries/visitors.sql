@@ -24,20 +24,29 @@ SELECT * FROM categories WHERE id = ? AND user_id = ?;
 -- name: UpdateCategory :exec
 UPDATE categories SET title = ? WHERE id = ? AND user_id = ?;
 
+-- name: UpdateCategoryRefreshInterval :exec
+UPDATE categories SET refresh_interval_minutes = ? WHERE id = ? AND user_id = ?;
+
+-- name: GetCategoryRefreshIntervals :many
+SELECT id, refresh_interval_minutes FROM categories WHERE refresh_interval_minutes IS NOT NULL;
+
 -- name: DeleteCategory :exec
 DELETE FROM categories WHERE id = ? AND user_id = ?;
 
+-- name: UncategorizeFeeds :exec
+UPDATE feeds SET category_id = NULL WHERE category_id = ? AND user_id = ?;
+
 -- Feed queries
 
 -- name: CreateFeed :one
-INSERT INTO feeds (user_id, category_id, url, title, site_url, description)
-VALUES (?, ?, ?, ?, ?, ?) RETURNING *;
+INSERT INTO feeds (user_id, category_id, url, title, site_url, description, logo_url)
+VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING *;
 
 -- name: GetFeeds :many
 SELECT f.*, c.title as category_title,
   (SELECT COUNT(*) FROM articles a 
    LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
-   WHERE a.feed_id = f.id AND (s.is_read IS NULL OR s.is_read = 0)) as unread_count
+   WHERE a.feed_id = f.id AND (s.is_read IS NULL OR (s.is_read = 0 AND s.is_hidden = 0))) as unread_count
 FROM feeds f
 LEFT JOIN categories c ON f.category_id = c.id
 WHERE f.user_id = ?
@@ -67,6 +76,7 @@ UPDATE feeds SET
   title = ?,
   site_url = ?,
   description = ?,
+  logo_url = ?,
   last_updated = ?,
   last_error = ?,
   etag = ?,
@@ -74,9 +84,45 @@ UPDATE feeds SET
   error_count = ?
 WHERE id = ?;
 
+-- name: UpdateFeedEmptyStatus :exec
+UPDATE feeds SET empty_count = ?, last_warning = ? WHERE id = ?;
+
 -- name: UpdateFeedDetails :exec
 UPDATE feeds SET title = ?, url = ? WHERE id = ? AND user_id = ?;
 
+-- name: UpdateFeedRefreshInterval :exec
+UPDATE feeds SET refresh_interval_minutes = ? WHERE id = ? AND user_id = ?;
+
+-- name: UpdateFeedDedupTitles :exec
+UPDATE feeds SET dedup_titles = ? WHERE id = ? AND user_id = ?;
+
+-- name: UpdateFeedGuidUnstable :exec
+UPDATE feeds SET guid_unstable = ? WHERE id = ? AND user_id = ?;
+
+-- name: UpdateFeedInsecureSkipVerify :exec
+UPDATE feeds SET insecure_skip_verify = ? WHERE id = ? AND user_id = ?;
+
+-- name: UpdateFeedDateSource :exec
+UPDATE feeds SET date_source = ? WHERE id = ? AND user_id = ?;
+
+-- name: UpdateFeedKeepOnly :exec
+UPDATE feeds SET keep_only_pattern = ? WHERE id = ? AND user_id = ?;
+
+-- name: UpdateFeedLastSuccess :exec
+UPDATE feeds SET last_success_at = ? WHERE id = ?;
+
+-- name: UpdateFeedDNSFailures :exec
+UPDATE feeds SET dns_fail_count = ?, dead = ? WHERE id = ?;
+
+-- name: UpdateFeedDead :exec
+UPDATE feeds SET dead = ?, dns_fail_count = 0 WHERE id = ? AND user_id = ?;
+
+-- name: UpdateFeedFetchAttempts :exec
+UPDATE feeds SET last_fetch_attempts = ?, fetch_attempts = fetch_attempts + ? WHERE id = ?;
+
+-- name: UpdateFeedAutoRead :exec
+UPDATE feeds SET auto_read_after_days = ? WHERE id = ? AND user_id = ?;
+
 -- name: DeleteFeed :exec
 DELETE FROM feeds WHERE id = ? AND user_id = ?;
 
@@ -86,21 +132,62 @@ SELECT * FROM feeds ORDER BY last_updated ASC NULLS FIRST LIMIT ?;
 -- Article queries
 
 -- name: UpsertArticle :one
-INSERT INTO articles (feed_id, guid, url, title, author, content, summary, published_at)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+INSERT INTO articles (feed_id, guid, url, title, author, content, content_compressed, summary, published_at,
+  duration_seconds, episode, season, episode_type, enclosure_url, enclosure_type, content_hash, updated_at, inserted)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, 1)
 ON CONFLICT (feed_id, guid) DO UPDATE SET
   url = excluded.url,
   title = excluded.title,
   author = excluded.author,
   content = excluded.content,
+  content_compressed = excluded.content_compressed,
   summary = excluded.summary,
-  published_at = excluded.published_at
+  published_at = excluded.published_at,
+  duration_seconds = excluded.duration_seconds,
+  episode = excluded.episode,
+  season = excluded.season,
+  episode_type = excluded.episode_type,
+  enclosure_url = excluded.enclosure_url,
+  enclosure_type = excluded.enclosure_type,
+  content_hash = excluded.content_hash,
+  updated_at = CASE
+    WHEN articles.url IS NOT excluded.url OR articles.title IS NOT excluded.title
+      OR articles.author IS NOT excluded.author OR articles.content IS NOT excluded.content
+      OR articles.summary IS NOT excluded.summary
+    THEN CURRENT_TIMESTAMP ELSE articles.updated_at END,
+  inserted = 0
 RETURNING *;
 
+-- name: GetFeedActivity :many
+SELECT feed_id, COUNT(*) AS article_count FROM articles
+WHERE created_at >= datetime('now', CAST(sqlc.arg(max_age) AS TEXT))
+GROUP BY feed_id;
+
+-- name: GetRecentArticleTitles :many
+SELECT guid, title FROM articles
+WHERE feed_id = ? AND created_at >= datetime('now', CAST(sqlc.arg(max_age) AS TEXT));
+
+-- name: GetArticleContentBatch :many
+SELECT a.id, a.title, a.content, a.content_compressed, a.summary
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+WHERE f.user_id = ? AND a.id > ?
+ORDER BY a.id
+LIMIT ?;
+
+-- name: UpdateArticleContent :exec
+UPDATE articles SET title = ?, content = ?, content_compressed = ?, summary = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?;
+
+-- name: IndexArticleContent :exec
+-- Sets an article's search index entry, for content stored compressed,
+-- which the articles_fts triggers index as empty.
+UPDATE articles_fts SET title = ?, content = ?, summary = ? WHERE rowid = ?;
+
 -- name: GetArticles :many
 SELECT a.*, f.title as feed_title, f.site_url as feed_site_url,
   COALESCE(s.is_read, 0) as is_read,
-  COALESCE(s.is_starred, 0) as is_starred
+  COALESCE(s.is_starred, 0) as is_starred,
+  s.read_at
 FROM articles a
 JOIN feeds f ON a.feed_id = f.id
 LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = ?
@@ -161,16 +248,10 @@ JOIN feeds f ON a.feed_id = f.id
 LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = ?
 WHERE a.id = ? AND f.user_id = ?;
 
--- name: SearchArticles :many
-SELECT a.*, f.title as feed_title, f.site_url as feed_site_url,
-  COALESCE(s.is_read, 0) as is_read,
-  COALESCE(s.is_starred, 0) as is_starred
-FROM articles a
+-- name: GetArticleEnclosure :one
+SELECT a.enclosure_url FROM articles a
 JOIN feeds f ON a.feed_id = f.id
-LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = ?
-WHERE f.user_id = ? AND (a.title LIKE ? OR a.content LIKE ? OR a.summary LIKE ?)
-ORDER BY a.published_at DESC
-LIMIT ? OFFSET ?;
+WHERE a.id = ? AND f.user_id = ?;
 
 -- Article state queries
 
@@ -181,6 +262,23 @@ ON CONFLICT (user_id, article_id) DO UPDATE SET
   is_read = 1,
   read_at = excluded.read_at;
 
+-- name: MarkArticleReadOnce :exec
+-- Like SetArticleRead, but an article that is already read keeps its read_at.
+INSERT INTO article_states (user_id, article_id, is_read, read_at)
+VALUES (?, ?, 1, ?)
+ON CONFLICT (user_id, article_id) DO UPDATE SET
+  is_read = 1,
+  read_at = CASE WHEN article_states.is_read = 1 THEN article_states.read_at ELSE excluded.read_at END;
+
+-- name: ClearReadIfEdited :execresult
+-- Marks an article unread for whoever had read it when its stored hash
+-- differs from content_hash. Articles stored before hashing have none.
+UPDATE article_states SET is_read = 0, read_at = NULL
+WHERE is_read = 1 AND article_id = (
+  SELECT id FROM articles
+  WHERE feed_id = ? AND guid = ? AND content_hash != '' AND content_hash != ?
+);
+
 -- name: SetArticleUnread :exec
 INSERT INTO article_states (user_id, article_id, is_read)
 VALUES (?, ?, 0)
@@ -195,6 +293,17 @@ ON CONFLICT (user_id, article_id) DO UPDATE SET
   is_starred = 1,
   starred_at = excluded.starred_at;
 
+-- name: StarArticleByGUID :exec
+-- Stars a feed's article by GUID for the feed's owner, if it is stored.
+INSERT INTO article_states (user_id, article_id, is_starred, starred_at)
+SELECT f.user_id, a.id, 1, ?
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+WHERE a.feed_id = ? AND a.guid = ?
+ON CONFLICT (user_id, article_id) DO UPDATE SET
+  is_starred = 1,
+  starred_at = excluded.starred_at;
+
 -- name: SetArticleUnstarred :exec
 INSERT INTO article_states (user_id, article_id, is_starred)
 VALUES (?, ?, 0)
@@ -202,24 +311,76 @@ ON CONFLICT (user_id, article_id) DO UPDATE SET
   is_starred = 0,
   starred_at = NULL;
 
--- name: MarkFeedRead :exec
+-- name: SetArticleHidden :exec
+INSERT INTO article_states (user_id, article_id, is_hidden)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, article_id) DO UPDATE SET
+  is_hidden = excluded.is_hidden;
+
+-- Only currently-unread articles are touched; the returned ids are exactly
+-- the articles that were flipped to read (used for counts and undo).
+
+-- name: MarkFeedRead :many
 INSERT INTO article_states (user_id, article_id, is_read, read_at)
 SELECT ?, a.id, 1, ?
 FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE a.feed_id = ? AND f.user_id = ? AND (s.is_read IS NULL OR s.is_read = 0)
+ON CONFLICT (user_id, article_id) DO UPDATE SET
+  is_read = 1,
+  read_at = excluded.read_at
+RETURNING article_id;
+
+-- name: AutoReadStaleArticles :execresult
+INSERT INTO article_states (user_id, article_id, is_read, read_at)
+SELECT f.user_id, a.id, 1, ?
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
 WHERE a.feed_id = ?
+  AND (s.is_read IS NULL OR s.is_read = 0)
+  AND COALESCE(s.is_starred, 0) = 0
+  AND COALESCE(a.published_at, a.created_at) < sqlc.narg(cutoff)
 ON CONFLICT (user_id, article_id) DO UPDATE SET
   is_read = 1,
   read_at = excluded.read_at;
 
--- name: MarkAllRead :exec
+-- name: MarkAllRead :many
 INSERT INTO article_states (user_id, article_id, is_read, read_at)
 SELECT ?, a.id, 1, ?
 FROM articles a
 JOIN feeds f ON a.feed_id = f.id
-WHERE f.user_id = ?
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE f.user_id = ? AND (s.is_read IS NULL OR s.is_read = 0)
 ON CONFLICT (user_id, article_id) DO UPDATE SET
   is_read = 1,
-  read_at = excluded.read_at;
+  read_at = excluded.read_at
+RETURNING article_id;
+
+-- Read undo log
+
+-- name: InsertReadUndo :exec
+INSERT INTO read_undo (token, user_id, article_id) VALUES (?, ?, ?);
+
+-- name: UndoRead :execresult
+UPDATE article_states SET is_read = 0, read_at = NULL
+WHERE article_states.is_read = 1 AND EXISTS (
+  SELECT 1 FROM read_undo u
+  WHERE u.token = sqlc.arg(token) AND u.user_id = article_states.user_id
+    AND u.article_id = article_states.article_id
+    AND u.created_at > datetime('now', CAST(sqlc.arg(max_age) AS TEXT))
+) AND article_states.user_id = sqlc.arg(user_id);
+
+-- name: CountReadUndo :one
+SELECT COUNT(*) FROM read_undo
+WHERE token = ? AND user_id = ? AND created_at > datetime('now', CAST(sqlc.arg(max_age) AS TEXT));
+
+-- name: DeleteReadUndo :exec
+DELETE FROM read_undo WHERE token = ? AND user_id = ?;
+
+-- name: PurgeExpiredReadUndo :exec
+DELETE FROM read_undo WHERE created_at <= datetime('now', CAST(sqlc.arg(max_age) AS TEXT));
 
 -- Stats queries
 
@@ -228,7 +389,23 @@ SELECT COUNT(*) as count
 FROM articles a
 JOIN feeds f ON a.feed_id = f.id
 LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
-WHERE f.user_id = ? AND (s.is_read IS NULL OR s.is_read = 0);
+WHERE f.user_id = ? AND (s.is_read IS NULL OR (s.is_read = 0 AND s.is_hidden = 0));
+
+-- name: GetCategoryUnreadCounts :many
+SELECT COALESCE(f.category_id, 0) AS category_id, COUNT(*) AS unread_count
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE f.user_id = ? AND (s.is_read IS NULL OR (s.is_read = 0 AND s.is_hidden = 0))
+GROUP BY COALESCE(f.category_id, 0);
+
+-- name: GetFreshCount :one
+SELECT COUNT(*) as count
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE f.user_id = ? AND (s.is_read IS NULL OR (s.is_read = 0 AND s.is_hidden = 0))
+  AND COALESCE(a.published_at, a.created_at) >= sqlc.narg(since);
 
 -- name: GetTotalArticleCount :one
 SELECT COUNT(*) as count
@@ -236,6 +413,13 @@ FROM articles a
 JOIN feeds f ON a.feed_id = f.id
 WHERE f.user_id = ?;
 
+-- name: GetReadCount :one
+SELECT COUNT(*) as count
+FROM article_states s
+JOIN articles a ON s.article_id = a.id
+JOIN feeds f ON a.feed_id = f.id
+WHERE s.user_id = ? AND s.is_read = 1;
+
 -- name: GetStarredCount :one
 SELECT COUNT(*) as count
 FROM article_states s
@@ -243,6 +427,22 @@ JOIN articles a ON s.article_id = a.id
 JOIN feeds f ON a.feed_id = f.id
 WHERE s.user_id = ? AND s.is_starred = 1;
 
+-- name: GetFeedReadCounts :many
+SELECT a.feed_id, COUNT(*) as count
+FROM article_states s
+JOIN articles a ON s.article_id = a.id
+JOIN feeds f ON a.feed_id = f.id AND f.user_id = s.user_id
+WHERE s.user_id = ? AND s.is_read = 1
+GROUP BY a.feed_id;
+
+-- name: GetStarredArticleRefs :many
+SELECT a.feed_id, a.guid, a.url, a.title, s.starred_at
+FROM article_states s
+JOIN articles a ON s.article_id = a.id
+JOIN feeds f ON a.feed_id = f.id AND f.user_id = s.user_id
+WHERE s.user_id = ? AND s.is_starred = 1
+ORDER BY s.starred_at DESC;
+
 -- name: PurgeOldReadArticles :execresult
 DELETE FROM articles
 WHERE id IN (
@@ -277,3 +477,55 @@ SELECT * FROM categories WHERE user_id = ? ORDER BY sort_order ASC, title ASC;
 
 -- name: GetFeedsOrdered :many
 SELECT * FROM feeds WHERE user_id = ? ORDER BY sort_order ASC, title ASC;
+
+-- Feed token queries
+
+-- name: GetFeedToken :one
+SELECT token FROM feed_tokens WHERE user_id = ?;
+
+-- name: SetFeedToken :exec
+INSERT INTO feed_tokens (user_id, token) VALUES (?, ?)
+ON CONFLICT (user_id) DO UPDATE SET
+  token = excluded.token,
+  created_at = CURRENT_TIMESTAMP;
+
+-- name: GetFeedUserIDs :many
+SELECT DISTINCT user_id FROM feeds ORDER BY user_id;
+
+-- name: GetUserByFeedToken :one
+SELECT user_id FROM feed_tokens WHERE token = ?;
+
+-- name: GetUserByFeverKey :one
+SELECT user_id FROM fever_keys WHERE api_key = ?;
+
+-- name: DeleteFeverKeys :exec
+DELETE FROM fever_keys WHERE user_id = ?;
+
+-- name: InsertFeverKey :exec
+INSERT INTO fever_keys (api_key, user_id) VALUES (?, ?);
+
+-- User preference queries
+
+-- name: GetUserPrefs :one
+SELECT * FROM user_prefs WHERE user_id = ?;
+
+-- name: SetDefaultCategory :exec
+INSERT INTO user_prefs (user_id, default_category_id) VALUES (?, ?)
+ON CONFLICT (user_id) DO UPDATE SET
+  default_category_id = excluded.default_category_id;
+
+-- Read propagation queries
+
+-- name: GetArticleURLs :many
+SELECT a.id, a.url
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+WHERE f.user_id = ? AND a.id IN (sqlc.slice(ids));
+
+-- name: GetRecentUnreadArticleURLs :many
+SELECT a.id, a.url
+FROM articles a
+JOIN feeds f ON a.feed_id = f.id
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE f.user_id = ? AND (s.is_read IS NULL OR s.is_read = 0)
+  AND a.created_at >= datetime('now', CAST(sqlc.arg(max_age) AS TEXT));
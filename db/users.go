@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrUserNotFound is returned by MigrateUser when the source user doesn't exist.
+var ErrUserNotFound = errors.New("user not found")
+
+// MigrateUserResult counts what MigrateUser moved to the new user.
+type MigrateUserResult struct {
+	Categories    int64 `json:"categories"`
+	Feeds         int64 `json:"feeds"`
+	ArticleStates int64 `json:"article_states"`
+	Skipped       int64 `json:"skipped_feeds"` // already subscribed by the new user; left with the old one
+}
+
+// MigrateUser reassigns one user's categories, feeds, article states and
+// preferences to another user in a single transaction, e.g. to recover
+// "anonymous" data after turning on authentication. Where both users have
+// the same thing the new user's copy wins: categories with the same title
+// are merged, and feeds the new user already subscribes to stay with the old
+// user and are counted as skipped.
+func MigrateUser(ctx context.Context, sqlDB *sql.DB, from, to string) (MigrateUserResult, error) {
+	var res MigrateUserResult
+	if from == "" || to == "" {
+		return res, errors.New("migrate user: both user IDs are required")
+	}
+	if from == to {
+		return res, errors.New("migrate user: source and target are the same user")
+	}
+
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return res, fmt.Errorf("migrate user: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = ?)`, from).Scan(&exists); err != nil {
+		return res, fmt.Errorf("migrate user: %w", err)
+	}
+	if !exists {
+		return res, fmt.Errorf("migrate user %q: %w", from, ErrUserNotFound)
+	}
+
+	exec := func(query string, args ...any) (int64, error) {
+		r, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return r.RowsAffected()
+	}
+	steps := []struct {
+		name  string
+		query string
+		args  []any
+		count *int64
+	}{
+		{"create user", `INSERT OR IGNORE INTO users (id) VALUES (?)`, []any{to}, nil},
+		// Point feeds in a category the new user also has at the new user's copy.
+		{"merge categories", `UPDATE feeds SET category_id = (
+				SELECT t.id FROM categories t JOIN categories f ON f.title = t.title
+				WHERE f.id = feeds.category_id AND t.user_id = ?)
+			WHERE user_id = ? AND category_id IN (
+				SELECT id FROM categories WHERE user_id = ?
+				AND title IN (SELECT title FROM categories WHERE user_id = ?))`, []any{to, from, from, to}, nil},
+		{"drop merged categories", `DELETE FROM categories WHERE user_id = ?
+			AND title IN (SELECT title FROM categories WHERE user_id = ?)`, []any{from, to}, nil},
+		{"move categories", `UPDATE categories SET user_id = ? WHERE user_id = ?`, []any{to, from}, &res.Categories},
+		// Duplicates of the new user's feeds keep their category, which
+		// just moved, so detach them before they're left behind.
+		{"detach skipped feeds", `UPDATE feeds SET category_id = NULL WHERE user_id = ?
+			AND url IN (SELECT url FROM feeds WHERE user_id = ?)`, []any{from, to}, &res.Skipped},
+		{"move feeds", `UPDATE OR IGNORE feeds SET user_id = ? WHERE user_id = ?`, []any{to, from}, &res.Feeds},
+		{"move article states", `UPDATE OR IGNORE article_states SET user_id = ? WHERE user_id = ?
+			AND article_id IN (SELECT a.id FROM articles a JOIN feeds f ON f.id = a.feed_id WHERE f.user_id = ?)`,
+			[]any{to, from, to}, &res.ArticleStates},
+		{"move prefs", `UPDATE OR IGNORE user_prefs SET user_id = ? WHERE user_id = ?`, []any{to, from}, nil},
+		{"move feed token", `UPDATE OR IGNORE feed_tokens SET user_id = ? WHERE user_id = ?`, []any{to, from}, nil},
+		// Fever keys hash the old username, so they can't simply move.
+		{"drop fever keys", `DELETE FROM fever_keys WHERE user_id = ?`, []any{from}, nil},
+		{"drop undo tokens", `DELETE FROM read_undo WHERE user_id = ?`, []any{from}, nil},
+	}
+	for _, st := range steps {
+		n, err := exec(st.query, st.args...)
+		if err != nil {
+			return MigrateUserResult{}, fmt.Errorf("migrate user: %s: %w", st.name, err)
+		}
+		if st.count != nil {
+			*st.count = n
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return MigrateUserResult{}, fmt.Errorf("migrate user: commit: %w", err)
+	}
+	return res, nil
+}
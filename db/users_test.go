@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateUser(t *testing.T) {
+	sqlDB, err := Open(filepath.Join(t.TempDir(), "users.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer sqlDB.Close()
+	if err := RunMigrations(sqlDB); err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+
+	mustExec := func(query string, args ...any) int64 {
+		t.Helper()
+		r, err := sqlDB.Exec(query, args...)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		id, _ := r.LastInsertId()
+		return id
+	}
+	count := func(query string, args ...any) int {
+		t.Helper()
+		var n int
+		if err := sqlDB.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		return n
+	}
+
+	mustExec(`INSERT INTO users (id) VALUES ('anonymous'), ('alice')`)
+	news := mustExec(`INSERT INTO categories (user_id, title) VALUES ('anonymous', 'News')`)
+	mustExec(`INSERT INTO categories (user_id, title) VALUES ('anonymous', 'Tech')`)
+	aliceNews := mustExec(`INSERT INTO categories (user_id, title) VALUES ('alice', 'News')`)
+	f1 := mustExec(`INSERT INTO feeds (user_id, category_id, url) VALUES ('anonymous', ?, 'https://a.example/feed')`, news)
+	mustExec(`INSERT INTO feeds (user_id, category_id, url) VALUES ('anonymous', ?, 'https://dup.example/feed')`, news)
+	mustExec(`INSERT INTO feeds (user_id, url) VALUES ('alice', 'https://dup.example/feed')`)
+	art := mustExec(`INSERT INTO articles (feed_id, guid) VALUES (?, 'g1')`, f1)
+	mustExec(`INSERT INTO article_states (user_id, article_id, is_read, is_starred) VALUES ('anonymous', ?, 1, 1)`, art)
+	mustExec(`INSERT INTO user_prefs (user_id) VALUES ('anonymous')`)
+
+	res, err := MigrateUser(context.Background(), sqlDB, "anonymous", "alice")
+	if err != nil {
+		t.Fatalf("MigrateUser: %v", err)
+	}
+	if res.Feeds != 1 || res.Skipped != 1 || res.Categories != 1 || res.ArticleStates != 1 {
+		t.Errorf("result = %+v, want 1 feed, 1 skipped, 1 category, 1 article state", res)
+	}
+
+	if n := count(`SELECT COUNT(*) FROM categories WHERE user_id = 'alice'`); n != 2 {
+		t.Errorf("alice has %d categories, want 2 (News merged, Tech moved)", n)
+	}
+	var catID int64
+	if err := sqlDB.QueryRow(`SELECT category_id FROM feeds WHERE id = ?`, f1).Scan(&catID); err != nil {
+		t.Fatal(err)
+	}
+	if catID != aliceNews {
+		t.Errorf("moved feed is in category %d, want alice's News %d", catID, aliceNews)
+	}
+	if n := count(`SELECT COUNT(*) FROM feeds WHERE user_id = 'alice'`); n != 2 {
+		t.Errorf("alice has %d feeds, want 2", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM feeds WHERE user_id = 'anonymous' AND category_id IS NULL`); n != 1 {
+		t.Errorf("skipped duplicate feed should stay with anonymous, uncategorized; got %d", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM article_states WHERE user_id = 'alice' AND is_read = 1 AND is_starred = 1`); n != 1 {
+		t.Errorf("alice has %d article states, want 1", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM user_prefs WHERE user_id = 'alice'`); n != 1 {
+		t.Errorf("prefs not moved")
+	}
+
+	t.Run("unknown user", func(t *testing.T) {
+		if _, err := MigrateUser(context.Background(), sqlDB, "nobody", "alice"); err == nil {
+			t.Error("expected error for unknown source user")
+		}
+	})
+
+	t.Run("same user", func(t *testing.T) {
+		if _, err := MigrateUser(context.Background(), sqlDB, "alice", "alice"); err == nil {
+			t.Error("expected error when source and target match")
+		}
+	})
+}
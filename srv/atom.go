@@ -0,0 +1,133 @@
+package srv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Atom structures for republishing stored articles
+type AtomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Author   AtomPerson  `xml:"author"`
+	Links    []AtomLink  `xml:"link"`
+	Entries  []AtomEntry `xml:"entry"`
+}
+
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type AtomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type AtomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Links     []AtomLink  `xml:"link"`
+	Author    *AtomPerson `xml:"author,omitempty"`
+	Summary   *AtomText   `xml:"summary,omitempty"`
+	Content   *AtomText   `xml:"content,omitempty"`
+}
+
+// AtomMeta holds the channel-level fields of a generated Atom feed.
+type AtomMeta struct {
+	Title    string
+	ID       string // feed URL or other IRI identifying the feed
+	Subtitle string
+	SiteURL  string
+	SelfURL  string
+	Author   string
+	Updated  time.Time // used when there are no entries
+}
+
+// AtomItem is an article to include in a generated Atom feed.
+type AtomItem struct {
+	ID        int64
+	GUID      string
+	URL       string
+	Title     string
+	Author    string
+	Content   string
+	Summary   string
+	Published time.Time
+}
+
+// GenerateAtom creates an Atom 1.0 document from articles
+func GenerateAtom(meta AtomMeta, items []AtomItem) ([]byte, error) {
+	feed := AtomFeed{
+		Title:    meta.Title,
+		ID:       meta.ID,
+		Subtitle: meta.Subtitle,
+		Author:   AtomPerson{Name: meta.Author},
+	}
+	if feed.Author.Name == "" {
+		feed.Author.Name = meta.Title
+	}
+	if meta.SiteURL != "" {
+		feed.Links = append(feed.Links, AtomLink{Href: meta.SiteURL, Rel: "alternate", Type: "text/html"})
+	}
+	if meta.SelfURL != "" {
+		feed.Links = append(feed.Links, AtomLink{Href: meta.SelfURL, Rel: "self", Type: "application/atom+xml"})
+	}
+
+	updated := meta.Updated
+	for _, it := range items {
+		if it.Published.After(updated) {
+			updated = it.Published
+		}
+		entry := AtomEntry{
+			Title:     it.Title,
+			ID:        atomEntryID(it),
+			Updated:   it.Published.UTC().Format(time.RFC3339),
+			Published: it.Published.UTC().Format(time.RFC3339),
+		}
+		if it.URL != "" {
+			entry.Links = []AtomLink{{Href: it.URL, Rel: "alternate"}}
+		}
+		if it.Author != "" {
+			entry.Author = &AtomPerson{Name: it.Author}
+		}
+		if it.Summary != "" {
+			entry.Summary = &AtomText{Type: "html", Body: it.Summary}
+		}
+		if it.Content != "" {
+			entry.Content = &AtomText{Type: "html", Body: it.Content}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	output, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal atom: %w", err)
+	}
+	return append([]byte(xml.Header), output...), nil
+}
+
+// atomEntryID keeps the original GUID when it is already an absolute IRI,
+// so readers see the same identity as upstream, and otherwise derives a URN.
+func atomEntryID(it AtomItem) string {
+	if u, err := url.Parse(it.GUID); err == nil && u.Scheme != "" {
+		return it.GUID
+	}
+	return fmt.Sprintf("urn:gorss:article:%d", it.ID)
+}
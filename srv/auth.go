@@ -4,9 +4,12 @@ import (
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -46,6 +49,67 @@ func GetPassword() string {
 	return os.Getenv("GORSS_PASSWORD")
 }
 
+// defaultCookieName is the session cookie name when GORSS_COOKIE_NAME is unset.
+const defaultCookieName = "gorss_session"
+
+// CookieConfig holds the session cookie name and attributes.
+type CookieConfig struct {
+	Name     string
+	SameSite http.SameSite
+	Secure   *bool // nil means derive from the request (r.TLS)
+}
+
+// GetCookieConfig returns the session cookie settings from environment.
+// Invalid values are logged and replaced with the defaults.
+func GetCookieConfig() CookieConfig {
+	cfg := CookieConfig{Name: defaultCookieName, SameSite: http.SameSiteLaxMode}
+	if name := os.Getenv("GORSS_COOKIE_NAME"); name != "" {
+		cfg.Name = name
+	}
+	if v := os.Getenv("GORSS_COOKIE_SAMESITE"); v != "" {
+		mode, err := parseSameSite(v)
+		if err != nil {
+			slog.Warn("invalid GORSS_COOKIE_SAMESITE, using lax", "value", v)
+		} else {
+			cfg.SameSite = mode
+		}
+	}
+	if v := os.Getenv("GORSS_COOKIE_SECURE"); v != "" {
+		secure, err := strconv.ParseBool(v)
+		if err != nil {
+			slog.Warn("invalid GORSS_COOKIE_SECURE, deriving from request", "value", v)
+		} else {
+			cfg.Secure = &secure
+		}
+	}
+	return cfg
+}
+
+// parseSameSite maps a SameSite setting (lax, strict, none) to its mode.
+func parseSameSite(v string) (http.SameSite, error) {
+	switch strings.ToLower(v) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("unknown SameSite value %q", v)
+}
+
+// secure reports whether the cookie should carry the Secure attribute.
+// Browsers reject SameSite=None cookies without it, so None forces Secure.
+func (c CookieConfig) secure(r *http.Request) bool {
+	if c.SameSite == http.SameSiteNoneMode {
+		return true
+	}
+	if c.Secure != nil {
+		return *c.Secure
+	}
+	return r.TLS != nil
+}
+
 // generateSessionID creates a random session ID
 func generateSessionID() string {
 	b := make([]byte, 32)
@@ -67,16 +131,56 @@ func validateSession(sessionID string) bool {
 	return time.Now().Before(expiry)
 }
 
-// createSession creates a new session
+// createSession creates a new session, evicting the sessions closest to
+// expiry when the store is over GetMaxSessions.
 func createSession() string {
 	sessionID := generateSessionID()
+	limit := GetMaxSessions()
 	sessionsLock.Lock()
 	defer sessionsLock.Unlock()
 
 	sessions[sessionID] = time.Now().Add(sessionTTL)
+	if len(sessions) > limit {
+		evictSessions(limit)
+	}
 	return sessionID
 }
 
+// defaultMaxSessions is the session store cap when GORSS_MAX_SESSIONS is unset.
+const defaultMaxSessions = 1000
+
+// GetMaxSessions returns the maximum number of stored sessions from environment.
+func GetMaxSessions() int {
+	if v := os.Getenv("GORSS_MAX_SESSIONS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		slog.Warn("invalid GORSS_MAX_SESSIONS, using default", "value", v)
+	}
+	return defaultMaxSessions
+}
+
+// evictSessions drops expired sessions, then the ones expiring soonest,
+// until at most limit remain. The caller must hold sessionsLock.
+func evictSessions(limit int) {
+	now := time.Now()
+	for id, expiry := range sessions {
+		if now.After(expiry) {
+			delete(sessions, id)
+		}
+	}
+	for len(sessions) > limit {
+		var oldestID string
+		var oldest time.Time
+		for id, expiry := range sessions {
+			if oldestID == "" || expiry.Before(oldest) {
+				oldestID, oldest = id, expiry
+			}
+		}
+		delete(sessions, oldestID)
+	}
+}
+
 // deleteSession removes a session
 func deleteSession(sessionID string) {
 	sessionsLock.Lock()
@@ -101,6 +205,10 @@ func cleanupSessions() {
 func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
 	mode := GetAuthMode()
 	password := GetPassword()
+	cookieName := GetCookieConfig().Name
+	if s.RequireUserID && mode != AuthModeProxy {
+		slog.Warn("GORSS_REQUIRE_USER_ID only applies in proxy auth mode, ignoring", "auth_mode", mode)
+	}
 
 	// Start session cleanup goroutine
 	go func() {
@@ -112,19 +220,14 @@ func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Skip auth for static files, favicons, and health check
-		if r.URL.Path == "/health" ||
-		   r.URL.Path == "/login" ||
-		   strings.HasPrefix(r.URL.Path, "/static/") ||
-		   r.URL.Path == "/favicon.ico" ||
-		   strings.HasPrefix(r.URL.Path, "/apple-touch-icon") {
+		if isPublicPath(r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
-
 		switch mode {
 		case AuthModeNone:
 			// No auth required
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, s.withUser(r))
 
 		case AuthModePassword:
 			if password == "" {
@@ -134,9 +237,9 @@ func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
 			}
 
 			// Check session cookie
-			cookie, err := r.Cookie("gorss_session")
+			cookie, err := r.Cookie(cookieName)
 			if err == nil && validateSession(cookie.Value) {
-				next.ServeHTTP(w, r)
+				next.ServeHTTP(w, s.withUser(r))
 				return
 			}
 
@@ -150,11 +253,68 @@ func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
 				http.Error(w, "Unauthorized - proxy auth required", http.StatusUnauthorized)
 				return
 			}
-			next.ServeHTTP(w, r)
+			// A blank ID would otherwise fall back to DefaultUser
+			if s.RequireUserID && getUserID(r) == "" {
+				http.Error(w, "Unauthorized - user ID required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, s.withUser(r))
 		}
 	})
 }
 
+// adminMiddleware restricts /api/admin/ endpoints to admins (see isAdmin).
+func (s *Server) adminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/admin/") && !s.requireAdmin(w, r) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdmin reports whether r may use the admin endpoints. Behind the proxy
+// that takes membership of AdminGroup, as listed in X-ExeDev-Groups, so they
+// stay closed until GORSS_ADMIN_GROUP is set. The none and password modes
+// serve a single user, who is always the admin.
+func (s *Server) isAdmin(r *http.Request) bool {
+	if GetAuthMode() != AuthModeProxy {
+		return true
+	}
+	return s.AdminGroup != "" && slices.Contains(getUserGroups(r), s.AdminGroup)
+}
+
+// requireAdmin answers 403 and returns false unless r comes from an admin.
+// Handlers that expose every user's data call it themselves as well, so
+// they fail closed however they are routed.
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if s.isAdmin(r) {
+		return true
+	}
+	logFrom(r.Context()).Warn("admin endpoint denied", "path", r.URL.Path, "user_id", getUserID(r), "email", getUserEmail(r))
+	jsonError(w, "admin access required", http.StatusForbidden)
+	return false
+}
+
+// isPublicPath reports whether a path is served without session auth.
+func isPublicPath(path string) bool {
+	return path == "/health" ||
+		path == "/login" ||
+		strings.HasPrefix(path, "/static/") ||
+		path == "/favicon.ico" ||
+		path == "/manifest.webmanifest" ||
+		path == "/sw.js" ||
+		strings.HasPrefix(path, "/apple-touch-icon") ||
+		isFeedTokenPath(path) ||
+		isFeverPath(path)
+}
+
+// isFeedTokenPath reports whether a path authenticates with a feed token
+// (?token=) instead of the session, e.g. /api/feeds/{id}/atom.
+func isFeedTokenPath(path string) bool {
+	return strings.HasPrefix(path, "/api/feeds/") && strings.HasSuffix(path, "/atom")
+}
+
 // HandleLogin handles the login page and form submission
 func (s *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	password := GetPassword()
@@ -164,13 +324,14 @@ func (s *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		if subtle.ConstantTimeCompare([]byte(submitted), []byte(password)) == 1 {
 			// Password correct, create session
 			sessionID := createSession()
+			cfg := GetCookieConfig()
 			http.SetCookie(w, &http.Cookie{
-				Name:     "gorss_session",
+				Name:     cfg.Name,
 				Value:    sessionID,
 				Path:     "/",
 				HttpOnly: true,
-				Secure:   r.TLS != nil,
-				SameSite: http.SameSiteLaxMode,
+				Secure:   cfg.secure(r),
+				SameSite: cfg.SameSite,
 				MaxAge:   int(sessionTTL.Seconds()),
 			})
 			http.Redirect(w, r, "/", http.StatusFound)
@@ -186,17 +347,20 @@ func (s *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
 
 // HandleLogout handles logout
 func (s *Server) HandleLogout(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("gorss_session")
+	cfg := GetCookieConfig()
+	cookie, err := r.Cookie(cfg.Name)
 	if err == nil {
 		deleteSession(cookie.Value)
 	}
 
 	// Clear cookie
 	http.SetCookie(w, &http.Cookie{
-		Name:     "gorss_session",
+		Name:     cfg.Name,
 		Value:    "",
 		Path:     "/",
 		HttpOnly: true,
+		Secure:   cfg.secure(r),
+		SameSite: cfg.SameSite,
 		MaxAge:   -1,
 	})
 
@@ -206,7 +370,7 @@ func (s *Server) HandleLogout(w http.ResponseWriter, r *http.Request) {
 func (s *Server) renderLoginPage(w http.ResponseWriter, errorMsg string) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	html := `<!DOCTYPE html>
-<html lang="en">
+<html lang="en" data-default-theme="` + s.DefaultTheme + `"` + themeAttr(s.DefaultTheme) + `>
 <head>
   <meta charset="utf-8">
   <meta name="viewport" content="width=device-width, initial-scale=1.0">
@@ -362,10 +526,11 @@ func (s *Server) renderLoginPage(w http.ResponseWriter, errorMsg string) {
   <script>
     // Respect the same theme preference as the main app
     (function() {
-      var mode = localStorage.getItem('gorss-theme-mode') || 'auto';
+      var mode = localStorage.getItem('gorss-theme-mode') || document.documentElement.dataset.defaultTheme || 'auto';
       if (mode === 'dark') { document.documentElement.dataset.theme = 'dark'; }
       else if (mode === 'light') { document.documentElement.dataset.theme = 'light'; }
       else {
+        delete document.documentElement.dataset.theme;
         var hour = new Date().getHours();
         if (hour < 6 || hour >= 21) { document.documentElement.dataset.theme = 'dark'; }
       }
@@ -376,6 +541,15 @@ func (s *Server) renderLoginPage(w http.ResponseWriter, errorMsg string) {
 	_, _ = w.Write([]byte(html))
 }
 
+// themeAttr pins the login page to a fixed default theme before the
+// script runs, so it renders right even with scripts blocked.
+func themeAttr(theme string) string {
+	if theme == "light" || theme == "dark" {
+		return ` data-theme="` + theme + `"`
+	}
+	return ""
+}
+
 func errorHTML(msg string) string {
 	if msg == "" {
 		return ""
@@ -0,0 +1,102 @@
+package srv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Limits on the bodies kept by GORSS_CAPTURE_FEED_BODIES, so a broken feed
+// refreshed every few minutes can't fill the disk.
+const (
+	maxCaptureFiles = 100
+	maxCaptureBytes = 100 << 20 // 100 MB
+)
+
+// capturePrefix names capture files: capture-YYYYMMDD-HHMMSS.000-<host>.txt.
+const capturePrefix = "capture-"
+
+// parse parses a feed response body. With CaptureDir set, the body is
+// buffered so it can be saved when parsing fails.
+func (f *FeedFetcher) parse(urlStr string, resp *http.Response) (*gofeed.Feed, error) {
+	contentType := resp.Header.Get("Content-Type")
+	body := io.LimitReader(resp.Body, maxFeedBodySize)
+	if f.CaptureDir == "" {
+		return parseFeedBody(contentType, body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	feed, err := parseFeedBody(contentType, bytes.NewReader(data))
+	if err != nil {
+		if capErr := f.captureBody(urlStr, resp, data, err, time.Now()); capErr != nil {
+			slog.Warn("capture feed body", "url", urlStr, "error", capErr)
+		}
+	}
+	return feed, err
+}
+
+// captureBody saves a response body that failed to parse, preceded by a
+// short header with the URL, time, status and error, unless the capture
+// directory is already at its file or size limit.
+func (f *FeedFetcher) captureBody(urlStr string, resp *http.Response, body []byte, parseErr error, now time.Time) error {
+	if err := os.MkdirAll(f.CaptureDir, 0o755); err != nil {
+		return err
+	}
+	count, size, err := captureUsage(f.CaptureDir)
+	if err != nil {
+		return err
+	}
+	if count >= maxCaptureFiles || size+int64(len(body)) > maxCaptureBytes {
+		slog.Warn("feed capture limit reached, not saving body", "url", urlStr, "files", count, "bytes", size)
+		return nil
+	}
+
+	host := "unknown"
+	if u, err := url.Parse(urlStr); err == nil && u.Host != "" {
+		host = safeFilename(u.Host)
+	}
+	name := capturePrefix + now.UTC().Format("20060102-150405.000") + "-" + host + ".txt"
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "URL: %s\nTime: %s\nStatus: %d\nContent-Type: %s\nError: %v\n\n",
+		urlStr, now.UTC().Format(time.RFC3339), resp.StatusCode, resp.Header.Get("Content-Type"), parseErr)
+	buf.Write(body)
+
+	path := filepath.Join(f.CaptureDir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	slog.Info("captured unparseable feed body", "url", urlStr, "path", path)
+	return nil
+}
+
+// captureUsage returns the number and total size of capture files in dir.
+func captureUsage(dir string) (count int, size int64, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), capturePrefix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		count++
+		size += info.Size()
+	}
+	return count, size, nil
+}
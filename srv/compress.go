@@ -0,0 +1,62 @@
+package srv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/johnwmail/gorss/db/dbgen"
+)
+
+// minCompressLen is the shortest content worth compressing; below it the
+// gzip header and footer eat most of the savings.
+const minCompressLen = 256
+
+// compressContent gzips article content for storage when GORSS_COMPRESS_CONTENT
+// is on. It returns the stored form and the content_compressed flag; content
+// that is short or doesn't shrink is stored as is.
+func (s *Server) compressContent(content string) (string, int64) {
+	if !s.CompressContent || len(content) < minCompressLen {
+		return content, 0
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, content); err != nil {
+		return content, 0
+	}
+	if err := zw.Close(); err != nil || buf.Len() >= len(content) {
+		return content, 0
+	}
+	return buf.String(), 1
+}
+
+// indexCompressedContent puts the text of an article stored compressed into
+// the search index. The articles_fts triggers can't read gzip, so they index
+// its content as empty.
+func indexCompressedContent(ctx context.Context, q *dbgen.Queries, id int64, item *FeedItem) error {
+	return q.IndexArticleContent(ctx, dbgen.IndexArticleContentParams{
+		Title: item.Title, Content: item.Content, Summary: item.Summary, Rowid: id,
+	})
+}
+
+// decompressContent returns the HTML for stored content. Compressed content
+// is readable whether or not compression is currently enabled.
+func decompressContent(stored string, compressed int64) string {
+	if compressed == 0 {
+		return stored
+	}
+	zr, err := gzip.NewReader(strings.NewReader(stored))
+	if err != nil {
+		slog.Warn("decompress article content", "error", err)
+		return ""
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		slog.Warn("decompress article content", "error", err)
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,73 @@
+package srv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds settings read from a YAML config file (-config or
+// GORSS_CONFIG). Each field mirrors a GORSS_* environment variable; values
+// already set in the environment take precedence over the file.
+type Config struct {
+	Port            string `yaml:"port"`
+	DBPath          string `yaml:"db_path"`
+	AuthMode        string `yaml:"auth_mode"`
+	Password        string `yaml:"password"`
+	RefreshInterval string `yaml:"refresh_interval"`
+	PurgeDays       string `yaml:"purge_days"`
+	BackupDir       string `yaml:"backup_dir"`
+	BackupInterval  string `yaml:"backup_interval"`
+	BackupKeep      string `yaml:"backup_keep"`
+}
+
+// LoadConfig reads a config file. Unknown keys are rejected so typos don't
+// go unnoticed.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) { // empty file is fine
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// env maps the config values to their environment variables.
+func (c *Config) env() map[string]string {
+	return map[string]string{
+		"GORSS_PORT":             c.Port,
+		"GORSS_DB_PATH":          c.DBPath,
+		"GORSS_AUTH_MODE":        c.AuthMode,
+		"GORSS_PASSWORD":         c.Password,
+		"GORSS_REFRESH_INTERVAL": c.RefreshInterval,
+		"GORSS_PURGE_DAYS":       c.PurgeDays,
+		"GORSS_BACKUP_DIR":       c.BackupDir,
+		"GORSS_BACKUP_INTERVAL":  c.BackupInterval,
+		"GORSS_BACKUP_KEEP":      c.BackupKeep,
+	}
+}
+
+// ApplyEnv exports the file's values as GORSS_* environment variables,
+// leaving variables that are already set untouched, so the precedence is
+// environment > config file > built-in defaults.
+func (c *Config) ApplyEnv() error {
+	for name, value := range c.env() {
+		if value == "" || os.Getenv(name) != "" {
+			continue
+		}
+		if err := os.Setenv(name, strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("set %s: %w", name, err)
+		}
+	}
+	return nil
+}
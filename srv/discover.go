@@ -0,0 +1,108 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Bounds on calls to the feed discovery backend.
+const (
+	discoveryTimeout    = 10 * time.Second
+	maxDiscoveryBody    = 1 << 20 // 1 MB
+	maxDiscoveryResults = 50
+)
+
+// DiscoveredFeed is a candidate feed returned by the discovery backend.
+type DiscoveredFeed struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// discover queries the feed discovery backend configured by
+// GORSS_DISCOVERY_URL. A {q} placeholder in the URL is replaced by the
+// escaped query; otherwise it is sent as the q parameter. GORSS_DISCOVERY_KEY,
+// if set, is sent as a bearer token. The backend must answer with
+// {"results": [{"url": ..., "title": ...}]}.
+func (s *Server) discover(ctx context.Context, query string) ([]DiscoveredFeed, error) {
+	endpoint := s.DiscoveryURL
+	if strings.Contains(endpoint, "{q}") {
+		endpoint = strings.ReplaceAll(endpoint, "{q}", url.QueryEscape(query))
+	} else {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid discovery URL: %w", err)
+		}
+		params := u.Query()
+		params.Set("q", query)
+		u.RawQuery = params.Encode()
+		endpoint = u.String()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", s.fetcher.UserAgent)
+	req.Header.Set("Accept", "application/json")
+	if s.DiscoveryKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.DiscoveryKey)
+	}
+
+	resp, err := s.fetcher.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("query discovery backend: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery backend returned %s", resp.Status)
+	}
+
+	var body struct {
+		Results []DiscoveredFeed `json:"results"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDiscoveryBody)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode discovery response: %w", err)
+	}
+
+	feeds := make([]DiscoveredFeed, 0, min(len(body.Results), maxDiscoveryResults))
+	for _, f := range body.Results {
+		if len(feeds) == maxDiscoveryResults {
+			break
+		}
+		if u, err := url.Parse(f.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, nil
+}
+
+// HandleDiscoverFeeds searches the configured discovery backend for feeds
+// matching ?q=. Without a backend it returns an empty list.
+func (s *Server) HandleDiscoverFeeds(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		jsonError(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	if s.DiscoveryURL == "" {
+		jsonResponse(w, []DiscoveredFeed{})
+		return
+	}
+
+	feeds, err := s.discover(r.Context(), query)
+	if err != nil {
+		logFrom(r.Context()).Warn("feed discovery failed", "error", err)
+		jsonError(w, "feed discovery failed", http.StatusBadGateway)
+		return
+	}
+	jsonResponse(w, feeds)
+}
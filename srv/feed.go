@@ -1,60 +1,313 @@
 package srv
 
 import (
+	"cmp"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"math/rand/v2"
+	"mime"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
 	"github.com/johnwmail/gorss/db"
 	"github.com/johnwmail/gorss/db/dbgen"
+	"golang.org/x/net/html"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
 )
 
 // FeedFetcher handles RSS/Atom feed fetching and parsing
 type FeedFetcher struct {
-	parser           *gofeed.Parser
-	client           *http.Client
-	AllowPrivateURLs bool // for testing only
+	client            *http.Client
+	insecureClient    *http.Client // skips TLS verification; only for feeds flagged insecure_skip_verify
+	mediaClient       *http.Client // no overall timeout, for streaming enclosures (see FetchMedia)
+	AllowPrivateURLs  bool         // for testing only
+	StrictContentType bool         // reject responses whose Content-Type isn't a known feed type
+	CaptureDir        string       // save bodies that fail to parse here (GORSS_CAPTURE_FEED_BODIES)
+	Retries           int          // extra attempts after a network failure or 5xx response (GORSS_FETCH_RETRIES)
+	UserAgent         string       // sent with every outgoing request (GORSS_USER_AGENT)
+	retryDelay        time.Duration
+
+	etagMu     sync.Mutex
+	etagMisses map[string]int // per URL: consecutive 200s that echoed the ETag we sent
 }
 
 const maxFeedBodySize = 10 << 20 // 10 MB
 
 func NewFeedFetcher() *FeedFetcher {
-	return &FeedFetcher{
-		parser: gofeed.NewParser(),
-		client: &http.Client{Timeout: 30 * time.Second},
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	configurePool(transport)
+	if err := configureProxy(transport); err != nil {
+		slog.Warn("invalid proxy configuration, fetching feeds directly", "error", err)
+		transport.Proxy = nil
+	}
+	insecureTransport := transport.Clone()
+	insecureTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	f := &FeedFetcher{
+		client:            &http.Client{Timeout: 30 * time.Second, Transport: transport},
+		insecureClient:    &http.Client{Timeout: 30 * time.Second, Transport: insecureTransport},
+		StrictContentType: os.Getenv("GORSS_STRICT_CONTENT_TYPE") == "1",
+		CaptureDir:        os.Getenv("GORSS_CAPTURE_FEED_BODIES"),
+		Retries:           fetchRetriesFromEnv(),
+		UserAgent:         defaultUserAgent,
+		retryDelay:        200 * time.Millisecond,
+	}
+	if ua := strings.TrimSpace(os.Getenv("GORSS_USER_AGENT")); ua != "" {
+		f.UserAgent = ua
+	}
+	f.mediaClient = &http.Client{Transport: transport, CheckRedirect: f.checkMediaRedirect}
+	return f
+}
+
+// defaultUserAgent identifies GoRSS unless GORSS_USER_AGENT overrides it,
+// e.g. for sites whose firewall blocks unfamiliar clients.
+const defaultUserAgent = "GoRSS/1.0 (feed reader)"
+
+// Default connection pool limits for feed fetching.
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// configurePool sets the transport's keep-alive connection pool from
+// GORSS_HTTP_MAX_IDLE_CONNS, GORSS_HTTP_MAX_IDLE_CONNS_PER_HOST and
+// GORSS_HTTP_IDLE_CONN_TIMEOUT. The limits only bound how many idle
+// connections are kept for reuse, not how many requests run at once; there
+// is no per-host rate limiting beyond refreshAllFeeds fetching one feed at a
+// time, so a host serving several feeds reuses one pooled connection.
+func configurePool(transport *http.Transport) {
+	transport.DisableKeepAlives = false
+	transport.MaxIdleConns = maxLenFromEnv("GORSS_HTTP_MAX_IDLE_CONNS", defaultMaxIdleConns)
+	transport.MaxIdleConnsPerHost = maxLenFromEnv("GORSS_HTTP_MAX_IDLE_CONNS_PER_HOST", defaultMaxIdleConnsPerHost)
+	transport.IdleConnTimeout = defaultIdleConnTimeout
+	if v := os.Getenv("GORSS_HTTP_IDLE_CONN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			slog.Warn("invalid GORSS_HTTP_IDLE_CONN_TIMEOUT, using default", "value", v, "default", defaultIdleConnTimeout)
+		} else {
+			transport.IdleConnTimeout = d
+		}
 	}
 }
 
+// fetchRetriesFromEnv parses GORSS_FETCH_RETRIES (default
+// defaultFetchRetries, capped at maxFetchRetries).
+func fetchRetriesFromEnv() int {
+	v := os.Getenv("GORSS_FETCH_RETRIES")
+	if v == "" {
+		return defaultFetchRetries
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		slog.Warn("invalid GORSS_FETCH_RETRIES, not retrying", "value", v)
+		return 0
+	}
+	return min(n, maxFetchRetries)
+}
+
+const (
+	defaultFetchRetries = 3
+	maxFetchRetries     = 5
+)
+
+// Retry calls fn up to attempts times, waiting delay (doubled each time)
+// between calls. Only transient failures are retried; any other
+// error, or success, ends the loop. It returns the number of calls made and
+// fn's last error.
+func Retry(ctx context.Context, attempts int, delay time.Duration, fn func() error) (int, error) {
+	for n := 1; ; n++ {
+		err := fn()
+		if err == nil || n >= attempts || !isTransientFetchError(err) {
+			return n, err
+		}
+		select {
+		case <-ctx.Done():
+			return n, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// isTransientFetchError reports whether err is a connection-level failure
+// (timeout, connection refused or reset) or a 5xx response worth retrying, as
+// opposed to a 4xx response, a certificate error, a parse error or a host
+// that does not exist.
+func isTransientFetchError(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// resolveFeedURL resolves ref, as found in the feed at feedURL, to an absolute
+// http(s) URL. Anything else yields "".
+func resolveFeedURL(feedURL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+// isDNSNotFound reports whether err means the host does not exist (no such
+// host), as opposed to a resolver timeout or outage.
+func isDNSNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// httpStatusError is returned for a feed response with a 5xx status.
+type httpStatusError struct {
+	StatusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// configureProxy routes feed fetches through the proxy configured in the
+// environment. GORSS_HTTP_PROXY takes precedence over the standard
+// HTTP_PROXY/HTTPS_PROXY variables, and ALL_PROXY is used when neither is
+// set. NO_PROXY lists hosts that are always fetched directly. socks5://
+// proxies are dialed through golang.org/x/net/proxy.
+func configureProxy(transport *http.Transport) error {
+	cfg := httpproxy.FromEnvironment()
+	if p := os.Getenv("GORSS_HTTP_PROXY"); p != "" {
+		cfg.HTTPProxy, cfg.HTTPSProxy = p, p
+	} else if all := getenvAny("ALL_PROXY", "all_proxy"); all != "" {
+		if cfg.HTTPProxy == "" {
+			cfg.HTTPProxy = all
+		}
+		if cfg.HTTPSProxy == "" {
+			cfg.HTTPSProxy = all
+		}
+	}
+
+	proxyURL := cfg.HTTPSProxy
+	if proxyURL == "" {
+		proxyURL = cfg.HTTPProxy
+	}
+	if u, err := url.Parse(proxyURL); err == nil && strings.HasPrefix(u.Scheme, "socks5") {
+		dialer, err := proxy.FromURL(u, proxy.Direct)
+		if err != nil {
+			return fmt.Errorf("socks proxy %s: %w", u.Redacted(), err)
+		}
+		perHost := proxy.NewPerHost(dialer, proxy.Direct)
+		perHost.AddFromString(cfg.NoProxy)
+		transport.Proxy = nil
+		transport.DialContext = perHost.DialContext
+		return nil
+	}
+
+	proxyFunc := cfg.ProxyFunc()
+	transport.Proxy = func(r *http.Request) (*url.URL, error) {
+		return proxyFunc(r.URL)
+	}
+	return nil
+}
+
+// getenvAny returns the first non-empty environment variable among keys.
+func getenvAny(keys ...string) string {
+	for _, k := range keys {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 // isPrivateURL checks whether a URL resolves to a private, loopback, or link-local address.
 func isPrivateURL(rawURL string) bool {
+	private, err := checkURLAddress(rawURL)
+	return private || err != nil
+}
+
+// checkURLAddress is isPrivateURL that also returns the lookup error for a
+// host that does not resolve, so it can be told apart from a private one.
+func checkURLAddress(rawURL string) (private bool, err error) {
 	u, err := url.Parse(rawURL)
 	if err != nil {
-		return true
+		return true, nil
 	}
 	if u.Scheme != "http" && u.Scheme != "https" {
-		return true
+		return true, nil
 	}
 	host := u.Hostname()
 	ip := net.ParseIP(host)
 	if ip != nil {
-		return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()
+		return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast(), nil
 	}
 	ips, err := net.LookupIP(host)
 	if err != nil {
-		return true
+		return false, err
 	}
 	for _, ip := range ips {
 		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
-			return true
+			return true, nil
 		}
 	}
-	return false
+	return false, nil
+}
+
+// feedContentTypes are the media types feeds are normally served with.
+var feedContentTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/rdf+xml":   true,
+	"application/xml":       true,
+	"text/xml":              true,
+	"application/feed+json": true,
+	"application/json":      true,
+	"text/json":             true,
+	"application/x-rss+xml": true,
+}
+
+// isFeedContentType reports whether a Content-Type header names a known feed type.
+func isFeedContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return feedContentTypes[mediaType]
 }
 
 // FeedFetchResult contains the parsed feed data
@@ -62,6 +315,7 @@ type FeedFetchResult struct {
 	Title       string
 	SiteURL     string
 	Description string
+	LogoURL     string // channel image (RSS <image>, Atom <logo>), absolute
 	Items       []FeedItem
 	// HTTP caching headers from the response
 	ETag         string
@@ -75,7 +329,114 @@ type FeedItem struct {
 	Author      string
 	Content     string
 	Summary     string
-	PublishedAt *time.Time
+	PublishedAt *time.Time // article date; see applyDateSource
+	UpdatedAt   *time.Time
+
+	// Podcast episode metadata from the itunes: namespace; nil when absent
+	DurationSeconds *int64
+	Episode         *int64
+	Season          *int64
+	EpisodeType     *string
+
+	// First audio/video enclosure, proxied by GET /api/articles/{id}/media
+	EnclosureURL  *string
+	EnclosureType *string
+}
+
+// setPodcastMetadata copies the iTunes episode fields onto fi, skipping
+// values that are missing or malformed.
+func setPodcastMetadata(fi *FeedItem, it *ext.ITunesItemExtension) {
+	if it == nil {
+		return
+	}
+	if d, ok := parseITunesDuration(it.Duration); ok {
+		fi.DurationSeconds = &d
+	}
+	if n, err := strconv.ParseInt(strings.TrimSpace(it.Episode), 10, 64); err == nil && n >= 0 {
+		fi.Episode = &n
+	}
+	if n, err := strconv.ParseInt(strings.TrimSpace(it.Season), 10, 64); err == nil && n >= 0 {
+		fi.Season = &n
+	}
+	if t := strings.ToLower(strings.TrimSpace(it.EpisodeType)); t != "" {
+		fi.EpisodeType = &t
+	}
+}
+
+// setEnclosure records the first audio or video enclosure on fi. Enclosures
+// without a type are accepted, since many podcast feeds leave it out.
+func setEnclosure(fi *FeedItem, enclosures []*gofeed.Enclosure) {
+	for _, e := range enclosures {
+		if e == nil || strings.TrimSpace(e.URL) == "" {
+			continue
+		}
+		typ := strings.ToLower(strings.TrimSpace(e.Type))
+		if typ != "" && !strings.HasPrefix(typ, "audio/") && !strings.HasPrefix(typ, "video/") {
+			continue
+		}
+		u := strings.TrimSpace(e.URL)
+		fi.EnclosureURL = &u
+		if typ != "" {
+			fi.EnclosureType = &typ
+		}
+		return
+	}
+}
+
+// parseITunesDuration parses an itunes:duration, given either as seconds or
+// as [[HH:]MM:]SS, into seconds.
+func parseITunesDuration(s string) (int64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+	var total int64
+	for _, p := range parts {
+		n, err := strconv.ParseFloat(p, 64) // seconds are sometimes fractional
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		total = total*60 + int64(n)
+	}
+	return total, true
+}
+
+// Per-feed choices of the timestamp used as the article date (feeds.date_source).
+const (
+	dateSourcePreferPublished = "prefer_published" // published, else updated
+	dateSourcePublished       = "published"
+	dateSourceUpdated         = "updated"
+)
+
+func validDateSource(source string) bool {
+	switch source {
+	case dateSourcePreferPublished, dateSourcePublished, dateSourceUpdated:
+		return true
+	}
+	return false
+}
+
+// applyDateSource sets each item's PublishedAt from the dates the feed gave
+// (PublishedAt and UpdatedAt as fetched) according to source. Feeds that keep
+// re-updating old posts can use "published" so edits don't resurface them.
+func applyDateSource(items []FeedItem, source string) {
+	for i := range items {
+		switch source {
+		case dateSourcePublished:
+			// keep the published date (the parser itself falls back to
+			// updated for entries that have none)
+		case dateSourceUpdated:
+			items[i].PublishedAt = items[i].UpdatedAt
+		default:
+			if items[i].PublishedAt == nil {
+				items[i].PublishedAt = items[i].UpdatedAt
+			}
+		}
+	}
 }
 
 // errNotModified is returned when the server responds with 304 Not Modified.
@@ -83,45 +444,167 @@ var errNotModified = fmt.Errorf("feed not modified")
 
 // Fetch fetches and parses a feed URL (unconditional GET, used for initial subscribe).
 func (f *FeedFetcher) Fetch(ctx context.Context, url string) (*FeedFetchResult, error) {
-	return f.fetchWithCaching(ctx, url, "", "")
+	return f.fetchWithCaching(ctx, url, "", "", false)
+}
+
+// FetchInsecure is FetchConditional without TLS certificate verification,
+// for feeds explicitly flagged insecure_skip_verify.
+func (f *FeedFetcher) FetchInsecure(ctx context.Context, url, etag, lastModified string) (*FeedFetchResult, error) {
+	return f.fetchWithCaching(ctx, url, etag, lastModified, true)
 }
 
 // FetchConditional does a conditional GET using saved ETag/Last-Modified.
 // Returns errNotModified if the server says nothing changed.
 func (f *FeedFetcher) FetchConditional(ctx context.Context, url, etag, lastModified string) (*FeedFetchResult, error) {
-	return f.fetchWithCaching(ctx, url, etag, lastModified)
+	return f.fetchWithCaching(ctx, url, etag, lastModified, false)
 }
 
-func (f *FeedFetcher) fetchWithCaching(ctx context.Context, urlStr, etag, lastModified string) (*FeedFetchResult, error) {
-	if !f.AllowPrivateURLs && isPrivateURL(urlStr) {
-		return nil, fmt.Errorf("invalid feed URL: private or reserved address")
+// get issues the feed GET request, applying the private-address check and
+// any conditional headers. With insecure, TLS certificates are not verified.
+// The caller must close the response body.
+func (f *FeedFetcher) get(ctx context.Context, urlStr, etag, lastModified string, insecure bool) (*http.Response, error) {
+	if !f.AllowPrivateURLs {
+		private, err := checkURLAddress(urlStr)
+		if err != nil {
+			return nil, fmt.Errorf("resolve: %w", err)
+		}
+		if private {
+			return nil, fmt.Errorf("invalid feed URL: private or reserved address")
+		}
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
-	req.Header.Set("User-Agent", "GoRSS/1.0 (feed reader)")
+	req.Header.Set("User-Agent", f.UserAgent)
 
 	// Conditional GET headers
 	if etag != "" {
-		req.Header.Set("If-None-Match", etag)
+		req.Header.Set("If-None-Match", quoteETag(etag))
 	}
 	if lastModified != "" {
 		req.Header.Set("If-Modified-Since", lastModified)
 	}
 
-	resp, err := f.client.Do(req)
+	client := f.client
+	if insecure {
+		logFrom(ctx).Warn("fetching feed WITHOUT TLS certificate verification", "url", urlStr)
+		client = f.insecureClient
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetch: %w", err)
 	}
+	return resp, nil
+}
+
+// quoteETag returns etag as an If-None-Match entity tag. Stored ETags are
+// kept exactly as the server sent them, weak (W/"...") or strong ("...");
+// only malformed unquoted values are quoted, which some servers send.
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// etagIgnoredAfter is how many consecutive full (200) responses carrying the
+// same ETag we revalidated with it takes to conclude a server ignores
+// If-None-Match. From then on only If-Modified-Since is sent for that URL.
+const etagIgnoredAfter = 3
+
+// etagIgnored reports whether revalidating urlStr by ETag has been given up.
+func (f *FeedFetcher) etagIgnored(urlStr string) bool {
+	f.etagMu.Lock()
+	defer f.etagMu.Unlock()
+	return f.etagMisses[urlStr] >= etagIgnoredAfter
+}
+
+// trackETagRevalidation records whether a request sent with If-None-Match
+// sentETag was answered as expected. A 304 or a changed ETag resets the
+// count; a 200 with the ETag unchanged means the server didn't honor it.
+func (f *FeedFetcher) trackETagRevalidation(urlStr, sentETag string, resp *http.Response) {
+	f.etagMu.Lock()
+	defer f.etagMu.Unlock()
+	switch {
+	case resp.StatusCode == http.StatusNotModified,
+		quoteETag(resp.Header.Get("ETag")) != quoteETag(sentETag):
+		delete(f.etagMisses, urlStr)
+		return
+	case resp.StatusCode != http.StatusOK:
+		return
+	}
+	if f.etagMisses == nil {
+		f.etagMisses = make(map[string]int)
+	}
+	f.etagMisses[urlStr]++
+	if f.etagMisses[urlStr] == etagIgnoredAfter {
+		slog.Warn("server ignores ETag revalidation, falling back to If-Modified-Since",
+			"url", urlStr, "etag", sentETag, "weak", strings.HasPrefix(sentETag, "W/"))
+	}
+}
+
+// RawFeed is an unparsed feed response, used for troubleshooting.
+type RawFeed struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	Truncated   bool // body exceeded maxFeedBodySize and was cut off
+}
+
+// FetchRaw fetches a feed URL and returns the response body as-is, without
+// parsing. The body is capped at maxFeedBodySize.
+func (f *FeedFetcher) FetchRaw(ctx context.Context, urlStr string, insecure bool) (*RawFeed, error) {
+	resp, err := f.get(ctx, urlStr, "", "", insecure)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	raw := &RawFeed{StatusCode: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: body}
+	if len(body) > maxFeedBodySize {
+		raw.Body = body[:maxFeedBodySize]
+		raw.Truncated = true
+	}
+	return raw, nil
+}
+
+func (f *FeedFetcher) fetchWithCaching(ctx context.Context, urlStr, etag, lastModified string, insecure bool) (*FeedFetchResult, error) {
+	sentETag := etag
+	if f.etagIgnored(urlStr) {
+		sentETag = ""
+	}
+	resp, err := f.get(ctx, urlStr, sentETag, lastModified, insecure)
+	if err != nil {
+		return nil, err
+	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if sentETag != "" {
+		f.trackETagRevalidation(urlStr, sentETag, resp)
+	}
 	if resp.StatusCode == http.StatusNotModified {
 		return nil, errNotModified
 	}
+	if resp.StatusCode >= 500 {
+		return nil, &httpStatusError{StatusCode: resp.StatusCode}
+	}
 
-	feed, err := f.parser.Parse(io.LimitReader(resp.Body, maxFeedBodySize))
+	// Plenty of servers mislabel feeds (text/html, application/octet-stream),
+	// so parsing is attempted anyway unless strict mode is on.
+	if ct := resp.Header.Get("Content-Type"); !isFeedContentType(ct) {
+		if f.StrictContentType {
+			return nil, fmt.Errorf("unexpected content type %q", ct)
+		}
+		logFrom(ctx).Warn("feed response has non-feed content type", "url", urlStr, "content_type", ct)
+	}
+
+	feed, err := f.parse(urlStr, resp)
 	if err != nil {
 		return nil, fmt.Errorf("parse feed: %w", err)
 	}
@@ -134,6 +617,9 @@ func (f *FeedFetcher) fetchWithCaching(ctx context.Context, urlStr, etag, lastMo
 		ETag:         resp.Header.Get("ETag"),
 		LastModified: resp.Header.Get("Last-Modified"),
 	}
+	if feed.Image != nil {
+		result.LogoURL = resolveFeedURL(urlStr, feed.Image.URL)
+	}
 
 	for _, item := range feed.Items {
 		fi := FeedItem{
@@ -152,11 +638,14 @@ func (f *FeedFetcher) fetchWithCaching(ctx context.Context, urlStr, etag, lastMo
 			fi.Author = item.Author.Name
 		}
 
-		if item.PublishedParsed != nil {
-			fi.PublishedAt = item.PublishedParsed
-		} else if item.UpdatedParsed != nil {
-			fi.PublishedAt = item.UpdatedParsed
+		// Raw dates; callers pick the article date with applyDateSource
+		fi.PublishedAt = item.PublishedParsed
+		fi.UpdatedAt = item.UpdatedParsed
+		if fi.GUID == "" {
+			fi.GUID = syntheticGUID(&fi)
 		}
+		setPodcastMetadata(&fi, item.ITunesExt)
+		setEnclosure(&fi, item.Enclosures)
 
 		result.Items = append(result.Items, fi)
 	}
@@ -164,6 +653,37 @@ func (f *FeedFetcher) fetchWithCaching(ctx context.Context, urlStr, etag, lastMo
 	return result, nil
 }
 
+// syntheticGUID identifies an item by a hash of its title, published date and
+// content (or summary, when there is no content). It stands in for items
+// with neither a GUID nor a link, and for every item of feeds flagged
+// guid_unstable. The raw fetched values are hashed, so it must be computed
+// before applyDateSource and processItem, and the same item hashes the same
+// on every refresh.
+func syntheticGUID(item *FeedItem) string {
+	h := sha256.New()
+	body := item.Content
+	if body == "" {
+		body = item.Summary
+	}
+	published := ""
+	if item.PublishedAt != nil {
+		published = item.PublishedAt.UTC().Format(time.RFC3339)
+	}
+	fmt.Fprintf(h, "%s\n%s\n%s", item.Title, published, body)
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// fallbackFeedTitle derives a title for a feed that has none: the site's
+// hostname without "www.", else the feed URL's hostname, else the URL itself.
+func fallbackFeedTitle(siteURL, feedURL string) string {
+	for _, raw := range []string{siteURL, feedURL} {
+		if u, err := url.Parse(strings.TrimSpace(raw)); err == nil && u.Hostname() != "" {
+			return strings.TrimPrefix(u.Hostname(), "www.")
+		}
+	}
+	return feedURL
+}
+
 // filterOldItems removes items older than the cutoff from the result in place.
 func filterOldItems(items []FeedItem, cutoff time.Time) []FeedItem {
 	filtered := items[:0]
@@ -176,6 +696,259 @@ func filterOldItems(items []FeedItem, cutoff time.Time) []FeedItem {
 	return filtered
 }
 
+// compileKeepOnly compiles a feed's keep_only_pattern. Matching is
+// case-insensitive; an empty pattern returns nil (keep everything).
+func compileKeepOnly(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// filterKeepOnly removes items whose title and summary both fail to match re
+// from the result in place. A nil re keeps every item.
+func filterKeepOnly(items []FeedItem, re *regexp.Regexp) []FeedItem {
+	if re == nil {
+		return items
+	}
+	filtered := items[:0]
+	for _, item := range items {
+		if re.MatchString(item.Title) || re.MatchString(item.Summary) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// processItem runs the content processing pipeline on a fetched item before
+// it is stored. reprocessContent re-applies the same pipeline to articles
+// already in the database, so every ingestion-time transformation belongs here.
+func processItem(item *FeedItem) {
+	item.Title = strings.TrimSpace(item.Title)
+	item.Content = strings.TrimSpace(item.Content)
+	item.Summary = strings.TrimSpace(item.Summary)
+}
+
+// Default caps on stored title and summary length, in characters. They are
+// generous so they only catch feeds that put whole paragraphs in a title.
+const (
+	defaultMaxTitleLen   = 500
+	defaultMaxSummaryLen = 5000
+)
+
+// defaultExcerptLen is the length, in characters, of the plain-text summary
+// generated for items that have content but no summary.
+const defaultExcerptLen = 200
+
+// maxLenFromEnv parses a length cap from the environment (0 = no limit).
+func maxLenFromEnv(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		slog.Warn("invalid "+name+", using default", "value", v, "default", def)
+		return def
+	}
+	return n
+}
+
+// truncateText shortens s to at most limit characters, cutting at a word
+// boundary where possible and ending with an ellipsis. limit <= 0 disables it.
+func truncateText(s string, limit int) string {
+	if limit <= 0 || utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	cut := string(runes[:limit-1]) // leave room for the ellipsis
+	// Back up to the last word break unless the cut already falls on one
+	if !unicode.IsSpace(runes[limit-1]) {
+		if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > len(cut)/2 {
+			cut = cut[:i]
+		}
+	}
+	return strings.TrimRightFunc(cut, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsPunct(r) }) + "…"
+}
+
+// excerptItem fills in a missing summary with a plain-text excerpt of the
+// content, so list previews aren't empty for full-content-only feeds.
+func (s *Server) excerptItem(item *FeedItem) {
+	if item.Summary == "" && item.Content != "" && s.ExcerptLen > 0 {
+		item.Summary = truncateText(htmlToText(item.Content), s.ExcerptLen)
+	}
+}
+
+// htmlToText returns the text of an HTML fragment with tags, scripts and
+// styles removed and runs of whitespace collapsed to single spaces.
+func htmlToText(fragment string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	skip := 0 // depth inside <script> or <style>
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(z.Text())
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style":
+				if tt == html.StartTagToken {
+					skip++
+				} else if tt == html.EndTagToken && skip > 0 {
+					skip--
+				}
+			case "p", "div", "br", "li", "tr", "td", "th", "blockquote", "pre",
+				"h1", "h2", "h3", "h4", "h5", "h6", "hr":
+				b.WriteByte(' ') // keep words in adjacent blocks apart
+			}
+		}
+	}
+}
+
+// truncateItem applies the title and summary length caps. Content is never
+// truncated.
+func (s *Server) truncateItem(item *FeedItem) {
+	item.Title = truncateText(item.Title, s.MaxTitleLen)
+	item.Summary = truncateText(item.Summary, s.MaxSummaryLen)
+}
+
+// contentHash hashes an item's text, or its summary's when it has no
+// content. Markup and whitespace are dropped first, so reformatting alone
+// doesn't count as an edit.
+func contentHash(item *FeedItem) string {
+	text := htmlToText(item.Content)
+	if text == "" {
+		text = htmlToText(item.Summary)
+	}
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// markEditedUnread marks the article with guid unread for its readers if
+// its stored hash differs from hash. It must run before the upsert replaces
+// the stored hash.
+func markEditedUnread(ctx context.Context, q *dbgen.Queries, feedID int64, guid, hash string) {
+	res, err := q.ClearReadIfEdited(ctx, dbgen.ClearReadIfEditedParams{FeedID: feedID, Guid: guid, ContentHash: hash})
+	if err != nil {
+		logFrom(ctx).Warn("mark edited article unread", "error", err, "guid", guid)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		logFrom(ctx).Debug("edited article marked unread", "feed_id", feedID, "guid", guid, "readers", n)
+	}
+}
+
+// storeItems processes and upserts fetched items into a feed. It returns
+// how many distinct articles were stored, where failed upserts and repeats
+// of a GUID within items don't count, and the items that were new.
+func (s *Server) storeItems(ctx context.Context, q *dbgen.Queries, feed *dbgen.Feed, items []FeedItem) (int, []FeedItem) {
+	stored := make(map[string]bool, len(items))
+	var added []FeedItem
+	var titles map[string]string
+	if feed.DedupTitles != 0 {
+		titles = recentTitleGUIDs(ctx, q, feed.ID)
+	}
+	for _, item := range items {
+		processItem(&item)
+		s.excerptItem(&item)
+		s.truncateItem(&item)
+		if titles != nil {
+			item.GUID = dedupTitleGUID(titles, &item)
+		}
+		hash := contentHash(&item)
+		if s.UnreadOnUpdate && !stored[item.GUID] {
+			markEditedUnread(ctx, q, feed.ID, item.GUID, hash)
+		}
+		content, compressed := s.compressContent(item.Content)
+		article, err := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
+			FeedID:            feed.ID,
+			Guid:              item.GUID,
+			Url:               item.URL,
+			Title:             item.Title,
+			Author:            item.Author,
+			Content:           content,
+			ContentCompressed: compressed,
+			Summary:           item.Summary,
+			PublishedAt:       item.PublishedAt,
+
+			DurationSeconds: item.DurationSeconds,
+			Episode:         item.Episode,
+			Season:          item.Season,
+			EpisodeType:     item.EpisodeType,
+			EnclosureUrl:    item.EnclosureURL,
+			EnclosureType:   item.EnclosureType,
+			ContentHash:     hash,
+		})
+		if err != nil {
+			logFrom(ctx).Warn("upsert article", "error", err, "guid", item.GUID)
+			continue
+		}
+		if compressed != 0 {
+			if err := indexCompressedContent(ctx, q, article.ID, &item); err != nil {
+				logFrom(ctx).Warn("index compressed content", "error", err, "article_id", article.ID)
+			}
+		}
+		// A GUID repeated within items was inserted by its first copy
+		if article.Inserted != 0 && !stored[item.GUID] {
+			added = append(added, item)
+		}
+		stored[item.GUID] = true
+	}
+	return len(stored), added
+}
+
+// titleDedupWindow is how far back title dedup looks for an earlier article.
+const titleDedupWindow = 7 * 24 * time.Hour
+
+// titleDedupMaxAge is titleDedupWindow as an SQLite datetime() modifier.
+var titleDedupMaxAge = fmt.Sprintf("-%d seconds", int(titleDedupWindow.Seconds()))
+
+// recentTitleGUIDs maps the normalized titles of a feed's recent articles
+// to their GUIDs.
+func recentTitleGUIDs(ctx context.Context, q *dbgen.Queries, feedID int64) map[string]string {
+	rows, err := q.GetRecentArticleTitles(ctx, dbgen.GetRecentArticleTitlesParams{FeedID: feedID, MaxAge: titleDedupMaxAge})
+	if err != nil {
+		logFrom(ctx).Warn("get recent article titles", "error", err, "feed_id", feedID)
+	}
+	titles := make(map[string]string, len(rows))
+	for _, row := range rows {
+		if key := normalizeTitle(row.Title); key != "" {
+			titles[key] = row.Guid
+		}
+	}
+	return titles
+}
+
+// dedupTitleGUID returns the GUID of an earlier article with the same
+// normalized title, so the upsert updates it instead of inserting a
+// near-duplicate. Otherwise the item's own GUID is recorded and returned.
+func dedupTitleGUID(titles map[string]string, item *FeedItem) string {
+	key := normalizeTitle(item.Title)
+	if key == "" {
+		return item.GUID
+	}
+	if guid, ok := titles[key]; ok {
+		return guid
+	}
+	titles[key] = item.GUID
+	return item.GUID
+}
+
+// normalizeTitle lowercases a title and drops punctuation, symbols and
+// extra whitespace.
+func normalizeTitle(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	return strings.Join(words, " ")
+}
+
 // maxBackoffHours caps exponential backoff at 24 hours.
 const maxBackoffHours = 24
 
@@ -194,7 +967,8 @@ func shouldSkipFeed(feed *dbgen.Feed) bool {
 	return time.Now().Before(nextAllowed)
 }
 
-// RefreshFeed fetches a feed and stores new articles
+// RefreshFeed fetches a feed and stores new articles. As a manual,
+// single-feed refresh it ignores error backoff.
 func (s *Server) RefreshFeed(ctx context.Context, feedID int64) error {
 	q := dbgen.New(s.DB)
 
@@ -217,57 +991,75 @@ func (s *Server) RefreshFeed(ctx context.Context, feedID int64) error {
 	return s.refreshFeedInternal(ctx, q, feed)
 }
 
-func (s *Server) refreshFeedInternal(ctx context.Context, q *dbgen.Queries, feed *dbgen.Feed) error {
-	// Skip feeds in error backoff
-	if shouldSkipFeed(feed) {
-		slog.Debug("skipping feed (backoff)", "feed_id", feed.ID, "error_count", feed.ErrorCount)
-		return nil
-	}
+// skipVerify reports whether a feed with the given insecure_skip_verify
+// flag is fetched without TLS certificate verification. A stored flag
+// stops counting once GORSS_ALLOW_INSECURE_TLS is turned off.
+func (s *Server) skipVerify(insecureSkipVerify int64) bool {
+	return s.AllowInsecureTLS && insecureSkipVerify != 0
+}
 
+// refreshFeedInternal fetches one feed and stores its items. Error backoff
+// is up to the caller (see refreshAllFeeds).
+func (s *Server) refreshFeedInternal(ctx context.Context, q *dbgen.Queries, feed *dbgen.Feed) error {
 	// Use conditional GET with saved caching headers
-	result, err := s.fetcher.FetchConditional(ctx, feed.Url, feed.Etag, feed.LastModified)
+	fetch := s.fetcher.FetchConditional
+	if s.skipVerify(feed.InsecureSkipVerify) {
+		fetch = s.fetcher.FetchInsecure
+	}
+	var result *FeedFetchResult
+	attempts, err := Retry(ctx, s.fetcher.Retries+1, s.fetcher.retryDelay, func() error {
+		var err error
+		result, err = fetch(ctx, feed.Url, feed.Etag, feed.LastModified)
+		return err
+	})
 	now := time.Now()
+	if err := q.UpdateFeedFetchAttempts(ctx, dbgen.UpdateFeedFetchAttemptsParams{
+		LastFetchAttempts: int64(attempts),
+		FetchAttempts:     int64(attempts),
+		ID:                feed.ID,
+	}); err != nil {
+		logFrom(ctx).Warn("update feed fetch attempts", "error", err, "feed_id", feed.ID)
+	}
 
 	if err == errNotModified {
-		slog.Debug("feed not modified (304)", "feed_id", feed.ID, "title", feed.Title)
-		// Update last_updated timestamp, reset error count, keep caching headers
-		_ = q.UpdateFeedMeta(ctx, dbgen.UpdateFeedMetaParams{
-			ID:           feed.ID,
-			Title:        feed.Title,
-			SiteUrl:      feed.SiteUrl,
-			Description:  feed.Description,
-			LastUpdated:  &now,
-			LastError:    nil,
-			Etag:         feed.Etag,
-			LastModified: feed.LastModified,
-			ErrorCount:   0,
-		})
+		logFrom(ctx).Debug("feed not modified (304)", "feed_id", feed.ID, "title", feed.Title, "attempts", attempts)
+		s.feedNotModified(ctx, q, feed, now)
 		return nil
 	}
 
+	s.trackDNSFailures(ctx, q, feed, err)
 	if err != nil {
-		errStr := err.Error()
-		_ = q.UpdateFeedMeta(ctx, dbgen.UpdateFeedMetaParams{
-			ID:           feed.ID,
-			Title:        feed.Title,
-			SiteUrl:      feed.SiteUrl,
-			Description:  feed.Description,
-			LastUpdated:  &now,
-			LastError:    &errStr,
-			Etag:         feed.Etag,
-			LastModified: feed.LastModified,
-			ErrorCount:   feed.ErrorCount + 1,
-		})
+		feedFetchFailed(ctx, q, feed, now, err)
+		logFrom(ctx).Debug("feed fetch failed", "feed_id", feed.ID, "attempts", attempts, "error", err)
 		return fmt.Errorf("fetch feed %s: %w", feed.Url, err)
 	}
 
+	s.trackEmptyFeed(ctx, q, feed, len(result.Items))
+	if feed.GuidUnstable != 0 {
+		for i := range result.Items {
+			result.Items[i].GUID = syntheticGUID(&result.Items[i])
+		}
+	}
+	applyDateSource(result.Items, feed.DateSource)
+
 	// Filter out articles older than purge threshold
 	if s.PurgeDays > 0 {
 		cutoff := time.Now().AddDate(0, 0, -s.PurgeDays)
 		beforeCount := len(result.Items)
 		result.Items = filterOldItems(result.Items, cutoff)
 		if skipped := beforeCount - len(result.Items); skipped > 0 {
-			slog.Debug("filtered old articles", "feed_id", feed.ID, "skipped", skipped, "cutoff_days", s.PurgeDays)
+			logFrom(ctx).Debug("filtered old articles", "feed_id", feed.ID, "skipped", skipped, "cutoff_days", s.PurgeDays)
+		}
+	}
+
+	// Drop items outside the feed's keep-only filter
+	if re, err := compileKeepOnly(feed.KeepOnlyPattern); err != nil {
+		logFrom(ctx).Warn("invalid keep-only pattern", "error", err, "feed_id", feed.ID)
+	} else if re != nil {
+		beforeCount := len(result.Items)
+		result.Items = filterKeepOnly(result.Items, re)
+		if skipped := beforeCount - len(result.Items); skipped > 0 {
+			logFrom(ctx).Debug("filtered non-matching articles", "feed_id", feed.ID, "skipped", skipped, "pattern", feed.KeepOnlyPattern)
 		}
 	}
 
@@ -276,11 +1068,15 @@ func (s *Server) refreshFeedInternal(ctx context.Context, q *dbgen.Queries, feed
 	if title == "" {
 		title = feed.Title
 	}
+	if title == "" {
+		title = fallbackFeedTitle(result.SiteURL, feed.Url)
+	}
 	err = q.UpdateFeedMeta(ctx, dbgen.UpdateFeedMetaParams{
 		ID:           feed.ID,
 		Title:        title,
 		SiteUrl:      result.SiteURL,
 		Description:  result.Description,
+		LogoUrl:      result.LogoURL,
 		LastUpdated:  &now,
 		LastError:    nil,
 		Etag:         result.ETag,
@@ -288,62 +1084,360 @@ func (s *Server) refreshFeedInternal(ctx context.Context, q *dbgen.Queries, feed
 		ErrorCount:   0,
 	})
 	if err != nil {
-		slog.Warn("update feed meta", "error", err, "feed_id", feed.ID)
+		logFrom(ctx).Warn("update feed meta", "error", err, "feed_id", feed.ID)
 	}
+	recordFeedSuccess(ctx, q, feed, now)
 
 	// Insert articles
-	for _, item := range result.Items {
-		_, err := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
-			FeedID:      feed.ID,
-			Guid:        item.GUID,
-			Url:         item.URL,
-			Title:       item.Title,
-			Author:      item.Author,
-			Content:     item.Content,
-			Summary:     item.Summary,
-			PublishedAt: item.PublishedAt,
-		})
-		if err != nil {
-			slog.Warn("upsert article", "error", err, "guid", item.GUID)
+	_, added := s.storeItems(ctx, q, feed, result.Items)
+	s.notifyNewArticles(ctx, feed, title, added)
+	autoReadStale(ctx, q, feed)
+
+	logFrom(ctx).Info("refreshed feed", "feed_id", feed.ID, "title", title, "items", len(result.Items), "new_articles", len(added), "attempts", attempts)
+	return nil
+}
+
+// feedNotModified records a 304 refresh: last_updated moves on and the
+// error count is reset, keeping the caching headers.
+func (s *Server) feedNotModified(ctx context.Context, q *dbgen.Queries, feed *dbgen.Feed, now time.Time) {
+	_ = q.UpdateFeedMeta(ctx, dbgen.UpdateFeedMetaParams{
+		ID:           feed.ID,
+		Title:        feed.Title,
+		SiteUrl:      feed.SiteUrl,
+		Description:  feed.Description,
+		LogoUrl:      feed.LogoUrl,
+		LastUpdated:  &now,
+		LastError:    nil,
+		Etag:         feed.Etag,
+		LastModified: feed.LastModified,
+		ErrorCount:   0,
+	})
+	recordFeedSuccess(ctx, q, feed, now)
+	s.trackDNSFailures(ctx, q, feed, nil)
+	autoReadStale(ctx, q, feed)
+}
+
+// feedFetchFailed records a failed fetch's error and counts it towards the
+// feed's backoff.
+func feedFetchFailed(ctx context.Context, q *dbgen.Queries, feed *dbgen.Feed, now time.Time, err error) {
+	errStr := err.Error()
+	_ = q.UpdateFeedMeta(ctx, dbgen.UpdateFeedMetaParams{
+		ID:           feed.ID,
+		Title:        feed.Title,
+		SiteUrl:      feed.SiteUrl,
+		Description:  feed.Description,
+		LogoUrl:      feed.LogoUrl,
+		LastUpdated:  &now,
+		LastError:    &errStr,
+		Etag:         feed.Etag,
+		LastModified: feed.LastModified,
+		ErrorCount:   feed.ErrorCount + 1,
+	})
+}
+
+// recordFeedSuccess sets when the feed was last fetched successfully.
+func recordFeedSuccess(ctx context.Context, q *dbgen.Queries, feed *dbgen.Feed, now time.Time) {
+	if err := q.UpdateFeedLastSuccess(ctx, dbgen.UpdateFeedLastSuccessParams{LastSuccessAt: &now, ID: feed.ID}); err != nil {
+		logFrom(ctx).Warn("update feed last success", "error", err, "feed_id", feed.ID)
+	}
+}
+
+// trackEmptyFeed counts consecutive refreshes in which a feed parsed but
+// returned no items, and sets a warning once EmptyFeedWarnAfter is reached.
+// Both are reset as soon as items reappear.
+func (s *Server) trackEmptyFeed(ctx context.Context, q *dbgen.Queries, feed *dbgen.Feed, itemCount int) {
+	emptyCount := int64(0)
+	var warning *string
+	if itemCount == 0 {
+		emptyCount = feed.EmptyCount + 1
+		if s.EmptyFeedWarnAfter > 0 && emptyCount >= int64(s.EmptyFeedWarnAfter) {
+			msg := fmt.Sprintf("feed returned no items in the last %d refreshes", emptyCount)
+			warning = &msg
 		}
+	} else if feed.EmptyCount == 0 && feed.LastWarning == nil {
+		return
 	}
 
-	slog.Info("refreshed feed", "feed_id", feed.ID, "title", title, "articles", len(result.Items))
-	return nil
+	if err := q.UpdateFeedEmptyStatus(ctx, dbgen.UpdateFeedEmptyStatusParams{
+		EmptyCount: emptyCount, LastWarning: warning, ID: feed.ID,
+	}); err != nil {
+		logFrom(ctx).Warn("update feed empty status", "error", err, "feed_id", feed.ID)
+		return
+	}
+	if warning != nil && feed.LastWarning == nil {
+		logFrom(ctx).Warn("feed returning no items", "feed_id", feed.ID, "url", feed.Url, "refreshes", emptyCount)
+	}
+}
+
+// trackDNSFailures counts consecutive fetches that failed because the feed's
+// host does not exist, and flags the feed dead once DNSFailDisableAfter is
+// reached. Any other outcome shows the host resolves and resets the count.
+func (s *Server) trackDNSFailures(ctx context.Context, q *dbgen.Queries, feed *dbgen.Feed, fetchErr error) {
+	var failCount, dead int64
+	if isDNSNotFound(fetchErr) {
+		failCount, dead = feed.DnsFailCount+1, feed.Dead
+		if s.DNSFailDisableAfter > 0 && failCount >= int64(s.DNSFailDisableAfter) {
+			dead = 1
+		}
+	} else if feed.DnsFailCount == 0 && feed.Dead == 0 {
+		return
+	}
+
+	if err := q.UpdateFeedDNSFailures(ctx, dbgen.UpdateFeedDNSFailuresParams{
+		DnsFailCount: failCount, Dead: dead, ID: feed.ID,
+	}); err != nil {
+		logFrom(ctx).Warn("update feed dns failures", "error", err, "feed_id", feed.ID)
+		return
+	}
+	if dead != 0 && feed.Dead == 0 {
+		logFrom(ctx).Warn("feed host does not resolve, marking dead", "feed_id", feed.ID, "url", feed.Url, "failures", failCount)
+	}
+}
+
+// autoReadStale marks unread, unstarred articles read once they are older than
+// the feed's auto_read_after_days setting. A setting of 0 disables the rule.
+func autoReadStale(ctx context.Context, q *dbgen.Queries, feed *dbgen.Feed) {
+	if feed.AutoReadAfterDays <= 0 {
+		return
+	}
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -int(feed.AutoReadAfterDays))
+	result, err := q.AutoReadStaleArticles(ctx, dbgen.AutoReadStaleArticlesParams{
+		ReadAt: &now, FeedID: feed.ID, Cutoff: &cutoff,
+	})
+	if err != nil {
+		logFrom(ctx).Warn("auto-read stale articles", "error", err, "feed_id", feed.ID)
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		logFrom(ctx).Info("auto-read stale articles", "feed_id", feed.ID, "count", n, "after_days", feed.AutoReadAfterDays)
+	}
 }
 
-// StartBackgroundRefresh starts a goroutine that periodically refreshes all feeds
+// defaultRefreshInterval is used when Server.RefreshInterval is unset.
+const defaultRefreshInterval = time.Hour
+
+// maxRefreshTick bounds how long the background loop sleeps between checks
+// for due feeds, so short per-feed/category intervals are honored.
+const maxRefreshTick = time.Minute
+
+// StartBackgroundRefresh starts a goroutine that periodically refreshes feeds
+// whose effective refresh interval has elapsed
 func (s *Server) StartBackgroundRefresh(ctx context.Context, interval time.Duration) {
-	go func() {
-		ticker := time.NewTicker(interval)
+	s.jobs.Go(func() {
+		ticker := time.NewTicker(min(interval, maxRefreshTick))
 		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ctx.Done():
-				slog.Info("stopping background feed refresh")
+				logFrom(ctx).Info("stopping background feed refresh")
 				return
 			case <-ticker.C:
-				s.refreshAllFeeds(ctx)
+				s.refreshAllFeeds(withJobID(ctx, "refresh"), refreshScheduled)
 			}
 		}
-	}()
+	})
+}
+
+// effectiveRefreshInterval resolves a feed's refresh interval: the feed's
+// own override, then its category's interval, then the global default.
+func (s *Server) effectiveRefreshInterval(feed *dbgen.Feed, categoryIntervals map[int64]int64) time.Duration {
+	if feed.RefreshIntervalMinutes != nil && *feed.RefreshIntervalMinutes > 0 {
+		return time.Duration(*feed.RefreshIntervalMinutes) * time.Minute
+	}
+	if feed.CategoryID != nil {
+		if m := categoryIntervals[*feed.CategoryID]; m > 0 {
+			return time.Duration(m) * time.Minute
+		}
+	}
+	if s.RefreshInterval > 0 {
+		return s.RefreshInterval
+	}
+	return defaultRefreshInterval
+}
+
+// categoryRefreshIntervals maps category IDs to their refresh interval in minutes.
+func categoryRefreshIntervals(ctx context.Context, q *dbgen.Queries) map[int64]int64 {
+	rows, err := q.GetCategoryRefreshIntervals(ctx)
+	if err != nil {
+		logFrom(ctx).Warn("get category refresh intervals", "error", err)
+	}
+	intervals := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		if row.RefreshIntervalMinutes != nil {
+			intervals[row.ID] = *row.RefreshIntervalMinutes
+		}
+	}
+	return intervals
+}
+
+// feedDue reports whether a feed's refresh interval has elapsed.
+func feedDue(feed *dbgen.Feed, interval time.Duration, now time.Time) bool {
+	return feed.LastUpdated == nil || now.Sub(*feed.LastUpdated) >= interval
+}
+
+// refreshOnStart does the initial refresh of every feed at startup, unless
+// RefreshOnStart is off, in which case feeds wait for the first background
+// tick. Skipping it avoids a burst of fetches on every restart or deploy.
+func (s *Server) refreshOnStart(ctx context.Context) {
+	if !s.RefreshOnStart {
+		logFrom(ctx).Info("skipping startup refresh (GORSS_REFRESH_ON_START=false)")
+		return
+	}
+	s.refreshAllFeeds(withJobID(ctx, "refresh"), refreshAll)
+}
+
+// quietHours is a daily window, in server local time (TZ), during which
+// background refresh cycles are skipped. The window runs from start up to
+// (not including) end and may wrap past midnight, e.g. 23-06.
+type quietHours struct {
+	start, end int
+}
+
+// parseQuietHours parses a window of the form "HH-HH".
+func parseQuietHours(v string) (*quietHours, error) {
+	from, to, ok := strings.Cut(v, "-")
+	if !ok {
+		return nil, fmt.Errorf("expected HH-HH")
+	}
+	start, err1 := strconv.Atoi(strings.TrimSpace(from))
+	end, err2 := strconv.Atoi(strings.TrimSpace(to))
+	if err1 != nil || err2 != nil || start < 0 || start > 23 || end < 0 || end > 23 {
+		return nil, fmt.Errorf("hours must be 0-23")
+	}
+	if start == end {
+		return nil, fmt.Errorf("start and end must differ")
+	}
+	return &quietHours{start: start, end: end}, nil
+}
+
+// quietHoursFromEnv parses GORSS_REFRESH_QUIET_HOURS (default: none).
+func quietHoursFromEnv() *quietHours {
+	v := os.Getenv("GORSS_REFRESH_QUIET_HOURS")
+	if v == "" {
+		return nil
+	}
+	qh, err := parseQuietHours(v)
+	if err != nil {
+		slog.Warn("invalid GORSS_REFRESH_QUIET_HOURS, ignoring", "value", v, "error", err)
+		return nil
+	}
+	return qh
+}
+
+// contains reports whether t falls within the window. A nil window
+// contains nothing.
+func (qh *quietHours) contains(t time.Time) bool {
+	if qh == nil {
+		return false
+	}
+	h := t.Local().Hour()
+	if qh.start < qh.end {
+		return h >= qh.start && h < qh.end
+	}
+	return h >= qh.start || h < qh.end
+}
+
+// Feed orderings for a refresh cycle (GORSS_REFRESH_ORDER).
+const (
+	refreshOrderStale  = "stale"  // least recently updated first, for fairness
+	refreshOrderActive = "active" // most articles in the last activityWindow first
+	refreshOrderRandom = "random"
+)
+
+// activityWindow is how far back refreshOrderActive counts new articles.
+const activityWindow = 7 * 24 * time.Hour
+
+// refreshOrderFromEnv parses GORSS_REFRESH_ORDER (default stale).
+func refreshOrderFromEnv() string {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv("GORSS_REFRESH_ORDER")))
+	switch v {
+	case "":
+		return refreshOrderStale
+	case refreshOrderStale, refreshOrderActive, refreshOrderRandom:
+		return v
+	}
+	slog.Warn("invalid GORSS_REFRESH_ORDER, refreshing stalest first", "value", v)
+	return refreshOrderStale
+}
+
+// orderFeedsForRefresh reorders feeds, as returned stalest first by
+// GetAllFeedsForRefresh, according to s.RefreshOrder. Feeds with equal
+// activity stay stalest first.
+func (s *Server) orderFeedsForRefresh(ctx context.Context, q *dbgen.Queries, feeds []dbgen.Feed) {
+	switch s.RefreshOrder {
+	case refreshOrderActive:
+		rows, err := q.GetFeedActivity(ctx, fmt.Sprintf("-%d seconds", int(activityWindow.Seconds())))
+		if err != nil {
+			logFrom(ctx).Warn("get feed activity", "error", err)
+			return
+		}
+		activity := make(map[int64]int64, len(rows))
+		for _, row := range rows {
+			activity[row.FeedID] = row.ArticleCount
+		}
+		slices.SortStableFunc(feeds, func(a, b dbgen.Feed) int {
+			return cmp.Compare(activity[b.ID], activity[a.ID])
+		})
+	case refreshOrderRandom:
+		rand.Shuffle(len(feeds), func(i, j int) { feeds[i], feeds[j] = feeds[j], feeds[i] })
+	}
 }
 
-func (s *Server) refreshAllFeeds(ctx context.Context) {
+// refreshMode selects which feeds refreshAllFeeds fetches.
+type refreshMode int
+
+const (
+	refreshScheduled refreshMode = iota // background tick: due feeds only, outside quiet hours
+	refreshAll                          // startup or manual: every feed not in error backoff
+	refreshForced                       // manual with force=1: every feed, ignoring backoff and dead flags
+)
+
+// refreshAllFeeds refreshes feeds according to mode. Scheduled cycles only
+// fetch feeds whose effective refresh interval has elapsed and are skipped
+// during quiet hours; feeds in error backoff or flagged dead are skipped
+// unless forced.
+func (s *Server) refreshAllFeeds(ctx context.Context, mode refreshMode) {
+	dueOnly := mode == refreshScheduled
+	if dueOnly && s.QuietHours.contains(time.Now()) {
+		logFrom(ctx).Debug("skipping refresh cycle (quiet hours)")
+		return
+	}
 	q := dbgen.New(s.DB)
 	feeds, err := q.GetAllFeedsForRefresh(ctx, 1000)
 	if err != nil {
-		slog.Error("get feeds for refresh", "error", err)
+		logFrom(ctx).Error("get feeds for refresh", "error", err)
 		return
 	}
+	s.orderFeedsForRefresh(ctx, q, feeds)
 
+	var catIntervals map[int64]int64
+	if dueOnly {
+		catIntervals = categoryRefreshIntervals(ctx, q)
+	}
+	now := time.Now()
 	for _, feed := range feeds {
+		if dueOnly && !feedDue(&feed, s.effectiveRefreshInterval(&feed, catIntervals), now) {
+			continue
+		}
+		if feed.Dead != 0 && mode != refreshForced {
+			logFrom(ctx).Debug("skipping feed (dead)", "feed_id", feed.ID)
+			continue
+		}
+		if mode != refreshForced && shouldSkipFeed(&feed) {
+			logFrom(ctx).Debug("skipping feed (backoff)", "feed_id", feed.ID, "error_count", feed.ErrorCount)
+			continue
+		}
 		if err := s.refreshFeedInternal(ctx, q, &feed); err != nil {
-			slog.Warn("refresh feed", "error", err, "feed_id", feed.ID)
+			logFrom(ctx).Warn("refresh feed", "error", err, "feed_id", feed.ID)
+		}
+		// Small delay between feeds to be nice to servers; stop at shutdown
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return
 		}
-		// Small delay between feeds to be nice to servers
-		time.Sleep(time.Second)
 	}
 }
 
@@ -352,9 +1446,13 @@ func (s *Server) StartAutoPurge(ctx context.Context) {
 	if s.PurgeDays <= 0 {
 		return
 	}
-	go func() {
+	s.jobs.Go(func() {
 		// Run purge once at startup after a short delay
-		time.Sleep(30 * time.Second)
+		select {
+		case <-time.After(s.StartupDelay):
+		case <-ctx.Done():
+			return
+		}
 		s.purgeOldArticles()
 
 		// Then run daily
@@ -364,40 +1462,58 @@ func (s *Server) StartAutoPurge(ctx context.Context) {
 		for {
 			select {
 			case <-ctx.Done():
-				slog.Info("stopping auto-purge")
+				logFrom(ctx).Info("stopping auto-purge")
 				return
 			case <-ticker.C:
 				s.purgeOldArticles()
 			}
 		}
-	}()
+	})
 }
 
 func (s *Server) purgeOldArticles() {
 	q := dbgen.New(s.DB)
-	ctx := context.Background()
+	ctx := withJobID(context.Background(), "purge")
+	log := logFrom(ctx)
 
 	cutoff := time.Now().AddDate(0, 0, -s.PurgeDays)
 
 	count, err := q.CountOldReadArticles(ctx, &cutoff)
 	if err != nil {
-		slog.Error("count old articles", "error", err)
+		log.Error("count old articles", "error", err)
 		return
 	}
 
 	if count == 0 {
-		slog.Debug("no old read articles to purge")
+		log.Debug("no old read articles to purge")
 		return
 	}
 
 	result, err := q.PurgeOldReadArticles(ctx, &cutoff)
 	if err != nil {
-		slog.Error("purge old articles", "error", err)
+		log.Error("purge old articles", "error", err)
 		return
 	}
 
 	deleted, _ := result.RowsAffected()
-	slog.Info("purged old read articles", "count", deleted, "cutoff_days", s.PurgeDays)
+	log.Info("purged old read articles", "count", deleted, "cutoff_days", s.PurgeDays)
+}
+
+// checkBackupAge warns at startup when the newest backup in backupDir is
+// older than twice the interval, i.e. the schedule silently stopped at some
+// point. /health reports "degraded" until the next backup succeeds.
+func (s *Server) checkBackupAge(backupDir string, interval time.Duration) {
+	age := db.LatestBackupAge(backupDir)
+	if age == math.MaxInt64 {
+		// No valid backup yet, as on a fresh install; one runs shortly
+		slog.Info("no existing backups found", "dir", backupDir)
+		return
+	}
+	if age > 2*interval {
+		s.backupStale.Store(true)
+		slog.Warn("LATEST BACKUP IS STALE: check that periodic backups are running",
+			"dir", backupDir, "age", age.Round(time.Minute), "interval", interval)
+	}
 }
 
 // StartPeriodicBackup starts a goroutine that backs up the database periodically.
@@ -405,10 +1521,10 @@ func (s *Server) purgeOldArticles() {
 // the interval has already elapsed — this prevents duplicate backups when the
 // container or process restarts within the same backup period.
 func (s *Server) StartPeriodicBackup(ctx context.Context, backupDir string, interval time.Duration, keep int) {
-	go func() {
+	s.jobs.Go(func() {
 		// Wait a moment for the server to settle.
 		select {
-		case <-time.After(30 * time.Second):
+		case <-time.After(s.StartupDelay):
 		case <-ctx.Done():
 			return
 		}
@@ -418,7 +1534,7 @@ func (s *Server) StartPeriodicBackup(ctx context.Context, backupDir string, inte
 		if age >= interval {
 			s.runBackup(backupDir, keep)
 		} else {
-			slog.Info("skipping startup backup — recent backup exists",
+			logFrom(ctx).Info("skipping startup backup — recent backup exists",
 				"age", age.Round(time.Minute),
 				"interval", interval)
 		}
@@ -433,18 +1549,114 @@ func (s *Server) StartPeriodicBackup(ctx context.Context, backupDir string, inte
 				return
 			}
 		}
-	}()
+	})
 }
 
 func (s *Server) runBackup(backupDir string, keep int) {
+	ctx := withJobID(context.Background(), "backup")
+	log := logFrom(ctx)
+	s.backupMu.Lock()
 	path, err := db.Backup(s.DB, backupDir)
+	s.backupMu.Unlock()
 	if err != nil {
-		slog.Error("database backup failed", "error", err)
-		return
+		log.Error("database backup failed", "error", err)
+	} else {
+		log.Info("database backup complete", "path", path)
+		s.backupStale.Store(false)
+		if err := db.PruneBackups(backupDir, keep); err != nil {
+			log.Warn("backup pruning failed", "error", err)
+		}
+	}
+
+	// The OPML export is written even if the database copy failed, so a
+	// portable feed list survives a corrupt database.
+	if s.BackupOPML {
+		if err := s.backupOPML(ctx, backupDir, keep); err != nil {
+			log.Error("OPML backup failed", "error", err)
+		}
+	}
+}
+
+// opmlBackupPrefix and opmlBackupTimeFormat name OPML backups:
+// gorss-feeds[-<user>]-YYYY-MM-DD-HHMMSS.opml.
+const (
+	opmlBackupPrefix     = "gorss-feeds"
+	opmlBackupTimeFormat = "2006-01-02-150405"
+)
+
+// backupOPML writes a dated OPML export of each user's feeds into dir, one
+// file per user (without the user in the name when there is only one),
+// and prunes each user's exports to the newest keep.
+func (s *Server) backupOPML(ctx context.Context, dir string, keep int) error {
+	q := dbgen.New(s.DB)
+	users, err := q.GetFeedUserIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("list users: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create backup dir: %w", err)
+	}
+	timestamp := time.Now().Format(opmlBackupTimeFormat)
+	for _, userID := range users {
+		feeds, err := q.GetFeeds(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("list feeds for %s: %w", userID, err)
+		}
+		opml, err := GenerateOPML("GoRSS Backup", feedExports(feeds, categoryTitles(ctx, q, userID)))
+		if err != nil {
+			return fmt.Errorf("generate OPML for %s: %w", userID, err)
+		}
+		prefix := opmlBackupPrefix
+		if len(users) > 1 {
+			prefix += "-" + safeFilename(userID)
+		}
+		path := filepath.Join(dir, prefix+"-"+timestamp+".opml")
+		if err := os.WriteFile(path, opml, 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+		logFrom(ctx).Info("OPML backup complete", "path", path, "feeds", len(feeds))
+	}
+	return pruneOPMLBackups(dir, keep)
+}
+
+// pruneOPMLBackups keeps the newest keep OPML backups per user.
+func pruneOPMLBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read backup dir: %w", err)
 	}
-	slog.Info("database backup complete", "path", path)
 
-	if err := db.PruneBackups(backupDir, keep); err != nil {
-		slog.Warn("backup pruning failed", "error", err)
+	// Group by the name without its timestamp; names sort chronologically
+	// within a group because ReadDir returns them sorted.
+	suffixLen := len("-" + opmlBackupTimeFormat + ".opml")
+	groups := make(map[string][]string)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, opmlBackupPrefix) || !strings.HasSuffix(name, ".opml") || len(name) < len(opmlBackupPrefix)+suffixLen {
+			continue
+		}
+		group := name[:len(name)-suffixLen]
+		groups[group] = append(groups[group], name)
 	}
+	for _, names := range groups {
+		for _, name := range names[:max(0, len(names)-keep)] {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				slog.Warn("failed to remove old OPML backup", "file", name, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// safeFilename replaces characters that are unsafe in file names.
+func safeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || r == '@' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, s)
 }
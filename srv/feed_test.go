@@ -2,12 +2,20 @@ package srv
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/johnwmail/gorss/db/dbgen"
 )
@@ -85,6 +93,85 @@ func TestFeedFetcher_FetchConditional_NotModified(t *testing.T) {
 	}
 }
 
+func TestFeedFetcher_WeakETag(t *testing.T) {
+	const lastModified = "Mon, 01 Jan 2024 00:00:00 GMT"
+
+	t.Run("revalidates with weak etag", func(t *testing.T) {
+		var got []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = append(got, r.Header.Get("If-None-Match"))
+			if r.Header.Get("If-None-Match") == `W/"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		fetcher := NewFeedFetcher()
+		fetcher.AllowPrivateURLs = true
+		for range etagIgnoredAfter + 1 {
+			_, err := fetcher.FetchConditional(context.Background(), server.URL, `W/"v1"`, lastModified)
+			if err != errNotModified {
+				t.Fatalf("expected errNotModified, got %v", err)
+			}
+		}
+		for i, h := range got {
+			if h != `W/"v1"` {
+				t.Errorf("request %d: If-None-Match = %q, want weak etag unchanged", i, h)
+			}
+		}
+	})
+
+	t.Run("unquoted etag is quoted", func(t *testing.T) {
+		var got string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Get("If-None-Match")
+			w.WriteHeader(http.StatusNotModified)
+		}))
+		defer server.Close()
+
+		fetcher := NewFeedFetcher()
+		fetcher.AllowPrivateURLs = true
+		_, _ = fetcher.FetchConditional(context.Background(), server.URL, "v1", "")
+		if got != `"v1"` {
+			t.Errorf("If-None-Match = %q, want %q", got, `"v1"`)
+		}
+	})
+
+	t.Run("falls back to If-Modified-Since", func(t *testing.T) {
+		var etags, dates []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			etags = append(etags, r.Header.Get("If-None-Match"))
+			dates = append(dates, r.Header.Get("If-Modified-Since"))
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Header().Set("ETag", `W/"v1"`)
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title></channel></rss>`)
+		}))
+		defer server.Close()
+
+		fetcher := NewFeedFetcher()
+		fetcher.AllowPrivateURLs = true
+		for range etagIgnoredAfter + 2 {
+			if _, err := fetcher.FetchConditional(context.Background(), server.URL, `W/"v1"`, lastModified); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		for i := range etags {
+			wantETag := `W/"v1"`
+			if i >= etagIgnoredAfter {
+				wantETag = ""
+			}
+			if etags[i] != wantETag {
+				t.Errorf("request %d: If-None-Match = %q, want %q", i, etags[i], wantETag)
+			}
+			if dates[i] != lastModified {
+				t.Errorf("request %d: If-Modified-Since = %q, want %q", i, dates[i], lastModified)
+			}
+		}
+	})
+}
+
 func TestFeedFetcher_Fetch_Error(t *testing.T) {
 	// Test fetch error
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -289,3 +376,1986 @@ func TestFeedFetcher_MultipleItems(t *testing.T) {
 		t.Errorf("expected GUID to fallback to URL, got %q", result.Items[2].GUID)
 	}
 }
+
+func TestFeedFetcher_JSONFeed(t *testing.T) {
+	const body = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "JSON Blog",
+  "home_page_url": "https://example.org/",
+  "authors": [{"name": "Feed Author"}],
+  "items": [
+    {"id": "post-1", "url": "https://example.org/1", "title": "HTML post",
+     "content_html": "<p>Hello</p>", "summary": "Short", "date_published": "2024-03-01T10:00:00Z",
+     "authors": [{"name": "Jo"}]},
+    {"id": 42, "external_url": "https://elsewhere.example/2", "content_text": "a < b\nnext line",
+     "attachments": [{"url": "https://example.org/ep.mp3", "mime_type": "audio/mpeg", "duration_in_seconds": 90}]}
+  ]
+}`
+	for _, contentType := range []string{"application/feed+json", "text/plain"} {
+		t.Run(contentType, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", contentType)
+				fmt.Fprint(w, "\n  "+body)
+			}))
+			defer server.Close()
+
+			fetcher := NewFeedFetcher()
+			fetcher.AllowPrivateURLs = true
+			result, err := fetcher.Fetch(context.Background(), server.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Title != "JSON Blog" || result.SiteURL != "https://example.org/" {
+				t.Errorf("feed = %q %q", result.Title, result.SiteURL)
+			}
+			if len(result.Items) != 2 {
+				t.Fatalf("expected 2 items, got %d", len(result.Items))
+			}
+
+			first := result.Items[0]
+			if first.GUID != "post-1" || first.URL != "https://example.org/1" || first.Content != "<p>Hello</p>" ||
+				first.Summary != "Short" || first.Author != "Jo" {
+				t.Errorf("first item = %+v", first)
+			}
+			if want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC); first.PublishedAt == nil || !first.PublishedAt.Equal(want) {
+				t.Errorf("published = %v, want %v", first.PublishedAt, want)
+			}
+
+			second := result.Items[1]
+			if second.GUID != "42" || second.URL != "https://elsewhere.example/2" {
+				t.Errorf("second item guid/url = %q %q", second.GUID, second.URL)
+			}
+			if second.Content != "a &lt; b<br>\nnext line" {
+				t.Errorf("plain-text content = %q, want it escaped", second.Content)
+			}
+			if second.Author != "Feed Author" {
+				t.Errorf("author = %q, want the feed's author", second.Author)
+			}
+			if second.EnclosureURL == nil || *second.EnclosureURL != "https://example.org/ep.mp3" ||
+				second.DurationSeconds == nil || *second.DurationSeconds != 90 {
+				t.Errorf("enclosure = %v, duration = %v", second.EnclosureURL, second.DurationSeconds)
+			}
+		})
+	}
+
+	t.Run("not a JSON Feed", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"items": []}`)
+		}))
+		defer server.Close()
+
+		fetcher := NewFeedFetcher()
+		fetcher.AllowPrivateURLs = true
+		if _, err := fetcher.Fetch(context.Background(), server.URL); err == nil || !strings.Contains(err.Error(), "parse") {
+			t.Errorf("expected parse error, got %v", err)
+		}
+	})
+}
+
+func TestFeedLogo(t *testing.T) {
+	bodies := map[string]string{
+		"/rss": `<?xml version="1.0"?><rss version="2.0"><channel><title>R</title>
+<image><url>/img/logo.png</url><title>R</title><link>https://example.org/</link></image>
+<item><guid>r1</guid><title>Item</title></item></channel></rss>`,
+		"/atom": `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"><title>A</title>
+<logo>https://cdn.example.org/atom-logo.svg</logo>
+<entry><id>a1</id><title>Item</title><updated>2024-01-01T00:00:00Z</updated></entry></feed>`,
+		"/json": `{"version": "https://jsonfeed.org/version/1.1", "title": "J", "icon": "https://example.org/icon.png", "items": []}`,
+		"/none": `<?xml version="1.0"?><rss version="2.0"><channel><title>N</title></channel></rss>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, bodies[r.URL.Path])
+	}))
+	defer server.Close()
+
+	fetcher := NewFeedFetcher()
+	fetcher.AllowPrivateURLs = true
+	for path, want := range map[string]string{
+		"/rss":  server.URL + "/img/logo.png",
+		"/atom": "https://cdn.example.org/atom-logo.svg",
+		"/json": "https://example.org/icon.png",
+		"/none": "",
+	} {
+		result, err := fetcher.Fetch(context.Background(), server.URL+path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if result.LogoURL != want {
+			t.Errorf("%s: LogoURL = %q, want %q", path, result.LogoURL, want)
+		}
+	}
+
+	// Stored on subscribe and returned by the feeds API
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	w := httptest.NewRecorder()
+	s.HandleSubscribe(w, authReq("POST", "/api/feeds", `{"url":"`+server.URL+`/rss"}`))
+	assertStatus(t, w, 200)
+	w = httptest.NewRecorder()
+	s.HandleGetFeeds(w, authReq("GET", "/api/feeds", ""))
+	if !strings.Contains(w.Body.String(), `"logo_url":"`+server.URL+`/img/logo.png"`) {
+		t.Errorf("feeds response missing logo_url: %s", w.Body.String())
+	}
+
+	// Updated by refresh when the feed drops its image
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	bodies["/rss"] = bodies["/none"]
+	feed, _ := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL + "/rss"})
+	if err := s.refreshFeedInternal(ctx, q, &feed); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	feed, _ = q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL + "/rss"})
+	if feed.LogoUrl != "" {
+		t.Errorf("logo_url after refresh = %q, want empty", feed.LogoUrl)
+	}
+}
+
+func TestFeedFetcher_UserAgent(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct{ env, want string }{
+		{"", defaultUserAgent},
+		{"Mozilla/5.0 (compatible; MyReader)", "Mozilla/5.0 (compatible; MyReader)"},
+	} {
+		t.Setenv("GORSS_USER_AGENT", tc.env)
+		fetcher := NewFeedFetcher()
+		fetcher.AllowPrivateURLs = true
+		_, _ = fetcher.FetchConditional(context.Background(), server.URL, "", "")
+		if got != tc.want {
+			t.Errorf("GORSS_USER_AGENT=%q: User-Agent = %q, want %q", tc.env, got, tc.want)
+		}
+	}
+}
+
+func TestConfigurePool(t *testing.T) {
+	check := func(t *testing.T, transport *http.Transport, idle, perHost int, timeout time.Duration) {
+		t.Helper()
+		if transport.DisableKeepAlives {
+			t.Error("keep-alives disabled")
+		}
+		if transport.MaxIdleConns != idle || transport.MaxIdleConnsPerHost != perHost || transport.IdleConnTimeout != timeout {
+			t.Errorf("pool = %d/%d/%v, want %d/%d/%v", transport.MaxIdleConns, transport.MaxIdleConnsPerHost,
+				transport.IdleConnTimeout, idle, perHost, timeout)
+		}
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		fetcher := NewFeedFetcher()
+		check(t, fetcher.client.Transport.(*http.Transport), 100, 10, 90*time.Second)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("GORSS_HTTP_MAX_IDLE_CONNS", "20")
+		t.Setenv("GORSS_HTTP_MAX_IDLE_CONNS_PER_HOST", "4")
+		t.Setenv("GORSS_HTTP_IDLE_CONN_TIMEOUT", "30s")
+		fetcher := NewFeedFetcher()
+		check(t, fetcher.client.Transport.(*http.Transport), 20, 4, 30*time.Second)
+		check(t, fetcher.insecureClient.Transport.(*http.Transport), 20, 4, 30*time.Second)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Setenv("GORSS_HTTP_MAX_IDLE_CONNS_PER_HOST", "-1")
+		t.Setenv("GORSS_HTTP_IDLE_CONN_TIMEOUT", "soon")
+		fetcher := NewFeedFetcher()
+		check(t, fetcher.client.Transport.(*http.Transport), 100, 10, 90*time.Second)
+	})
+}
+
+func TestConfigureProxy(t *testing.T) {
+	for _, k := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "ALL_PROXY",
+		"http_proxy", "https_proxy", "no_proxy", "all_proxy"} {
+		t.Setenv(k, "")
+	}
+
+	proxyFor := func(t *testing.T, transport *http.Transport, target string) string {
+		t.Helper()
+		if transport.Proxy == nil {
+			t.Fatal("expected transport.Proxy to be set")
+		}
+		u, err := transport.Proxy(httptest.NewRequest("GET", target, nil))
+		if err != nil {
+			t.Fatalf("proxy lookup: %v", err)
+		}
+		if u == nil {
+			return ""
+		}
+		return u.String()
+	}
+
+	t.Run("GORSS_HTTP_PROXY", func(t *testing.T) {
+		t.Setenv("GORSS_HTTP_PROXY", "http://proxy.corp:3128")
+		t.Setenv("NO_PROXY", "internal.corp")
+		fetcher := NewFeedFetcher()
+		transport := fetcher.client.Transport.(*http.Transport)
+		if got := proxyFor(t, transport, "https://example.com/feed"); got != "http://proxy.corp:3128" {
+			t.Errorf("proxy = %q, want http://proxy.corp:3128", got)
+		}
+		if got := proxyFor(t, transport, "http://feeds.internal.corp/rss"); got != "" {
+			t.Errorf("NO_PROXY host proxied via %q", got)
+		}
+	})
+
+	t.Run("ALL_PROXY fallback", func(t *testing.T) {
+		t.Setenv("GORSS_HTTP_PROXY", "")
+		t.Setenv("ALL_PROXY", "http://all.corp:8080")
+		transport := NewFeedFetcher().client.Transport.(*http.Transport)
+		if got := proxyFor(t, transport, "http://example.com/feed"); got != "http://all.corp:8080" {
+			t.Errorf("proxy = %q, want http://all.corp:8080", got)
+		}
+	})
+
+	t.Run("socks5", func(t *testing.T) {
+		t.Setenv("GORSS_HTTP_PROXY", "socks5://127.0.0.1:1080")
+		transport := &http.Transport{}
+		if err := configureProxy(transport); err != nil {
+			t.Fatalf("configureProxy: %v", err)
+		}
+		if transport.Proxy != nil {
+			t.Error("socks5 should dial through the proxy, not set transport.Proxy")
+		}
+		if transport.DialContext == nil {
+			t.Error("expected socks5 dialer on transport")
+		}
+	})
+
+	t.Run("no proxy", func(t *testing.T) {
+		t.Setenv("GORSS_HTTP_PROXY", "")
+		transport := NewFeedFetcher().client.Transport.(*http.Transport)
+		if got := proxyFor(t, transport, "http://example.com/feed"); got != "" {
+			t.Errorf("unexpected proxy %q", got)
+		}
+	})
+}
+
+func TestFeedFetcher_ContentType(t *testing.T) {
+	const body = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>Mislabelled</title>
+<item><guid>ct-1</guid><title>Item</title></item>
+</channel></rss>`
+
+	tests := []struct {
+		contentType string
+		strict      bool
+		wantErr     bool
+	}{
+		{"application/rss+xml; charset=utf-8", true, false},
+		{"application/octet-stream", false, false},
+		{"text/html; charset=utf-8", false, false},
+		{"application/octet-stream", true, true},
+		{"text/html", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s strict=%v", tt.contentType, tt.strict), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				fmt.Fprint(w, body)
+			}))
+			defer server.Close()
+
+			fetcher := NewFeedFetcher()
+			fetcher.AllowPrivateURLs = true
+			fetcher.StrictContentType = tt.strict
+			result, err := fetcher.Fetch(context.Background(), server.URL)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "content type") {
+					t.Fatalf("expected content type error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Title != "Mislabelled" || len(result.Items) != 1 {
+				t.Errorf("got title %q with %d items", result.Title, len(result.Items))
+			}
+		})
+	}
+}
+
+func TestRefreshAutoReadStale(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	seeded := seedFeed(t, s, "stale", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "stale", Url: server.URL, ID: seeded.ID, UserID: "testuser"})
+	_ = q.UpdateFeedAutoRead(ctx, dbgen.UpdateFeedAutoReadParams{AutoReadAfterDays: 7, ID: seeded.ID, UserID: "testuser"})
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -10)
+	add := func(guid string, pub time.Time) int64 {
+		a, err := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
+			FeedID: seeded.ID, Guid: guid, Url: "http://example.com/" + guid, Title: guid, PublishedAt: &pub,
+		})
+		if err != nil {
+			t.Fatalf("UpsertArticle: %v", err)
+		}
+		return a.ID
+	}
+	oldID := add("old", old)
+	starredID := add("old-starred", old)
+	freshID := add("fresh", now)
+	_ = q.SetArticleStarred(ctx, dbgen.SetArticleStarredParams{UserID: "testuser", ArticleID: starredID, StarredAt: &now})
+
+	feed, err := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL})
+	if err != nil {
+		t.Fatalf("GetFeedByURL: %v", err)
+	}
+	if err := s.refreshFeedInternal(ctx, q, &feed); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	for id, wantRead := range map[int64]bool{oldID: true, starredID: false, freshID: false} {
+		a, err := q.GetArticle(ctx, dbgen.GetArticleParams{UserID: "testuser", ID: id, UserID_2: "testuser"})
+		if err != nil {
+			t.Fatalf("GetArticle: %v", err)
+		}
+		if got := a.IsRead == 1; got != wantRead {
+			t.Errorf("article %q read = %v, want %v", a.Title, got, wantRead)
+		}
+	}
+}
+
+func TestRefreshEmptyFeedWarning(t *testing.T) {
+	items := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Quiet</title>%s</channel></rss>`, items)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	s.EmptyFeedWarnAfter = 3
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	seeded := seedFeed(t, s, "quiet", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "quiet", Url: server.URL, ID: seeded.ID, UserID: "testuser"})
+
+	refresh := func() dbgen.Feed {
+		t.Helper()
+		feed, err := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL})
+		if err != nil {
+			t.Fatalf("GetFeedByURL: %v", err)
+		}
+		if err := s.refreshFeedInternal(ctx, q, &feed); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		feed, _ = q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL})
+		return feed
+	}
+
+	for i := 1; i <= 2; i++ {
+		if feed := refresh(); feed.EmptyCount != int64(i) || feed.LastWarning != nil {
+			t.Fatalf("refresh %d: empty_count = %d, warning = %v", i, feed.EmptyCount, feed.LastWarning)
+		}
+	}
+	feed := refresh()
+	if feed.LastWarning == nil || feed.LastError != nil {
+		t.Fatalf("expected warning without error after 3 empty refreshes, got warning %v error %v", feed.LastWarning, feed.LastError)
+	}
+
+	// Warning is surfaced in the feeds API
+	w := httptest.NewRecorder()
+	s.HandleGetFeeds(w, authReq("GET", "/api/feeds", ""))
+	if !strings.Contains(w.Body.String(), "no items") {
+		t.Errorf("feeds response missing warning: %s", w.Body.String())
+	}
+
+	items = `<item><guid>back</guid><title>Back</title></item>`
+	if feed := refresh(); feed.EmptyCount != 0 || feed.LastWarning != nil {
+		t.Errorf("after items reappear: empty_count = %d, warning = %v", feed.EmptyCount, feed.LastWarning)
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Big News!", "big news"},
+		{"  big   NEWS ", "big news"},
+		{"Big — News...", "big news"},
+		{"“Quoted” title", "quoted title"},
+		{"!!!", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeTitle(tt.in); got != tt.want {
+			t.Errorf("normalizeTitle(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshTitleDedup(t *testing.T) {
+	items := `<item><guid>a</guid><title>Big News!</title><link>http://example.com/story?utm_source=a</link></item>
+<item><guid>b</guid><title>big news</title><link>http://example.com/story?utm_source=b</link></item>
+<item><guid>c</guid><title>Other</title><link>http://example.com/other</link></item>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Dupes</title>%s</channel></rss>`, items)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	setup := func(title string, dedup bool) dbgen.Feed {
+		t.Helper()
+		seeded := seedFeed(t, s, title, nil, 0)
+		url := server.URL + "/" + title
+		_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: title, Url: url, ID: seeded.ID, UserID: "testuser"})
+		fidStr := fmt.Sprint(seeded.ID)
+		w := httptest.NewRecorder()
+		r := authReq("PUT", "/api/feeds/"+fidStr, fmt.Sprintf(`{"dedup_titles":%t}`, dedup))
+		r.SetPathValue("id", fidStr)
+		s.HandleUpdateFeed(w, r)
+		assertStatus(t, w, 200)
+		feed, err := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: url})
+		if err != nil {
+			t.Fatalf("GetFeedByURL: %v", err)
+		}
+		return feed
+	}
+	count := func(feedID int64) int {
+		t.Helper()
+		var n int
+		if err := s.DB.QueryRow("SELECT COUNT(*) FROM articles WHERE feed_id = ?", feedID).Scan(&n); err != nil {
+			t.Fatalf("count articles: %v", err)
+		}
+		return n
+	}
+
+	deduped := setup("deduped", true)
+	plain := setup("plain", false)
+	for _, feed := range []dbgen.Feed{deduped, plain} {
+		if err := s.refreshFeedInternal(ctx, q, &feed); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+	}
+
+	if n := count(deduped.ID); n != 2 {
+		t.Errorf("deduped feed has %d articles, want 2", n)
+	}
+	if n := count(plain.ID); n != 3 {
+		t.Errorf("plain feed has %d articles, want 3", n)
+	}
+
+	// The later duplicate updates the existing article rather than inserting
+	var url string
+	if err := s.DB.QueryRow("SELECT url FROM articles WHERE feed_id = ? AND guid = 'a'", deduped.ID).Scan(&url); err != nil {
+		t.Fatalf("get article: %v", err)
+	}
+	if !strings.HasSuffix(url, "utm_source=b") {
+		t.Errorf("url = %q, want the latest duplicate's URL", url)
+	}
+
+	// Matches against stored articles on later refreshes too
+	items = `<item><guid>d</guid><title>BIG NEWS.</title><link>http://example.com/story?utm_source=d</link></item>`
+	if err := s.refreshFeedInternal(ctx, q, &deduped); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if n := count(deduped.ID); n != 2 {
+		t.Errorf("after second refresh deduped feed has %d articles, want 2", n)
+	}
+}
+
+func TestFetchInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Internal</title><item><guid>i1</guid><title>Internal item</title></item></channel></rss>`)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	ctx := context.Background()
+
+	if _, err := s.fetcher.Fetch(ctx, server.URL); err == nil {
+		t.Fatal("expected certificate error for self-signed server")
+	}
+	if _, err := s.fetcher.FetchInsecure(ctx, server.URL, "", ""); err != nil {
+		t.Fatalf("FetchInsecure: %v", err)
+	}
+
+	subscribe := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.HandleSubscribe(w, authReq("POST", "/api/feeds", fmt.Sprintf(`{"url":%q,"insecure_skip_verify":true}`, server.URL)))
+		return w
+	}
+
+	// Disabled by default
+	assertStatus(t, subscribe(), http.StatusForbidden)
+
+	s.AllowInsecureTLS = true
+	w := subscribe()
+	assertStatus(t, w, 200)
+	var feed dbgen.Feed
+	decodeJSON(t, w, &feed)
+	if feed.InsecureSkipVerify != 1 {
+		t.Fatalf("insecure_skip_verify = %d, want 1", feed.InsecureSkipVerify)
+	}
+
+	// Refresh honors the flag; clearing it makes verification fail again
+	q := dbgen.New(s.DB)
+	stored, _ := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL})
+	if err := s.refreshFeedInternal(ctx, q, &stored); err != nil {
+		t.Fatalf("refresh with insecure_skip_verify: %v", err)
+	}
+
+	// The stored flag stops counting once GORSS_ALLOW_INSECURE_TLS is off
+	s.AllowInsecureTLS = false
+	if err := s.refreshFeedInternal(ctx, q, &stored); err == nil {
+		t.Error("expected certificate error with GORSS_ALLOW_INSECURE_TLS off")
+	}
+	s.AllowInsecureTLS = true
+
+	fidStr := fmt.Sprint(feed.ID)
+	w = httptest.NewRecorder()
+	r := authReq("PUT", "/api/feeds/"+fidStr, `{"insecure_skip_verify":false}`)
+	r.SetPathValue("id", fidStr)
+	s.HandleUpdateFeed(w, r)
+	assertStatus(t, w, 200)
+	stored, _ = q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL})
+	if err := s.refreshFeedInternal(ctx, q, &stored); err == nil {
+		t.Error("expected certificate error after clearing insecure_skip_verify")
+	}
+
+	// Enabling via update is gated too
+	s.AllowInsecureTLS = false
+	w = httptest.NewRecorder()
+	r = authReq("PUT", "/api/feeds/"+fidStr, `{"insecure_skip_verify":true}`)
+	r.SetPathValue("id", fidStr)
+	s.HandleUpdateFeed(w, r)
+	assertStatus(t, w, http.StatusForbidden)
+
+	// With proxy auth, only admins may set it
+	t.Setenv("GORSS_AUTH_MODE", "proxy")
+	s.AllowInsecureTLS = true
+	s.AdminGroup = "rss-admins"
+	update := func(groups string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := authReq("PUT", "/api/feeds/"+fidStr, `{"insecure_skip_verify":true}`)
+		r.Header.Set("X-ExeDev-Groups", groups)
+		r.SetPathValue("id", fidStr)
+		s.HandleUpdateFeed(w, r)
+		return w
+	}
+	assertStatus(t, subscribe(), http.StatusForbidden)
+	assertStatus(t, update("readers"), http.StatusForbidden)
+	assertStatus(t, update("rss-admins"), 200)
+}
+
+func TestRefreshLastSuccess(t *testing.T) {
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Flaky</title><item><guid>f1</guid><title>Item</title></item></channel></rss>`)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	seeded := seedFeed(t, s, "flaky", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "flaky", Url: server.URL, ID: seeded.ID, UserID: "testuser"})
+	load := func() dbgen.Feed {
+		t.Helper()
+		feed, err := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL})
+		if err != nil {
+			t.Fatalf("GetFeedByURL: %v", err)
+		}
+		return feed
+	}
+
+	feed := load()
+	if err := s.refreshFeedInternal(ctx, q, &feed); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	feed = load()
+	if feed.LastSuccessAt == nil || feed.LastUpdated == nil || !feed.LastSuccessAt.Equal(*feed.LastUpdated) {
+		t.Fatalf("after success: last_success_at = %v, last_updated = %v", feed.LastSuccessAt, feed.LastUpdated)
+	}
+	success := *feed.LastSuccessAt
+
+	time.Sleep(10 * time.Millisecond)
+	failing = true
+	if err := s.refreshFeedInternal(ctx, q, &feed); err == nil {
+		t.Fatal("expected refresh error")
+	}
+	feed = load()
+	if feed.LastSuccessAt == nil || !feed.LastSuccessAt.Equal(success) {
+		t.Errorf("last_success_at changed on error: %v, want %v", feed.LastSuccessAt, success)
+	}
+	if !feed.LastUpdated.After(success) {
+		t.Errorf("last_updated = %v, want after last success %v", feed.LastUpdated, success)
+	}
+
+	// Exposed in the feeds API
+	w := httptest.NewRecorder()
+	s.HandleGetFeeds(w, authReq("GET", "/api/feeds", ""))
+	if !strings.Contains(w.Body.String(), `"last_success_at":"`) {
+		t.Errorf("feeds response missing last_success_at: %s", w.Body.String())
+	}
+}
+
+func TestRunBackupOPML(t *testing.T) {
+	s := newTestServer(t)
+	seedFeed(t, s, "backed-up", nil, 0)
+	dir := t.TempDir()
+
+	s.runBackup(dir, 2)
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.opml")); len(matches) != 0 {
+		t.Fatalf("OPML written with GORSS_BACKUP_OPML off: %v", matches)
+	}
+
+	s.BackupOPML = true
+	s.runBackup(dir, 2)
+	matches, _ := filepath.Glob(filepath.Join(dir, "gorss-feeds-*.opml"))
+	if len(matches) != 1 {
+		t.Fatalf("got OPML backups %v, want one", matches)
+	}
+	f, err := os.Open(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	feeds, err := ParseOPML(f)
+	if err != nil {
+		t.Fatalf("parse OPML backup: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].URL != "http://example.com/backed-up" {
+		t.Errorf("OPML backup feeds = %+v", feeds)
+	}
+
+	// Older exports beyond keep are pruned
+	for _, ts := range []string{"2020-01-01-000000", "2020-01-02-000000"} {
+		if err := os.WriteFile(filepath.Join(dir, "gorss-feeds-"+ts+".opml"), []byte("<opml/>"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pruneOPMLBackups(dir, 2); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	matches, _ = filepath.Glob(filepath.Join(dir, "gorss-feeds-*.opml"))
+	if len(matches) != 2 || strings.Contains(matches[0], "2020-01-01") {
+		t.Errorf("after prune: %v", matches)
+	}
+}
+
+func TestFeedFetcher_CaptureParseFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, "<rss><channel><title>Broken")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	fetcher := NewFeedFetcher()
+	fetcher.AllowPrivateURLs = true
+	fetcher.CaptureDir = dir
+
+	if _, err := fetcher.Fetch(context.Background(), server.URL); err == nil {
+		t.Fatal("expected parse error")
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "capture-*.txt"))
+	if len(matches) != 1 {
+		t.Fatalf("got captures %v, want one", matches)
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "URL: "+server.URL) || !strings.HasSuffix(string(data), "<rss><channel><title>Broken") {
+		t.Errorf("unexpected capture contents:\n%s", data)
+	}
+
+	t.Run("cap", func(t *testing.T) {
+		for i := range maxCaptureFiles {
+			_ = os.WriteFile(filepath.Join(dir, fmt.Sprintf("capture-filler-%d.txt", i)), nil, 0o644)
+		}
+		_, _ = fetcher.Fetch(context.Background(), server.URL)
+		if count, _, _ := captureUsage(dir); count != maxCaptureFiles+1 {
+			t.Errorf("captures = %d, want %d (no new capture past the cap)", count, maxCaptureFiles+1)
+		}
+	})
+}
+
+func TestRefreshFetchAttempts(t *testing.T) {
+	drops := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if drops > 0 {
+			// Reset the connection without a response: a transient failure
+			drops--
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.(*net.TCPConn).SetLinger(0)
+			_ = conn.Close()
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Flaky</title><item><guid>a1</guid><title>Item</title></item></channel></rss>`)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	s.fetcher.retryDelay = time.Millisecond
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	seeded := seedFeed(t, s, "flaky", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "flaky", Url: server.URL, ID: seeded.ID, UserID: "testuser"})
+	refresh := func() (dbgen.Feed, error) {
+		t.Helper()
+		feed, err := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL})
+		if err != nil {
+			t.Fatalf("GetFeedByURL: %v", err)
+		}
+		feed.ErrorCount = 0 // ignore backoff
+		refreshErr := s.refreshFeedInternal(ctx, q, &feed)
+		feed, _ = q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL})
+		return feed, refreshErr
+	}
+
+	// Without retries a dropped connection is attempted once and fails
+	s.fetcher.Retries = 0
+	drops = 1
+	feed, err := refresh()
+	if err == nil {
+		t.Fatal("expected refresh error")
+	}
+	if feed.LastFetchAttempts != 1 || feed.FetchAttempts != 1 {
+		t.Errorf("no retries: attempts = %d/%d, want 1/1", feed.LastFetchAttempts, feed.FetchAttempts)
+	}
+
+	// With retries the third attempt succeeds
+	s.fetcher.Retries = 2
+	drops = 2
+	feed, err = refresh()
+	if err != nil {
+		t.Fatalf("refresh with retries: %v", err)
+	}
+	if feed.LastFetchAttempts != 3 || feed.FetchAttempts != 4 {
+		t.Errorf("with retries: attempts = %d/%d, want 3/4", feed.LastFetchAttempts, feed.FetchAttempts)
+	}
+
+	// Exposed in the feeds API
+	w := httptest.NewRecorder()
+	s.HandleGetFeeds(w, authReq("GET", "/api/feeds", ""))
+	if !strings.Contains(w.Body.String(), `"fetch_attempts":4`) {
+		t.Errorf("feeds response missing fetch_attempts: %s", w.Body.String())
+	}
+}
+
+func TestFetchRetryStatus(t *testing.T) {
+	var failures, requests int
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if failures > 0 {
+			failures--
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title></channel></rss>`)
+	}))
+	defer server.Close()
+
+	fetcher := NewFeedFetcher()
+	fetcher.AllowPrivateURLs = true
+	fetcher.retryDelay = time.Millisecond
+	if fetcher.Retries != defaultFetchRetries {
+		t.Fatalf("Retries = %d, want default %d", fetcher.Retries, defaultFetchRetries)
+	}
+	fetch := func() (int, error) {
+		requests = 0
+		return Retry(context.Background(), fetcher.Retries+1, fetcher.retryDelay, func() error {
+			_, err := fetcher.FetchConditional(context.Background(), server.URL, "", "")
+			return err
+		})
+	}
+
+	for _, tc := range []struct {
+		name         string
+		status       int
+		failures     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"5xx retried until success", http.StatusServiceUnavailable, 2, 3, false},
+		{"5xx gives up", http.StatusBadGateway, 10, defaultFetchRetries + 1, true},
+		{"4xx not retried", http.StatusNotFound, 10, 1, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, failures = tc.status, tc.failures
+			attempts, err := fetch()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if attempts != tc.wantAttempts || requests != tc.wantAttempts {
+				t.Errorf("attempts = %d, requests = %d, want %d", attempts, requests, tc.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestFetchRetryConnectionErrors(t *testing.T) {
+	fetcher := NewFeedFetcher()
+	fetcher.AllowPrivateURLs = true
+	fetcher.retryDelay = time.Millisecond
+	attempts := func(url string) int {
+		t.Helper()
+		n, err := Retry(context.Background(), fetcher.Retries+1, fetcher.retryDelay, func() error {
+			_, err := fetcher.FetchConditional(context.Background(), url, "", "")
+			return err
+		})
+		if err == nil {
+			t.Fatalf("fetch %s: expected an error", url)
+		}
+		return n
+	}
+
+	// A bad certificate won't fix itself, so it isn't retried
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	if n := attempts(tlsServer.URL); n != 1 {
+		t.Errorf("bad certificate: %d attempts, want 1", n)
+	}
+
+	// A refused connection is
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+	if n := attempts(closed.URL); n != defaultFetchRetries+1 {
+		t.Errorf("connection refused: %d attempts, want %d", n, defaultFetchRetries+1)
+	}
+}
+
+func TestRefreshDNSFailureDead(t *testing.T) {
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	s.fetcher.retryDelay = time.Millisecond
+	s.DNSFailDisableAfter = 3
+	dials := 0
+	s.fetcher.client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials++
+		return nil, &net.DNSError{Err: "no such host", Name: "gone.invalid", IsNotFound: true}
+	}
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	const feedURL = "http://gone.invalid/feed.xml"
+	seeded := seedFeed(t, s, "gone", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "gone", Url: feedURL, ID: seeded.ID, UserID: "testuser"})
+	stored := func() dbgen.Feed {
+		t.Helper()
+		feed, err := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: feedURL})
+		if err != nil {
+			t.Fatalf("GetFeedByURL: %v", err)
+		}
+		return feed
+	}
+
+	for i := 1; i <= 3; i++ {
+		feed := stored()
+		feed.ErrorCount = 0 // ignore backoff
+		if err := s.refreshFeedInternal(ctx, q, &feed); err == nil {
+			t.Fatal("expected refresh error")
+		}
+		feed = stored()
+		if feed.DnsFailCount != int64(i) {
+			t.Errorf("after %d failures: dns_fail_count = %d", i, feed.DnsFailCount)
+		}
+		if wantDead := i >= 3; (feed.Dead != 0) != wantDead {
+			t.Errorf("after %d failures: dead = %d, want %v", i, feed.Dead, wantDead)
+		}
+		// A host that does not exist is not retried
+		if feed.LastFetchAttempts != 1 {
+			t.Errorf("after %d failures: attempts = %d, want 1", i, feed.LastFetchAttempts)
+		}
+	}
+
+	// Dead feeds are left out of refresh cycles, but not forced ones
+	if _, err := s.DB.ExecContext(ctx, "UPDATE feeds SET error_count = 0 WHERE id = ?", seeded.ID); err != nil {
+		t.Fatal(err)
+	}
+	dials = 0
+	s.refreshAllFeeds(ctx, refreshAll)
+	if dials != 0 {
+		t.Errorf("refresh cycle fetched a dead feed %d times", dials)
+	}
+	s.refreshAllFeeds(ctx, refreshForced)
+	if dials != 1 {
+		t.Errorf("forced refresh fetched a dead feed %d times, want 1", dials)
+	}
+	w := httptest.NewRecorder()
+	s.HandleGetFeeds(w, authReq("GET", "/api/feeds", ""))
+	if !strings.Contains(w.Body.String(), `"dead":1`) {
+		t.Errorf("feeds response missing dead flag: %s", w.Body.String())
+	}
+
+	// The user can revive it
+	fidStr := fmt.Sprint(seeded.ID)
+	w = httptest.NewRecorder()
+	r := authReq("PUT", "/api/feeds/"+fidStr, `{"dead":false}`)
+	r.SetPathValue("id", fidStr)
+	s.HandleUpdateFeed(w, r)
+	assertStatus(t, w, 200)
+	if feed := stored(); feed.Dead != 0 || feed.DnsFailCount != 0 {
+		t.Errorf("after revive: dead = %d, dns_fail_count = %d, want 0/0", feed.Dead, feed.DnsFailCount)
+	}
+	if _, err := s.DB.ExecContext(ctx, "UPDATE feeds SET error_count = 0 WHERE id = ?", seeded.ID); err != nil {
+		t.Fatal(err)
+	}
+	dials = 0
+	s.refreshAllFeeds(ctx, refreshAll)
+	if dials == 0 {
+		t.Error("revived feed was not refreshed")
+	}
+
+	// Other failures show the host resolves and reset the count
+	s.fetcher.client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+	s.fetcher.Retries = 0
+	feed := stored()
+	_ = s.refreshFeedInternal(ctx, q, &feed)
+	if feed := stored(); feed.DnsFailCount != 0 {
+		t.Errorf("after a non-DNS failure: dns_fail_count = %d, want 0", feed.DnsFailCount)
+	}
+}
+
+func TestUpsertArticleInserted(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	feed := seedFeed(t, s, "upsert", nil, 0)
+
+	arg := dbgen.UpsertArticleParams{FeedID: feed.ID, Guid: "g1", Title: "First"}
+	first, err := q.UpsertArticle(ctx, arg)
+	if err != nil || first.Inserted == 0 {
+		t.Fatalf("first upsert: inserted = %d, err = %v; want 1, nil", first.Inserted, err)
+	}
+	arg.Title = "Edited"
+	second, err := q.UpsertArticle(ctx, arg)
+	if err != nil || second.Inserted != 0 {
+		t.Fatalf("second upsert: inserted = %d, err = %v; want 0, nil", second.Inserted, err)
+	}
+	if second.ID != first.ID || second.Title != "Edited" {
+		t.Errorf("second upsert = id %d title %q, want id %d title %q", second.ID, second.Title, first.ID, "Edited")
+	}
+
+	// storeItems reports only the items that weren't stored before
+	_, added := s.storeItems(ctx, q, &feed, []FeedItem{{GUID: "g1", Title: "Edited again"}, {GUID: "g2", Title: "Second"}, {GUID: "g2", Title: "Second"}})
+	if len(added) != 1 || added[0].GUID != "g2" {
+		t.Errorf("added = %+v, want only g2", added)
+	}
+}
+
+func TestUnreadOnUpdate(t *testing.T) {
+	s := newTestServer(t)
+	s.UnreadOnUpdate = true
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	feed := seedFeed(t, s, "edits", nil, 0)
+
+	var id int64
+	store := func(content string) {
+		t.Helper()
+		s.storeItems(ctx, q, &feed, []FeedItem{{GUID: "post", Title: "Post", Content: content}})
+		if err := s.DB.QueryRow("SELECT id FROM articles WHERE guid = ?", "post").Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	markRead := func() {
+		now := time.Now()
+		_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: id, ReadAt: &now})
+	}
+	isRead := func() bool {
+		var read int64
+		_ = s.DB.QueryRow("SELECT is_read FROM article_states WHERE article_id = ?", id).Scan(&read)
+		return read == 1
+	}
+
+	store("<p>First draft.</p>")
+	markRead()
+
+	// Reformatting the same text is not an edit
+	store("<div>\n  First   draft.\n</div>")
+	if !isRead() {
+		t.Error("whitespace-only change marked the article unread")
+	}
+
+	store("<p>Second draft, rewritten.</p>")
+	if isRead() {
+		t.Error("edited article is still read")
+	}
+
+	// Without the option edits leave the read state alone
+	s.UnreadOnUpdate = false
+	markRead()
+	store("<p>Third draft.</p>")
+	if !isRead() {
+		t.Error("edit marked the article unread with GORSS_UNREAD_ON_UPDATE off")
+	}
+}
+
+func TestWebhookNewArticles(t *testing.T) {
+	items := `<item><guid>a1</guid><title>One</title><link>https://example.org/1</link></item>
+<item><guid>a2</guid><title>Two</title><link>https://example.org/2</link></item>`
+	feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Hooked</title>%s</channel></rss>`, items)
+	}))
+	defer feedSrv.Close()
+
+	var payloads []webhookPayload
+	requests, failures := 0, 1
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var p webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("decode webhook body: %v", err)
+		}
+		payloads = append(payloads, p)
+	}))
+	defer hook.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	s.WebhookURL = hook.URL
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	seeded := seedFeed(t, s, "hooked", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "hooked", Url: feedSrv.URL, ID: seeded.ID, UserID: "testuser"})
+	refresh := func() {
+		t.Helper()
+		feed, _ := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: feedSrv.URL})
+		if err := s.refreshFeedInternal(ctx, q, &feed); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		s.jobs.Wait()
+	}
+
+	// The first delivery fails with a 503 and is retried once
+	refresh()
+	if requests != 2 || len(payloads) != 1 {
+		t.Fatalf("requests = %d, payloads = %d, want 2 and 1", requests, len(payloads))
+	}
+	want := webhookPayload{FeedID: seeded.ID, FeedTitle: "Hooked", FeedURL: feedSrv.URL, Articles: []webhookArticle{
+		{Title: "One", URL: "https://example.org/1"}, {Title: "Two", URL: "https://example.org/2"},
+	}}
+	if !reflect.DeepEqual(payloads[0], want) {
+		t.Errorf("payload = %+v, want %+v", payloads[0], want)
+	}
+
+	// Only articles that didn't exist before are sent
+	items += `<item><guid>a3</guid><title>Three</title><link>https://example.org/3</link></item>`
+	refresh()
+	if len(payloads) != 2 || len(payloads[1].Articles) != 1 || payloads[1].Articles[0].Title != "Three" {
+		t.Fatalf("second refresh payloads = %+v, want one with article Three", payloads)
+	}
+
+	// Nothing new, nothing sent
+	refresh()
+	if requests != 3 {
+		t.Errorf("requests = %d after a refresh with no new articles, want 3", requests)
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2026, 1, 1, h, 30, 0, 0, time.Local) }
+
+	qh, err := parseQuietHours("23-06")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	for h, want := range map[int]bool{22: false, 23: true, 0: true, 5: true, 6: false, 12: false} {
+		if got := qh.contains(at(h)); got != want {
+			t.Errorf("23-06 contains %02d:30 = %v, want %v", h, got, want)
+		}
+	}
+
+	qh, _ = parseQuietHours("9-17")
+	for h, want := range map[int]bool{8: false, 9: true, 16: true, 17: false} {
+		if got := qh.contains(at(h)); got != want {
+			t.Errorf("9-17 contains %02d:30 = %v, want %v", h, got, want)
+		}
+	}
+
+	for _, v := range []string{"", "23", "a-b", "24-06", "5-5", "-1-3"} {
+		if _, err := parseQuietHours(v); err == nil {
+			t.Errorf("parseQuietHours(%q) succeeded, want error", v)
+		}
+	}
+	if (*quietHours)(nil).contains(at(3)) {
+		t.Error("nil window should contain nothing")
+	}
+}
+
+func TestRefreshQuietHours(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Q</title></channel></rss>`)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	seeded := seedFeed(t, s, "quiet", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "quiet", Url: server.URL, ID: seeded.ID, UserID: "testuser"})
+
+	// Windows a couple of hours either side of now, so the test doesn't
+	// depend on the time of day it runs at
+	h := time.Now().Hour()
+	s.QuietHours = &quietHours{start: (h + 23) % 24, end: (h + 2) % 24}
+
+	s.refreshAllFeeds(ctx, refreshScheduled)
+	if hits != 0 {
+		t.Fatalf("background cycle during quiet hours fetched %d times, want 0", hits)
+	}
+
+	// Manual refreshes ignore quiet hours
+	s.refreshAllFeeds(ctx, refreshAll)
+	if hits != 1 {
+		t.Fatalf("manual refresh during quiet hours fetched %d times, want 1", hits)
+	}
+
+	// Outside the window the cycle runs (the feed is due again once its
+	// last update is older than the refresh interval)
+	s.QuietHours = &quietHours{start: (h + 3) % 24, end: (h + 5) % 24}
+	_, _ = s.DB.ExecContext(ctx, "UPDATE feeds SET last_updated = datetime('now', '-2 hours') WHERE id = ?", seeded.ID)
+	s.refreshAllFeeds(ctx, refreshScheduled)
+	if hits != 2 {
+		t.Fatalf("background cycle outside quiet hours fetched %d times, want 2", hits)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		in    string
+		limit int
+		want  string
+	}{
+		{"short title", 20, "short title"},
+		{"exactly ten", 11, "exactly ten"},
+		{"the quick brown fox jumps", 16, "the quick brown…"},
+		{"the quick brown, fox", 17, "the quick brown…"},
+		{"supercalifragilistic", 10, "supercali…"},
+		{"héllo wörld ünïcode", 13, "héllo wörld…"},
+		{"anything", 0, "anything"},
+	}
+	for _, tt := range tests {
+		got := truncateText(tt.in, tt.limit)
+		if got != tt.want {
+			t.Errorf("truncateText(%q, %d) = %q, want %q", tt.in, tt.limit, got, tt.want)
+		}
+		if tt.limit > 0 && utf8.RuneCountInString(got) > tt.limit {
+			t.Errorf("truncateText(%q, %d) = %q is over the limit", tt.in, tt.limit, got)
+		}
+	}
+}
+
+func TestStoreItemsTruncation(t *testing.T) {
+	s := newTestServer(t)
+	if s.MaxTitleLen != defaultMaxTitleLen || s.MaxSummaryLen != defaultMaxSummaryLen {
+		t.Fatalf("defaults = %d/%d", s.MaxTitleLen, s.MaxSummaryLen)
+	}
+	s.MaxTitleLen, s.MaxSummaryLen = 20, 30
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	feed := seedFeed(t, s, "long", nil, 0)
+
+	content := strings.Repeat("full content stays intact ", 20)
+	s.storeItems(ctx, q, &feed, []FeedItem{{
+		GUID:    "long-1",
+		Title:   "This title is an entire paragraph that never seems to end",
+		Summary: "A summary that also goes on and on well past any reasonable length",
+		Content: content,
+	}})
+
+	var title, summary, stored string
+	if err := s.DB.QueryRow("SELECT title, summary, content FROM articles WHERE feed_id = ? AND guid = 'long-1'", feed.ID).
+		Scan(&title, &summary, &stored); err != nil {
+		t.Fatalf("query article: %v", err)
+	}
+	if title != "This title is an…" {
+		t.Errorf("title = %q", title)
+	}
+	if summary != "A summary that also goes on…" {
+		t.Errorf("summary = %q", summary)
+	}
+	if stored != strings.TrimSpace(content) {
+		t.Errorf("content was modified: %q", stored)
+	}
+}
+
+func TestGeneratedExcerpt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><title>Full</title>
+<entry><id>full-1</id><title>Only content</title><updated>2026-01-01T00:00:00Z</updated>
+<content type="html">&lt;style&gt;p{color:red}&lt;/style&gt;&lt;h1&gt;Heading&lt;/h1&gt;&lt;p&gt;The &lt;b&gt;first&lt;/b&gt; paragraph.&lt;/p&gt;&lt;p&gt;A second paragraph that goes on for a while.&lt;/p&gt;</content></entry>
+<entry><id>full-2</id><title>Has summary</title><updated>2026-01-01T00:00:00Z</updated>
+<summary>Given summary</summary><content type="html">&lt;p&gt;Body&lt;/p&gt;</content></entry>
+</feed>`)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	if s.ExcerptLen != defaultExcerptLen {
+		t.Fatalf("ExcerptLen = %d, want default %d", s.ExcerptLen, defaultExcerptLen)
+	}
+	s.ExcerptLen = 40
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	feed := seedFeed(t, s, "full", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "full", Url: server.URL, ID: feed.ID, UserID: "testuser"})
+	feed.Url = server.URL
+	if err := s.refreshFeedInternal(ctx, q, &feed); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	summary := func(guid string) string {
+		var v string
+		if err := s.DB.QueryRow("SELECT summary FROM articles WHERE feed_id = ? AND guid = ?", feed.ID, guid).Scan(&v); err != nil {
+			t.Fatalf("query %s: %v", guid, err)
+		}
+		return v
+	}
+	if got, want := summary("full-1"), "Heading The first paragraph. A second…"; got != want {
+		t.Errorf("generated excerpt = %q, want %q", got, want)
+	}
+	if got := summary("full-2"); got != "Given summary" {
+		t.Errorf("feed summary replaced: %q", got)
+	}
+}
+
+func TestRefreshOnStart(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>S</title></channel></rss>`)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	if !s.RefreshOnStart {
+		t.Fatal("RefreshOnStart should default to true")
+	}
+	s.fetcher.AllowPrivateURLs = true
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	seeded := seedFeed(t, s, "startup", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "startup", Url: server.URL, ID: seeded.ID, UserID: "testuser"})
+
+	s.RefreshOnStart = false
+	s.refreshOnStart(ctx)
+	if hits != 0 {
+		t.Fatalf("disabled: startup refresh fetched %d times, want 0", hits)
+	}
+
+	s.RefreshOnStart = true
+	s.refreshOnStart(ctx)
+	if hits != 1 {
+		t.Fatalf("enabled: startup refresh fetched %d times, want 1", hits)
+	}
+
+	t.Setenv("GORSS_REFRESH_ON_START", "false")
+	if refreshOnStartFromEnv() {
+		t.Error("GORSS_REFRESH_ON_START=false should disable it")
+	}
+	t.Setenv("GORSS_REFRESH_ON_START", "bogus")
+	if !refreshOnStartFromEnv() {
+		t.Error("invalid GORSS_REFRESH_ON_START should keep the default")
+	}
+}
+
+func TestStartupDelay(t *testing.T) {
+	if d := startupDelayFromEnv(); d != defaultStartupDelay {
+		t.Errorf("default startup delay = %v, want %v", d, defaultStartupDelay)
+	}
+	t.Setenv("GORSS_STARTUP_DELAY", "bogus")
+	if d := startupDelayFromEnv(); d != defaultStartupDelay {
+		t.Errorf("invalid GORSS_STARTUP_DELAY: delay = %v, want default", d)
+	}
+	t.Setenv("GORSS_STARTUP_DELAY", "0")
+	s := newTestServer(t)
+	if s.StartupDelay != 0 {
+		t.Fatalf("StartupDelay = %v, want 0", s.StartupDelay)
+	}
+
+	// With no delay the startup backup runs right away
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	s.StartPeriodicBackup(ctx, dir, time.Hour, 2)
+	defer func() {
+		cancel()
+		s.jobs.Wait()
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if matches, _ := filepath.Glob(filepath.Join(dir, "*")); len(matches) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no backup written within 2s of startup")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRefreshOrder(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	// never: not yet fetched, one article; older: fetched 2h ago, no
+	// articles; busy: fetched 1h ago, three articles
+	never := seedFeed(t, s, "never", nil, 1)
+	older := seedFeed(t, s, "older", nil, 0)
+	busy := seedFeed(t, s, "busy", nil, 3)
+	now := time.Now()
+	for feedID, ago := range map[int64]time.Duration{older.ID: 2 * time.Hour, busy.ID: time.Hour} {
+		if _, err := s.DB.Exec("UPDATE feeds SET last_updated = ? WHERE id = ?", now.Add(-ago), feedID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	order := func(mode string) []int64 {
+		t.Helper()
+		s.RefreshOrder = mode
+		feeds, err := q.GetAllFeedsForRefresh(ctx, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.orderFeedsForRefresh(ctx, q, feeds)
+		var ids []int64
+		for _, f := range feeds {
+			ids = append(ids, f.ID)
+		}
+		return ids
+	}
+
+	if got, want := order(refreshOrderStale), []int64{never.ID, older.ID, busy.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stale order = %v, want %v", got, want)
+	}
+	if got, want := order(refreshOrderActive), []int64{busy.ID, never.ID, older.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("active order = %v, want %v", got, want)
+	}
+	got := order(refreshOrderRandom)
+	slices.Sort(got)
+	if want := []int64{never.ID, older.ID, busy.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("random order has feeds %v, want %v", got, want)
+	}
+
+	for env, want := range map[string]string{"": refreshOrderStale, "Active": refreshOrderActive, "random": refreshOrderRandom, "bogus": refreshOrderStale} {
+		t.Setenv("GORSS_REFRESH_ORDER", env)
+		if got := refreshOrderFromEnv(); got != want {
+			t.Errorf("GORSS_REFRESH_ORDER=%q: got %q, want %q", env, got, want)
+		}
+	}
+}
+
+func TestRefreshForceBypassesBackoff(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>B</title></channel></rss>`)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	seeded := seedFeed(t, s, "backoff", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "backoff", Url: server.URL, ID: seeded.ID, UserID: "testuser"})
+	backOff := func() {
+		t.Helper()
+		if _, err := s.DB.ExecContext(ctx, "UPDATE feeds SET error_count = 3, last_updated = ? WHERE id = ?", time.Now(), seeded.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backOff()
+	s.refreshAllFeeds(ctx, refreshAll)
+	if hits != 0 {
+		t.Fatalf("unforced refresh fetched a feed in backoff %d times", hits)
+	}
+
+	s.refreshAllFeeds(ctx, refreshForced)
+	if hits != 1 {
+		t.Fatalf("forced refresh fetched %d times, want 1", hits)
+	}
+	feed, _ := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL})
+	if feed.ErrorCount != 0 {
+		t.Errorf("error_count = %d after a successful forced refresh, want 0", feed.ErrorCount)
+	}
+
+	// The single-feed manual refresh ignores backoff too
+	backOff()
+	if err := s.RefreshFeed(ctx, seeded.ID); err != nil {
+		t.Fatalf("RefreshFeed: %v", err)
+	}
+	if hits != 2 {
+		t.Errorf("RefreshFeed fetched %d times in total, want 2", hits)
+	}
+}
+
+func TestDateSource(t *testing.T) {
+	published := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Second)
+	updated := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Dates</title>
+  <entry><id>both</id><title>Both</title><published>%[1]s</published><updated>%[2]s</updated></entry>
+  <entry><id>updated-only</id><title>Updated only</title><updated>%[2]s</updated></entry>
+</feed>`, published.Format(time.RFC3339), updated.Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	result, err := s.fetcher.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+
+	tests := []struct {
+		source      string
+		both        *time.Time
+		updatedOnly *time.Time
+	}{
+		{dateSourcePreferPublished, &published, &updated},
+		// gofeed already falls back to the updated date when an entry has
+		// no published date, so published-only can't leave it empty
+		{dateSourcePublished, &published, &updated},
+		{dateSourceUpdated, &updated, &updated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			items := append([]FeedItem(nil), result.Items...)
+			applyDateSource(items, tt.source)
+			for i, want := range []*time.Time{tt.both, tt.updatedOnly} {
+				got := items[i].PublishedAt
+				if (got == nil) != (want == nil) || (got != nil && !got.Equal(*want)) {
+					t.Errorf("%s: date = %v, want %v", items[i].GUID, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("per-feed setting", func(t *testing.T) {
+		q := dbgen.New(s.DB)
+		ctx := context.Background()
+		seeded := seedFeed(t, s, "dates", nil, 0)
+		_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "dates", Url: server.URL, ID: seeded.ID, UserID: "testuser"})
+		fidStr := fmt.Sprint(seeded.ID)
+
+		w := httptest.NewRecorder()
+		r := authReq("PUT", "/api/feeds/"+fidStr, `{"date_source":"sometimes"}`)
+		r.SetPathValue("id", fidStr)
+		s.HandleUpdateFeed(w, r)
+		assertStatus(t, w, http.StatusBadRequest)
+
+		w = httptest.NewRecorder()
+		r = authReq("PUT", "/api/feeds/"+fidStr, `{"date_source":"updated"}`)
+		r.SetPathValue("id", fidStr)
+		s.HandleUpdateFeed(w, r)
+		assertStatus(t, w, 200)
+
+		if err := s.RefreshFeed(ctx, seeded.ID); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		var got time.Time
+		if err := s.DB.QueryRow("SELECT published_at FROM articles WHERE feed_id = ? AND guid = 'both'", seeded.ID).Scan(&got); err != nil {
+			t.Fatalf("query article: %v", err)
+		}
+		if !got.Equal(updated) {
+			t.Errorf("stored date = %v, want updated date %v", got, updated)
+		}
+	})
+}
+
+func TestPodcastMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+<channel>
+  <title>Pod</title>
+  <item><guid>ep12</guid><title>Episode 12</title>
+    <itunes:duration>1:02:03</itunes:duration><itunes:episode>12</itunes:episode>
+    <itunes:season>2</itunes:season><itunes:episodeType>Full</itunes:episodeType></item>
+  <item><guid>trailer</guid><title>Trailer</title>
+    <itunes:duration>95</itunes:duration><itunes:episodeType>trailer</itunes:episodeType>
+    <itunes:episode>soon</itunes:episode></item>
+  <item><guid>plain</guid><title>No metadata</title></item>
+</channel>
+</rss>`)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	result, err := s.fetcher.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	feed := seedFeed(t, s, "pod", nil, 0)
+	s.storeItems(context.Background(), dbgen.New(s.DB), &feed, result.Items)
+
+	type episode struct {
+		DurationSeconds *int64  `json:"duration_seconds"`
+		Episode         *int64  `json:"episode"`
+		Season          *int64  `json:"season"`
+		EpisodeType     *string `json:"episode_type"`
+	}
+	get := func(guid string) episode {
+		t.Helper()
+		var id string
+		if err := s.DB.QueryRow("SELECT id FROM articles WHERE guid = ?", guid).Scan(&id); err != nil {
+			t.Fatalf("find %s: %v", guid, err)
+		}
+		w := httptest.NewRecorder()
+		r := authReq("GET", "/api/articles/"+id, "")
+		r.SetPathValue("id", id)
+		s.HandleGetArticle(w, r)
+		assertStatus(t, w, 200)
+		var ep episode
+		decodeJSON(t, w, &ep)
+		return ep
+	}
+	str := func(p *string) string {
+		if p == nil {
+			return "<nil>"
+		}
+		return *p
+	}
+	num := func(p *int64) string {
+		if p == nil {
+			return "<nil>"
+		}
+		return fmt.Sprint(*p)
+	}
+
+	tests := []struct {
+		guid                            string
+		duration, episode, season, kind string
+	}{
+		{"ep12", "3723", "12", "2", "full"},
+		{"trailer", "95", "<nil>", "<nil>", "trailer"},
+		{"plain", "<nil>", "<nil>", "<nil>", "<nil>"},
+	}
+	for _, tt := range tests {
+		ep := get(tt.guid)
+		got := []string{num(ep.DurationSeconds), num(ep.Episode), num(ep.Season), str(ep.EpisodeType)}
+		want := []string{tt.duration, tt.episode, tt.season, tt.kind}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("%s: duration/episode/season/type = %v, want %v", tt.guid, got, want)
+		}
+	}
+}
+
+func TestParseITunesDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"3723", 3723, true},
+		{"62:03", 3723, true},
+		{"1:02:03", 3723, true},
+		{"95.5", 95, true},
+		{"", 0, false},
+		{"1:2:3:4", 0, false},
+		{"an hour", 0, false},
+		{"-5", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseITunesDuration(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseITunesDuration(%q) = %d, %v; want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestContentCompression(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	feed := seedFeed(t, s, "big", nil, 0)
+	long := strings.Repeat("<p>Plenty of repetitive article text.</p>\n", 100)
+
+	// Stored before compression was enabled
+	s.storeItems(ctx, q, &feed, []FeedItem{{GUID: "plain", Title: "Plain", Content: long}})
+	s.CompressContent = true
+	s.storeItems(ctx, q, &feed, []FeedItem{
+		{GUID: "packed", Title: "Packed", Content: long},
+		{GUID: "short", Title: "Short", Content: "<p>tiny</p>"},
+	})
+
+	stored := func(guid string) (id int64, size int, compressed int64) {
+		t.Helper()
+		if err := s.DB.QueryRow("SELECT id, length(CAST(content AS BLOB)), content_compressed FROM articles WHERE guid = ?", guid).
+			Scan(&id, &size, &compressed); err != nil {
+			t.Fatalf("query %s: %v", guid, err)
+		}
+		return id, size, compressed
+	}
+	plainID, plainSize, plainFlag := stored("plain")
+	packedID, packedSize, packedFlag := stored("packed")
+	_, _, shortFlag := stored("short")
+	if plainFlag != 0 || shortFlag != 0 || packedFlag != 1 {
+		t.Fatalf("compressed flags plain/short/packed = %d/%d/%d, want 0/0/1", plainFlag, shortFlag, packedFlag)
+	}
+	if packedSize*5 > plainSize {
+		t.Errorf("compressed size %d not much smaller than %d", packedSize, plainSize)
+	}
+
+	// Both forms read back as the original HTML
+	for _, id := range []int64{plainID, packedID} {
+		idStr := fmt.Sprint(id)
+		w := httptest.NewRecorder()
+		r := authReq("GET", "/api/articles/"+idStr, "")
+		r.SetPathValue("id", idStr)
+		s.HandleGetArticle(w, r)
+		assertStatus(t, w, 200)
+		var a struct {
+			Content string `json:"content"`
+		}
+		decodeJSON(t, w, &a)
+		if a.Content != strings.TrimSpace(long) {
+			t.Errorf("article %d: content not round-tripped (%d bytes)", id, len(a.Content))
+		}
+	}
+	list, err := queryArticles(ctx, s.DB, "testuser", articleQueryOpts{FeedID: &feed.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range list {
+		if a.Guid != "short" && a.Content != strings.TrimSpace(long) {
+			t.Errorf("queryArticles %s: content not round-tripped", a.Guid)
+		}
+	}
+
+	// Re-storing identical content isn't treated as a change
+	_, _ = s.DB.ExecContext(ctx, "UPDATE articles SET updated_at = '2000-01-01 00:00:00' WHERE id = ?", packedID)
+	s.storeItems(ctx, q, &feed, []FeedItem{{GUID: "packed", Title: "Packed", Content: long}})
+	var updatedAt time.Time
+	_ = s.DB.QueryRow("SELECT updated_at FROM articles WHERE id = ?", packedID).Scan(&updatedAt)
+	if updatedAt.Year() != 2000 {
+		t.Errorf("updated_at bumped to %v by identical content", updatedAt)
+	}
+
+	// The list endpoint doesn't load content at all
+	w := httptest.NewRecorder()
+	s.HandleGetArticles(w, authReq("GET", fmt.Sprintf("/api/articles?feed_id=%d", feed.ID), ""))
+	assertStatus(t, w, 200)
+	if strings.Contains(w.Body.String(), "repetitive") {
+		t.Error("list response includes content")
+	}
+}
+
+func TestFallbackFeedTitle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		link := ""
+		if r.URL.Path == "/with-site" {
+			link = "<link>https://www.example.org/blog</link>"
+		}
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title></title>%s
+<item><guid>1</guid><title>Item</title></item></channel></rss>`, link)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	subscribe := func(path string) dbgen.Feed {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleSubscribe(w, authReq("POST", "/api/feeds", fmt.Sprintf(`{"url":%q}`, server.URL+path)))
+		assertStatus(t, w, 200)
+		var feed dbgen.Feed
+		decodeJSON(t, w, &feed)
+		return feed
+	}
+
+	if feed := subscribe("/with-site"); feed.Title != "example.org" {
+		t.Errorf("title = %q, want the site hostname example.org", feed.Title)
+	}
+	feed := subscribe("/bare")
+	if feed.Title != "127.0.0.1" {
+		t.Errorf("title = %q, want the feed hostname 127.0.0.1", feed.Title)
+	}
+
+	// A title the user set survives refreshes of the untitled feed
+	fidStr := fmt.Sprint(feed.ID)
+	w := httptest.NewRecorder()
+	r := authReq("PUT", "/api/feeds/"+fidStr, `{"title":"My Feed"}`)
+	r.SetPathValue("id", fidStr)
+	s.HandleUpdateFeed(w, r)
+	assertStatus(t, w, 200)
+	if err := s.RefreshFeed(context.Background(), feed.ID); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	got, _ := dbgen.New(s.DB).GetFeed(context.Background(), dbgen.GetFeedParams{ID: feed.ID, UserID: "testuser"})
+	if got.Title != "My Feed" {
+		t.Errorf("title after refresh = %q, want My Feed", got.Title)
+	}
+
+	if got := fallbackFeedTitle("", "not a url"); got != "not a url" {
+		t.Errorf("fallbackFeedTitle of an unparseable URL = %q", got)
+	}
+}
+
+func TestArticleMediaProxy(t *testing.T) {
+	audio := strings.Repeat("0123456789", 100)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+  <title>Pod</title>
+  <item><guid>ep1</guid><title>Episode 1</title>
+    <enclosure url="http://%[1]s/transcript.html" length="10" type="text/html"/>
+    <enclosure url="http://%[1]s/ep1.mp3" length="1000" type="audio/mpeg"/></item>
+  <item><guid>post</guid><title>No audio</title></item>
+</channel>
+</rss>`, r.Host)
+	})
+	mux.HandleFunc("/ep1.mp3", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		http.ServeContent(w, r, "ep1.mp3", time.Time{}, strings.NewReader(audio))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	result, err := s.fetcher.Fetch(context.Background(), server.URL+"/feed")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	feed := seedFeed(t, s, "pod", nil, 0)
+	s.storeItems(context.Background(), dbgen.New(s.DB), &feed, result.Items)
+
+	media := func(guid, rangeHeader string) *httptest.ResponseRecorder {
+		t.Helper()
+		var id string
+		if err := s.DB.QueryRow("SELECT id FROM articles WHERE guid = ?", guid).Scan(&id); err != nil {
+			t.Fatalf("find %s: %v", guid, err)
+		}
+		r := authReq("GET", "/api/articles/"+id+"/media", "")
+		r.SetPathValue("id", id)
+		if rangeHeader != "" {
+			r.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		s.HandleArticleMedia(w, r)
+		return w
+	}
+
+	w := media("ep1", "bytes=100-199")
+	assertStatus(t, w, http.StatusPartialContent)
+	if got := w.Header().Get("Content-Range"); got != "bytes 100-199/1000" {
+		t.Errorf("Content-Range = %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "audio/mpeg" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if w.Body.String() != audio[100:200] {
+		t.Errorf("body = %q, want bytes 100-199", w.Body.String())
+	}
+
+	w = media("ep1", "")
+	assertStatus(t, w, http.StatusOK)
+	if w.Body.Len() != len(audio) || w.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("full fetch: %d bytes, Accept-Ranges %q", w.Body.Len(), w.Header().Get("Accept-Ranges"))
+	}
+
+	assertStatus(t, media("post", ""), http.StatusNotFound)
+
+	s.fetcher.AllowPrivateURLs = false
+	assertStatus(t, media("ep1", "bytes=0-9"), http.StatusForbidden)
+}
+
+func TestRefreshKeepOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Firehose</title>
+<item><guid>a</guid><title>Go 1.30 released</title></item>
+<item><guid>b</guid><title>Weekly roundup</title><description>Notes on golang generics</description></item>
+<item><guid>c</guid><title>Rust news</title></item>
+</channel></rss>`)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	seeded := seedFeed(t, s, "firehose", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "firehose", Url: server.URL, ID: seeded.ID, UserID: "testuser"})
+	fidStr := fmt.Sprint(seeded.ID)
+
+	update := func(body string, wantStatus int) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := authReq("PUT", "/api/feeds/"+fidStr, body)
+		r.SetPathValue("id", fidStr)
+		s.HandleUpdateFeed(w, r)
+		assertStatus(t, w, wantStatus)
+	}
+	guids := func() []string {
+		t.Helper()
+		feed, err := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL})
+		if err != nil {
+			t.Fatalf("GetFeedByURL: %v", err)
+		}
+		if err := s.refreshFeedInternal(ctx, q, &feed); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		var got []string
+		rows, err := s.DB.Query("SELECT guid FROM articles WHERE feed_id = ? ORDER BY guid", feed.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var g string
+			_ = rows.Scan(&g)
+			got = append(got, g)
+		}
+		return got
+	}
+
+	update(`{"keep_only_pattern":"("}`, http.StatusBadRequest)
+
+	// Matches title or summary, case-insensitively
+	update(`{"keep_only_pattern":"\\bgo(lang)?\\b"}`, http.StatusOK)
+	if got := guids(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("keep-only articles = %v, want [a b]", got)
+	}
+
+	// Clearing the pattern keeps everything again
+	update(`{"keep_only_pattern":""}`, http.StatusOK)
+	if got := guids(); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("unfiltered articles = %v, want [a b c]", got)
+	}
+}
+
+func TestSyntheticGUIDs(t *testing.T) {
+	// Neither GUIDs nor links on the first two; the last two share a link
+	items := `<item><title>First</title><description>one</description><pubDate>Mon, 01 Jan 2024 00:00:00 GMT</pubDate></item>
+<item><title>Second</title><description>two</description></item>
+<item><link>http://example.com/latest</link><title>Third</title></item>
+<item><link>http://example.com/latest</link><title>Fourth</title></item>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>No GUIDs</title>%s</channel></rss>`, items)
+	}))
+	defer server.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	seeded := seedFeed(t, s, "noguid", nil, 0)
+	_ = q.UpdateFeedDetails(ctx, dbgen.UpdateFeedDetailsParams{Title: "noguid", Url: server.URL, ID: seeded.ID, UserID: "testuser"})
+	refresh := func() []string {
+		t.Helper()
+		feed, err := q.GetFeedByURL(ctx, dbgen.GetFeedByURLParams{UserID: "testuser", Url: server.URL})
+		if err != nil {
+			t.Fatalf("GetFeedByURL: %v", err)
+		}
+		if err := s.refreshFeedInternal(ctx, q, &feed); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		var guids []string
+		rows, err := s.DB.Query("SELECT guid FROM articles WHERE feed_id = ? ORDER BY id", feed.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var g string
+			_ = rows.Scan(&g)
+			guids = append(guids, g)
+		}
+		return guids
+	}
+
+	// Items without GUID or link get distinct hashes; the shared link collides
+	guids := refresh()
+	if len(guids) != 3 {
+		t.Fatalf("stored %d articles (%v), want 3", len(guids), guids)
+	}
+	for _, g := range guids[:2] {
+		if !strings.HasPrefix(g, "sha256:") {
+			t.Errorf("guid = %q, want a synthetic sha256 GUID", g)
+		}
+	}
+	if guids[0] == guids[1] {
+		t.Errorf("distinct items share synthetic GUID %q", guids[0])
+	}
+
+	// Re-fetching matches the stored synthetic GUIDs
+	if again := refresh(); !reflect.DeepEqual(again, guids) {
+		t.Errorf("after refetch guids = %v, want %v", again, guids)
+	}
+
+	// Flagging the feed keys every item by hash, so the shared link no
+	// longer collapses the last two
+	fidStr := fmt.Sprint(seeded.ID)
+	w := httptest.NewRecorder()
+	r := authReq("PUT", "/api/feeds/"+fidStr, `{"guid_unstable":true}`)
+	r.SetPathValue("id", fidStr)
+	s.HandleUpdateFeed(w, r)
+	assertStatus(t, w, 200)
+	guids = refresh()
+	if len(guids) != 5 {
+		t.Errorf("stored %d articles with guid_unstable, want 5 (3 earlier + Third and Fourth by hash)", len(guids))
+	}
+	if again := refresh(); len(again) != len(guids) {
+		t.Errorf("refetch with guid_unstable stored %d articles, want %d", len(again), len(guids))
+	}
+}
@@ -0,0 +1,453 @@
+package srv
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnwmail/gorss/db/dbgen"
+)
+
+// This file implements the subset of the Fever API
+// (https://feedafever.com/api) used by mobile clients such as Reeder and
+// FeedMe: auth, groups, feeds, items, unread/saved id lists and marking.
+//
+// Clients send api_key = md5("<username>:<password>"). The username is the
+// gorss user ID or email and the password is one issued by
+// POST /api/fever-password. Only the api_key is stored, so requests are
+// authenticated by a single lookup.
+
+const (
+	feverAPIVersion = 3
+	feverPageSize   = 50 // items per request, as in the reference implementation
+)
+
+// isFeverPath reports whether a path is served by the Fever API, which does
+// its own api_key auth instead of the session.
+func isFeverPath(path string) bool {
+	return path == "/fever" || path == "/fever/"
+}
+
+// feverUser returns the user whose credentials hash to apiKey, or "".
+func (s *Server) feverUser(ctx context.Context, apiKey string) string {
+	apiKey = strings.ToLower(strings.TrimSpace(apiKey))
+	if apiKey == "" {
+		return ""
+	}
+	userID, err := dbgen.New(s.DB).GetUserByFeverKey(ctx, apiKey)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logFrom(ctx).Error("fever auth", "error", err)
+		}
+		return ""
+	}
+	return userID
+}
+
+// feverAPIKey is the api_key a client sends for username and password.
+func feverAPIKey(username, password string) string {
+	sum := md5.Sum([]byte(username + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// HandleSetFeverPassword issues the user a new Fever password, revoking any
+// earlier one, and returns it with the usernames it works for. It can't be
+// shown again later, since only the api_keys are stored.
+func (s *Server) HandleSetFeverPassword(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	usernames := []string{userID}
+	if email := getUserEmail(r); email != "" && email != userID {
+		usernames = append(usernames, email)
+	}
+	password := generateSessionID()
+	if err := s.setFeverKeys(r.Context(), userID, password, usernames); err != nil {
+		logFrom(r.Context()).Error("set fever password", "error", err)
+		jsonError(w, "failed to set Fever password", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]any{"usernames": usernames, "password": password})
+}
+
+// HandleDeleteFeverPassword revokes the user's Fever access.
+func (s *Server) HandleDeleteFeverPassword(w http.ResponseWriter, r *http.Request) {
+	if err := dbgen.New(s.DB).DeleteFeverKeys(r.Context(), s.userFromContext(r)); err != nil {
+		jsonError(w, "failed to revoke Fever password", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// setFeverKeys replaces userID's Fever keys with one per username.
+func (s *Server) setFeverKeys(ctx context.Context, userID, password string, usernames []string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	q := dbgen.New(s.DB).WithTx(tx)
+	if err := q.DeleteFeverKeys(ctx, userID); err != nil {
+		return err
+	}
+	for _, name := range usernames {
+		if err := q.InsertFeverKey(ctx, dbgen.InsertFeverKeyParams{ApiKey: feverAPIKey(name, password), UserID: userID}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// HandleFever serves /fever/?api. Which parts of the response are built
+// depends on the query flags present (groups, feeds, items, ...), and a
+// mark=... form marks items, feeds or groups.
+func (s *Server) HandleFever(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]any{"api_version": feverAPIVersion, "auth": 0}
+	if _, ok := r.URL.Query()["api"]; !ok {
+		jsonError(w, "not a Fever API request", http.StatusBadRequest)
+		return
+	}
+	userID := s.feverUser(r.Context(), r.FormValue("api_key"))
+	if userID == "" {
+		jsonResponse(w, resp)
+		return
+	}
+	resp["auth"] = 1
+
+	ctx := r.Context()
+	q := dbgen.New(s.DB)
+	if err := s.feverMark(ctx, userID, r); err != nil {
+		if errors.Is(err, errFeverBadMark) || errors.Is(err, errFeverItemNotFound) {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		jsonError(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	if r.FormValue("mark") != "" {
+		s.countsCache.invalidate(userID)
+	}
+	if err := s.feverRead(ctx, q, userID, r, resp); err != nil {
+		jsonError(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, resp)
+}
+
+// feverRead adds the sections requested by the query flags to resp.
+func (s *Server) feverRead(ctx context.Context, q *dbgen.Queries, userID string, r *http.Request, resp map[string]any) error {
+	query := r.URL.Query()
+	feeds, err := q.GetFeedsOrdered(ctx, userID)
+	if err != nil {
+		return err
+	}
+	resp["last_refreshed_on_time"] = feverLastRefreshed(feeds)
+
+	_, wantGroups := query["groups"]
+	_, wantFeeds := query["feeds"]
+	if wantGroups {
+		cats, err := q.GetCategories(ctx, userID)
+		if err != nil {
+			return err
+		}
+		groups := make([]map[string]any, 0, len(cats))
+		for _, c := range cats {
+			groups = append(groups, map[string]any{"id": c.ID, "title": c.Title})
+		}
+		resp["groups"] = groups
+	}
+	if wantFeeds {
+		resp["feeds"] = feverFeeds(feeds)
+	}
+	if wantGroups || wantFeeds {
+		resp["feeds_groups"] = feverFeedsGroups(feeds)
+	}
+	if _, ok := query["favicons"]; ok {
+		resp["favicons"] = []any{}
+	}
+	if _, ok := query["links"]; ok {
+		resp["links"] = []any{}
+	}
+	if _, ok := query["items"]; ok {
+		if err := s.feverItems(ctx, userID, r, resp); err != nil {
+			return err
+		}
+	}
+	return s.feverIDLists(ctx, userID, r, resp)
+}
+
+// feverIDLists adds unread_item_ids and saved_item_ids when requested, or
+// after an item was marked so the client can update its state.
+func (s *Server) feverIDLists(ctx context.Context, userID string, r *http.Request, resp map[string]any) error {
+	query := r.URL.Query()
+	mark := r.FormValue("mark")
+	if _, ok := query["unread_item_ids"]; ok || mark == "item" {
+		ids, err := s.feverIDs(ctx, userID, "(s.is_read IS NULL OR s.is_read = 0)")
+		if err != nil {
+			return err
+		}
+		resp["unread_item_ids"] = ids
+	}
+	if _, ok := query["saved_item_ids"]; ok || mark == "item" {
+		ids, err := s.feverIDs(ctx, userID, "s.is_starred = 1")
+		if err != nil {
+			return err
+		}
+		resp["saved_item_ids"] = ids
+	}
+	return nil
+}
+
+// feverLastRefreshed returns the newest feed refresh as a Unix time.
+func feverLastRefreshed(feeds []dbgen.Feed) int64 {
+	var last int64
+	for _, f := range feeds {
+		if f.LastUpdated != nil && f.LastUpdated.Unix() > last {
+			last = f.LastUpdated.Unix()
+		}
+	}
+	return last
+}
+
+func feverFeeds(feeds []dbgen.Feed) []map[string]any {
+	out := make([]map[string]any, 0, len(feeds))
+	for _, f := range feeds {
+		var updated int64
+		if f.LastUpdated != nil {
+			updated = f.LastUpdated.Unix()
+		}
+		out = append(out, map[string]any{
+			"id":                   f.ID,
+			"favicon_id":           0,
+			"title":                f.Title,
+			"url":                  f.Url,
+			"site_url":             f.SiteUrl,
+			"is_spark":             0,
+			"last_updated_on_time": updated,
+		})
+	}
+	return out
+}
+
+// feverFeedsGroups lists the feed ids in each category as comma-separated
+// strings, as Fever expects.
+func feverFeedsGroups(feeds []dbgen.Feed) []map[string]any {
+	byGroup := make(map[int64][]string)
+	var order []int64
+	for _, f := range feeds {
+		if f.CategoryID == nil {
+			continue
+		}
+		if _, ok := byGroup[*f.CategoryID]; !ok {
+			order = append(order, *f.CategoryID)
+		}
+		byGroup[*f.CategoryID] = append(byGroup[*f.CategoryID], strconv.FormatInt(f.ID, 10))
+	}
+	out := make([]map[string]any, 0, len(order))
+	for _, id := range order {
+		out = append(out, map[string]any{"group_id": id, "feed_ids": strings.Join(byGroup[id], ",")})
+	}
+	return out
+}
+
+// feverItems adds up to feverPageSize items, selected by since_id (ids
+// above, ascending), max_id (ids below, descending) or with_ids, plus
+// total_items.
+func (s *Server) feverItems(ctx context.Context, userID string, r *http.Request, resp map[string]any) error {
+	query := `SELECT a.id, a.feed_id, a.title, a.author, a.content, a.content_compressed, a.summary, a.url,
+			a.published_at, a.created_at, COALESCE(s.is_read, 0), COALESCE(s.is_starred, 0)
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+		WHERE f.user_id = ?`
+	args := []any{userID}
+	order := " ORDER BY a.id ASC"
+	switch {
+	case r.FormValue("with_ids") != "":
+		ids := feverParseIDs(r.FormValue("with_ids"))
+		if len(ids) == 0 {
+			resp["items"] = []any{}
+			return s.feverTotalItems(ctx, userID, resp)
+		}
+		query += " AND a.id IN (" + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	case r.FormValue("max_id") != "":
+		maxID, _ := strconv.ParseInt(r.FormValue("max_id"), 10, 64)
+		query += " AND a.id < ?"
+		args = append(args, maxID)
+		order = " ORDER BY a.id DESC"
+	default:
+		sinceID, _ := strconv.ParseInt(r.FormValue("since_id"), 10, 64)
+		query += " AND a.id > ?"
+		args = append(args, sinceID)
+	}
+	query += order + " LIMIT " + strconv.Itoa(feverPageSize)
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	items := []map[string]any{}
+	for rows.Next() {
+		var (
+			id, feedID, isRead, isStarred   int64
+			title, author, content, summary string
+			link                            string
+			compressed                      int64
+			publishedAt                     *time.Time
+			createdAt                       time.Time
+		)
+		if err := rows.Scan(&id, &feedID, &title, &author, &content, &compressed, &summary, &link,
+			&publishedAt, &createdAt, &isRead, &isStarred); err != nil {
+			return err
+		}
+		content = decompressContent(content, compressed)
+		if content == "" {
+			content = summary
+		}
+		if publishedAt != nil {
+			createdAt = *publishedAt
+		}
+		items = append(items, map[string]any{
+			"id": id, "feed_id": feedID, "title": title, "author": author, "html": content,
+			"url": link, "is_saved": isStarred, "is_read": isRead, "created_on_time": createdAt.Unix(),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	resp["items"] = items
+	return s.feverTotalItems(ctx, userID, resp)
+}
+
+func (s *Server) feverTotalItems(ctx context.Context, userID string, resp map[string]any) error {
+	total, err := dbgen.New(s.DB).GetTotalArticleCount(ctx, userID)
+	if err != nil {
+		return err
+	}
+	resp["total_items"] = total
+	return nil
+}
+
+// feverIDs returns the ids of the user's articles matching cond as a
+// comma-separated string.
+func (s *Server) feverIDs(ctx context.Context, userID, cond string) (string, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT a.id FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+		WHERE f.user_id = ? AND `+cond+` ORDER BY a.id`, userID)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rows.Close() }()
+	var ids []string
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return "", err
+		}
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(ids, ","), rows.Err()
+}
+
+// feverParseIDs parses a comma-separated id list, capped at feverPageSize.
+func feverParseIDs(v string) []int64 {
+	var ids []int64
+	for part := range strings.SplitSeq(v, ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+		if len(ids) == feverPageSize {
+			break
+		}
+	}
+	return ids
+}
+
+// feverMark applies a mark=item|feed|group request, if any.
+func (s *Server) feverMark(ctx context.Context, userID string, r *http.Request) error {
+	mark := r.FormValue("mark")
+	if mark == "" {
+		return nil
+	}
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return errFeverBadMark
+	}
+	as := r.FormValue("as")
+	if mark == "item" {
+		return s.feverMarkItem(ctx, userID, id, as)
+	}
+	if as != "read" || (mark != "feed" && mark != "group") {
+		return errFeverBadMark
+	}
+	before := time.Now()
+	if v := r.FormValue("before"); v != "" {
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return errFeverBadMark
+		}
+		before = time.Unix(ts, 0)
+	}
+
+	scope, args := " AND a.feed_id = ?", []any{id}
+	if mark == "group" {
+		// Group 0 is Fever's "Kindling" group: everything
+		scope, args = " AND f.category_id = ?", []any{id}
+		if id == 0 {
+			scope, args = "", nil
+		}
+	}
+	now := time.Now()
+	args = append([]any{userID, now, userID, before.UTC().Format(time.DateTime)}, args...)
+	_, err = s.DB.ExecContext(ctx, `INSERT INTO article_states (user_id, article_id, is_read, read_at)
+		SELECT ?, a.id, 1, ?
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+		WHERE f.user_id = ? AND (s.is_read IS NULL OR s.is_read = 0) AND a.created_at <= ?`+scope+`
+		ON CONFLICT (user_id, article_id) DO UPDATE SET is_read = 1, read_at = excluded.read_at`, args...)
+	return err
+}
+
+// Errors for mark requests that are the client's fault.
+var (
+	errFeverBadMark      = errors.New("invalid mark request")
+	errFeverItemNotFound = errors.New("item not found")
+)
+
+// feverMarkItem marks one article read, unread, saved or unsaved.
+func (s *Server) feverMarkItem(ctx context.Context, userID string, id int64, as string) error {
+	q := dbgen.New(s.DB)
+	if _, err := q.GetArticle(ctx, dbgen.GetArticleParams{UserID: userID, ID: id, UserID_2: userID}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errFeverItemNotFound
+		}
+		return err
+	}
+	now := time.Now()
+	switch as {
+	case "read":
+		if err := q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: userID, ArticleID: id, ReadAt: &now}); err != nil {
+			return err
+		}
+		s.propagateRead(ctx, userID, []int64{id}, now)
+		return nil
+	case "unread":
+		return q.SetArticleUnread(ctx, dbgen.SetArticleUnreadParams{UserID: userID, ArticleID: id})
+	case "saved":
+		return q.SetArticleStarred(ctx, dbgen.SetArticleStarredParams{UserID: userID, ArticleID: id, StarredAt: &now})
+	case "unsaved":
+		return q.SetArticleUnstarred(ctx, dbgen.SetArticleUnstarredParams{UserID: userID, ArticleID: id})
+	}
+	return errFeverBadMark
+}
@@ -3,15 +3,26 @@ package srv
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/johnwmail/gorss/db"
 	"github.com/johnwmail/gorss/db/dbgen"
+	"modernc.org/sqlite"
 )
 
 // JSON response helper
@@ -35,16 +46,208 @@ func getUserEmail(r *http.Request) string {
 	return strings.TrimSpace(r.Header.Get("X-ExeDev-Email"))
 }
 
-// ensureUser creates or updates user record
+// getUserGroups returns the comma-separated groups from X-ExeDev-Groups.
+// Only the proxy sets the header, so in other modes it is ignored.
+func getUserGroups(r *http.Request) []string {
+	if GetAuthMode() != AuthModeProxy {
+		return nil
+	}
+	var groups []string
+	for g := range strings.SplitSeq(r.Header.Get("X-ExeDev-Groups"), ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// userSeenCache remembers when each user record was last upserted so that
+// read-only requests don't write to the database every time.
+type userSeenCache struct {
+	mu       sync.Mutex
+	seen     map[string]time.Time // keyed by user ID + email
+	interval time.Duration
+}
+
+// maxSeenUsers bounds the cache; expired entries are pruned past this size.
+const maxSeenUsers = 10000
+
+func newUserSeenCache(interval time.Duration) *userSeenCache {
+	return &userSeenCache{seen: make(map[string]time.Time), interval: interval}
+}
+
+// due reports whether the user record should be written now, and if so
+// records the write time. A zero interval disables debouncing.
+func (c *userSeenCache) due(key string, now time.Time) bool {
+	if c.interval <= 0 {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if last, ok := c.seen[key]; ok && now.Sub(last) < c.interval {
+		return false
+	}
+	if len(c.seen) >= maxSeenUsers {
+		for k, last := range c.seen {
+			if now.Sub(last) >= c.interval {
+				delete(c.seen, k)
+			}
+		}
+	}
+	c.seen[key] = now
+	return true
+}
+
+// forget drops a key so the next request writes again (used after a failed write).
+func (c *userSeenCache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.seen, key)
+}
+
+// countsCache holds each user's /api/counts response for a short TTL, so
+// clients polling for badge counts don't rerun the count queries every time.
+// Entries are dropped as soon as the user makes a write request.
+type countsCache struct {
+	mu      sync.Mutex
+	entries map[string]countsEntry
+	gen     uint64 // bumped on every invalidation
+	ttl     time.Duration
+}
+
+type countsEntry struct {
+	counts  map[string]any
+	expires time.Time
+}
+
+// maxCountsEntries bounds the cache; expired entries are pruned past this size.
+const maxCountsEntries = 10000
+
+func newCountsCache(ttl time.Duration) *countsCache {
+	return &countsCache{entries: make(map[string]countsEntry), ttl: ttl}
+}
+
+// get returns the user's cached counts if they haven't expired, along with
+// the current generation to pass to put. A zero TTL disables caching.
+func (c *countsCache) get(userID string, now time.Time) (map[string]any, uint64, bool) {
+	if c.ttl <= 0 {
+		return nil, 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[userID]
+	if !ok || !now.Before(e.expires) {
+		return nil, c.gen, false
+	}
+	return e.counts, c.gen, true
+}
+
+// put caches counts computed since get returned gen. If anything was
+// invalidated in the meantime the counts may predate that write, so they
+// are not stored.
+func (c *countsCache) put(userID string, gen uint64, counts map[string]any, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if len(c.entries) >= maxCountsEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[userID] = countsEntry{counts: counts, expires: now.Add(c.ttl)}
+}
+
+// invalidate drops the user's cached counts.
+func (c *countsCache) invalidate(userID string) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+	c.gen++
+}
+
+// streamLimiter caps concurrent long-lived streaming responses, overall and
+// per user, so open connections can't exhaust file descriptors and one user
+// can't take every slot. A zero limit means unlimited.
+type streamLimiter struct {
+	mu      sync.Mutex
+	max     int
+	perUser int
+	total   int
+	users   map[string]int
+}
+
+func newStreamLimiter(limit, perUser int) *streamLimiter {
+	return &streamLimiter{max: limit, perUser: perUser, users: make(map[string]int)}
+}
+
+// acquire takes a stream slot for userID, reporting false if the overall
+// or per-user cap is reached. Each successful acquire must be released.
+func (l *streamLimiter) acquire(userID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.max > 0 && l.total >= l.max {
+		return false
+	}
+	if l.perUser > 0 && l.users[userID] >= l.perUser {
+		return false
+	}
+	l.total++
+	l.users[userID]++
+	return true
+}
+
+// release frees a slot taken by acquire.
+func (l *streamLimiter) release(userID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total--
+	if l.users[userID]--; l.users[userID] <= 0 {
+		delete(l.users, userID)
+	}
+}
+
+// limitStream wraps a streaming handler so it only runs while holding a
+// stream slot, answering 503 when none is free. The slot is released when
+// the handler returns, which is also when the client disconnects.
+func (s *Server) limitStream(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := s.userFromContext(r)
+		if !s.streams.acquire(userID) {
+			w.Header().Set("Retry-After", "5")
+			jsonError(w, "too many open streams", http.StatusServiceUnavailable)
+			return
+		}
+		defer s.streams.release(userID)
+		h(w, r)
+	}
+}
+
+// ensureUser creates or updates user record. The write is skipped when the
+// same user (and email) was already written within the debounce interval.
 func (s *Server) ensureUser(r *http.Request) (string, error) {
 	userID := getUserID(r)
 	if userID == "" {
-		userID = "anonymous"
+		userID = s.DefaultUser
 	}
 
 	email := getUserEmail(r)
 	now := time.Now()
 
+	key := userID + "\x00" + email
+	if !s.usersSeen.due(key, now) {
+		return userID, nil
+	}
+
 	q := dbgen.New(s.DB)
 	err := q.UpsertUser(r.Context(), dbgen.UpsertUserParams{
 		ID:        userID,
@@ -52,31 +255,54 @@ func (s *Server) ensureUser(r *http.Request) (string, error) {
 		CreatedAt: now,
 		LastSeen:  now,
 	})
+	if err != nil {
+		s.usersSeen.forget(key)
+	}
 	return userID, err
 }
 
-// requireUser ensures a user exists and returns the user ID.
-// Falls back to "anonymous" on error so callers are never blocked.
+// requireUser ensures a user exists and returns the user ID. A failed
+// upsert is only logged so callers are never blocked.
 func (s *Server) requireUser(r *http.Request) string {
 	userID, err := s.ensureUser(r)
 	if err != nil {
-		slog.Error("ensure user", "error", err)
-		if userID == "" {
-			userID = "anonymous"
-		}
+		logFrom(r.Context()).Error("ensure user", "error", err)
 	}
 	return userID
 }
 
-// HandleHealth returns health status
+// userIDKey is the request context key holding the resolved user ID.
+type userIDKey struct{}
+
+// withUser resolves the request's user once (upserting the record) and
+// stores the ID in the request context for handlers.
+func (s *Server) withUser(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userIDKey{}, s.requireUser(r)))
+}
+
+// userFromContext returns the user ID resolved by AuthMiddleware. Handlers
+// invoked without the middleware resolve it directly instead.
+func (s *Server) userFromContext(r *http.Request) string {
+	if userID, ok := r.Context().Value(userIDKey{}).(string); ok {
+		return userID
+	}
+	return s.requireUser(r)
+}
+
+// HandleHealth returns health status. A stale backup reports "degraded"
+// but still answers 200, so container health checks don't restart the server.
 func (s *Server) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	status := map[string]string{"status": "ok"}
+	if s.backupStale.Load() {
+		status = map[string]string{"status": "degraded", "backup": "stale"}
+	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	_ = json.NewEncoder(w).Encode(status)
 }
 
 // HandleGetFeeds returns all feeds for the user
 func (s *Server) HandleGetFeeds(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+	userID := s.userFromContext(r)
 
 	q := dbgen.New(s.DB)
 	feeds, err := q.GetFeedsOrdered(r.Context(), userID)
@@ -84,19 +310,35 @@ func (s *Server) HandleGetFeeds(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, "failed to get feeds", http.StatusInternalServerError)
 		return
 	}
-	if feeds == nil {
-		feeds = []dbgen.Feed{}
+
+	// Include the interval each feed is actually refreshed at
+	type feedWithInterval struct {
+		dbgen.Feed
+		EffectiveRefreshIntervalMinutes int64 `json:"effective_refresh_interval_minutes"`
 	}
-	jsonResponse(w, feeds)
+	catIntervals := make(map[int64]int64)
+	cats, _ := q.GetCategories(r.Context(), userID)
+	for _, c := range cats {
+		if c.RefreshIntervalMinutes != nil {
+			catIntervals[c.ID] = *c.RefreshIntervalMinutes
+		}
+	}
+	result := make([]feedWithInterval, 0, len(feeds))
+	for _, f := range feeds {
+		interval := s.effectiveRefreshInterval(&f, catIntervals)
+		result = append(result, feedWithInterval{Feed: f, EffectiveRefreshIntervalMinutes: int64(interval / time.Minute)})
+	}
+	jsonResponse(w, result)
 }
 
 // HandleSubscribe subscribes to a new feed
 func (s *Server) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+	userID := s.userFromContext(r)
 
 	var req struct {
-		URL        string `json:"url"`
-		CategoryID *int64 `json:"category_id"`
+		URL                string `json:"url"`
+		CategoryID         *int64 `json:"category_id"`
+		InsecureSkipVerify bool   `json:"insecure_skip_verify"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		jsonError(w, "invalid request body", http.StatusBadRequest)
@@ -107,21 +349,33 @@ func (s *Server) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, "url is required", http.StatusBadRequest)
 		return
 	}
+	if err := s.checkInsecureTLS(r, req.InsecureSkipVerify); err != nil {
+		jsonError(w, err.Error(), http.StatusForbidden)
+		return
+	}
 
 	// Fetch feed to get title
-	result, err := s.fetcher.Fetch(r.Context(), req.URL)
+	result, err := s.fetcher.fetchWithCaching(r.Context(), req.URL, "", "", req.InsecureSkipVerify)
 	if err != nil {
 		jsonError(w, "failed to fetch feed: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Filter out articles older than purge threshold on initial subscribe
+	applyDateSource(result.Items, dateSourcePreferPublished)
 	if s.PurgeDays > 0 {
 		cutoff := time.Now().AddDate(0, 0, -s.PurgeDays)
 		result.Items = filterOldItems(result.Items, cutoff)
 	}
 
+	if result.Title == "" {
+		result.Title = fallbackFeedTitle(result.SiteURL, req.URL)
+	}
+
 	q := dbgen.New(s.DB)
+	if req.CategoryID == nil {
+		req.CategoryID = defaultCategory(r.Context(), q, userID)
+	}
 	feed, err := q.CreateFeed(r.Context(), dbgen.CreateFeedParams{
 		UserID:      userID,
 		CategoryID:  req.CategoryID,
@@ -129,6 +383,7 @@ func (s *Server) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 		Title:       result.Title,
 		SiteUrl:     result.SiteURL,
 		Description: result.Description,
+		LogoUrl:     result.LogoURL,
 	})
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE") {
@@ -139,26 +394,27 @@ func (s *Server) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Store initial articles
-	for _, item := range result.Items {
-		_, _ = q.UpsertArticle(r.Context(), dbgen.UpsertArticleParams{
-			FeedID:      feed.ID,
-			Guid:        item.GUID,
-			Url:         item.URL,
-			Title:       item.Title,
-			Author:      item.Author,
-			Content:     item.Content,
-			Summary:     item.Summary,
-			PublishedAt: item.PublishedAt,
-		})
+	if req.InsecureSkipVerify {
+		if err := q.UpdateFeedInsecureSkipVerify(r.Context(), dbgen.UpdateFeedInsecureSkipVerifyParams{
+			InsecureSkipVerify: 1, ID: feed.ID, UserID: userID,
+		}); err != nil {
+			logFrom(r.Context()).Warn("set insecure_skip_verify", "error", err, "feed_id", feed.ID)
+		}
+		feed.InsecureSkipVerify = 1
 	}
 
-	jsonResponse(w, feed)
+	// Store initial articles
+	articles, _ := s.storeItems(r.Context(), q, &feed, result.Items)
+
+	jsonResponse(w, struct {
+		dbgen.Feed
+		Articles int `json:"articles"`
+	}{feed, articles})
 }
 
 // HandleUpdateFeed updates a feed's title and/or URL
 func (s *Server) HandleUpdateFeed(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+	userID := s.userFromContext(r)
 	feedID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		jsonError(w, "invalid feed id", http.StatusBadRequest)
@@ -168,11 +424,20 @@ func (s *Server) HandleUpdateFeed(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Title string `json:"title"`
 		URL   string `json:"url"`
+		feedSettings
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		jsonError(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
+	if err := req.validate(); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.checkInsecureTLS(r, req.InsecureSkipVerify != nil && *req.InsecureSkipVerify); err != nil {
+		jsonError(w, err.Error(), http.StatusForbidden)
+		return
+	}
 
 	q := dbgen.New(s.DB)
 
@@ -192,13 +457,9 @@ func (s *Server) HandleUpdateFeed(w http.ResponseWriter, r *http.Request) {
 		url = feed.Url
 	}
 
-	// If URL changed, validate it's a working feed
-	if url != feed.Url {
-		_, fetchErr := s.fetcher.Fetch(r.Context(), url)
-		if fetchErr != nil {
-			jsonError(w, "invalid feed URL: "+fetchErr.Error(), http.StatusBadRequest)
-			return
-		}
+	if err := s.checkFeedURL(r.Context(), &feed, url, req.InsecureSkipVerify); err != nil {
+		jsonError(w, "invalid feed URL: "+err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	if err := q.UpdateFeedDetails(r.Context(), dbgen.UpdateFeedDetailsParams{
@@ -215,511 +476,2332 @@ func (s *Server) HandleUpdateFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jsonResponse(w, map[string]string{"status": "ok"})
-}
-
-// HandleUnsubscribe removes a feed subscription
-func (s *Server) HandleUnsubscribe(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
-	feedID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	if err != nil {
-		jsonError(w, "invalid feed id", http.StatusBadRequest)
+	if err := reviveMovedFeed(r.Context(), q, &feed, url); err != nil {
+		jsonError(w, "failed to update feed", http.StatusInternalServerError)
 		return
 	}
 
-	q := dbgen.New(s.DB)
-	if err := q.DeleteFeed(r.Context(), dbgen.DeleteFeedParams{
-		ID:     feedID,
-		UserID: userID,
-	}); err != nil {
-		jsonError(w, "failed to delete feed", http.StatusInternalServerError)
+	if err := req.apply(r.Context(), q, feedID, userID); err != nil {
+		jsonError(w, "failed to update feed", http.StatusInternalServerError)
 		return
 	}
+
 	jsonResponse(w, map[string]string{"status": "ok"})
 }
 
-// queryArticlesByCategory runs a category-filtered article query.
-// categoryID=0 means uncategorized (NULL category_id).
-// unreadOnly=true filters to unread articles only.
-// queryArticles builds and executes a flexible article query with optional filters and sort direction.
-// buildArticleFilters constructs WHERE clause filters and args from query options.
-func buildArticleFilters(opts articleQueryOpts, orderCol string) (filters []string, filterArgs []any) {
-	if opts.CategoryID != nil {
-		cid := *opts.CategoryID
-		if cid == 0 {
-			filters = append(filters, "f.category_id IS NULL")
-		} else {
-			filters = append(filters, "f.category_id = ?")
-			filterArgs = append(filterArgs, cid)
-		}
+// checkFeedURL fetches url if it differs from feed's, so a feed is only moved
+// to a working feed URL. insecure is the request's insecure_skip_verify, if
+// given.
+func (s *Server) checkFeedURL(ctx context.Context, feed *dbgen.GetFeedRow, url string, insecure *bool) error {
+	if url == feed.Url {
+		return nil
 	}
-	if opts.FeedID != nil {
-		filters = append(filters, "f.id = ?")
-		filterArgs = append(filterArgs, *opts.FeedID)
+	skip := s.skipVerify(feed.InsecureSkipVerify) || (insecure != nil && *insecure)
+	_, err := s.fetcher.fetchWithCaching(ctx, url, "", "", skip)
+	return err
+}
+
+// reviveMovedFeed clears the dead flag of a feed whose URL changed to url:
+// checkFeedURL fetched the new URL, so the feed is working again.
+func reviveMovedFeed(ctx context.Context, q *dbgen.Queries, feed *dbgen.GetFeedRow, url string) error {
+	if url == feed.Url || feed.Dead == 0 {
+		return nil
 	}
-	if opts.UnreadOnly {
-		filters = append(filters, "(s.is_read IS NULL OR s.is_read = 0)")
+	return q.UpdateFeedDead(ctx, dbgen.UpdateFeedDeadParams{Dead: 0, ID: feed.ID, UserID: feed.UserID})
+}
+
+// feedSettings are the optional per-feed settings accepted by HandleUpdateFeed;
+// nil fields are left unchanged.
+type feedSettings struct {
+	AutoReadAfterDays  *int64  `json:"auto_read_after_days"`
+	RefreshInterval    *int64  `json:"refresh_interval_minutes"` // 0 clears the override
+	DedupTitles        *bool   `json:"dedup_titles"`
+	GuidUnstable       *bool   `json:"guid_unstable"` // key articles by content hash, not GUID/link
+	InsecureSkipVerify *bool   `json:"insecure_skip_verify"`
+	DateSource         *string `json:"date_source"`       // prefer_published, published or updated
+	KeepOnlyPattern    *string `json:"keep_only_pattern"` // "" keeps every item
+	Dead               *bool   `json:"dead"`              // false resumes refreshing a feed whose host stopped resolving
+}
+
+// Errors for a feed asking to skip TLS verification, answered with 403.
+var (
+	// The operator has not enabled GORSS_ALLOW_INSECURE_TLS
+	errInsecureTLSDisabled = errors.New("insecure_skip_verify is disabled on this server (set GORSS_ALLOW_INSECURE_TLS=1)")
+	// Only admins may turn verification off
+	errInsecureTLSAdminOnly = errors.New("insecure_skip_verify requires admin access")
+)
+
+// checkInsecureTLS returns why r may not turn off TLS verification for a
+// feed, if it asks to (insecure).
+func (s *Server) checkInsecureTLS(r *http.Request, insecure bool) error {
+	switch {
+	case !insecure:
+		return nil
+	case !s.AllowInsecureTLS:
+		return errInsecureTLSDisabled
+	case !s.isAdmin(r):
+		return errInsecureTLSAdminOnly
+	}
+	return nil
+}
+
+func (fs *feedSettings) validate() error {
+	if fs.AutoReadAfterDays != nil && *fs.AutoReadAfterDays < 0 {
+		return errors.New("auto_read_after_days must not be negative")
 	}
-	if opts.StarredOnly {
-		filters = append(filters, "s.is_starred = 1")
+	if fs.RefreshInterval != nil && *fs.RefreshInterval < 0 {
+		return errors.New("refresh_interval_minutes must not be negative")
 	}
-
-	// Cursor-based pagination
-	if opts.BeforeTime != nil && opts.BeforeID != nil {
-		filters = append(filters, "("+orderCol+" < ? OR ("+orderCol+" = ? AND a.id < ?))")
-		filterArgs = append(filterArgs, *opts.BeforeTime, *opts.BeforeTime, *opts.BeforeID)
-	} else if opts.AfterTime != nil && opts.AfterID != nil {
-		filters = append(filters, "("+orderCol+" > ? OR ("+orderCol+" = ? AND a.id > ?))")
-		filterArgs = append(filterArgs, *opts.AfterTime, *opts.AfterTime, *opts.AfterID)
+	if fs.DateSource != nil && !validDateSource(*fs.DateSource) {
+		return errors.New("date_source must be prefer_published, published or updated")
 	}
-	return
+	if fs.KeepOnlyPattern != nil {
+		if _, err := compileKeepOnly(*fs.KeepOnlyPattern); err != nil {
+			return fmt.Errorf("invalid keep_only_pattern: %w", err)
+		}
+	}
+	return nil
 }
 
-// buildPagination returns the pagination SQL clause and args.
-func buildPagination(opts articleQueryOpts) (string, []any) {
-	if opts.BeforeTime != nil || opts.AfterTime != nil {
-		return "LIMIT ?", []any{opts.Limit}
+// apply stores the settings that were provided.
+func (fs *feedSettings) apply(ctx context.Context, q *dbgen.Queries, feedID int64, userID string) error {
+	setters := []struct {
+		given  bool
+		update func() error
+	}{
+		{fs.AutoReadAfterDays != nil, func() error {
+			return q.UpdateFeedAutoRead(ctx, dbgen.UpdateFeedAutoReadParams{
+				AutoReadAfterDays: *fs.AutoReadAfterDays, ID: feedID, UserID: userID,
+			})
+		}},
+		{fs.RefreshInterval != nil, func() error {
+			return q.UpdateFeedRefreshInterval(ctx, dbgen.UpdateFeedRefreshIntervalParams{
+				RefreshIntervalMinutes: nullIfZero(*fs.RefreshInterval), ID: feedID, UserID: userID,
+			})
+		}},
+		{fs.DedupTitles != nil, func() error {
+			return q.UpdateFeedDedupTitles(ctx, dbgen.UpdateFeedDedupTitlesParams{
+				DedupTitles: boolToInt(*fs.DedupTitles), ID: feedID, UserID: userID,
+			})
+		}},
+		{fs.GuidUnstable != nil, func() error {
+			return q.UpdateFeedGuidUnstable(ctx, dbgen.UpdateFeedGuidUnstableParams{
+				GuidUnstable: boolToInt(*fs.GuidUnstable), ID: feedID, UserID: userID,
+			})
+		}},
+		{fs.InsecureSkipVerify != nil, func() error {
+			return q.UpdateFeedInsecureSkipVerify(ctx, dbgen.UpdateFeedInsecureSkipVerifyParams{
+				InsecureSkipVerify: boolToInt(*fs.InsecureSkipVerify), ID: feedID, UserID: userID,
+			})
+		}},
+		{fs.DateSource != nil, func() error {
+			return q.UpdateFeedDateSource(ctx, dbgen.UpdateFeedDateSourceParams{
+				DateSource: *fs.DateSource, ID: feedID, UserID: userID,
+			})
+		}},
+		{fs.KeepOnlyPattern != nil, func() error {
+			return q.UpdateFeedKeepOnly(ctx, dbgen.UpdateFeedKeepOnlyParams{
+				KeepOnlyPattern: *fs.KeepOnlyPattern, ID: feedID, UserID: userID,
+			})
+		}},
+		{fs.Dead != nil, func() error {
+			return q.UpdateFeedDead(ctx, dbgen.UpdateFeedDeadParams{
+				Dead: boolToInt(*fs.Dead), ID: feedID, UserID: userID,
+			})
+		}},
 	}
-	return "LIMIT ? OFFSET ?", []any{opts.Limit, opts.Offset}
+	for _, setter := range setters {
+		if !setter.given {
+			continue
+		}
+		if err := setter.update(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func queryArticles(ctx context.Context, db *sql.DB, userID string, opts articleQueryOpts) ([]dbgen.GetArticlesRow, error) {
-	joinType := "LEFT JOIN"
-	if opts.StarredOnly {
-		joinType = "JOIN"
+// boolToInt maps a bool to SQLite's 0/1 flag representation.
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
 	}
+	return 0
+}
 
-	orderCol := "a.published_at"
-	if opts.StarredOnly {
-		orderCol = "s.starred_at"
+// HandleGetFeedRaw re-fetches a feed and returns the upstream body unparsed,
+// with its original content type, to help diagnose parse problems. Nothing
+// is stored.
+func (s *Server) HandleGetFeedRaw(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	feedID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid feed id", http.StatusBadRequest)
+		return
 	}
-	orderDir := "DESC"
-	if opts.SortOldest {
-		orderDir = "ASC"
+
+	q := dbgen.New(s.DB)
+	feed, err := q.GetFeed(r.Context(), dbgen.GetFeedParams{ID: feedID, UserID: userID})
+	if err != nil {
+		jsonError(w, "feed not found", http.StatusNotFound)
+		return
 	}
 
-	filters, filterArgs := buildArticleFilters(opts, orderCol)
+	raw, err := s.fetcher.FetchRaw(r.Context(), feed.Url, s.skipVerify(feed.InsecureSkipVerify))
+	if err != nil {
+		jsonError(w, "fetch failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
 
-	whereExtra := ""
-	if len(filters) > 0 {
-		whereExtra = " AND " + strings.Join(filters, " AND ")
+	contentType := raw.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
 	}
+	// The body is untrusted upstream content served from our origin; keep
+	// browsers from sniffing it or running anything it contains.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox; default-src 'none'")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Upstream-Status", strconv.Itoa(raw.StatusCode))
+	if raw.Truncated {
+		w.Header().Set("X-Truncated", "true")
+	}
+	_, _ = w.Write(raw.Body)
+}
 
-	pagination, paginationArgs := buildPagination(opts)
+// feedContentItem is one article in a GET /api/feeds/{id}/content response.
+type feedContentItem struct {
+	ID          int64      `json:"id"`
+	GUID        string     `json:"guid"`
+	URL         string     `json:"url"`
+	Title       string     `json:"title"`
+	Author      string     `json:"author"`
+	Content     string     `json:"content"`
+	Summary     string     `json:"summary"`
+	PublishedAt *time.Time `json:"published_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+	IsRead      bool       `json:"is_read"`
+	IsStarred   bool       `json:"is_starred"`
+}
 
-	query := `
-SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, a.content, a.summary, a.published_at, a.created_at,
-  f.title as feed_title, f.site_url as feed_site_url,
-  COALESCE(s.is_read, 0) as is_read,
-  COALESCE(s.is_starred, 0) as is_starred
-FROM articles a
-JOIN feeds f ON a.feed_id = f.id
-` + joinType + ` article_states s ON s.article_id = a.id AND s.user_id = ?
-WHERE f.user_id = ?` + whereExtra + `
-ORDER BY ` + orderCol + ` ` + orderDir + `
-` + pagination
+// HandleGetFeedContent returns the full stored content of a feed's
+// articles for offline caching. With ?since=<RFC 3339 time> only articles
+// created or changed at or after that time are returned; clients pass the
+// newest updated_at they hold. ?unread=1 limits it to unread articles. The
+// JSON array is streamed so large feeds aren't buffered in memory.
+func (s *Server) HandleGetFeedContent(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	feedID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid feed id", http.StatusBadRequest)
+		return
+	}
+	if _, err := dbgen.New(s.DB).GetFeed(r.Context(), dbgen.GetFeedParams{ID: feedID, UserID: userID}); err != nil {
+		jsonError(w, "feed not found", http.StatusNotFound)
+		return
+	}
 
-	var args []any
-	args = append(args, userID, userID)
-	args = append(args, filterArgs...)
-	args = append(args, paginationArgs...)
+	query := `SELECT a.id, a.guid, a.url, a.title, a.author, a.content, a.content_compressed, a.summary,
+			a.published_at, a.created_at, a.updated_at,
+			COALESCE(s.is_read, 0), COALESCE(s.is_starred, 0)
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+		WHERE a.feed_id = ? AND f.user_id = ?`
+	args := []any{feedID, userID}
+	if v := r.URL.Query().Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			jsonError(w, "invalid since: expected RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		query += " AND a.updated_at >= ?"
+		args = append(args, since.UTC().Format(time.DateTime))
+	}
+	if r.URL.Query().Get("unread") == "1" {
+		query += " AND (s.is_read IS NULL OR s.is_read = 0)"
+	}
+	query += " ORDER BY a.updated_at, a.id"
 
-	rows, err := db.QueryContext(ctx, query, args...)
+	rows, err := s.DB.QueryContext(r.Context(), query, args...)
 	if err != nil {
-		return nil, err
+		jsonError(w, "failed to get content", http.StatusInternalServerError)
+		return
 	}
 	defer func() { _ = rows.Close() }()
 
-	var articles []dbgen.GetArticlesRow
-	for rows.Next() {
-		var a dbgen.GetArticlesRow
-		if err := rows.Scan(
-			&a.ID, &a.FeedID, &a.Guid, &a.Url, &a.Title, &a.Author,
-			&a.Content, &a.Summary, &a.PublishedAt, &a.CreatedAt,
-			&a.FeedTitle, &a.FeedSiteUrl, &a.IsRead, &a.IsStarred,
-		); err != nil {
-			return nil, err
+	w.Header().Set("Content-Type", "application/json")
+	_ = streamFeedContent(w, rows)
+}
+
+// streamFeedContent writes rows as a JSON array, flushing as it goes. Once
+// the first byte is written the status can't change, so a mid-stream error
+// just ends the response early.
+func streamFeedContent(w http.ResponseWriter, rows *sql.Rows) error {
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for n := 0; rows.Next(); n++ {
+		var it feedContentItem
+		var compressed int64
+		if err := rows.Scan(&it.ID, &it.GUID, &it.URL, &it.Title, &it.Author, &it.Content, &compressed, &it.Summary,
+			&it.PublishedAt, &it.CreatedAt, &it.UpdatedAt, &it.IsRead, &it.IsStarred); err != nil {
+			slog.Error("feed content: scan", "error", err)
+			return err
+		}
+		it.Content = decompressContent(it.Content, compressed)
+		if n > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(it); err != nil {
+			return err
+		}
+		if flusher != nil && n%100 == 99 {
+			flusher.Flush()
 		}
-		articles = append(articles, a)
 	}
-	if articles == nil {
-		articles = []dbgen.GetArticlesRow{}
+	if err := rows.Err(); err != nil {
+		slog.Error("feed content: rows", "error", err)
+		return err
 	}
-	return articles, rows.Err()
+	_, err := io.WriteString(w, "]\n")
+	return err
 }
 
-type articleQueryOpts struct {
-	CategoryID  *int64
-	FeedID      *int64
-	UnreadOnly  bool
-	StarredOnly bool
-	SortOldest  bool
-	Limit       int64
-	Offset      int64
-	BeforeTime  *time.Time // cursor: articles before this timestamp
-	BeforeID    *int64     // cursor: tie-breaker for same timestamp
-	AfterTime   *time.Time // cursor: articles after this timestamp (for oldest-first)
-	AfterID     *int64     // cursor: tie-breaker for same timestamp
+// defaultStaleDays is the threshold used by HandleGetStaleFeeds without ?days=.
+const defaultStaleDays = 90
+
+// staleFeed is one feed in a GET /api/feeds/stale response.
+type staleFeed struct {
+	ID            int64      `json:"id"`
+	Title         string     `json:"title"`
+	URL           string     `json:"url"`
+	NewestArticle *time.Time `json:"newest_article_at"` // nil if it has no dated articles
+	Status        string     `json:"status"`            // "failing" or "no_new_articles"
+	LastError     *string    `json:"last_error"`
+	ErrorCount    int64      `json:"error_count"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
 }
 
-// HandleGetArticles returns articles with optional filters
-// parsePagination extracts limit and offset from query params.
-func parsePagination(r *http.Request) (limit, offset int64) {
-	limit = 50
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.ParseInt(l, 10, 64); err == nil {
-			limit = parsed
+// HandleGetStaleFeeds lists the user's feeds whose newest article was
+// published more than ?days= (default 90) days ago, stalest first. Feeds
+// without dated articles come first. Status tells a feed whose fetches are
+// failing apart from one that fetches fine but has nothing new.
+func (s *Server) HandleGetStaleFeeds(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	days := defaultStaleDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			jsonError(w, "days must be a positive integer", http.StatusBadRequest)
+			return
 		}
+		days = n
+	}
+	cutoff := time.Now().AddDate(0, 0, -days).UTC().Format(time.DateTime)
+
+	// MAX() loses the column type, so the newest article is joined back in
+	// to scan its published_at as a time.
+	rows, err := s.DB.QueryContext(r.Context(), `
+		SELECT f.id, f.title, f.url, newest.published_at, f.last_error, f.error_count, f.last_success_at
+		FROM feeds f
+		LEFT JOIN (
+			SELECT feed_id, MAX(published_at) AS published_at
+			FROM articles
+			WHERE feed_id IN (SELECT id FROM feeds WHERE user_id = ?)
+			GROUP BY feed_id
+		) m ON m.feed_id = f.id
+		LEFT JOIN articles newest ON newest.feed_id = f.id AND newest.published_at = m.published_at
+		WHERE f.user_id = ? AND (m.published_at IS NULL OR m.published_at < ?)
+		GROUP BY f.id
+		ORDER BY m.published_at ASC NULLS FIRST, f.title`,
+		userID, userID, cutoff)
+	if err != nil {
+		jsonError(w, "failed to get feeds", http.StatusInternalServerError)
+		return
 	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if parsed, err := strconv.ParseInt(o, 10, 64); err == nil {
-			offset = parsed
+	defer func() { _ = rows.Close() }()
+
+	feeds := []staleFeed{}
+	for rows.Next() {
+		var f staleFeed
+		if err := rows.Scan(&f.ID, &f.Title, &f.URL, &f.NewestArticle, &f.LastError, &f.ErrorCount, &f.LastSuccessAt); err != nil {
+			jsonError(w, "failed to get feeds", http.StatusInternalServerError)
+			return
+		}
+		f.Status = "no_new_articles"
+		if f.ErrorCount > 0 {
+			f.Status = "failing"
+		}
+		feeds = append(feeds, f)
+	}
+	if err := rows.Err(); err != nil {
+		jsonError(w, "failed to get feeds", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, feeds)
+}
+
+// maxAtomEntries caps the number of articles in a republished Atom feed.
+const maxAtomEntries = 200
+
+// HandleGetFeedToken returns the user's feed token, creating one on first use.
+// The token authenticates cookie-less requests such as feed readers polling
+// /api/feeds/{id}/atom.
+func (s *Server) HandleGetFeedToken(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	q := dbgen.New(s.DB)
+
+	token, err := q.GetFeedToken(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		token = generateSessionID()
+		err = q.SetFeedToken(r.Context(), dbgen.SetFeedTokenParams{UserID: userID, Token: token})
+	}
+	if err != nil {
+		jsonError(w, "failed to get feed token", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]string{"token": token})
+}
+
+// HandleRotateFeedToken replaces the user's feed token, invalidating the old one.
+func (s *Server) HandleRotateFeedToken(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	token := generateSessionID()
+	if err := dbgen.New(s.DB).SetFeedToken(r.Context(), dbgen.SetFeedTokenParams{UserID: userID, Token: token}); err != nil {
+		jsonError(w, "failed to rotate feed token", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]string{"token": token})
+}
+
+// HandleGetPrefs returns the user's preferences.
+func (s *Server) HandleGetPrefs(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	prefs, err := dbgen.New(s.DB).GetUserPrefs(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		prefs, err = dbgen.UserPref{UserID: userID}, nil
+	}
+	if err != nil {
+		jsonError(w, "failed to get preferences", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, prefs)
+}
+
+// HandleUpdatePrefs sets the user's preferences. default_category_id is the
+// category new subscriptions land in when they don't name one (null =
+// uncategorized).
+func (s *Server) HandleUpdatePrefs(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+
+	var req struct {
+		DefaultCategoryID *int64 `json:"default_category_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	q := dbgen.New(s.DB)
+	if req.DefaultCategoryID != nil {
+		if _, err := q.GetCategory(r.Context(), dbgen.GetCategoryParams{ID: *req.DefaultCategoryID, UserID: userID}); err != nil {
+			jsonError(w, "category not found", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := q.SetDefaultCategory(r.Context(), dbgen.SetDefaultCategoryParams{
+		UserID: userID, DefaultCategoryID: req.DefaultCategoryID,
+	}); err != nil {
+		jsonError(w, "failed to update preferences", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, dbgen.UserPref{UserID: userID, DefaultCategoryID: req.DefaultCategoryID})
+}
+
+// defaultCategory returns the user's default category for new
+// subscriptions, or nil if none is set or it no longer belongs to them.
+func defaultCategory(ctx context.Context, q *dbgen.Queries, userID string) *int64 {
+	prefs, err := q.GetUserPrefs(ctx, userID)
+	if err != nil || prefs.DefaultCategoryID == nil {
+		return nil
+	}
+	if _, err := q.GetCategory(ctx, dbgen.GetCategoryParams{ID: *prefs.DefaultCategoryID, UserID: userID}); err != nil {
+		return nil
+	}
+	return prefs.DefaultCategoryID
+}
+
+// HandleFeedAtom republishes a feed's stored articles as an Atom document.
+// It is authenticated by the ?token= feed token rather than the session, so
+// it is exempt from AuthMiddleware.
+func (s *Server) HandleFeedAtom(w http.ResponseWriter, r *http.Request) {
+	feedID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid feed id", http.StatusBadRequest)
+		return
+	}
+	q := dbgen.New(s.DB)
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "token required", http.StatusUnauthorized)
+		return
+	}
+	userID, err := q.GetUserByFeedToken(r.Context(), token)
+	if err != nil {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	feed, err := q.GetFeed(r.Context(), dbgen.GetFeedParams{ID: feedID, UserID: userID})
+	if err != nil {
+		http.Error(w, "feed not found", http.StatusNotFound)
+		return
+	}
+
+	limit, _ := parsePagination(r)
+	articles, err := queryArticles(r.Context(), s.DB, userID, articleQueryOpts{
+		FeedID: &feedID, Limit: min(max(limit, 1), maxAtomEntries),
+	})
+	if err != nil {
+		http.Error(w, "failed to get articles", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]AtomItem, 0, len(articles))
+	for _, a := range articles {
+		published := a.CreatedAt
+		if a.PublishedAt != nil {
+			published = *a.PublishedAt
+		}
+		items = append(items, AtomItem{
+			ID: a.ID, GUID: a.Guid, URL: a.Url, Title: a.Title, Author: a.Author,
+			Content: a.Content, Summary: a.Summary, Published: published,
+		})
+	}
+	meta := AtomMeta{
+		Title: feed.Title, ID: feed.Url, Subtitle: feed.Description, SiteURL: feed.SiteUrl,
+	}
+	if feed.LastUpdated != nil {
+		meta.Updated = *feed.LastUpdated
+	}
+
+	atom, err := GenerateAtom(meta, items)
+	if err != nil {
+		http.Error(w, "failed to generate feed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	_, _ = w.Write(atom)
+}
+
+// HandleUnsubscribe removes a feed subscription
+func (s *Server) HandleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	feedID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid feed id", http.StatusBadRequest)
+		return
+	}
+
+	q := dbgen.New(s.DB)
+	if err := q.DeleteFeed(r.Context(), dbgen.DeleteFeedParams{
+		ID:     feedID,
+		UserID: userID,
+	}); err != nil {
+		jsonError(w, "failed to delete feed", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// HandlePurgeFeedArticles deletes all of a feed's articles, with their
+// read/starred state, but keeps the subscription. ?keep_starred=1 spares
+// starred articles. The feed's caching headers are cleared so the next
+// refresh repopulates it with a full fetch.
+func (s *Server) HandlePurgeFeedArticles(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	feedID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid feed id", http.StatusBadRequest)
+		return
+	}
+	if _, err := dbgen.New(s.DB).GetFeed(r.Context(), dbgen.GetFeedParams{ID: feedID, UserID: userID}); err != nil {
+		jsonError(w, "feed not found", http.StatusNotFound)
+		return
+	}
+
+	victims := "SELECT id FROM articles WHERE feed_id = ?"
+	args := []any{feedID}
+	if r.URL.Query().Get("keep_starred") == "1" {
+		victims += " AND id NOT IN (SELECT article_id FROM article_states WHERE user_id = ? AND is_starred = 1)"
+		args = append(args, userID)
+	}
+
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		logFrom(r.Context()).Error("purge feed articles: begin tx", "error", err)
+		jsonError(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Dependent rows are deleted explicitly rather than relying on
+	// ON DELETE CASCADE, since foreign_keys is a per-connection pragma.
+	var deleted int64
+	for _, table := range []string{"read_undo", "article_states", "articles"} {
+		col := "article_id"
+		if table == "articles" {
+			col = "id"
+		}
+		res, err := tx.ExecContext(r.Context(), "DELETE FROM "+table+" WHERE "+col+" IN ("+victims+")", args...)
+		if err != nil {
+			logFrom(r.Context()).Error("purge feed articles", "table", table, "error", err)
+			jsonError(w, "database error", http.StatusInternalServerError)
+			return
+		}
+		deleted, _ = res.RowsAffected()
+	}
+	if _, err := tx.ExecContext(r.Context(), "UPDATE feeds SET etag = '', last_modified = '' WHERE id = ?", feedID); err != nil {
+		logFrom(r.Context()).Error("purge feed articles: clear caching headers", "error", err)
+		jsonError(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logFrom(r.Context()).Error("purge feed articles: commit", "error", err)
+		jsonError(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	logFrom(r.Context()).Info("purged feed articles", "feed_id", feedID, "deleted", deleted)
+	jsonResponse(w, map[string]int64{"deleted": deleted})
+}
+
+// queryArticlesByCategory runs a category-filtered article query.
+// categoryID=0 means uncategorized (NULL category_id).
+// unreadOnly=true filters to unread articles only.
+// queryArticles builds and executes a flexible article query with optional filters and sort direction.
+// buildArticleFilters constructs WHERE clause filters and args from query options.
+func buildArticleFilters(opts articleQueryOpts) (filters []string, filterArgs []any) {
+	if opts.CategoryID != nil {
+		cid := *opts.CategoryID
+		if cid == 0 {
+			filters = append(filters, "f.category_id IS NULL")
+		} else {
+			filters = append(filters, "f.category_id = ?")
+			filterArgs = append(filterArgs, cid)
+		}
+	}
+	if opts.FeedID != nil {
+		filters = append(filters, "f.id = ?")
+		filterArgs = append(filterArgs, *opts.FeedID)
+	}
+	if opts.UnreadOnly {
+		filters = append(filters, "(s.is_read IS NULL OR s.is_read = 0)")
+	}
+	if opts.StarredOnly {
+		filters = append(filters, "s.is_starred = 1")
+	}
+	if opts.ReadOnly {
+		filters = append(filters, "s.is_read = 1")
+	}
+	if opts.ReadSince != nil {
+		filters = append(filters, "s.read_at >= ?")
+		filterArgs = append(filterArgs, *opts.ReadSince)
+	}
+	if !opts.IncludeHidden {
+		filters = append(filters, "(s.is_hidden IS NULL OR s.is_hidden = 0)")
+	}
+	return
+}
+
+// buildCursorFilters constructs the WHERE clause filters and args for cursor
+// pagination on orderCol.
+func buildCursorFilters(opts articleQueryOpts, userID, orderCol string) (filters []string, filterArgs []any) {
+	// Articles whose sort key is NULL (e.g. starred
+	// before starred_at was recorded) sort last newest-first and first
+	// oldest-first; their cursor is the id alone.
+	switch {
+	case opts.BeforeID != nil && opts.BeforeTime != nil:
+		key, keyArgs := cursorKey(opts, userID, *opts.BeforeTime, *opts.BeforeID)
+		filters = append(filters, "("+orderCol+" < "+key+" OR ("+orderCol+" = "+key+" AND a.id < ?) OR "+orderCol+" IS NULL)")
+		filterArgs = append(filterArgs, keyArgs...)
+		filterArgs = append(filterArgs, keyArgs...)
+		filterArgs = append(filterArgs, *opts.BeforeID)
+	case opts.BeforeID != nil:
+		filters = append(filters, "("+orderCol+" IS NULL AND a.id < ?)")
+		filterArgs = append(filterArgs, *opts.BeforeID)
+	case opts.AfterID != nil && opts.AfterTime != nil:
+		key, keyArgs := cursorKey(opts, userID, *opts.AfterTime, *opts.AfterID)
+		filters = append(filters, "("+orderCol+" > "+key+" OR ("+orderCol+" = "+key+" AND a.id > ?))")
+		filterArgs = append(filterArgs, keyArgs...)
+		filterArgs = append(filterArgs, keyArgs...)
+		filterArgs = append(filterArgs, *opts.AfterID)
+	case opts.AfterID != nil:
+		filters = append(filters, "("+orderCol+" IS NOT NULL OR a.id > ?)")
+		filterArgs = append(filterArgs, *opts.AfterID)
+	}
+	return
+}
+
+// articleSortKey is the column expression articles are ordered by.
+const articleSortKey = "COALESCE(a.published_at, a.created_at)"
+
+// articleSortColumns returns the columns queryArticles orders opts' view by;
+// the first non-NULL one is an article's sort key.
+func articleSortColumns(opts articleQueryOpts) []string {
+	switch {
+	case opts.StarredOnly:
+		return []string{"s.starred_at"}
+	case opts.ReadOnly:
+		return []string{"s.read_at"}
+	}
+	// Undated articles fall back to ingestion time so they keep a stable
+	// position across requests instead of floating around as NULLs.
+	return []string{"a.published_at", "a.created_at"}
+}
+
+// articleSortColumn returns articleSortColumns as one SQL expression.
+func articleSortColumn(opts articleQueryOpts) string {
+	cols := articleSortColumns(opts)
+	if len(cols) == 1 {
+		return cols[0]
+	}
+	return "COALESCE(" + strings.Join(cols, ", ") + ")"
+}
+
+// cursorKey returns the SQL expression the sort column is compared against
+// for cursor pagination. Stored timestamps don't share one text format
+// (driver-formatted published_at vs CURRENT_TIMESTAMP created_at, or a
+// timestamp's zone), so the cursor article's own stored key is used when it
+// still exists, falling back to the client-supplied timestamp.
+func cursorKey(opts articleQueryOpts, userID string, t time.Time, id int64) (string, []any) {
+	return "COALESCE((SELECT " + articleSortColumn(opts) + sortKeyLookup + "), ?)", []any{userID, id, t}
+}
+
+// sortKeyLookup completes a SELECT of sort columns for one article (by id)
+// as a user (the join's user_id) sees it.
+const sortKeyLookup = `
+FROM articles a
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = ?
+WHERE a.id = ?`
+
+// buildPagination returns the pagination SQL clause and args.
+func buildPagination(opts articleQueryOpts) (string, []any) {
+	if opts.BeforeTime != nil || opts.AfterTime != nil || opts.BeforeID != nil || opts.AfterID != nil {
+		return "LIMIT ?", []any{opts.Limit}
+	}
+	return "LIMIT ? OFFSET ?", []any{opts.Limit, opts.Offset}
+}
+
+// articleRow is an article as listed by queryArticles.
+type articleRow struct {
+	dbgen.GetArticlesRow
+	// Same-URL articles folded into this one (articleQueryOpts.DedupByURL)
+	DuplicateCount int `json:"duplicate_count,omitempty"`
+}
+
+func queryArticles(ctx context.Context, db *sql.DB, userID string, opts articleQueryOpts) ([]articleRow, error) {
+	joinType := "LEFT JOIN"
+	if opts.StarredOnly || opts.ReadOnly {
+		joinType = "JOIN"
+	}
+
+	orderCol := articleSortColumn(opts)
+	orderDir := "DESC"
+	if opts.SortOldest {
+		orderDir = "ASC"
+	}
+	orderBy := orderCol + " " + orderDir + ", a.id " + orderDir
+	if opts.UnreadFirst {
+		// The cursor only tracks the date, so this sort pages by offset
+		opts.BeforeTime, opts.BeforeID, opts.AfterTime, opts.AfterID = nil, nil, nil, nil
+		orderBy = "COALESCE(s.is_read, 0) ASC, " + orderBy
+	}
+
+	filters, filterArgs := buildArticleFilters(opts)
+	cursorFilters, cursorArgs := buildCursorFilters(opts, userID, orderCol)
+
+	pagination, paginationArgs := buildPagination(opts)
+
+	contentCols := "a.content, a.content_compressed, a.summary"
+	if opts.SkipContent {
+		contentCols = "'', 0, ''"
+	}
+
+	joins := `
+JOIN feeds f ON a.feed_id = f.id
+` + joinType + ` article_states s ON s.article_id = a.id AND s.user_id = ?
+WHERE f.user_id = ?`
+	var args []any
+	with, grouped, duplicateCount := "", "", "0"
+	if opts.DedupByURL != "" {
+		// Articles sharing a normalized URL (those without one stand alone)
+		// are ranked in the view before any cursor or LIMIT applies, so a
+		// group spanning pages still lists once and has_more stays right.
+		urlKey := "COALESCE(NULLIF(normalize_url(a.url), ''), a.id)"
+		keepDir := "ASC"
+		if opts.DedupByURL == dedupKeepNewest {
+			keepDir = "DESC"
+		}
+		with = `
+WITH grouped AS (
+SELECT a.id,
+  ROW_NUMBER() OVER (PARTITION BY ` + urlKey + ` ORDER BY ` + articleSortKey + ` ` + keepDir + `, a.id ` + keepDir + `) AS dup_rank,
+  COUNT(*) OVER (PARTITION BY ` + urlKey + `) - 1 AS duplicate_count
+FROM articles a` + joins + andFilters(filters) + `
+)`
+		grouped = "\nJOIN grouped g ON g.id = a.id AND g.dup_rank = 1"
+		duplicateCount = "g.duplicate_count"
+		args = append(args, userID, userID)
+		args = append(args, filterArgs...)
+		filters, filterArgs = nil, nil
+	}
+
+	query := with + `
+SELECT a.id, a.feed_id, a.guid, a.url, a.title, a.author, ` + contentCols + `, a.published_at, a.created_at,
+  f.title as feed_title, f.site_url as feed_site_url,
+  COALESCE(s.is_read, 0) as is_read,
+  COALESCE(s.is_starred, 0) as is_starred,
+  s.read_at, ` + duplicateCount + `
+FROM articles a` + grouped + joins + andFilters(append(filters, cursorFilters...)) + `
+ORDER BY ` + orderBy + `
+` + pagination
+
+	args = append(args, userID, userID)
+	args = append(args, filterArgs...)
+	args = append(args, cursorArgs...)
+	args = append(args, paginationArgs...)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var articles []articleRow
+	for rows.Next() {
+		var a articleRow
+		if err := rows.Scan(
+			&a.ID, &a.FeedID, &a.Guid, &a.Url, &a.Title, &a.Author,
+			&a.Content, &a.ContentCompressed, &a.Summary, &a.PublishedAt, &a.CreatedAt,
+			&a.FeedTitle, &a.FeedSiteUrl, &a.IsRead, &a.IsStarred, &a.ReadAt, &a.DuplicateCount,
+		); err != nil {
+			return nil, err
+		}
+		a.Content, a.ContentCompressed = decompressContent(a.Content, a.ContentCompressed), 0
+		articles = append(articles, a)
+	}
+	if articles == nil {
+		articles = []articleRow{}
+	}
+	return articles, rows.Err()
+}
+
+// andFilters joins filters into a WHERE clause continuation.
+func andFilters(filters []string) string {
+	if len(filters) == 0 {
+		return ""
+	}
+	return " AND " + strings.Join(filters, " AND ")
+}
+
+type articleQueryOpts struct {
+	CategoryID    *int64
+	FeedID        *int64
+	UnreadOnly    bool
+	StarredOnly   bool
+	ReadOnly      bool
+	SortOldest    bool
+	UnreadFirst   bool       // unread before read, each newest first; no cursor paging
+	SkipContent   bool       // leave content and summary empty (list views strip them)
+	IncludeHidden bool       // also return articles the user hid
+	ReadSince     *time.Time // with ReadOnly: only articles read at or after this time
+	Limit         int64
+	Offset        int64
+	BeforeTime    *time.Time // cursor: articles before this timestamp
+	BeforeID      *int64     // cursor: tie-breaker for same timestamp
+	AfterTime     *time.Time // cursor: articles after this timestamp (for oldest-first)
+	AfterID       *int64     // cursor: tie-breaker for same timestamp
+	DedupByURL    string     // list one article per normalized URL: dedupKeepOldest or dedupKeepNewest ("" = off)
+}
+
+// articleSummary is an article without content/summary, as returned by list
+// endpoints.
+type articleSummary struct {
+	ID          int64      `json:"id"`
+	FeedID      int64      `json:"feed_id"`
+	Url         string     `json:"url"`
+	Title       string     `json:"title"`
+	Author      string     `json:"author"`
+	PublishedAt *time.Time `json:"published_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FeedTitle   string     `json:"feed_title"`
+	FeedSiteUrl string     `json:"feed_site_url"`
+	IsRead      int64      `json:"is_read"`
+	IsStarred   int64      `json:"is_starred"`
+	ReadAt      *time.Time `json:"read_at"`
+	// Same-URL articles collapsed into this one (GORSS_DEDUP_BY_URL)
+	DuplicateCount int `json:"duplicate_count,omitempty"`
+}
+
+// HandleGetArticles returns articles with optional filters
+// parsePagination extracts limit and offset from query params.
+func parsePagination(r *http.Request) (limit, offset int64) {
+	limit = 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.ParseInt(l, 10, 64); err == nil {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.ParseInt(o, 10, 64); err == nil {
+			offset = parsed
+		}
+	}
+	return
+}
+
+// parseCursorParams extracts cursor-based pagination params from the request.
+func parseCursorParams(q url.Values, opts *articleQueryOpts) {
+	if before := q.Get("before"); before != "" {
+		if t, err := time.Parse(time.RFC3339Nano, before); err == nil {
+			opts.BeforeTime = &t
+		}
+	}
+	if beforeID := q.Get("before_id"); beforeID != "" {
+		if id, err := strconv.ParseInt(beforeID, 10, 64); err == nil {
+			opts.BeforeID = &id
+		}
+	}
+	if after := q.Get("after"); after != "" {
+		if t, err := time.Parse(time.RFC3339Nano, after); err == nil {
+			opts.AfterTime = &t
+		}
+	}
+	if afterID := q.Get("after_id"); afterID != "" {
+		if id, err := strconv.ParseInt(afterID, 10, 64); err == nil {
+			opts.AfterID = &id
+		}
+	}
+}
+
+// applyViewFilters sets the query filter flags based on view/feed/category.
+func applyViewFilters(opts *articleQueryOpts, view, feedID, categoryID string) {
+	switch {
+	case view == "starred":
+		opts.StarredOnly = true
+	case view == "read":
+		opts.ReadOnly = true
+	case categoryID != "" && (view == "unread" || view == "fresh"):
+		cid, _ := strconv.ParseInt(categoryID, 10, 64)
+		opts.CategoryID = &cid
+		opts.UnreadOnly = true
+	case view == "unread" || view == "fresh":
+		opts.UnreadOnly = true
+	case categoryID != "":
+		cid, _ := strconv.ParseInt(categoryID, 10, 64)
+		opts.CategoryID = &cid
+	case feedID != "":
+		fid, _ := strconv.ParseInt(feedID, 10, 64)
+		opts.FeedID = &fid
+	}
+}
+
+// articleListOpts builds the query options for an article list from the
+// request's sort, cursor and view/feed/category params.
+func articleListOpts(r *http.Request, view, feedID, categoryID string, limit, offset int64) articleQueryOpts {
+	sort := r.URL.Query().Get("sort")
+	opts := articleQueryOpts{
+		SortOldest:    sort == "oldest",
+		UnreadFirst:   sort == "unread_first",
+		SkipContent:   true,
+		IncludeHidden: r.URL.Query().Get("include_hidden") == "1",
+		Limit:         limit,
+		Offset:        offset,
+	}
+	parseCursorParams(r.URL.Query(), &opts)
+	applyViewFilters(&opts, view, feedID, categoryID)
+	return opts
+}
+
+// articlePage is the ?format=page response of HandleGetArticles. The
+// cursor is set only when there is a next page: next_before/next_before_id,
+// or next_after/next_after_id with sort=oldest, to be passed back as the
+// before/after params. The timestamp is omitted when the last article has
+// no sort key, and the id alone is passed back. sort=unread_first pages by
+// offset and has no cursor.
+type articlePage struct {
+	Articles     []articleSummary `json:"articles"`
+	NextBefore   *time.Time       `json:"next_before,omitempty"`
+	NextBeforeID *int64           `json:"next_before_id,omitempty"`
+	NextAfter    *time.Time       `json:"next_after,omitempty"`
+	NextAfterID  *int64           `json:"next_after_id,omitempty"`
+	HasMore      bool             `json:"has_more"`
+}
+
+// HandleGetArticles returns articles with optional filters, as a bare array
+// or, with ?format=page, wrapped in an articlePage.
+func (s *Server) HandleGetArticles(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	limit, offset := parsePagination(r)
+
+	query := r.URL.Query()
+	paged := query.Get("format") == "page"
+	// One extra row tells whether there is a next page
+	probe := paged && limit > 0
+	if probe {
+		limit++
+	}
+	opts := articleListOpts(r, query.Get("view"), query.Get("feed_id"), query.Get("category_id"), limit, offset)
+	opts.DedupByURL = s.DedupByURL
+	articles, err := queryArticles(r.Context(), s.DB, userID, opts)
+	if err != nil {
+		logFrom(r.Context()).Error("get articles", "error", err)
+		jsonError(w, "failed to get articles", http.StatusInternalServerError)
+		return
+	}
+	hasMore := probe && int64(len(articles)) == limit
+	if hasMore {
+		articles = articles[:limit-1]
+	}
+
+	// Strip content/summary from list response to reduce payload size.
+	// Clients fetch full content via GET /api/articles/{id} on demand.
+	result := make([]articleSummary, 0, len(articles))
+	for _, a := range articles {
+		result = append(result, articleSummary{
+			ID: a.ID, FeedID: a.FeedID, Url: a.Url, Title: a.Title,
+			Author: a.Author, PublishedAt: a.PublishedAt, CreatedAt: a.CreatedAt,
+			FeedTitle: a.FeedTitle, FeedSiteUrl: a.FeedSiteUrl,
+			IsRead: a.IsRead, IsStarred: a.IsStarred, ReadAt: a.ReadAt,
+			DuplicateCount: a.DuplicateCount,
+		})
+	}
+	if !paged {
+		jsonResponse(w, result)
+		return
+	}
+	page := articlePage{Articles: result, HasMore: hasMore}
+	if hasMore {
+		s.setNextCursor(r.Context(), userID, opts, articles[len(articles)-1], &page)
+	}
+	jsonResponse(w, page)
+}
+
+// setNextCursor sets page's cursor from the last article returned, keyed by
+// the columns queryArticles orders opts' view by.
+func (s *Server) setNextCursor(ctx context.Context, userID string, opts articleQueryOpts, last articleRow, page *articlePage) {
+	if opts.UnreadFirst {
+		return
+	}
+	cols := articleSortColumns(opts)
+	keys := make([]*time.Time, len(cols))
+	dest := make([]any, len(cols))
+	for i := range keys {
+		dest[i] = &keys[i]
+	}
+	err := s.DB.QueryRowContext(ctx, "SELECT "+strings.Join(cols, ", ")+sortKeyLookup, userID, last.ID).Scan(dest...)
+	if err != nil {
+		logFrom(ctx).Warn("article cursor", "error", err, "article_id", last.ID)
+	}
+	var key *time.Time
+	for _, k := range keys {
+		if k != nil {
+			key = k
+			break
+		}
+	}
+	id := last.ID
+	if opts.SortOldest {
+		page.NextAfter, page.NextAfterID = key, &id
+	} else {
+		page.NextBefore, page.NextBeforeID = key, &id
+	}
+}
+
+// HandleGetArticle returns a single article with full content. With
+// ?read=1 (or ?mark_read=true) the article is marked read in the same
+// transaction, so the client needs no separate call that could arrive out
+// of order; an already-read article keeps its original read_at. The plain
+// GET, e.g. for hover previews, leaves read state alone.
+func (s *Server) HandleGetArticle(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid article id", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	markRead, _ := strconv.ParseBool(query.Get("read"))
+	if v, _ := strconv.ParseBool(query.Get("mark_read")); v {
+		markRead = true
+	}
+	markRead = markRead && !s.ReadOnly
+
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		jsonError(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+	q := dbgen.New(s.DB).WithTx(tx)
+
+	a, err := q.GetArticle(r.Context(), dbgen.GetArticleParams{
+		UserID:   userID,
+		ID:       articleID,
+		UserID_2: userID,
+	})
+	if err != nil {
+		jsonError(w, "article not found", http.StatusNotFound)
+		return
+	}
+	if markRead && a.IsRead == 0 {
+		now := time.Now()
+		err := q.MarkArticleReadOnce(r.Context(), dbgen.MarkArticleReadOnceParams{
+			UserID:    userID,
+			ArticleID: articleID,
+			ReadAt:    &now,
+		})
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			logFrom(r.Context()).Error("mark read", "article_id", articleID, "error", err)
+			jsonError(w, "failed to mark read", http.StatusInternalServerError)
+			return
+		}
+		s.propagateRead(r.Context(), userID, []int64{articleID}, now)
+		s.countsCache.invalidate(userID)
+		a.IsRead = 1
+	}
+	a.Content, a.ContentCompressed = decompressContent(a.Content, a.ContentCompressed), 0
+	jsonResponse(w, a)
+}
+
+// HandleOldestUnread returns the oldest unread article, with full content,
+// optionally filtered by feed_id or category_id (0 = uncategorized).
+func (s *Server) HandleOldestUnread(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	opts := articleQueryOpts{UnreadOnly: true, SortOldest: true, Limit: 1}
+
+	query := r.URL.Query()
+	if v := query.Get("feed_id"); v != "" {
+		fid, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			jsonError(w, "invalid feed_id", http.StatusBadRequest)
+			return
+		}
+		opts.FeedID = &fid
+	}
+	if v := query.Get("category_id"); v != "" {
+		cid, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			jsonError(w, "invalid category_id", http.StatusBadRequest)
+			return
+		}
+		opts.CategoryID = &cid
+	}
+
+	articles, err := queryArticles(r.Context(), s.DB, userID, opts)
+	if err != nil {
+		logFrom(r.Context()).Error("oldest unread", "error", err)
+		jsonError(w, "failed to get articles", http.StatusInternalServerError)
+		return
+	}
+	if len(articles) == 0 {
+		jsonError(w, "no unread articles", http.StatusNotFound)
+		return
+	}
+	jsonResponse(w, articles[0])
+}
+
+// Article caps for the recent-articles endpoint.
+const (
+	defaultRecentArticles = 10
+	maxRecentArticles     = 100
+)
+
+// HandleRecentArticles returns the user's newest articles across all feeds,
+// read or not, for dashboard widgets. limit defaults to 10 (max 100);
+// include_content=1 adds content and summary to each article.
+func (s *Server) HandleRecentArticles(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	query := r.URL.Query()
+
+	limit := int64(defaultRecentArticles)
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			jsonError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxRecentArticles)
+	}
+	includeContent := query.Get("include_content") == "1"
+
+	articles, err := queryArticles(r.Context(), s.DB, userID, articleQueryOpts{
+		SkipContent: !includeContent,
+		Limit:       limit,
+	})
+	if err != nil {
+		logFrom(r.Context()).Error("recent articles", "error", err)
+		jsonError(w, "failed to get articles", http.StatusInternalServerError)
+		return
+	}
+	if includeContent {
+		jsonResponse(w, articles)
+		return
+	}
+	result := make([]articleSummary, 0, len(articles))
+	for _, a := range articles {
+		result = append(result, articleSummary{
+			ID: a.ID, FeedID: a.FeedID, Url: a.Url, Title: a.Title,
+			Author: a.Author, PublishedAt: a.PublishedAt, CreatedAt: a.CreatedAt,
+			FeedTitle: a.FeedTitle, FeedSiteUrl: a.FeedSiteUrl,
+			IsRead: a.IsRead, IsStarred: a.IsStarred, ReadAt: a.ReadAt,
+		})
+	}
+	jsonResponse(w, result)
+}
+
+// Article caps for the read history endpoint.
+const (
+	defaultHistoryArticles = 50
+	maxHistoryArticles     = 200
+)
+
+// HandleReadHistory returns the articles the user has read, most recently
+// read first, with their read_at times. since limits it to reads at or
+// after a time; before and before_id (the last article's read_at and id)
+// fetch the next page. limit defaults to 50 (max 200).
+func (s *Server) HandleReadHistory(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	query := r.URL.Query()
+
+	opts := articleQueryOpts{
+		ReadOnly:      true,
+		IncludeHidden: true,
+		SkipContent:   true,
+		Limit:         defaultHistoryArticles,
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			jsonError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		opts.Limit = min(n, maxHistoryArticles)
+	}
+	if v := query.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			jsonError(w, "invalid since (want RFC 3339)", http.StatusBadRequest)
+			return
+		}
+		opts.ReadSince = &since
+	}
+	// History is newest first, so only the before cursor applies
+	parseCursorParams(query, &opts)
+	opts.AfterTime, opts.AfterID = nil, nil
+
+	articles, err := queryArticles(r.Context(), s.DB, userID, opts)
+	if err != nil {
+		logFrom(r.Context()).Error("read history", "error", err)
+		jsonError(w, "failed to get articles", http.StatusInternalServerError)
+		return
+	}
+	result := make([]articleSummary, 0, len(articles))
+	for _, a := range articles {
+		result = append(result, articleSummary{
+			ID: a.ID, FeedID: a.FeedID, Url: a.Url, Title: a.Title,
+			Author: a.Author, PublishedAt: a.PublishedAt, CreatedAt: a.CreatedAt,
+			FeedTitle: a.FeedTitle, FeedSiteUrl: a.FeedSiteUrl,
+			IsRead: a.IsRead, IsStarred: a.IsStarred, ReadAt: a.ReadAt,
+		})
+	}
+	jsonResponse(w, result)
+}
+
+// Per-feed article caps for the digest view.
+const (
+	defaultDigestPerFeed = 5
+	maxDigestPerFeed     = 50
+)
+
+// digestGroup is one feed's section of the digest.
+type digestGroup struct {
+	Feed struct {
+		ID         int64  `json:"id"`
+		Title      string `json:"title"`
+		SiteUrl    string `json:"site_url"`
+		CategoryID *int64 `json:"category_id"`
+	} `json:"feed"`
+	UnreadCount int64            `json:"unread_count"`
+	Articles    []articleSummary `json:"articles"`
+}
+
+// HandleDigest returns articles grouped by feed, newest first and capped at
+// per_feed (default 5) per feed. view=unread (default) only includes unread
+// articles; view=all includes read ones too. Feeds without matching
+// articles are omitted.
+func (s *Server) HandleDigest(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	query := r.URL.Query()
+
+	var unreadOnly bool
+	switch query.Get("view") {
+	case "", "unread":
+		unreadOnly = true
+	case "all":
+	default:
+		jsonError(w, "invalid view", http.StatusBadRequest)
+		return
+	}
+	perFeed := int64(defaultDigestPerFeed)
+	if v := query.Get("per_feed"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			jsonError(w, "invalid per_feed", http.StatusBadRequest)
+			return
+		}
+		perFeed = min(n, maxDigestPerFeed)
+	}
+
+	feeds, err := dbgen.New(s.DB).GetFeeds(r.Context(), userID)
+	if err != nil {
+		jsonError(w, "failed to get feeds", http.StatusInternalServerError)
+		return
+	}
+	byFeed, err := s.digestArticles(r.Context(), userID, unreadOnly, perFeed)
+	if err != nil {
+		logFrom(r.Context()).Error("digest", "error", err)
+		jsonError(w, "failed to get articles", http.StatusInternalServerError)
+		return
+	}
+
+	groups := []digestGroup{}
+	for _, f := range feeds {
+		articles := byFeed[f.ID]
+		if len(articles) == 0 {
+			continue
+		}
+		var g digestGroup
+		g.Feed.ID, g.Feed.Title, g.Feed.SiteUrl, g.Feed.CategoryID = f.ID, f.Title, f.SiteUrl, f.CategoryID
+		g.UnreadCount = f.UnreadCount
+		for i := range articles {
+			articles[i].FeedTitle, articles[i].FeedSiteUrl = f.Title, f.SiteUrl
+		}
+		g.Articles = articles
+		groups = append(groups, g)
+	}
+	jsonResponse(w, groups)
+}
+
+// digestArticles returns up to perFeed of the newest articles of each of the
+// user's feeds, keyed by feed id, using a window over the feed partition so
+// the cap is applied in a single query.
+func (s *Server) digestArticles(ctx context.Context, userID string, unreadOnly bool, perFeed int64) (map[int64][]articleSummary, error) {
+	filter := " AND (s.is_hidden IS NULL OR s.is_hidden = 0)"
+	if unreadOnly {
+		filter += " AND (s.is_read IS NULL OR s.is_read = 0)"
+	}
+	query := `SELECT id, feed_id, url, title, author, published_at, created_at, is_read, is_starred
+FROM (
+  SELECT a.id, a.feed_id, a.url, a.title, a.author, a.published_at, a.created_at,
+    COALESCE(s.is_read, 0) AS is_read, COALESCE(s.is_starred, 0) AS is_starred,
+    ROW_NUMBER() OVER (PARTITION BY a.feed_id ORDER BY ` + articleSortKey + ` DESC, a.id DESC) AS rn
+  FROM articles a
+  JOIN feeds f ON a.feed_id = f.id
+  LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+  WHERE f.user_id = ?` + filter + `
+)
+WHERE rn <= ?
+ORDER BY feed_id, rn`
+	rows, err := s.DB.QueryContext(ctx, query, userID, perFeed)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	byFeed := make(map[int64][]articleSummary)
+	for rows.Next() {
+		var a articleSummary
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.Url, &a.Title, &a.Author,
+			&a.PublishedAt, &a.CreatedAt, &a.IsRead, &a.IsStarred); err != nil {
+			return nil, err
+		}
+		byFeed[a.FeedID] = append(byFeed[a.FeedID], a)
+	}
+	return byFeed, rows.Err()
+}
+
+// HandleMarkRead marks an article as read
+func (s *Server) HandleMarkRead(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid article id", http.StatusBadRequest)
+		return
+	}
+
+	q := dbgen.New(s.DB)
+	now := time.Now()
+	if err := q.SetArticleRead(r.Context(), dbgen.SetArticleReadParams{
+		UserID:    userID,
+		ArticleID: articleID,
+		ReadAt:    &now,
+	}); err != nil {
+		logFrom(r.Context()).Error("mark read", "article_id", articleID, "error", err)
+		jsonError(w, "failed to mark read", http.StatusInternalServerError)
+		return
+	}
+	s.propagateRead(r.Context(), userID, []int64{articleID}, now)
+	jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// trackingParams are query parameters stripped when comparing article URLs.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "mc_cid": true, "mc_eid": true, "ref": true,
+}
+
+// normalizeArticleURL reduces an article URL to a comparison key: scheme,
+// "www.", fragment, trailing slash and tracking parameters are ignored.
+func normalizeArticleURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	query := u.Query()
+	for k := range query {
+		if trackingParams[strings.ToLower(k)] || strings.HasPrefix(strings.ToLower(k), "utm_") {
+			query.Del(k)
+		}
+	}
+	key := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if enc := query.Encode(); enc != "" {
+		key += "?" + enc
+	}
+	return key
+}
+
+// GORSS_DEDUP_BY_URL modes: which of the articles sharing a URL is listed.
+const (
+	dedupKeepOldest = "oldest"
+	dedupKeepNewest = "newest"
+)
+
+// normalize_url(url) is normalizeArticleURL for SQL, so list queries can
+// group same-URL articles (GORSS_DEDUP_BY_URL) before paginating.
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("normalize_url", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		raw, _ := args[0].(string)
+		return normalizeArticleURL(raw), nil
+	})
+}
+
+// propagateRead marks read the user's other unread articles, added within
+// ReadPropagationWindow, whose normalized URL matches one of articleIDs, so
+// the same story appearing in several feeds is only read once. No-op when
+// the window is zero.
+func (s *Server) propagateRead(ctx context.Context, userID string, articleIDs []int64, readAt time.Time) {
+	if s.ReadPropagationWindow <= 0 || len(articleIDs) == 0 {
+		return
+	}
+	q := dbgen.New(s.DB)
+	marked, err := q.GetArticleURLs(ctx, dbgen.GetArticleURLsParams{UserID: userID, Ids: articleIDs})
+	if err != nil {
+		logFrom(ctx).Warn("read propagation: article urls", "error", err)
+		return
+	}
+	keys := make(map[string]bool)
+	for _, a := range marked {
+		if k := normalizeArticleURL(a.Url); k != "" {
+			keys[k] = true
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	candidates, err := q.GetRecentUnreadArticleURLs(ctx, dbgen.GetRecentUnreadArticleURLsParams{
+		UserID: userID, MaxAge: fmt.Sprintf("-%d seconds", int(s.ReadPropagationWindow.Seconds())),
+	})
+	if err != nil {
+		logFrom(ctx).Warn("read propagation: candidates", "error", err)
+		return
+	}
+	for _, c := range candidates {
+		if !keys[normalizeArticleURL(c.Url)] {
+			continue
+		}
+		if err := q.SetArticleRead(ctx, dbgen.SetArticleReadParams{
+			UserID: userID, ArticleID: c.ID, ReadAt: &readAt,
+		}); err != nil {
+			logFrom(ctx).Warn("read propagation: mark read", "article_id", c.ID, "error", err)
+		}
+	}
+}
+
+// HandleMarkUnread marks an article as unread
+func (s *Server) HandleMarkUnread(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid article id", http.StatusBadRequest)
+		return
+	}
+
+	q := dbgen.New(s.DB)
+	if err := q.SetArticleUnread(r.Context(), dbgen.SetArticleUnreadParams{
+		UserID:    userID,
+		ArticleID: articleID,
+	}); err != nil {
+		jsonError(w, "failed to mark unread", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// HandleStar stars an article
+func (s *Server) HandleStar(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid article id", http.StatusBadRequest)
+		return
+	}
+
+	q := dbgen.New(s.DB)
+	now := time.Now()
+	if err := q.SetArticleStarred(r.Context(), dbgen.SetArticleStarredParams{
+		UserID:    userID,
+		ArticleID: articleID,
+		StarredAt: &now,
+	}); err != nil {
+		jsonError(w, "failed to star", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// HandleUnstar unstars an article
+func (s *Server) HandleUnstar(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid article id", http.StatusBadRequest)
+		return
+	}
+
+	q := dbgen.New(s.DB)
+	if err := q.SetArticleUnstarred(r.Context(), dbgen.SetArticleUnstarredParams{
+		UserID:    userID,
+		ArticleID: articleID,
+	}); err != nil {
+		jsonError(w, "failed to unstar", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// HandleHide hides an article from every list and from unread counts,
+// without marking it read.
+func (s *Server) HandleHide(w http.ResponseWriter, r *http.Request) {
+	s.setArticleHidden(w, r, true)
+}
+
+// HandleUnhide undoes HandleHide.
+func (s *Server) HandleUnhide(w http.ResponseWriter, r *http.Request) {
+	s.setArticleHidden(w, r, false)
+}
+
+func (s *Server) setArticleHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	userID := s.userFromContext(r)
+	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid article id", http.StatusBadRequest)
+		return
+	}
+
+	q := dbgen.New(s.DB)
+	if err := q.SetArticleHidden(r.Context(), dbgen.SetArticleHiddenParams{
+		UserID:    userID,
+		ArticleID: articleID,
+		IsHidden:  boolToInt(hidden),
+	}); err != nil {
+		jsonError(w, "failed to update article", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// markCategoryRead marks all unread articles in a category as read and
+// returns the ids of the articles that were marked.
+func (s *Server) markCategoryRead(ctx context.Context, userID string, categoryID int64) ([]int64, error) {
+	now := time.Now()
+	var catFilter string
+	var args []any
+	if categoryID == 0 {
+		catFilter = "f.category_id IS NULL"
+		args = []any{userID, now, userID}
+	} else {
+		catFilter = "f.category_id = ?"
+		args = []any{userID, now, categoryID, userID}
+	}
+	query := `INSERT OR REPLACE INTO article_states (user_id, article_id, is_read, read_at, is_starred, starred_at, is_hidden)
+		SELECT ?, a.id, 1, ?,
+			COALESCE(s.is_starred, 0), s.starred_at, COALESCE(s.is_hidden, 0)
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+		WHERE ` + catFilter + ` AND f.user_id = ? AND (s.is_read IS NULL OR s.is_read = 0)
+		RETURNING article_id`
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// markFeedRead marks all unread articles in a feed as read and returns the ids marked.
+func (s *Server) markFeedRead(ctx context.Context, userID string, feedID int64) ([]int64, error) {
+	now := time.Now()
+	return dbgen.New(s.DB).MarkFeedRead(ctx, dbgen.MarkFeedReadParams{
+		UserID: userID, ReadAt: &now, FeedID: feedID, UserID_2: userID,
+	})
+}
+
+// markAllRead marks all of the user's unread articles as read and returns the ids marked.
+func (s *Server) markAllRead(ctx context.Context, userID string) ([]int64, error) {
+	now := time.Now()
+	return dbgen.New(s.DB).MarkAllRead(ctx, dbgen.MarkAllReadParams{
+		UserID: userID, ReadAt: &now, UserID_2: userID,
+	})
+}
+
+// undoTTL is how long a bulk mark-read can be reverted with its undo token.
+const undoTTL = 5 * time.Minute
+
+// undoMaxAge is undoTTL as an SQLite datetime() modifier.
+var undoMaxAge = fmt.Sprintf("-%d seconds", int(undoTTL.Seconds()))
+
+// recordReadUndo stores the articles a bulk mark-read flipped to read and
+// returns a token that reverts them via POST /api/undo/{token}. Returns ""
+// when nothing was marked or the log could not be written.
+func (s *Server) recordReadUndo(ctx context.Context, userID string, articleIDs []int64) string {
+	if len(articleIDs) == 0 {
+		return ""
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logFrom(ctx).Error("read undo: begin tx", "error", err)
+		return ""
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	q := dbgen.New(s.DB).WithTx(tx)
+	if err := q.PurgeExpiredReadUndo(ctx, undoMaxAge); err != nil {
+		logFrom(ctx).Warn("read undo: purge expired", "error", err)
+	}
+	token := generateSessionID()
+	for _, id := range articleIDs {
+		if err := q.InsertReadUndo(ctx, dbgen.InsertReadUndoParams{
+			Token: token, UserID: userID, ArticleID: id,
+		}); err != nil {
+			logFrom(ctx).Error("read undo: insert", "article_id", id, "error", err)
+			return ""
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		logFrom(ctx).Error("read undo: commit", "error", err)
+		return ""
+	}
+	return token
+}
+
+// markedResponse is the response body for bulk mark-read operations.
+func markedResponse(w http.ResponseWriter, marked int, undoToken string) {
+	jsonResponse(w, map[string]any{"status": "ok", "marked": marked, "undo_token": undoToken})
+}
+
+// HandleMarkReadBatch marks multiple articles as read in a single transaction
+func (s *Server) HandleMarkReadBatch(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+
+	var body struct {
+		IDs []int64 `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.IDs) == 0 {
+		jsonError(w, "invalid request: ids required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		logFrom(r.Context()).Error("batch mark-read: begin tx", "error", err)
+		jsonError(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	stmt, err := tx.PrepareContext(r.Context(), `INSERT INTO article_states (user_id, article_id, is_read, read_at)
+		VALUES (?, ?, 1, ?)
+		ON CONFLICT (user_id, article_id) DO UPDATE SET is_read = 1, read_at = excluded.read_at`)
+	if err != nil {
+		logFrom(r.Context()).Error("batch mark-read: prepare", "error", err)
+		jsonError(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, id := range body.IDs {
+		if _, err := stmt.ExecContext(r.Context(), userID, id, &now); err != nil {
+			logFrom(r.Context()).Error("batch mark-read: exec", "article_id", id, "error", err)
 		}
 	}
-	return
+
+	if err := tx.Commit(); err != nil {
+		logFrom(r.Context()).Error("batch mark-read: commit", "error", err)
+		jsonError(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	s.propagateRead(r.Context(), userID, body.IDs, now)
+	jsonResponse(w, map[string]string{"status": "ok"})
 }
 
-// parseCursorParams extracts cursor-based pagination params from the request.
-func parseCursorParams(q url.Values, opts *articleQueryOpts) {
-	if before := q.Get("before"); before != "" {
-		if t, err := time.Parse(time.RFC3339Nano, before); err == nil {
-			opts.BeforeTime = &t
+// HandleMarkAllRead marks all articles as read (optionally filtered by feed or category)
+func (s *Server) HandleMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+
+	var ids []int64
+	switch {
+	case r.URL.Query().Get("feed_id") != "":
+		feedID, err := strconv.ParseInt(r.URL.Query().Get("feed_id"), 10, 64)
+		if err != nil {
+			jsonError(w, "invalid feed_id", http.StatusBadRequest)
+			return
 		}
-	}
-	if beforeID := q.Get("before_id"); beforeID != "" {
-		if id, err := strconv.ParseInt(beforeID, 10, 64); err == nil {
-			opts.BeforeID = &id
+		if ids, err = s.markFeedRead(r.Context(), userID, feedID); err != nil {
+			jsonError(w, "failed to mark feed read", http.StatusInternalServerError)
+			return
 		}
-	}
-	if after := q.Get("after"); after != "" {
-		if t, err := time.Parse(time.RFC3339Nano, after); err == nil {
-			opts.AfterTime = &t
+	case r.URL.Query().Get("category_id") != "":
+		catID, err := strconv.ParseInt(r.URL.Query().Get("category_id"), 10, 64)
+		if err != nil {
+			jsonError(w, "invalid category_id", http.StatusBadRequest)
+			return
 		}
-	}
-	if afterID := q.Get("after_id"); afterID != "" {
-		if id, err := strconv.ParseInt(afterID, 10, 64); err == nil {
-			opts.AfterID = &id
+		if ids, err = s.markCategoryRead(r.Context(), userID, catID); err != nil {
+			jsonError(w, "failed to mark category read", http.StatusInternalServerError)
+			return
+		}
+	default:
+		var err error
+		if ids, err = s.markAllRead(r.Context(), userID); err != nil {
+			jsonError(w, "failed to mark all read", http.StatusInternalServerError)
+			return
 		}
 	}
+	markedResponse(w, len(ids), s.recordReadUndo(r.Context(), userID, ids))
 }
 
-// applyViewFilters sets the query filter flags based on view/feed/category.
-func applyViewFilters(opts *articleQueryOpts, view, feedID, categoryID string) {
-	switch {
-	case view == "starred":
-		opts.StarredOnly = true
-	case categoryID != "" && (view == "unread" || view == "fresh"):
-		cid, _ := strconv.ParseInt(categoryID, 10, 64)
-		opts.CategoryID = &cid
-		opts.UnreadOnly = true
-	case view == "unread" || view == "fresh":
-		opts.UnreadOnly = true
-	case categoryID != "":
-		cid, _ := strconv.ParseInt(categoryID, 10, 64)
-		opts.CategoryID = &cid
-	case feedID != "":
-		fid, _ := strconv.ParseInt(feedID, 10, 64)
-		opts.FeedID = &fid
+// HandleMarkAllUnread marks every read article in a feed or category
+// (category_id=0 = uncategorized) unread again, for re-reading it. Starred
+// state is left alone.
+func (s *Server) HandleMarkAllUnread(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+
+	scope, scopeArgs, err := catchUpScope(r)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if scope == "" {
+		jsonError(w, "feed_id or category_id required", http.StatusBadRequest)
+		return
 	}
-}
 
-// fetchArticles dispatches the correct query based on view/feed/category filters.
-func (s *Server) fetchArticles(r *http.Request, userID, view, feedID, categoryID string, limit, offset int64) ([]dbgen.GetArticlesRow, error) {
-	opts := articleQueryOpts{
-		SortOldest: r.URL.Query().Get("sort") == "oldest",
-		Limit:      limit,
-		Offset:     offset,
+	args := append([]any{userID, userID}, scopeArgs...)
+	res, err := s.DB.ExecContext(r.Context(), `UPDATE article_states SET is_read = 0, read_at = NULL
+		WHERE user_id = ? AND is_read = 1 AND article_id IN (
+			SELECT a.id FROM articles a
+			JOIN feeds f ON a.feed_id = f.id
+			WHERE f.user_id = ?`+scope+`)`, args...)
+	if err != nil {
+		logFrom(r.Context()).Error("mark all unread", "error", err)
+		jsonError(w, "failed to mark unread", http.StatusInternalServerError)
+		return
 	}
-	parseCursorParams(r.URL.Query(), &opts)
-	applyViewFilters(&opts, view, feedID, categoryID)
-	return queryArticles(r.Context(), s.DB, userID, opts)
+	n, _ := res.RowsAffected()
+	jsonResponse(w, map[string]any{"status": "ok", "marked": n})
 }
 
-// HandleGetArticles returns articles with optional filters
-func (s *Server) HandleGetArticles(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
-	limit, offset := parsePagination(r)
+// defaultCatchUpKeep is how many of the newest unread articles catch-up
+// leaves unread when keep is not given.
+const defaultCatchUpKeep = 20
+
+// HandleCatchUp marks all of the user's unread articles read except the
+// newest `keep`, optionally scoped by feed_id or category_id (0 =
+// uncategorized).
+func (s *Server) HandleCatchUp(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+
+	keep := int64(defaultCatchUpKeep)
+	if v := r.URL.Query().Get("keep"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			jsonError(w, "invalid keep", http.StatusBadRequest)
+			return
+		}
+		keep = n
+	}
+	scope, scopeArgs, err := catchUpScope(r)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	query := r.URL.Query()
-	articles, err := s.fetchArticles(r, userID, query.Get("view"), query.Get("feed_id"), query.Get("category_id"), limit, offset)
+	ids, err := s.catchUp(r.Context(), userID, keep, scope, scopeArgs)
 	if err != nil {
-		slog.Error("get articles", "error", err)
-		jsonError(w, "failed to get articles", http.StatusInternalServerError)
+		logFrom(r.Context()).Error("catch up", "error", err)
+		jsonError(w, "failed to catch up", http.StatusInternalServerError)
 		return
 	}
+	markedResponse(w, len(ids), s.recordReadUndo(r.Context(), userID, ids))
+}
 
-	// Strip content/summary from list response to reduce payload size.
-	// Clients fetch full content via GET /api/articles/{id} on demand.
-	type articleSummary struct {
-		ID          int64      `json:"id"`
-		FeedID      int64      `json:"feed_id"`
-		Url         string     `json:"url"`
-		Title       string     `json:"title"`
-		Author      string     `json:"author"`
-		PublishedAt *time.Time `json:"published_at"`
-		FeedTitle   string     `json:"feed_title"`
-		FeedSiteUrl string     `json:"feed_site_url"`
-		IsRead      int64      `json:"is_read"`
-		IsStarred   int64      `json:"is_starred"`
+// catchUpScope returns the article filter for the optional feed_id or
+// category_id query parameter.
+func catchUpScope(r *http.Request) (string, []any, error) {
+	query := r.URL.Query()
+	switch {
+	case query.Get("feed_id") != "":
+		feedID, err := strconv.ParseInt(query.Get("feed_id"), 10, 64)
+		if err != nil {
+			return "", nil, errors.New("invalid feed_id")
+		}
+		return " AND a.feed_id = ?", []any{feedID}, nil
+	case query.Get("category_id") != "":
+		catID, err := strconv.ParseInt(query.Get("category_id"), 10, 64)
+		if err != nil {
+			return "", nil, errors.New("invalid category_id")
+		}
+		if catID == 0 {
+			return " AND f.category_id IS NULL", nil, nil
+		}
+		return " AND f.category_id = ?", []any{catID}, nil
 	}
-	result := make([]articleSummary, 0, len(articles))
-	for _, a := range articles {
-		result = append(result, articleSummary{
-			ID: a.ID, FeedID: a.FeedID, Url: a.Url, Title: a.Title,
-			Author: a.Author, PublishedAt: a.PublishedAt,
-			FeedTitle: a.FeedTitle, FeedSiteUrl: a.FeedSiteUrl,
-			IsRead: a.IsRead, IsStarred: a.IsStarred,
-		})
+	return "", nil, nil
+}
+
+// catchUp marks read, in a single transaction, the user's unread articles
+// in scope that come after the newest keep, and returns the ids marked.
+func (s *Server) catchUp(ctx context.Context, userID string, keep int64, scope string, scopeArgs []any) ([]int64, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
-	jsonResponse(w, result)
+	defer func() { _ = tx.Rollback() }()
+
+	query := `SELECT a.id
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+		WHERE f.user_id = ? AND (s.is_read IS NULL OR s.is_read = 0)` + scope + `
+		ORDER BY ` + articleSortKey + ` DESC, a.id DESC
+		LIMIT -1 OFFSET ?`
+	args := append(append([]any{userID}, scopeArgs...), keep)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO article_states (user_id, article_id, is_read, read_at)
+		VALUES (?, ?, 1, ?)
+		ON CONFLICT (user_id, article_id) DO UPDATE SET is_read = 1, read_at = excluded.read_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	now := time.Now()
+	for _, id := range ids {
+		if _, err := stmt.ExecContext(ctx, userID, id, now); err != nil {
+			return nil, err
+		}
+	}
+	return ids, tx.Commit()
 }
 
-// HandleGetArticle returns a single article with full content
-func (s *Server) HandleGetArticle(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
-	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+// HandleMarkFeedRead marks all articles in a feed as read
+func (s *Server) HandleMarkFeedRead(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	feedID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
-		jsonError(w, "invalid article id", http.StatusBadRequest)
+		jsonError(w, "invalid feed id", http.StatusBadRequest)
 		return
 	}
-	q := dbgen.New(s.DB)
-	a, err := q.GetArticle(r.Context(), dbgen.GetArticleParams{
-		UserID:   userID,
-		ID:       articleID,
-		UserID_2: userID,
-	})
+
+	ids, err := s.markFeedRead(r.Context(), userID, feedID)
 	if err != nil {
-		jsonError(w, "article not found", http.StatusNotFound)
+		jsonError(w, "failed to mark feed read", http.StatusInternalServerError)
 		return
 	}
-	jsonResponse(w, a)
+	markedResponse(w, len(ids), s.recordReadUndo(r.Context(), userID, ids))
 }
 
-// HandleMarkRead marks an article as read
-func (s *Server) HandleMarkRead(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
-	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+// HandleMarkFeedsReadBatch marks all articles in several feeds read in one
+// transaction. Feeds the user doesn't own are skipped and reported.
+func (s *Server) HandleMarkFeedsReadBatch(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+
+	var body struct {
+		FeedIDs []int64 `json:"feed_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.FeedIDs) == 0 {
+		jsonError(w, "invalid request: feed_ids required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.DB.BeginTx(r.Context(), nil)
 	if err != nil {
-		jsonError(w, "invalid article id", http.StatusBadRequest)
+		logFrom(r.Context()).Error("batch feed mark-read: begin tx", "error", err)
+		jsonError(w, "database error", http.StatusInternalServerError)
 		return
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	q := dbgen.New(s.DB)
+	q := dbgen.New(s.DB).WithTx(tx)
 	now := time.Now()
-	if err := q.SetArticleRead(r.Context(), dbgen.SetArticleReadParams{
-		UserID:    userID,
-		ArticleID: articleID,
-		ReadAt:    &now,
-	}); err != nil {
-		slog.Error("mark read", "article_id", articleID, "error", err)
-		jsonError(w, "failed to mark read", http.StatusInternalServerError)
+	counts := make(map[int64]int)
+	skipped := []int64{}
+	var ids []int64
+	for _, feedID := range body.FeedIDs {
+		if _, done := counts[feedID]; done {
+			continue
+		}
+		if _, err := q.GetFeed(r.Context(), dbgen.GetFeedParams{ID: feedID, UserID: userID}); err != nil {
+			skipped = append(skipped, feedID)
+			continue
+		}
+		marked, err := q.MarkFeedRead(r.Context(), dbgen.MarkFeedReadParams{
+			UserID: userID, ReadAt: &now, FeedID: feedID, UserID_2: userID,
+		})
+		if err != nil {
+			logFrom(r.Context()).Error("batch feed mark-read", "feed_id", feedID, "error", err)
+			jsonError(w, "failed to mark feeds read", http.StatusInternalServerError)
+			return
+		}
+		counts[feedID] = len(marked)
+		ids = append(ids, marked...)
+	}
+	if err := tx.Commit(); err != nil {
+		logFrom(r.Context()).Error("batch feed mark-read: commit", "error", err)
+		jsonError(w, "database error", http.StatusInternalServerError)
 		return
 	}
-	jsonResponse(w, map[string]string{"status": "ok"})
+
+	jsonResponse(w, map[string]any{
+		"status":     "ok",
+		"marked":     len(ids),
+		"feeds":      counts,
+		"skipped":    skipped,
+		"undo_token": s.recordReadUndo(r.Context(), userID, ids),
+	})
 }
 
-// HandleMarkUnread marks an article as unread
-func (s *Server) HandleMarkUnread(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
-	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+// HandleUndoRead reverts a bulk mark-read, restoring exactly the articles
+// that operation flipped to read back to unread.
+func (s *Server) HandleUndoRead(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	token := r.PathValue("token")
+	q := dbgen.New(s.DB)
+
+	n, err := q.CountReadUndo(r.Context(), dbgen.CountReadUndoParams{
+		Token: token, UserID: userID, MaxAge: undoMaxAge,
+	})
 	if err != nil {
-		jsonError(w, "invalid article id", http.StatusBadRequest)
+		jsonError(w, "failed to undo", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		jsonError(w, "undo token not found or expired", http.StatusNotFound)
 		return
 	}
 
-	q := dbgen.New(s.DB)
-	if err := q.SetArticleUnread(r.Context(), dbgen.SetArticleUnreadParams{
-		UserID:    userID,
-		ArticleID: articleID,
-	}); err != nil {
-		jsonError(w, "failed to mark unread", http.StatusInternalServerError)
+	result, err := q.UndoRead(r.Context(), dbgen.UndoReadParams{
+		Token: token, MaxAge: undoMaxAge, UserID: userID,
+	})
+	if err != nil {
+		logFrom(r.Context()).Error("undo read", "error", err)
+		jsonError(w, "failed to undo", http.StatusInternalServerError)
 		return
 	}
-	jsonResponse(w, map[string]string{"status": "ok"})
+	restored, _ := result.RowsAffected()
+	_ = q.DeleteReadUndo(r.Context(), dbgen.DeleteReadUndoParams{Token: token, UserID: userID})
+	jsonResponse(w, map[string]any{"status": "ok", "restored": restored})
 }
 
-// HandleStar stars an article
-func (s *Server) HandleStar(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
-	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+// HandleRefresh triggers a feed refresh. With force=1, feeds in error
+// backoff are fetched too.
+func (s *Server) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	mode := refreshAll
+	if r.URL.Query().Get("force") == "1" {
+		mode = refreshForced
+	}
+	ctx := s.jobContext(r)
+	s.jobs.Go(func() { s.refreshAllFeeds(ctx, mode) })
+	jsonResponse(w, map[string]string{"status": "refreshing"})
+}
+
+// HandleDownloadBackup streams a consistent snapshot of the database as an
+// attachment, for off-host backups over HTTP. The snapshot is written with
+// VACUUM INTO to a temporary file that is removed afterwards; only one
+// snapshot runs at a time.
+func (s *Server) HandleDownloadBackup(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if !s.backupMu.TryLock() {
+		jsonError(w, "a backup is already in progress", http.StatusConflict)
+		return
+	}
+	defer s.backupMu.Unlock()
+
+	tmpDir, err := os.MkdirTemp("", "gorss-backup-")
 	if err != nil {
-		jsonError(w, "invalid article id", http.StatusBadRequest)
+		logFrom(r.Context()).Error("backup download: temp dir", "error", err)
+		jsonError(w, "backup failed", http.StatusInternalServerError)
 		return
 	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
 
-	q := dbgen.New(s.DB)
-	now := time.Now()
-	if err := q.SetArticleStarred(r.Context(), dbgen.SetArticleStarredParams{
-		UserID:    userID,
-		ArticleID: articleID,
-		StarredAt: &now,
-	}); err != nil {
-		jsonError(w, "failed to star", http.StatusInternalServerError)
+	path, err := db.Backup(s.DB, tmpDir)
+	if err != nil {
+		logFrom(r.Context()).Error("backup download: snapshot", "error", err)
+		jsonError(w, "backup failed", http.StatusInternalServerError)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		logFrom(r.Context()).Error("backup download: open snapshot", "error", err)
+		jsonError(w, "backup failed", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(path)))
+	// The size is only known up front when gzipMiddleware isn't re-encoding
+	if info, err := f.Stat(); err == nil && w.Header().Get("Content-Encoding") == "" {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		logFrom(r.Context()).Warn("backup download: send", "error", err)
+	}
+}
+
+// integrityCheckTimeout bounds an integrity check, which reads the whole
+// database and can take a while on large ones.
+const integrityCheckTimeout = 2 * time.Minute
+
+// HandleIntegrityCheck runs PRAGMA integrity_check on the live database, and
+// PRAGMA foreign_key_check too with ?foreign_keys=1. It returns status "ok"
+// or "problems" with the list of problems reported.
+func (s *Server) HandleIntegrityCheck(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), integrityCheckTimeout)
+	defer cancel()
+
+	problems, err := integrityProblems(ctx, s.DB)
+	if err == nil && r.URL.Query().Get("foreign_keys") == "1" {
+		var fk []string
+		fk, err = foreignKeyProblems(ctx, s.DB)
+		problems = append(problems, fk...)
+	}
+	if err != nil {
+		logFrom(r.Context()).Error("integrity check", "error", err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			jsonError(w, "integrity check timed out", http.StatusGatewayTimeout)
+			return
+		}
+		jsonError(w, "integrity check failed", http.StatusInternalServerError)
 		return
 	}
-	jsonResponse(w, map[string]string{"status": "ok"})
+
+	status := "ok"
+	if len(problems) > 0 {
+		status = "problems"
+		logFrom(r.Context()).Warn("database integrity problems found", "count", len(problems))
+	}
+	jsonResponse(w, map[string]any{"status": status, "problems": problems})
+}
+
+// integrityProblems returns the rows of PRAGMA integrity_check, or none when
+// it reports "ok".
+func integrityProblems(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	problems := []string{}
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, err
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	return problems, rows.Err()
 }
 
-// HandleUnstar unstars an article
-func (s *Server) HandleUnstar(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
-	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+// foreignKeyProblems describes each row PRAGMA foreign_key_check reports.
+func foreignKeyProblems(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "PRAGMA foreign_key_check")
 	if err != nil {
-		jsonError(w, "invalid article id", http.StatusBadRequest)
-		return
+		return nil, err
 	}
-
-	q := dbgen.New(s.DB)
-	if err := q.SetArticleUnstarred(r.Context(), dbgen.SetArticleUnstarredParams{
-		UserID:    userID,
-		ArticleID: articleID,
-	}); err != nil {
-		jsonError(w, "failed to unstar", http.StatusInternalServerError)
-		return
+	defer func() { _ = rows.Close() }()
+	var problems []string
+	for rows.Next() {
+		var table, parent string
+		var rowid sql.NullInt64
+		var fkid int64
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return nil, err
+		}
+		problems = append(problems, fmt.Sprintf("%s row %d: missing %s row (foreign key %d)", table, rowid.Int64, parent, fkid))
 	}
-	jsonResponse(w, map[string]string{"status": "ok"})
+	return problems, rows.Err()
 }
 
-// markCategoryRead marks all articles in a category as read.
-func (s *Server) markCategoryRead(ctx context.Context, userID string, categoryID int64) error {
-	now := time.Now()
-	var catFilter string
-	var args []any
-	if categoryID == 0 {
-		catFilter = "f.category_id IS NULL"
-		args = []any{userID, now, userID}
-	} else {
-		catFilter = "f.category_id = ?"
-		args = []any{userID, now, categoryID, userID}
-	}
-	query := `INSERT OR REPLACE INTO article_states (user_id, article_id, is_read, read_at, is_starred, starred_at)
-		SELECT ?, a.id, 1, ?,
-			COALESCE(s.is_starred, 0), s.starred_at
-		FROM articles a
-		JOIN feeds f ON a.feed_id = f.id
-		LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
-		WHERE ` + catFilter + ` AND f.user_id = ?`
-	_, err := s.DB.ExecContext(ctx, query, args...)
-	return err
+// repairBatchSize is the number of orphaned rows deleted per transaction by
+// HandleRepair.
+const repairBatchSize = 500
+
+// orphanQueries select the rowids of rows whose parent is gone, for
+// databases written before foreign keys were enforced on every connection.
+// Articles go first so their states are caught by the second pass.
+var orphanQueries = []struct{ table, query string }{
+	{"articles", `SELECT a.rowid FROM articles a LEFT JOIN feeds f ON f.id = a.feed_id WHERE f.id IS NULL LIMIT ?`},
+	{"article_states", `SELECT s.rowid FROM article_states s LEFT JOIN articles a ON a.id = s.article_id WHERE a.id IS NULL LIMIT ?`},
 }
 
-// HandleMarkReadBatch marks multiple articles as read in a single transaction
-func (s *Server) HandleMarkReadBatch(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+// HandleRepair deletes articles whose feed no longer exists and article
+// states whose article no longer exists, and returns how many rows of each
+// were removed.
+func (s *Server) HandleRepair(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	removed := make(map[string]int64, len(orphanQueries))
+	for _, o := range orphanQueries {
+		for {
+			n, err := s.deleteOrphanBatch(r.Context(), o.table, o.query)
+			if err != nil {
+				logFrom(r.Context()).Error("repair", "table", o.table, "error", err)
+				jsonError(w, "repair failed", http.StatusInternalServerError)
+				return
+			}
+			removed[o.table] += n
+			if n < repairBatchSize {
+				break
+			}
+		}
+	}
+	if removed["articles"] > 0 || removed["article_states"] > 0 {
+		logFrom(r.Context()).Info("removed orphaned rows", "articles", removed["articles"], "article_states", removed["article_states"])
+	}
+	jsonResponse(w, removed)
+}
 
+// HandleMigrateUser moves all of one user's feeds, categories, article
+// states and preferences to another user, by default the caller. It
+// rescues data stored under "anonymous" after authentication is enabled.
+// Taking over another user's data, feed token included, is for admins only.
+func (s *Server) HandleMigrateUser(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
 	var body struct {
-		IDs []int64 `json:"ids"`
+		From string `json:"from"`
+		To   string `json:"to"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.IDs) == 0 {
-		jsonError(w, "invalid request: ids required", http.StatusBadRequest)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.From == "" {
+		jsonError(w, "invalid request: from required", http.StatusBadRequest)
 		return
 	}
-
-	tx, err := s.DB.BeginTx(r.Context(), nil)
-	if err != nil {
-		slog.Error("batch mark-read: begin tx", "error", err)
-		jsonError(w, "database error", http.StatusInternalServerError)
+	if body.To == "" {
+		body.To = s.userFromContext(r)
+	}
+	if body.From == body.To {
+		jsonError(w, "from and to are the same user", http.StatusBadRequest)
 		return
 	}
-	defer func() { _ = tx.Rollback() }()
 
-	now := time.Now()
-	stmt, err := tx.PrepareContext(r.Context(), `INSERT INTO article_states (user_id, article_id, is_read, read_at)
-		VALUES (?, ?, 1, ?)
-		ON CONFLICT (user_id, article_id) DO UPDATE SET is_read = 1, read_at = excluded.read_at`)
+	res, err := db.MigrateUser(r.Context(), s.DB, body.From, body.To)
+	if errors.Is(err, db.ErrUserNotFound) {
+		jsonError(w, "user not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		slog.Error("batch mark-read: prepare", "error", err)
-		jsonError(w, "database error", http.StatusInternalServerError)
+		logFrom(r.Context()).Error("migrate user", "from", body.From, "to", body.To, "error", err)
+		jsonError(w, "failed to migrate user", http.StatusInternalServerError)
 		return
 	}
-	defer func() { _ = stmt.Close() }()
+	s.countsCache.invalidate(body.From)
+	s.countsCache.invalidate(body.To)
+	logFrom(r.Context()).Info("migrated user data", "from", body.From, "to", body.To, "feeds", res.Feeds, "skipped_feeds", res.Skipped)
+	jsonResponse(w, res)
+}
 
-	for _, id := range body.IDs {
-		if _, err := stmt.ExecContext(r.Context(), userID, id, &now); err != nil {
-			slog.Error("batch mark-read: exec", "article_id", id, "error", err)
-		}
+// deleteOrphanBatch deletes up to repairBatchSize rows of table selected by
+// query in one transaction, returning the number deleted.
+func (s *Server) deleteOrphanBatch(ctx context.Context, table, query string) (int64, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	if err := tx.Commit(); err != nil {
-		slog.Error("batch mark-read: commit", "error", err)
-		jsonError(w, "database error", http.StatusInternalServerError)
-		return
+	res, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE rowid IN ("+query+")", repairBatchSize)
+	if err != nil {
+		return 0, err
 	}
-	jsonResponse(w, map[string]string{"status": "ok"})
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
 }
 
-// HandleMarkAllRead marks all articles as read (optionally filtered by feed or category)
-func (s *Server) HandleMarkAllRead(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
-	q := dbgen.New(s.DB)
-	now := time.Now()
+// reprocessBatchSize is the number of articles rewritten per transaction by
+// HandleReprocessContent.
+const reprocessBatchSize = 200
 
-	switch {
-	case r.URL.Query().Get("feed_id") != "":
-		feedID, err := strconv.ParseInt(r.URL.Query().Get("feed_id"), 10, 64)
-		if err != nil {
-			jsonError(w, "invalid feed_id", http.StatusBadRequest)
-			return
-		}
-		if err := q.MarkFeedRead(r.Context(), dbgen.MarkFeedReadParams{
-			UserID: userID, ReadAt: &now, FeedID: feedID,
-		}); err != nil {
-			jsonError(w, "failed to mark feed read", http.StatusInternalServerError)
-			return
-		}
-	case r.URL.Query().Get("category_id") != "":
-		catID, err := strconv.ParseInt(r.URL.Query().Get("category_id"), 10, 64)
+// HandleReprocessContent re-runs the content processing pipeline over the
+// user's stored articles, so changes to ingestion-time processing apply to
+// existing content without re-fetching feeds.
+func (s *Server) HandleReprocessContent(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	ctx := r.Context()
+
+	total, err := dbgen.New(s.DB).GetTotalArticleCount(ctx, userID)
+	if err != nil {
+		jsonError(w, "failed to count articles", http.StatusInternalServerError)
+		return
+	}
+
+	var processed, updated int
+	var lastID int64
+	for {
+		n, changed, next, err := s.reprocessBatch(ctx, userID, lastID)
 		if err != nil {
-			jsonError(w, "invalid category_id", http.StatusBadRequest)
-			return
-		}
-		if err := s.markCategoryRead(r.Context(), userID, catID); err != nil {
-			jsonError(w, "failed to mark category read", http.StatusInternalServerError)
+			logFrom(r.Context()).Error("reprocess content", "error", err, "after_id", lastID)
+			jsonError(w, "failed to reprocess content", http.StatusInternalServerError)
 			return
 		}
-	default:
-		if err := q.MarkAllRead(r.Context(), dbgen.MarkAllReadParams{
-			UserID: userID, ReadAt: &now, UserID_2: userID,
-		}); err != nil {
-			jsonError(w, "failed to mark all read", http.StatusInternalServerError)
-			return
+		if n == 0 {
+			break
 		}
+		processed += n
+		updated += changed
+		lastID = next
+		logFrom(r.Context()).Info("reprocess content", "user", userID, "processed", processed, "total", total)
 	}
-	jsonResponse(w, map[string]string{"status": "ok"})
+
+	jsonResponse(w, map[string]any{
+		"total":     total,
+		"processed": processed,
+		"updated":   updated,
+	})
 }
 
-// HandleMarkFeedRead marks all articles in a feed as read
-func (s *Server) HandleMarkFeedRead(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
-	feedID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+// reprocessBatch reprocesses one batch of articles with ids above afterID in
+// a single transaction. It returns the number of articles read, the number
+// rewritten, and the last id seen.
+func (s *Server) reprocessBatch(ctx context.Context, userID string, afterID int64) (int, int, int64, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
-		jsonError(w, "invalid feed id", http.StatusBadRequest)
-		return
+		return 0, 0, afterID, err
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	q := dbgen.New(s.DB)
-	now := time.Now()
-	if err := q.MarkFeedRead(r.Context(), dbgen.MarkFeedReadParams{
-		UserID: userID,
-		ReadAt: &now,
-		FeedID: feedID,
-	}); err != nil {
-		jsonError(w, "failed to mark feed read", http.StatusInternalServerError)
-		return
+	q := dbgen.New(s.DB).WithTx(tx)
+	rows, err := q.GetArticleContentBatch(ctx, dbgen.GetArticleContentBatchParams{
+		UserID: userID, ID: afterID, Limit: reprocessBatchSize,
+	})
+	if err != nil {
+		return 0, 0, afterID, err
+	}
+
+	updated := 0
+	for _, row := range rows {
+		afterID = row.ID
+		original := decompressContent(row.Content, row.ContentCompressed)
+		item := FeedItem{Title: row.Title, Content: original, Summary: row.Summary}
+		processItem(&item)
+		s.excerptItem(&item)
+		s.truncateItem(&item)
+		if item.Title == row.Title && item.Content == original && item.Summary == row.Summary {
+			// Also indexes compressed content stored before it was indexed
+			if row.ContentCompressed != 0 {
+				if err := indexCompressedContent(ctx, q, row.ID, &item); err != nil {
+					return 0, 0, afterID, err
+				}
+			}
+			continue
+		}
+		content, compressed := s.compressContent(item.Content)
+		if err := q.UpdateArticleContent(ctx, dbgen.UpdateArticleContentParams{
+			Title: item.Title, Content: content, ContentCompressed: compressed, Summary: item.Summary, ID: row.ID,
+		}); err != nil {
+			return 0, 0, afterID, err
+		}
+		if compressed != 0 {
+			if err := indexCompressedContent(ctx, q, row.ID, &item); err != nil {
+				return 0, 0, afterID, err
+			}
+		}
+		updated++
 	}
-	jsonResponse(w, map[string]string{"status": "ok"})
-}
-
-// HandleRefresh triggers a feed refresh
-func (s *Server) HandleRefresh(w http.ResponseWriter, r *http.Request) {
-	// Use background context — r.Context() is cancelled when the response is sent
-	go s.refreshAllFeeds(context.Background())
-	jsonResponse(w, map[string]string{"status": "refreshing"})
+	return len(rows), updated, afterID, tx.Commit()
 }
 
 // HandleGetCategories returns all categories
 func (s *Server) HandleGetCategories(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+	userID := s.userFromContext(r)
 	q := dbgen.New(s.DB)
 
 	categories, err := q.GetCategoriesOrdered(r.Context(), userID)
@@ -735,7 +2817,7 @@ func (s *Server) HandleGetCategories(w http.ResponseWriter, r *http.Request) {
 
 // HandleCreateCategory creates a new category
 func (s *Server) HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+	userID := s.userFromContext(r)
 
 	var req struct {
 		Title string `json:"title"`
@@ -762,14 +2844,128 @@ func (s *Server) HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, cat)
 }
 
+// HandleUpdateCategory renames a category and/or sets its refresh interval,
+// which applies to its feeds that have no interval of their own.
+func (s *Server) HandleUpdateCategory(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	catID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid category id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Title           *string `json:"title"`
+		RefreshInterval *int64  `json:"refresh_interval_minutes"` // 0 clears the interval
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	var title string
+	if req.Title != nil {
+		if title = strings.TrimSpace(*req.Title); title == "" {
+			jsonError(w, "title is required", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.RefreshInterval != nil && *req.RefreshInterval < 0 {
+		jsonError(w, "refresh_interval_minutes must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	q := dbgen.New(s.DB)
+	cat, err := q.GetCategory(r.Context(), dbgen.GetCategoryParams{ID: catID, UserID: userID})
+	if err != nil {
+		jsonError(w, "category not found", http.StatusNotFound)
+		return
+	}
+
+	if title != "" && title != cat.Title {
+		if err := q.UpdateCategory(r.Context(), dbgen.UpdateCategoryParams{Title: title, ID: catID, UserID: userID}); err != nil {
+			jsonError(w, "failed to update category", http.StatusInternalServerError)
+			return
+		}
+	}
+	if req.RefreshInterval != nil {
+		if err := q.UpdateCategoryRefreshInterval(r.Context(), dbgen.UpdateCategoryRefreshIntervalParams{
+			RefreshIntervalMinutes: nullIfZero(*req.RefreshInterval),
+			ID:                     catID,
+			UserID:                 userID,
+		}); err != nil {
+			jsonError(w, "failed to update category", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// HandleDeleteCategory deletes a category. Its feeds are kept and become
+// uncategorized.
+func (s *Server) HandleDeleteCategory(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	catID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid category id", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		jsonError(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+	q := dbgen.New(s.DB).WithTx(tx)
+
+	if _, err := q.GetCategory(r.Context(), dbgen.GetCategoryParams{ID: catID, UserID: userID}); err != nil {
+		jsonError(w, "category not found", http.StatusNotFound)
+		return
+	}
+	err = q.UncategorizeFeeds(r.Context(), dbgen.UncategorizeFeedsParams{CategoryID: &catID, UserID: userID})
+	if err == nil {
+		err = q.DeleteCategory(r.Context(), dbgen.DeleteCategoryParams{ID: catID, UserID: userID})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		logFrom(r.Context()).Error("delete category", "category_id", catID, "error", err)
+		jsonError(w, "failed to delete category", http.StatusInternalServerError)
+		return
+	}
+	s.countsCache.invalidate(userID)
+	jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// nullIfZero maps 0 to NULL for optional integer settings.
+func nullIfZero(v int64) *int64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
 // HandleGetCounts returns unread and starred counts, plus per-feed counts
 func (s *Server) HandleGetCounts(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+	userID := s.userFromContext(r)
+	now := time.Now()
+	cached, gen, ok := s.countsCache.get(userID, now)
+	if ok {
+		jsonResponse(w, cached)
+		return
+	}
 	q := dbgen.New(s.DB)
 
 	total, _ := q.GetTotalArticleCount(r.Context(), userID)
 	unread, _ := q.GetUnreadCount(r.Context(), userID)
 	starred, _ := q.GetStarredCount(r.Context(), userID)
+	read, _ := q.GetReadCount(r.Context(), userID)
+	var fresh int64
+	if since := s.freshSince(); since != nil {
+		fresh, _ = q.GetFreshCount(r.Context(), dbgen.GetFreshCountParams{UserID: userID, Since: since})
+	}
 
 	// Get per-feed unread counts
 	feeds, _ := q.GetFeeds(r.Context(), userID)
@@ -780,17 +2976,40 @@ func (s *Server) HandleGetCounts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	jsonResponse(w, map[string]interface{}{
-		"total":   total,
-		"unread":  unread,
-		"starred": starred,
-		"feeds":   feedCounts,
-	})
+	// Per-category unread counts; uncategorized feeds are under 0
+	catRows, _ := q.GetCategoryUnreadCounts(r.Context(), userID)
+	catCounts := make(map[int64]int64, len(catRows))
+	for _, c := range catRows {
+		catCounts[c.CategoryID] = c.UnreadCount
+	}
+
+	counts := map[string]any{
+		"total":      total,
+		"unread":     unread,
+		"starred":    starred,
+		"read":       read,
+		"fresh":      fresh,
+		"feeds":      feedCounts,
+		"categories": catCounts,
+	}
+	s.countsCache.put(userID, gen, counts, now)
+	jsonResponse(w, counts)
+}
+
+// freshSince returns the start of the fresh window, or nil when FreshHours
+// is disabled. It is in UTC so it compares consistently with stored
+// CURRENT_TIMESTAMP values.
+func (s *Server) freshSince() *time.Time {
+	if s.FreshHours <= 0 {
+		return nil
+	}
+	since := time.Now().UTC().Add(-time.Duration(s.FreshHours) * time.Hour)
+	return &since
 }
 
 // HandleExportOPML exports feeds as OPML
 func (s *Server) HandleExportOPML(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+	userID := s.userFromContext(r)
 	q := dbgen.New(s.DB)
 
 	feeds, err := q.GetFeeds(r.Context(), userID)
@@ -799,14 +3018,70 @@ func (s *Server) HandleExportOPML(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get categories for mapping
-	categories, _ := q.GetCategories(r.Context(), userID)
+	catMap := categoryTitles(r.Context(), q, userID)
+
+	// Optional category filter (0 = uncategorized)
+	var filterCat *int64
+	filtered := false
+	title := "GoRSS Export"
+	if v := r.URL.Query().Get("category_id"); v != "" {
+		filtered = true
+		cid, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid category_id", http.StatusBadRequest)
+			return
+		}
+		if cid != 0 {
+			name, ok := catMap[cid]
+			if !ok {
+				http.Error(w, "category not found", http.StatusNotFound)
+				return
+			}
+			filterCat = &cid
+			title += " - " + name
+		} else {
+			title += " - Uncategorized"
+		}
+	}
+
+	// Build export list
+	if filtered {
+		feeds = slices.DeleteFunc(feeds, func(f dbgen.GetFeedsRow) bool {
+			return !sameCategory(f.CategoryID, filterCat)
+		})
+	}
+	exports := feedExports(feeds, catMap)
+	// Reading state is non-standard, so it is only added on request
+	if r.URL.Query().Get("state") == "1" {
+		if err := addOPMLState(r.Context(), q, userID, feeds, exports); err != nil {
+			logFrom(r.Context()).Error("export reading state", "error", err)
+			http.Error(w, "failed to export reading state", http.StatusInternalServerError)
+			return
+		}
+	}
+	opml, err := GenerateOPML(title, exports)
+	if err != nil {
+		http.Error(w, "failed to generate OPML", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", "attachment; filename=gorss-feeds.opml")
+	_, _ = w.Write(opml)
+}
+
+// categoryTitles maps the user's category IDs to their titles.
+func categoryTitles(ctx context.Context, q *dbgen.Queries, userID string) map[int64]string {
+	categories, _ := q.GetCategories(ctx, userID)
 	catMap := make(map[int64]string)
 	for _, c := range categories {
 		catMap[c.ID] = c.Title
 	}
+	return catMap
+}
 
-	// Build export list
+// feedExports converts feeds to OPML export entries, resolving category names.
+func feedExports(feeds []dbgen.GetFeedsRow, catMap map[int64]string) []FeedExport {
 	var exports []FeedExport
 	for _, f := range feeds {
 		cat := ""
@@ -820,16 +3095,60 @@ func (s *Server) HandleExportOPML(w http.ResponseWriter, r *http.Request) {
 			Category: cat,
 		})
 	}
+	return exports
+}
 
-	opml, err := GenerateOPML("GoRSS Export", exports)
+// addOPMLState fills in the reading state of each export. exports must be
+// feedExports(feeds, ...), in the same order.
+func addOPMLState(ctx context.Context, q *dbgen.Queries, userID string, feeds []dbgen.GetFeedsRow, exports []FeedExport) error {
+	readCounts, err := q.GetFeedReadCounts(ctx, userID)
 	if err != nil {
-		http.Error(w, "failed to generate OPML", http.StatusInternalServerError)
-		return
+		return err
+	}
+	starred, err := q.GetStarredArticleRefs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	states := make(map[int64]*OPMLState, len(feeds))
+	for i, f := range feeds {
+		exports[i].State = &OPMLState{Unread: f.UnreadCount}
+		states[f.ID] = exports[i].State
+	}
+	for _, c := range readCounts {
+		if st := states[c.FeedID]; st != nil {
+			st.Read = c.Count
+		}
+	}
+	for _, a := range starred {
+		st := states[a.FeedID]
+		if st == nil {
+			continue
+		}
+		ref := OPMLStarred{GUID: a.Guid, URL: a.Url, Title: a.Title}
+		if a.StarredAt != nil {
+			ref.StarredAt = a.StarredAt.UTC().Format(time.RFC3339)
+		}
+		st.Starred = append(st.Starred, ref)
 	}
+	return nil
+}
 
-	w.Header().Set("Content-Type", "application/xml")
-	w.Header().Set("Content-Disposition", "attachment; filename=gorss-feeds.opml")
-	_, _ = w.Write(opml)
+// restoreStarred stars the articles an OPML export listed as starred in a
+// newly imported feed. Ones the feed no longer carries are skipped.
+func restoreStarred(ctx context.Context, q *dbgen.Queries, feedID int64, state *OPMLState) {
+	if state == nil {
+		return
+	}
+	now := time.Now()
+	for _, ref := range state.Starred {
+		at := now
+		if t, err := time.Parse(time.RFC3339, ref.StarredAt); err == nil {
+			at = t
+		}
+		if err := q.StarArticleByGUID(ctx, dbgen.StarArticleByGUIDParams{StarredAt: &at, FeedID: feedID, Guid: ref.GUID}); err != nil {
+			logFrom(ctx).Warn("restore starred article", "error", err, "feed_id", feedID, "guid", ref.GUID)
+		}
+	}
 }
 
 func stringVal(s string) string {
@@ -862,15 +3181,34 @@ func (s *Server) resolveCategoryMap(ctx context.Context, userID string, feeds []
 	return catMap
 }
 
-// importSingleFeed fetches, creates and stores articles for one feed. Returns true if imported.
-func (s *Server) importSingleFeed(ctx context.Context, userID string, f FeedImport, catMap map[string]int64) bool {
+// importReason is the outcome of importing a single OPML feed.
+type importReason string
+
+const (
+	importCreated      importReason = "created"
+	importDuplicate    importReason = "duplicate"
+	importFetchFailed  importReason = "fetch_failed"
+	importCreateFailed importReason = "create_failed"
+	importInvalid      importReason = "invalid"
+)
+
+// importResult reports what happened to one feed in an OPML import.
+type importResult struct {
+	URL    string       `json:"url"`
+	Reason importReason `json:"reason"`
+	Error  string       `json:"error,omitempty"`
+	FeedID int64        `json:"feed_id,omitempty"` // set when created
+}
+
+// importSingleFeed fetches, creates and stores articles for one feed.
+func (s *Server) importSingleFeed(ctx context.Context, userID string, f FeedImport, catMap map[string]int64) importResult {
 	q := dbgen.New(s.DB)
 
 	// Check if already subscribed
 	existing, _ := q.GetFeeds(ctx, userID)
 	for _, e := range existing {
 		if e.Url == f.URL {
-			return false
+			return importResult{URL: f.URL, Reason: importDuplicate}
 		}
 	}
 
@@ -882,75 +3220,329 @@ func (s *Server) importSingleFeed(ctx context.Context, userID string, f FeedImpo
 
 	result, err := s.fetcher.Fetch(ctx, f.URL)
 	if err != nil {
-		slog.Warn("import feed fetch failed", "url", f.URL, "error", err)
-		return false
+		logFrom(ctx).Warn("import feed fetch failed", "url", f.URL, "error", err)
+		return importResult{URL: f.URL, Reason: importFetchFailed, Error: err.Error()}
 	}
 
 	// Filter out articles older than purge threshold
+	applyDateSource(result.Items, dateSourcePreferPublished)
 	if s.PurgeDays > 0 {
 		cutoff := time.Now().AddDate(0, 0, -s.PurgeDays)
 		result.Items = filterOldItems(result.Items, cutoff)
 	}
 
+	// Untitled feeds take the OPML outline's title, else the hostname
+	if result.Title == "" {
+		result.Title = f.Title
+	}
+	if result.Title == "" {
+		result.Title = fallbackFeedTitle(result.SiteURL, f.URL)
+	}
+
+	// Imports fetch concurrently but write one feed at a time
+	s.importMu.Lock()
+	defer s.importMu.Unlock()
 	feed, err := q.CreateFeed(ctx, dbgen.CreateFeedParams{
 		UserID: userID, CategoryID: catID, Url: f.URL,
 		Title: result.Title, SiteUrl: result.SiteURL, Description: result.Description,
+		LogoUrl: result.LogoURL,
 	})
 	if err != nil {
-		slog.Warn("import feed create failed", "url", f.URL, "error", err)
-		return false
+		logFrom(ctx).Warn("import feed create failed", "url", f.URL, "error", err)
+		return importResult{URL: f.URL, Reason: importCreateFailed, Error: err.Error()}
 	}
 
-	for _, item := range result.Items {
-		_, _ = q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
-			FeedID: feed.ID, Guid: item.GUID, Url: item.URL, Title: item.Title,
-			Content: item.Content, Author: item.Author, PublishedAt: item.PublishedAt,
+	s.storeItems(ctx, q, &feed, result.Items)
+	restoreStarred(ctx, q, feed.ID, f.State)
+	return importResult{URL: f.URL, Reason: importCreated, FeedID: feed.ID}
+}
+
+// importWorkers is the number of feeds an OPML import fetches at once.
+const importWorkers = 8
+
+// importFeeds imports feeds with up to importWorkers fetching concurrently,
+// returning one result per feed in input order. catMap must already hold
+// every category, since workers only read it.
+func (s *Server) importFeeds(ctx context.Context, userID string, feeds []FeedImport, catMap map[string]int64) []importResult {
+	results := make([]importResult, len(feeds))
+	sem := make(chan struct{}, importWorkers)
+	var wg sync.WaitGroup
+	for i, f := range feeds {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			results[i] = s.importSingleFeed(ctx, userID, f, catMap)
 		})
 	}
-	return true
+	wg.Wait()
+	return results
 }
 
-// HandleImportOPML imports feeds from OPML
-func (s *Server) HandleImportOPML(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+// Defaults for the OPML import limits. A large personal OPML has a few
+// thousand feeds at most, in well under a megabyte.
+const (
+	defaultMaxImportMB    = 10
+	defaultMaxImportFeeds = 5000
+)
+
+// importFormMemory is how much of an upload form is held in memory; the rest
+// goes to temporary files.
+const importFormMemory = 10 << 20
 
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+// parseImportForm parses an OPML upload form, refusing bodies larger than
+// MaxImportBytes with 413. It writes the error response and returns false
+// on failure.
+func (s *Server) parseImportForm(w http.ResponseWriter, r *http.Request) bool {
+	if s.MaxImportBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.MaxImportBytes)
+	}
+	err := r.ParseMultipartForm(importFormMemory)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		jsonError(w, fmt.Sprintf("upload is larger than the %d MB limit", s.MaxImportBytes>>20), http.StatusRequestEntityTooLarge)
+	} else {
 		jsonError(w, "failed to parse form", http.StatusBadRequest)
+	}
+	return false
+}
+
+// HandleImportOPML imports feeds from OPML. If the form has urls values, only
+// the feeds with those URLs are imported; the rest count as unselected. More
+// than MaxImportFeeds feeds are refused before anything is fetched.
+func (s *Server) HandleImportOPML(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+
+	if !s.parseImportForm(w, r) {
 		return
 	}
 
-	file, _, err := r.FormFile("file")
-	if err != nil {
+	uploads := slices.Concat(r.MultipartForm.File["file"], r.MultipartForm.File["file[]"])
+	if len(uploads) == 0 {
 		jsonError(w, "no file provided", http.StatusBadRequest)
 		return
 	}
-	defer func() { _ = file.Close() }()
 
-	feeds, err := ParseOPML(file)
-	if err != nil {
-		jsonError(w, "failed to parse OPML: "+err.Error(), http.StatusBadRequest)
+	feeds, files, parsed := parseOPMLUploads(uploads)
+	if parsed == 0 {
+		jsonError(w, "failed to parse OPML: "+files[0].Error, http.StatusBadRequest)
+		return
+	}
+
+	// An optional urls list (e.g. chosen from a preview) limits the import
+	unselected := 0
+	if chosen := slices.Concat(r.MultipartForm.Value["urls"], r.MultipartForm.Value["urls[]"]); len(chosen) > 0 {
+		before := len(feeds)
+		feeds = slices.DeleteFunc(feeds, func(f FeedImport) bool {
+			return !slices.Contains(chosen, f.URL)
+		})
+		unselected = before - len(feeds)
+	}
+
+	if s.MaxImportFeeds > 0 && len(feeds) > s.MaxImportFeeds {
+		jsonError(w, fmt.Sprintf("OPML lists %d feeds, more than the import limit of %d", len(feeds), s.MaxImportFeeds), http.StatusRequestEntityTooLarge)
 		return
 	}
 
 	catMap := s.resolveCategoryMap(r.Context(), userID, feeds)
 
-	imported := 0
+	reasons := make(map[importReason]int)
+	failed := []importResult{}
+	for _, res := range s.importFeeds(r.Context(), userID, feeds, catMap) {
+		reasons[res.Reason]++
+		if res.Reason == importFetchFailed || res.Reason == importCreateFailed {
+			failed = append(failed, res)
+		}
+	}
+
+	jsonResponse(w, map[string]any{
+		"imported":   reasons[importCreated],
+		"skipped":    len(feeds) - reasons[importCreated],
+		"total":      len(feeds),
+		"unselected": unselected,
+		"reasons":    reasons,
+		"failed":     failed,
+		"files":      files,
+	})
+}
+
+// opmlPreviewFeed is one feed of an OPML preview.
+type opmlPreviewFeed struct {
+	URL               string `json:"url"`
+	Title             string `json:"title"`
+	AlreadySubscribed bool   `json:"already_subscribed"`
+}
+
+// opmlPreviewCategory groups the previewed feeds of one category ("" for
+// feeds outside any category).
+type opmlPreviewCategory struct {
+	Name  string            `json:"name"`
+	Feeds []opmlPreviewFeed `json:"feeds"`
+}
+
+// HandlePreviewOPML parses uploaded OPML files like HandleImportOPML but
+// creates nothing. It returns the feeds grouped by category, in file order,
+// each flagged if the user already subscribes to it.
+func (s *Server) HandlePreviewOPML(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+
+	if !s.parseImportForm(w, r) {
+		return
+	}
+
+	uploads := slices.Concat(r.MultipartForm.File["file"], r.MultipartForm.File["file[]"])
+	if len(uploads) == 0 {
+		jsonError(w, "no file provided", http.StatusBadRequest)
+		return
+	}
+
+	feeds, files, parsed := parseOPMLUploads(uploads)
+	if parsed == 0 {
+		jsonError(w, "failed to parse OPML: "+files[0].Error, http.StatusBadRequest)
+		return
+	}
+
+	existing, err := dbgen.New(s.DB).GetFeeds(r.Context(), userID)
+	if err != nil {
+		jsonError(w, "failed to get feeds", http.StatusInternalServerError)
+		return
+	}
+	subscribed := make(map[string]bool, len(existing))
+	for _, e := range existing {
+		subscribed[e.Url] = true
+	}
+
+	categories := []opmlPreviewCategory{}
+	catIndex := make(map[string]int)
+	duplicates := 0
 	for _, f := range feeds {
-		if s.importSingleFeed(r.Context(), userID, f, catMap) {
-			imported++
+		i, ok := catIndex[f.Category]
+		if !ok {
+			i = len(categories)
+			catIndex[f.Category] = i
+			categories = append(categories, opmlPreviewCategory{Name: f.Category})
+		}
+		if subscribed[f.URL] {
+			duplicates++
 		}
+		categories[i].Feeds = append(categories[i].Feeds, opmlPreviewFeed{
+			URL: f.URL, Title: f.Title, AlreadySubscribed: subscribed[f.URL],
+		})
 	}
 
-	jsonResponse(w, map[string]int{
-		"imported": imported,
-		"skipped":  len(feeds) - imported,
-		"total":    len(feeds),
+	jsonResponse(w, map[string]any{
+		"total":              len(feeds),
+		"already_subscribed": duplicates,
+		"categories":         categories,
+		"files":              files,
 	})
 }
 
+// maxBulkSubscribe caps the number of entries in one bulk-subscribe request.
+const maxBulkSubscribe = 500
+
+// HandleBulkSubscribe subscribes to a JSON array of {url, category, title}
+// entries, creating categories as needed like the OPML import. Feeds the user
+// already follows are reported as duplicates. The response has one result per
+// entry, in request order; a title, if given, replaces the feed's own.
+func (s *Server) HandleBulkSubscribe(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+
+	var entries []struct {
+		URL      string `json:"url"`
+		Category string `json:"category"`
+		Title    string `json:"title"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&entries); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(entries) > maxBulkSubscribe {
+		jsonError(w, fmt.Sprintf("at most %d entries per request", maxBulkSubscribe), http.StatusBadRequest)
+		return
+	}
+
+	feeds := make([]FeedImport, len(entries))
+	for i, e := range entries {
+		feeds[i] = FeedImport{
+			URL:      strings.TrimSpace(e.URL),
+			Title:    strings.TrimSpace(e.Title),
+			Category: strings.TrimSpace(e.Category),
+		}
+	}
+	catMap := s.resolveCategoryMap(r.Context(), userID, feeds)
+
+	q := dbgen.New(s.DB)
+	results := make([]importResult, 0, len(feeds))
+	for _, f := range feeds {
+		if f.URL == "" {
+			results = append(results, importResult{Reason: importInvalid, Error: "url is required"})
+			continue
+		}
+		res := s.importSingleFeed(r.Context(), userID, f, catMap)
+		if res.Reason == importCreated && f.Title != "" {
+			if err := q.UpdateFeedDetails(r.Context(), dbgen.UpdateFeedDetailsParams{
+				Title: f.Title, Url: f.URL, ID: res.FeedID, UserID: userID,
+			}); err != nil {
+				logFrom(r.Context()).Warn("bulk subscribe set title", "error", err, "feed_id", res.FeedID)
+			}
+		}
+		results = append(results, res)
+	}
+	jsonResponse(w, results)
+}
+
+// opmlFileResult reports the outcome of parsing one uploaded OPML file.
+type opmlFileResult struct {
+	Name  string `json:"name"`
+	Feeds int    `json:"feeds"`
+	Error string `json:"error,omitempty"`
+}
+
+// parseOPMLUploads parses each uploaded OPML file and merges their feeds,
+// deduplicated by URL (first occurrence wins). It returns the merged feeds,
+// a per-file report, and the number of files that parsed successfully.
+func parseOPMLUploads(uploads []*multipart.FileHeader) ([]FeedImport, []opmlFileResult, int) {
+	var merged []FeedImport
+	seen := make(map[string]bool)
+	files := make([]opmlFileResult, 0, len(uploads))
+	parsed := 0
+	for _, fh := range uploads {
+		res := opmlFileResult{Name: fh.Filename}
+		feeds, err := parseOPMLUpload(fh)
+		if err != nil {
+			res.Error = err.Error()
+			files = append(files, res)
+			continue
+		}
+		parsed++
+		res.Feeds = len(feeds)
+		files = append(files, res)
+		for _, f := range feeds {
+			if !seen[f.URL] {
+				seen[f.URL] = true
+				merged = append(merged, f)
+			}
+		}
+	}
+	return merged, files, parsed
+}
+
+// parseOPMLUpload opens and parses a single uploaded OPML file.
+func parseOPMLUpload(fh *multipart.FileHeader) ([]FeedImport, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return ParseOPML(file)
+}
+
 // HandleReorderCategories updates category sort orders
 func (s *Server) HandleReorderCategories(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+	userID := s.userFromContext(r)
 	q := dbgen.New(s.DB)
 
 	var req []struct {
@@ -975,7 +3567,7 @@ func (s *Server) HandleReorderCategories(w http.ResponseWriter, r *http.Request)
 
 // HandleReorderFeeds updates feed sort orders and optionally moves feeds between categories
 func (s *Server) HandleReorderFeeds(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+	userID := s.userFromContext(r)
 	q := dbgen.New(s.DB)
 
 	var req []struct {
@@ -1008,58 +3600,339 @@ func (s *Server) HandleReorderFeeds(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, map[string]string{"status": "ok"})
 }
 
-// HandleSearchArticles searches articles by title, content, or summary
+// moveRequest is the body of the feed/category move endpoints. AfterID places
+// the item directly after another one (and, for feeds, in that feed's
+// category); otherwise Position is a zero-based index, clamped to the list.
+type moveRequest struct {
+	AfterID    *int64 `json:"after_id"`
+	Position   *int   `json:"position"`
+	CategoryID *int64 `json:"category_id"` // feeds only; 0 = uncategorized
+}
+
+// orderEntry is one item of the resulting order returned by move endpoints.
+type orderEntry struct {
+	ID         int64  `json:"id"`
+	Order      int64  `json:"order"`
+	CategoryID *int64 `json:"category_id,omitempty"`
+}
+
+var (
+	errMoveNoTarget        = errors.New("after_id or position is required")
+	errMoveAfterNotFound   = errors.New("after_id not found")
+	errMoveCategoryMissing = errors.New("category not found")
+)
+
+// moveID returns ids with id inserted where req asks. ids must not contain id.
+func moveID(ids []int64, id int64, req moveRequest) ([]int64, error) {
+	var pos int
+	switch {
+	case req.AfterID != nil:
+		idx := slices.Index(ids, *req.AfterID)
+		if idx < 0 {
+			return nil, errMoveAfterNotFound
+		}
+		pos = idx + 1
+	case req.Position != nil:
+		pos = min(max(*req.Position, 0), len(ids))
+	default:
+		return nil, errMoveNoTarget
+	}
+	return slices.Insert(ids, pos, id), nil
+}
+
+// sameCategory reports whether two nullable category ids are equal.
+func sameCategory(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// moveErrorResponse writes the error response for a failed move.
+func moveErrorResponse(w http.ResponseWriter, err error, notFound string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		jsonError(w, notFound, http.StatusNotFound)
+	case errors.Is(err, errMoveNoTarget), errors.Is(err, errMoveAfterNotFound), errors.Is(err, errMoveCategoryMissing):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error("move", "error", err)
+		jsonError(w, "failed to move", http.StatusInternalServerError)
+	}
+}
+
+// HandleMoveFeed moves a feed after another feed or to a position within a
+// category, renumbering the target category's sort orders server-side.
+func (s *Server) HandleMoveFeed(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	feedID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid feed id", http.StatusBadRequest)
+		return
+	}
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	order, err := s.moveFeed(r.Context(), userID, feedID, req)
+	if err != nil {
+		moveErrorResponse(w, err, "feed not found")
+		return
+	}
+	jsonResponse(w, map[string]any{"status": "ok", "order": order})
+}
+
+// HandleMoveFeedToCategory places a feed at a position within a category,
+// as when it is dragged there. Both fields are required; category_id 0 is
+// uncategorized. The rest of the category shifts to make room.
+func (s *Server) HandleMoveFeedToCategory(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	feedID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid feed id", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		CategoryID *int64 `json:"category_id"`
+		Position   *int   `json:"position"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.CategoryID == nil || req.Position == nil {
+		jsonError(w, "category_id and position are required", http.StatusBadRequest)
+		return
+	}
+
+	order, err := s.moveFeed(r.Context(), userID, feedID, moveRequest{CategoryID: req.CategoryID, Position: req.Position})
+	if err != nil {
+		moveErrorResponse(w, err, "feed not found")
+		return
+	}
+	jsonResponse(w, map[string]any{"status": "ok", "order": order})
+}
+
+// moveTargetCategory resolves the category a feed is moved into.
+func moveTargetCategory(ctx context.Context, q *dbgen.Queries, userID string, feeds []dbgen.Feed, moving dbgen.Feed, req moveRequest) (*int64, error) {
+	switch {
+	case req.AfterID != nil:
+		for _, f := range feeds {
+			if f.ID == *req.AfterID && f.ID != moving.ID {
+				return f.CategoryID, nil
+			}
+		}
+		return nil, errMoveAfterNotFound
+	case req.CategoryID == nil:
+		return moving.CategoryID, nil
+	case *req.CategoryID == 0:
+		return nil, nil
+	}
+	if _, err := q.GetCategory(ctx, dbgen.GetCategoryParams{ID: *req.CategoryID, UserID: userID}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errMoveCategoryMissing
+		}
+		return nil, err
+	}
+	return req.CategoryID, nil
+}
+
+// moveFeed applies a feed move in a transaction and returns the resulting
+// order of the target category.
+func (s *Server) moveFeed(ctx context.Context, userID string, feedID int64, req moveRequest) ([]orderEntry, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	q := dbgen.New(s.DB).WithTx(tx)
+
+	feeds, err := q.GetFeedsOrdered(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	idx := slices.IndexFunc(feeds, func(f dbgen.Feed) bool { return f.ID == feedID })
+	if idx < 0 {
+		return nil, sql.ErrNoRows
+	}
+	moving := feeds[idx]
+	catID, err := moveTargetCategory(ctx, q, userID, feeds, moving, req)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[int64]int64)
+	var ids []int64
+	for _, f := range feeds {
+		if f.ID != feedID && sameCategory(f.CategoryID, catID) {
+			ids = append(ids, f.ID)
+			current[f.ID] = f.SortOrder
+		}
+	}
+	if ids, err = moveID(ids, feedID, req); err != nil {
+		return nil, err
+	}
+
+	order := make([]orderEntry, 0, len(ids))
+	for i, id := range ids {
+		pos := int64(i)
+		order = append(order, orderEntry{ID: id, Order: pos, CategoryID: catID})
+		if id == feedID {
+			err = q.UpdateFeedCategory(ctx, dbgen.UpdateFeedCategoryParams{CategoryID: catID, SortOrder: pos, ID: id, UserID: userID})
+		} else if current[id] != pos {
+			err = q.UpdateFeedSortOrder(ctx, dbgen.UpdateFeedSortOrderParams{SortOrder: pos, ID: id, UserID: userID})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return order, tx.Commit()
+}
+
+// HandleMoveCategory moves a category after another category or to a
+// position, renumbering category sort orders server-side.
+func (s *Server) HandleMoveCategory(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	catID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid category id", http.StatusBadRequest)
+		return
+	}
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	order, err := s.moveCategory(r.Context(), userID, catID, req)
+	if err != nil {
+		moveErrorResponse(w, err, "category not found")
+		return
+	}
+	jsonResponse(w, map[string]any{"status": "ok", "order": order})
+}
+
+// moveCategory applies a category move in a transaction and returns the
+// resulting category order.
+func (s *Server) moveCategory(ctx context.Context, userID string, catID int64, req moveRequest) ([]orderEntry, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	q := dbgen.New(s.DB).WithTx(tx)
+
+	cats, err := q.GetCategoriesOrdered(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[int64]int64)
+	var ids []int64
+	for _, c := range cats {
+		current[c.ID] = c.SortOrder
+		if c.ID != catID {
+			ids = append(ids, c.ID)
+		}
+	}
+	if _, ok := current[catID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	if ids, err = moveID(ids, catID, req); err != nil {
+		return nil, err
+	}
+
+	order := make([]orderEntry, 0, len(ids))
+	for i, id := range ids {
+		pos := int64(i)
+		order = append(order, orderEntry{ID: id, Order: pos})
+		if current[id] == pos {
+			continue
+		}
+		if err := q.UpdateCategorySortOrder(ctx, dbgen.UpdateCategorySortOrderParams{SortOrder: pos, ID: id, UserID: userID}); err != nil {
+			return nil, err
+		}
+	}
+	return order, tx.Commit()
+}
+
+// HandleSearchArticles searches the user's articles by title, content and
+// summary, best matches first. feed_id limits it to one feed; limit and
+// offset page through the results.
 func (s *Server) HandleSearchArticles(w http.ResponseWriter, r *http.Request) {
-	userID := s.requireUser(r)
+	userID := s.userFromContext(r)
 
 	query := r.URL.Query().Get("q")
-	if query == "" {
+	if strings.TrimSpace(query) == "" {
 		jsonError(w, "query parameter 'q' is required", http.StatusBadRequest)
 		return
 	}
-
+	var feedID *int64
+	if v := r.URL.Query().Get("feed_id"); v != "" {
+		fid, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			jsonError(w, "invalid feed_id", http.StatusBadRequest)
+			return
+		}
+		feedID = &fid
+	}
 	limit, offset := parsePagination(r)
 
-	// Use the same search term for all three fields
-	searchPattern := "%" + query + "%"
-
-	q := dbgen.New(s.DB)
-	articles, err := q.SearchArticles(r.Context(), dbgen.SearchArticlesParams{
-		UserID:   userID,
-		UserID_2: userID,
-		Title:    searchPattern,
-		Content:  searchPattern,
-		Summary:  searchPattern,
-		Limit:    limit,
-		Offset:   offset,
-	})
+	articles, err := searchArticles(r.Context(), s.DB, userID, query, feedID, limit, offset)
 	if err != nil {
-		slog.Error("search articles", "error", err)
+		logFrom(r.Context()).Error("search articles", "error", err)
 		jsonError(w, "failed to search articles", http.StatusInternalServerError)
 		return
 	}
+	jsonResponse(w, articles)
+}
 
-	// Strip content/summary from list response
-	type articleSummary struct {
-		ID          int64      `json:"id"`
-		FeedID      int64      `json:"feed_id"`
-		Url         string     `json:"url"`
-		Title       string     `json:"title"`
-		Author      string     `json:"author"`
-		PublishedAt *time.Time `json:"published_at"`
-		FeedTitle   string     `json:"feed_title"`
-		FeedSiteUrl string     `json:"feed_site_url"`
-		IsRead      int64      `json:"is_read"`
-		IsStarred   int64      `json:"is_starred"`
+// searchArticles returns the user's articles matching every word of terms,
+// ranked by relevance using the articles_fts full-text index (migration 021).
+func searchArticles(ctx context.Context, db *sql.DB, userID, terms string, feedID *int64, limit, offset int64) ([]articleSummary, error) {
+	const cols = `SELECT a.id, a.feed_id, a.url, a.title, a.author, a.published_at, a.created_at,
+  f.title, f.site_url, COALESCE(s.is_read, 0), COALESCE(s.is_starred, 0), s.read_at`
+	feedFilter := ""
+	var feedArgs []any
+	if feedID != nil {
+		feedFilter = " AND a.feed_id = ?"
+		feedArgs = []any{*feedID}
+	}
+
+	ftsQuery := cols + `
+FROM articles_fts
+JOIN articles a ON a.id = articles_fts.rowid
+JOIN feeds f ON a.feed_id = f.id
+LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = f.user_id
+WHERE articles_fts MATCH ? AND f.user_id = ? AND (s.is_hidden IS NULL OR s.is_hidden = 0)` + feedFilter + `
+ORDER BY articles_fts.rank, a.id DESC
+LIMIT ? OFFSET ?`
+	args := append([]any{ftsMatchQuery(terms), userID}, feedArgs...)
+	rows, err := db.QueryContext(ctx, ftsQuery, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
 	}
-	result := make([]articleSummary, 0, len(articles))
-	for _, a := range articles {
-		result = append(result, articleSummary{
-			ID: a.ID, FeedID: a.FeedID, Url: a.Url, Title: a.Title,
-			Author: a.Author, PublishedAt: a.PublishedAt,
-			FeedTitle: a.FeedTitle, FeedSiteUrl: a.FeedSiteUrl,
-			IsRead: a.IsRead, IsStarred: a.IsStarred,
-		})
+	defer func() { _ = rows.Close() }()
+
+	articles := []articleSummary{}
+	for rows.Next() {
+		var a articleSummary
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.Url, &a.Title, &a.Author, &a.PublishedAt, &a.CreatedAt,
+			&a.FeedTitle, &a.FeedSiteUrl, &a.IsRead, &a.IsStarred, &a.ReadAt); err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
 	}
-	jsonResponse(w, result)
+	return articles, rows.Err()
+}
+
+// ftsMatchQuery turns free text into an FTS5 query matching every word, with
+// each word quoted so FTS5 operators and punctuation in it are taken literally.
+func ftsMatchQuery(terms string) string {
+	words := strings.Fields(terms)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+	}
+	return strings.Join(words, " ")
 }
@@ -0,0 +1,182 @@
+package srv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+// jsonFeedVersionPrefix starts the version URL of every JSON Feed.
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+// jsonFeed is the part of a JSON Feed (https://jsonfeed.org, 1.0 and 1.1)
+// that GoRSS stores.
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	Description string           `json:"description"`
+	Icon        string           `json:"icon"`
+	Author      *jsonFeedAuthor  `json:"author"`  // 1.0
+	Authors     []jsonFeedAuthor `json:"authors"` // 1.1
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            jsonFeedID           `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Author        *jsonFeedAuthor      `json:"author"`
+	Authors       []jsonFeedAuthor     `json:"authors"`
+	Attachments   []jsonFeedAttachment `json:"attachments"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedAttachment struct {
+	URL               string `json:"url"`
+	MimeType          string `json:"mime_type"`
+	SizeInBytes       int64  `json:"size_in_bytes"`
+	DurationInSeconds int64  `json:"duration_in_seconds"`
+}
+
+// jsonFeedID is an item id. The spec requires a string, but readers must
+// accept numbers too.
+type jsonFeedID string
+
+func (id *jsonFeedID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = jsonFeedID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("item id must be a string or number: %s", data)
+	}
+	*id = jsonFeedID(n.String())
+	return nil
+}
+
+// isJSONFeed reports whether a response is JSON rather than XML: either it
+// says so in its Content-Type or its body starts with "{".
+func isJSONFeed(contentType string, body *bufio.Reader) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == "application/feed+json" {
+		return true
+	}
+	head, _ := body.Peek(512)
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	head = bytes.TrimLeft(head, " \t\r\n")
+	return len(head) > 0 && head[0] == '{'
+}
+
+// parseFeedBody parses a feed body as JSON Feed or, for anything else, with
+// gofeed. A gofeed.Parser keeps state while parsing, so each call gets its
+// own.
+func parseFeedBody(contentType string, body io.Reader) (*gofeed.Feed, error) {
+	br := bufio.NewReader(body)
+	if isJSONFeed(contentType, br) {
+		return parseJSONFeed(br)
+	}
+	return gofeed.NewParser().Parse(br)
+}
+
+// parseJSONFeed decodes a JSON Feed into a gofeed.Feed, so it is stored
+// like any RSS or Atom feed. Plain-text content is escaped into HTML, and
+// items without an author inherit the feed's.
+func parseJSONFeed(r io.Reader) (*gofeed.Feed, error) {
+	var jf jsonFeed
+	if err := json.NewDecoder(r).Decode(&jf); err != nil {
+		return nil, fmt.Errorf("decode JSON Feed: %w", err)
+	}
+	if !strings.HasPrefix(jf.Version, jsonFeedVersionPrefix) {
+		return nil, errors.New("not a JSON Feed: missing or unknown version")
+	}
+	feedAuthor := jsonFeedAuthorName(jf.Author, jf.Authors)
+
+	feed := &gofeed.Feed{
+		Title:       jf.Title,
+		Link:        jf.HomePageURL,
+		Description: jf.Description,
+		FeedType:    "json",
+		FeedVersion: strings.TrimPrefix(jf.Version, jsonFeedVersionPrefix),
+		Items:       make([]*gofeed.Item, 0, len(jf.Items)),
+	}
+	if jf.Icon != "" {
+		feed.Image = &gofeed.Image{URL: jf.Icon}
+	}
+	for _, it := range jf.Items {
+		item := &gofeed.Item{
+			GUID:        string(it.ID),
+			Link:        it.URL,
+			Title:       it.Title,
+			Content:     it.ContentHTML,
+			Description: it.Summary,
+			Published:   it.DatePublished,
+			Updated:     it.DateModified,
+		}
+		if item.Link == "" {
+			item.Link = it.ExternalURL
+		}
+		if item.Content == "" && it.ContentText != "" {
+			item.Content = strings.ReplaceAll(html.EscapeString(it.ContentText), "\n", "<br>\n")
+		}
+		if t, err := time.Parse(time.RFC3339, it.DatePublished); err == nil {
+			item.PublishedParsed = &t
+		}
+		if t, err := time.Parse(time.RFC3339, it.DateModified); err == nil {
+			item.UpdatedParsed = &t
+		}
+		name := jsonFeedAuthorName(it.Author, it.Authors)
+		if name == "" {
+			name = feedAuthor
+		}
+		if name != "" {
+			item.Author = &gofeed.Person{Name: name}
+		}
+		for _, a := range it.Attachments {
+			item.Enclosures = append(item.Enclosures, &gofeed.Enclosure{
+				URL: a.URL, Type: a.MimeType, Length: strconv.FormatInt(a.SizeInBytes, 10),
+			})
+			// Podcast durations are stored from the iTunes extension
+			if a.DurationInSeconds > 0 && item.ITunesExt == nil {
+				item.ITunesExt = &ext.ITunesItemExtension{Duration: strconv.FormatInt(a.DurationInSeconds, 10)}
+			}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed, nil
+}
+
+// jsonFeedAuthorName returns the first author's name, from the 1.1 authors
+// list or else the 1.0 author object.
+func jsonFeedAuthorName(author *jsonFeedAuthor, authors []jsonFeedAuthor) string {
+	for _, a := range authors {
+		if a.Name != "" {
+			return a.Name
+		}
+	}
+	if author != nil {
+		return author.Name
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/johnwmail/gorss/db/dbgen"
+)
+
+// errPrivateMediaURL is returned when an enclosure, or a redirect from it,
+// points at a private or reserved address.
+var errPrivateMediaURL = errors.New("media URL points to a private or reserved address")
+
+// mediaRequestHeaders are forwarded from the player to the upstream server,
+// so seeking and revalidation work through the proxy.
+var mediaRequestHeaders = []string{"Range", "If-Range", "If-None-Match", "If-Modified-Since"}
+
+// mediaResponseHeaders are copied from the upstream response to the player.
+var mediaResponseHeaders = []string{
+	"Accept-Ranges", "Content-Length", "Content-Range", "Content-Type",
+	"ETag", "Last-Modified", "Cache-Control",
+}
+
+// checkMediaRedirect applies the private-address check to every redirect hop,
+// since podcast hosts routinely redirect through tracking and CDN domains.
+func (f *FeedFetcher) checkMediaRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !f.AllowPrivateURLs && isPrivateURL(req.URL.String()) {
+		return errPrivateMediaURL
+	}
+	return nil
+}
+
+// FetchMedia requests an enclosure for proxying, forwarding the player's
+// Range and conditional headers. There is no overall timeout, since an episode
+// can take a long time to stream; the request ends with ctx. The caller must
+// close the response body.
+func (f *FeedFetcher) FetchMedia(ctx context.Context, method, urlStr string, header http.Header) (*http.Response, error) {
+	if !f.AllowPrivateURLs && isPrivateURL(urlStr) {
+		return nil, errPrivateMediaURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+	for _, h := range mediaRequestHeaders {
+		if v := header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	resp, err := f.mediaClient.Do(req)
+	if err != nil {
+		if errors.Is(err, errPrivateMediaURL) {
+			return nil, errPrivateMediaURL
+		}
+		return nil, fmt.Errorf("fetch media: %w", err)
+	}
+	return resp, nil
+}
+
+// HandleArticleMedia proxies an article's enclosure, so players can stream
+// podcast audio without contacting the host directly. Range requests are
+// passed through, so upstream 206 responses (and seeking) work as usual.
+func (s *Server) HandleArticleMedia(w http.ResponseWriter, r *http.Request) {
+	userID := s.userFromContext(r)
+	articleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		jsonError(w, "invalid article id", http.StatusBadRequest)
+		return
+	}
+	q := dbgen.New(s.DB)
+	enclosure, err := q.GetArticleEnclosure(r.Context(), dbgen.GetArticleEnclosureParams{ID: articleID, UserID: userID})
+	if err != nil {
+		jsonError(w, "article not found", http.StatusNotFound)
+		return
+	}
+	if enclosure == nil || *enclosure == "" {
+		jsonError(w, "article has no media", http.StatusNotFound)
+		return
+	}
+
+	resp, err := s.fetcher.FetchMedia(r.Context(), r.Method, *enclosure, r.Header)
+	if errors.Is(err, errPrivateMediaURL) {
+		jsonError(w, "media URL not allowed", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		logFrom(r.Context()).Warn("proxy media", "article", articleID, "error", err)
+		jsonError(w, "failed to fetch media", http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusNotModified, http.StatusRequestedRangeNotSatisfiable:
+	default:
+		logFrom(r.Context()).Warn("proxy media", "article", articleID, "status", resp.StatusCode)
+		jsonError(w, fmt.Sprintf("media server returned %d", resp.StatusCode), http.StatusBadGateway)
+		return
+	}
+	for _, h := range mediaResponseHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		logFrom(r.Context()).Debug("proxy media interrupted", "article", articleID, "error", err)
+	}
+}
@@ -30,9 +30,29 @@ type OPMLOutline struct {
 	Type     string        `xml:"type,attr,omitempty"`
 	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
 	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	State    *OPMLState    `xml:"https://github.com/johnwmail/gorss/ns/opml-state state,omitempty"`
 	Outlines []OPMLOutline `xml:"outline,omitempty"`
 }
 
+// OPMLState is a feed's reading state, exported as a <state> element in
+// the https://github.com/johnwmail/gorss/ns/opml-state namespace inside the
+// feed's outline, where other OPML readers ignore it. Read and unread are
+// counts for reference; starred articles are listed so an import can star
+// them again.
+type OPMLState struct {
+	Read    int64         `xml:"read,attr"`
+	Unread  int64         `xml:"unread,attr"`
+	Starred []OPMLStarred `xml:"https://github.com/johnwmail/gorss/ns/opml-state starred"`
+}
+
+// OPMLStarred identifies a starred article by its GUID within the feed.
+type OPMLStarred struct {
+	GUID      string `xml:"guid,attr"`
+	URL       string `xml:"url,attr,omitempty"`
+	Title     string `xml:"title,attr,omitempty"`
+	StarredAt string `xml:"starredAt,attr,omitempty"` // RFC 3339
+}
+
 // ParseOPML parses an OPML file and returns a flat list of feed URLs
 func ParseOPML(r io.Reader) ([]FeedImport, error) {
 	data, err := io.ReadAll(r)
@@ -55,6 +75,7 @@ type FeedImport struct {
 	URL      string
 	Title    string
 	Category string
+	State    *OPMLState // reading state, if the OPML carries it
 }
 
 // extractFeeds recursively extracts feeds from OPML outlines
@@ -70,6 +91,7 @@ func extractFeeds(outlines []OPMLOutline, category string, feeds *[]FeedImport)
 				URL:      o.XMLURL,
 				Title:    title,
 				Category: category,
+				State:    o.State,
 			})
 		} else if len(o.Outlines) > 0 {
 			// This is a category/folder
@@ -113,6 +135,7 @@ func GenerateOPML(title string, feeds []FeedExport) ([]byte, error) {
 					Type:    "rss",
 					XMLURL:  f.URL,
 					HTMLURL: f.SiteURL,
+					State:   f.State,
 				})
 			}
 		} else {
@@ -128,6 +151,7 @@ func GenerateOPML(title string, feeds []FeedExport) ([]byte, error) {
 					Type:    "rss",
 					XMLURL:  f.URL,
 					HTMLURL: f.SiteURL,
+					State:   f.State,
 				})
 			}
 			opml.Body.Outlines = append(opml.Body.Outlines, catOutline)
@@ -148,4 +172,5 @@ type FeedExport struct {
 	Title    string
 	SiteURL  string
 	Category string
+	State    *OPMLState // written only when set
 }
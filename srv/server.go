@@ -4,16 +4,20 @@ import (
 	"compress/gzip"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/johnwmail/gorss/db"
@@ -21,14 +25,49 @@ import (
 )
 
 type Server struct {
-	DB           *sql.DB
-	Hostname     string
-	TemplatesDir string
-	StaticDir    string
-	Version      string            // used as cache-buster for static assets
-	PurgeDays    int               // articles older than this are filtered on fetch and purged
-	fetcher      *FeedFetcher
-	templates    map[string]*template.Template // pre-compiled templates
+	DB                    *sql.DB
+	Hostname              string
+	TemplatesDir          string
+	StaticDir             string
+	Version               string        // used as cache-buster for static assets
+	BackupOPML            bool          // also write per-user OPML exports with each backup
+	AllowInsecureTLS      bool          // permit feeds to opt out of TLS certificate verification
+	DiscoveryURL          string        // feed discovery backend for /api/feeds/discover (empty = disabled)
+	DiscoveryKey          string        // bearer token sent to the discovery backend
+	RefreshInterval       time.Duration // default feed refresh interval (per-category/feed settings override it)
+	PurgeDays             int           // articles older than this are filtered on fetch and purged
+	EmptyFeedWarnAfter    int           // consecutive empty refreshes before a feed is flagged (0 = never)
+	DNSFailDisableAfter   int           // consecutive "no such host" failures before a feed is flagged dead (0 = never)
+	FreshHours            int           // unread articles newer than this count as "fresh" (0 = disabled)
+	ReadPropagationWindow time.Duration // marking read also reads same-URL articles added within this window (0 = off)
+	MaxTitleLen           int           // stored titles are truncated to this many characters (0 = no limit)
+	MaxSummaryLen         int           // stored summaries are truncated to this many characters (0 = no limit)
+	ExcerptLen            int           // length of summaries generated from content when a feed has none (0 = off)
+	QuietHours            *quietHours   // background refresh is paused in this daily window (nil = never)
+	RefreshOnStart        bool          // refresh all feeds at startup instead of waiting for the first tick
+	StartupDelay          time.Duration // wait before the first purge and backup after startup (GORSS_STARTUP_DELAY)
+	RefreshOrder          string        // order feeds are fetched in each cycle: stale (default), active or random
+	ReadOnly              bool          // reject API writes (public demos); background jobs still run
+	DefaultUser           string        // user ID for requests that carry none (GORSS_DEFAULT_USER)
+	RequireUserID         bool          // in proxy mode, reject requests with a blank user ID instead of using DefaultUser
+	CompressContent       bool          // gzip article content at rest (GORSS_COMPRESS_CONTENT)
+	DefaultTheme          string        // theme for browsers without a saved choice: auto, light or dark (GORSS_DEFAULT_THEME)
+	DedupByURL            string        // collapse same-URL articles in lists, keeping the oldest or newest ("" = off)
+	AdminGroup            string        // proxy group required for /api/admin/ endpoints ("" = nobody in proxy mode; GORSS_ADMIN_GROUP)
+	WebhookURL            string        // POSTed a JSON summary of each refresh's new articles ("" = off; GORSS_WEBHOOK_URL)
+	UnreadOnUpdate        bool          // mark articles unread again when a refresh finds their text edited (GORSS_UNREAD_ON_UPDATE)
+	MaxImportBytes        int64         // largest OPML upload form accepted (0 = no limit; GORSS_MAX_IMPORT_MB)
+	MaxImportFeeds        int           // most feeds one OPML import processes (0 = no limit; GORSS_MAX_IMPORT_FEEDS)
+	fetcher               *FeedFetcher
+	backupMu              sync.Mutex                    // serializes database snapshots (periodic and downloaded)
+	importMu              sync.Mutex                    // serializes feed creation while imports fetch concurrently
+	jobs                  sync.WaitGroup                // background jobs, waited for at shutdown
+	jobsCtx               context.Context               // cancelled at shutdown; parent of jobs started by requests (nil = never)
+	backupStale           atomic.Bool                   // newest backup was too old at startup; cleared by the next backup
+	usersSeen             *userSeenCache                // debounces UpsertUser per request
+	countsCache           *countsCache                  // short-lived per-user /api/counts responses
+	streams               *streamLimiter                // caps concurrent streaming responses (GORSS_MAX_STREAMS)
+	templates             map[string]*template.Template // pre-compiled templates
 }
 
 func New(dbPath, hostname, version string) (*Server, error) {
@@ -38,12 +77,38 @@ func New(dbPath, hostname, version string) (*Server, error) {
 		version = "dev"
 	}
 	srv := &Server{
-		Hostname:     hostname,
-		TemplatesDir: filepath.Join(baseDir, "templates"),
-		StaticDir:    filepath.Join(baseDir, "static"),
-		Version:      version,
-		fetcher:      NewFeedFetcher(),
-		templates:    make(map[string]*template.Template),
+		Hostname:         hostname,
+		TemplatesDir:     filepath.Join(baseDir, "templates"),
+		StaticDir:        filepath.Join(baseDir, "static"),
+		Version:          version,
+		fetcher:          NewFeedFetcher(),
+		usersSeen:        newUserSeenCache(userSeenInterval()),
+		countsCache:      newCountsCache(countsCacheTTL()),
+		streams:          newStreamLimiter(maxStreamsFromEnv()),
+		AllowInsecureTLS: os.Getenv("GORSS_ALLOW_INSECURE_TLS") == "1",
+		DiscoveryURL:     os.Getenv("GORSS_DISCOVERY_URL"),
+		DiscoveryKey:     os.Getenv("GORSS_DISCOVERY_KEY"),
+		MaxTitleLen:      maxLenFromEnv("GORSS_MAX_TITLE_LEN", defaultMaxTitleLen),
+		MaxSummaryLen:    maxLenFromEnv("GORSS_MAX_SUMMARY_LEN", defaultMaxSummaryLen),
+		ExcerptLen:       maxLenFromEnv("GORSS_EXCERPT_LEN", defaultExcerptLen),
+		RefreshOnStart:   refreshOnStartFromEnv(),
+		StartupDelay:     startupDelayFromEnv(),
+		RefreshOrder:     refreshOrderFromEnv(),
+		ReadOnly:         os.Getenv("GORSS_READONLY") == "1",
+		DefaultUser:      defaultUserFromEnv(),
+		RequireUserID:    os.Getenv("GORSS_REQUIRE_USER_ID") == "1",
+		CompressContent:  os.Getenv("GORSS_COMPRESS_CONTENT") == "1",
+		DefaultTheme:     defaultThemeFromEnv(),
+		DedupByURL:       dedupByURLFromEnv(),
+		AdminGroup:       strings.TrimSpace(os.Getenv("GORSS_ADMIN_GROUP")),
+		WebhookURL:       strings.TrimSpace(os.Getenv("GORSS_WEBHOOK_URL")),
+		UnreadOnUpdate:   os.Getenv("GORSS_UNREAD_ON_UPDATE") == "1",
+		MaxImportBytes:   int64(maxLenFromEnv("GORSS_MAX_IMPORT_MB", defaultMaxImportMB)) << 20,
+		MaxImportFeeds:   maxLenFromEnv("GORSS_MAX_IMPORT_FEEDS", defaultMaxImportFeeds),
+		templates:        make(map[string]*template.Template),
+	}
+	if err := checkAssetDirs(srv.TemplatesDir, srv.StaticDir); err != nil {
+		return nil, err
 	}
 	if err := srv.setUpDatabase(dbPath); err != nil {
 		return nil, err
@@ -54,6 +119,138 @@ func New(dbPath, hostname, version string) (*Server, error) {
 	return srv, nil
 }
 
+// checkAssetDirs verifies the templates and static directories exist, so a
+// binary moved away from its assets fails at startup instead of serving
+// blank pages.
+func checkAssetDirs(templatesDir, staticDir string) error {
+	for _, d := range []struct{ kind, path string }{
+		{"templates", templatesDir},
+		{"static", staticDir},
+	} {
+		info, err := os.Stat(d.path)
+		if err != nil {
+			return fmt.Errorf("%s directory %q not found (assets are loaded relative to the source tree): %w", d.kind, d.path, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s path %q is not a directory", d.kind, d.path)
+		}
+	}
+	return nil
+}
+
+// userSeenInterval returns how often a user's last_seen is written, from
+// GORSS_USER_SEEN_INTERVAL (default 5 minutes, 0 to write on every request).
+func userSeenInterval() time.Duration {
+	interval := 5 * time.Minute
+	if env := os.Getenv("GORSS_USER_SEEN_INTERVAL"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed >= 0 {
+			interval = parsed
+		} else {
+			slog.Warn("invalid GORSS_USER_SEEN_INTERVAL, using default", "value", env)
+		}
+	}
+	return interval
+}
+
+// defaultStartupDelay lets the server settle before the first purge and
+// backup run.
+const defaultStartupDelay = 30 * time.Second
+
+// startupDelayFromEnv parses GORSS_STARTUP_DELAY, e.g. 0 for tests or 5s in
+// development (default 30s).
+func startupDelayFromEnv() time.Duration {
+	env := os.Getenv("GORSS_STARTUP_DELAY")
+	if env == "" {
+		return defaultStartupDelay
+	}
+	delay, err := time.ParseDuration(env)
+	if err != nil || delay < 0 {
+		slog.Warn("invalid GORSS_STARTUP_DELAY, using default", "value", env, "default", defaultStartupDelay)
+		return defaultStartupDelay
+	}
+	return delay
+}
+
+// countsCacheTTL returns how long /api/counts responses are cached per user,
+// from GORSS_COUNTS_CACHE_TTL (default 0, no caching).
+func countsCacheTTL() time.Duration {
+	env := os.Getenv("GORSS_COUNTS_CACHE_TTL")
+	if env == "" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(env)
+	if err != nil || ttl < 0 {
+		slog.Warn("invalid GORSS_COUNTS_CACHE_TTL, not caching counts", "value", env)
+		return 0
+	}
+	return ttl
+}
+
+// maxStreamsFromEnv returns the overall and per-user caps on concurrent
+// streaming responses from GORSS_MAX_STREAMS and GORSS_MAX_STREAMS_PER_USER.
+// Both default to 0 (unlimited); with only the overall cap set, one user
+// may hold up to a quarter of it.
+func maxStreamsFromEnv() (int, int) {
+	total := maxLenFromEnv("GORSS_MAX_STREAMS", 0)
+	perUser := 0
+	if total > 0 {
+		perUser = (total + 3) / 4
+	}
+	return total, maxLenFromEnv("GORSS_MAX_STREAMS_PER_USER", perUser)
+}
+
+// defaultUserID is the user requests fall back to when they carry no user
+// ID, e.g. everyone in the none and password auth modes.
+const defaultUserID = "anonymous"
+
+// defaultUserFromEnv returns GORSS_DEFAULT_USER, or defaultUserID if unset.
+func defaultUserFromEnv() string {
+	if v := strings.TrimSpace(os.Getenv("GORSS_DEFAULT_USER")); v != "" {
+		return v
+	}
+	return defaultUserID
+}
+
+// refreshOnStartFromEnv parses GORSS_REFRESH_ON_START (default true).
+func refreshOnStartFromEnv() bool {
+	v := os.Getenv("GORSS_REFRESH_ON_START")
+	if v == "" {
+		return true
+	}
+	on, err := strconv.ParseBool(v)
+	if err != nil {
+		slog.Warn("invalid GORSS_REFRESH_ON_START, refreshing on start", "value", v)
+		return true
+	}
+	return on
+}
+
+// defaultThemeFromEnv parses GORSS_DEFAULT_THEME (auto, light or dark;
+// default auto).
+func defaultThemeFromEnv() string {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv("GORSS_DEFAULT_THEME")))
+	switch v {
+	case "":
+		return "auto"
+	case "auto", "light", "dark":
+		return v
+	}
+	slog.Warn("invalid GORSS_DEFAULT_THEME, using auto", "value", v)
+	return "auto"
+}
+
+// dedupByURLFromEnv parses GORSS_DEDUP_BY_URL (oldest or newest; default
+// off).
+func dedupByURLFromEnv() string {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv("GORSS_DEDUP_BY_URL")))
+	switch v {
+	case "", dedupKeepOldest, dedupKeepNewest:
+		return v
+	}
+	slog.Warn("invalid GORSS_DEDUP_BY_URL, not deduplicating", "value", v)
+	return ""
+}
+
 // setUpDatabase initializes the database connection and runs migrations
 func (s *Server) setUpDatabase(dbPath string) error {
 	// Support env var override
@@ -127,40 +324,82 @@ func (s *Server) Serve(port string) error {
 		http.ServeFile(w, r, filepath.Join(s.StaticDir, "favicon-180.png"))
 	})
 
+	// PWA manifest and service worker (must be served from the root scope)
+	mux.HandleFunc("GET /manifest.webmanifest", s.HandleManifest)
+	mux.HandleFunc("GET /sw.js", s.HandleServiceWorker)
+
 	// Health check
 	mux.HandleFunc("GET /health", s.HandleHealth)
 
 	// API routes
 	mux.HandleFunc("GET /api/feeds", s.HandleGetFeeds)
 	mux.HandleFunc("POST /api/feeds", s.HandleSubscribe)
+	mux.HandleFunc("POST /api/feeds/bulk-subscribe", s.HandleBulkSubscribe)
+	mux.HandleFunc("GET /api/feeds/discover", s.HandleDiscoverFeeds)
+	mux.HandleFunc("GET /api/feeds/stale", s.HandleGetStaleFeeds)
 	mux.HandleFunc("PUT /api/feeds/{id}", s.HandleUpdateFeed)
 	mux.HandleFunc("DELETE /api/feeds/{id}", s.HandleUnsubscribe)
+	mux.HandleFunc("DELETE /api/feeds/{id}/articles", s.HandlePurgeFeedArticles)
+	mux.HandleFunc("GET /api/feeds/{id}/raw", s.HandleGetFeedRaw)
+	mux.HandleFunc("GET /api/feeds/{id}/atom", s.HandleFeedAtom)
+	mux.HandleFunc("GET /api/feeds/{id}/content", s.limitStream(s.HandleGetFeedContent))
+	mux.HandleFunc("/fever/", s.HandleFever) // Fever API for mobile clients; own api_key auth
+	mux.HandleFunc("GET /api/feed-token", s.HandleGetFeedToken)
+	mux.HandleFunc("POST /api/feed-token", s.HandleRotateFeedToken)
+	mux.HandleFunc("POST /api/fever-password", s.HandleSetFeverPassword)
+	mux.HandleFunc("DELETE /api/fever-password", s.HandleDeleteFeverPassword)
+	mux.HandleFunc("GET /api/prefs", s.HandleGetPrefs)
+	mux.HandleFunc("PUT /api/prefs", s.HandleUpdatePrefs)
 
 	mux.HandleFunc("GET /api/articles", s.HandleGetArticles)
-	mux.HandleFunc("GET /api/articles/search", s.HandleSearchArticles)
+	mux.HandleFunc("GET /api/articles/search", s.HandleSearchArticles) // Alias for JS client
+	mux.HandleFunc("GET /api/search", s.HandleSearchArticles)
+	mux.HandleFunc("GET /api/articles/oldest-unread", s.HandleOldestUnread)
+	mux.HandleFunc("GET /api/articles/recent", s.HandleRecentArticles)
+	mux.HandleFunc("GET /api/digest", s.HandleDigest)
+	mux.HandleFunc("GET /api/history", s.HandleReadHistory)
 	mux.HandleFunc("GET /api/articles/{id}", s.HandleGetArticle)
+	mux.HandleFunc("GET /api/articles/{id}/media", s.limitStream(s.HandleArticleMedia))
 	mux.HandleFunc("POST /api/articles/{id}/read", s.HandleMarkRead)
 	mux.HandleFunc("POST /api/articles/{id}/unread", s.HandleMarkUnread)
 	mux.HandleFunc("POST /api/articles/{id}/star", s.HandleStar)
 	mux.HandleFunc("POST /api/articles/{id}/unstar", s.HandleUnstar)
+	mux.HandleFunc("POST /api/articles/{id}/hide", s.HandleHide)
+	mux.HandleFunc("POST /api/articles/{id}/unhide", s.HandleUnhide)
 
 	mux.HandleFunc("POST /api/feeds/{id}/mark-read", s.HandleMarkFeedRead)
+	mux.HandleFunc("POST /api/feeds/mark-read-batch", s.HandleMarkFeedsReadBatch)
 	mux.HandleFunc("POST /api/refresh", s.HandleRefresh)
 	mux.HandleFunc("POST /api/feeds/refresh", s.HandleRefresh) // Alias for JS client
 
 	mux.HandleFunc("POST /api/articles/mark-read-batch", s.HandleMarkReadBatch)
 	mux.HandleFunc("POST /api/articles/mark-all-read", s.HandleMarkAllRead)
+	mux.HandleFunc("POST /api/articles/mark-all-unread", s.HandleMarkAllUnread)
+	mux.HandleFunc("POST /api/mark-all-unread", s.HandleMarkAllUnread)
+	mux.HandleFunc("POST /api/catch-up", s.HandleCatchUp)
+	mux.HandleFunc("POST /api/undo/{token}", s.HandleUndoRead)
 
 	mux.HandleFunc("GET /api/categories", s.HandleGetCategories)
 	mux.HandleFunc("POST /api/categories", s.HandleCreateCategory)
 	mux.HandleFunc("PUT /api/categories/reorder", s.HandleReorderCategories)
+	mux.HandleFunc("PUT /api/categories/{id}", s.HandleUpdateCategory)
+	mux.HandleFunc("DELETE /api/categories/{id}", s.HandleDeleteCategory)
 	mux.HandleFunc("PUT /api/feeds/reorder", s.HandleReorderFeeds)
+	mux.HandleFunc("POST /api/categories/{id}/move", s.HandleMoveCategory)
+	mux.HandleFunc("POST /api/feeds/{id}/move", s.HandleMoveFeed)
+	mux.HandleFunc("PUT /api/feeds/{id}/move-to-category", s.HandleMoveFeedToCategory)
 
 	// OPML import/export
 	mux.HandleFunc("GET /api/opml/export", s.HandleExportOPML)
 	mux.HandleFunc("POST /api/opml/import", s.HandleImportOPML)
+	mux.HandleFunc("POST /api/opml/preview", s.HandlePreviewOPML)
 
 	mux.HandleFunc("GET /api/counts", s.HandleGetCounts)
+	mux.HandleFunc("POST /api/admin/reprocess-content", s.HandleReprocessContent)
+	mux.HandleFunc("GET /api/admin/backup/download", s.HandleDownloadBackup)
+	mux.HandleFunc("GET /api/admin/integrity-check", s.HandleIntegrityCheck)
+	mux.HandleFunc("POST /api/admin/repair", s.HandleRepair)
+	mux.HandleFunc("POST /api/admin/migrate-user", s.HandleMigrateUser)
 
 	// Start background feed refresh
 	refreshInterval := 1 * time.Hour // default 1 hour
@@ -172,17 +411,16 @@ func (s *Server) Serve(port string) error {
 		}
 	}
 
-	// Parse purge days setting (default 30 days, 0 to disable)
-	s.PurgeDays = 30
-	if envPurge := os.Getenv("GORSS_PURGE_DAYS"); envPurge != "" {
-		if parsed, err := strconv.Atoi(envPurge); err == nil {
-			s.PurgeDays = parsed
-		}
-	}
+	s.loadArticleSettings()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// SIGINT/SIGTERM cancel ctx, which stops the background jobs and
+	// starts a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s.jobsCtx = ctx
 
+	s.RefreshInterval = refreshInterval
+	s.QuietHours = quietHoursFromEnv()
 	slog.Info("starting background feed refresh", "interval", refreshInterval)
 	s.StartBackgroundRefresh(ctx, refreshInterval)
 
@@ -190,8 +428,7 @@ func (s *Server) Serve(port string) error {
 	slog.Info("starting auto-purge for old read articles", "days", s.PurgeDays)
 	s.StartAutoPurge(ctx)
 
-	// Also do an initial refresh on startup
-	go s.refreshAllFeeds(ctx)
+	s.jobs.Go(func() { s.refreshOnStart(ctx) })
 
 	// Start periodic backup if configured
 	if backupDir := os.Getenv("GORSS_BACKUP_DIR"); backupDir != "" {
@@ -207,16 +444,156 @@ func (s *Server) Serve(port string) error {
 				backupInterval = parsed
 			}
 		}
-		slog.Info("starting periodic database backup", "dir", backupDir, "interval", backupInterval, "keep", backupKeep)
+		s.BackupOPML = os.Getenv("GORSS_BACKUP_OPML") == "1"
+		slog.Info("starting periodic database backup", "dir", backupDir, "interval", backupInterval, "keep", backupKeep, "opml", s.BackupOPML)
+		s.checkBackupAge(backupDir, backupInterval)
 		s.StartPeriodicBackup(ctx, backupDir, backupInterval, backupKeep)
 	}
 
 	// Apply auth middleware
 	authMode := GetAuthMode()
-	slog.Info("starting server", "addr", addr, "auth_mode", authMode)
+	slog.Info("starting server", "addr", addr, "auth_mode", authMode, "read_only", s.ReadOnly)
+
+	handler := requestIDMiddleware(gzipMiddleware(s.AuthMiddleware(s.adminMiddleware(s.readOnlyMiddleware(s.countsCacheMiddleware(cspMiddleware(mux)))))))
+	httpServer := &http.Server{Addr: addr, Handler: handler}
+	listenErr := make(chan error, 1)
+	go func() { listenErr <- httpServer.ListenAndServe() }()
+	select {
+	case err := <-listenErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+	return s.shutdown(httpServer)
+}
+
+// shutdownTimeout bounds how long shutdown waits for in-flight requests
+// and background jobs before closing the database anyway.
+const shutdownTimeout = 15 * time.Second
+
+// shutdown stops accepting requests, waits for in-flight ones and for
+// background jobs (already cancelled) to finish, then closes the database.
+func (s *Server) shutdown(httpServer *http.Server) error {
+	slog.Info("shutting down", "timeout", shutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		slog.Warn("http server shutdown", "error", err)
+	}
 
-	handler := gzipMiddleware(s.AuthMiddleware(cspMiddleware(mux)))
-	return http.ListenAndServe(addr, handler)
+	done := make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Warn("background jobs still running at shutdown")
+	}
+	return s.Close()
+}
+
+// Close checkpoints the WAL into the main database file, so the file is
+// complete on its own, and closes the database.
+func (s *Server) Close() error {
+	if _, err := s.DB.Exec("PRAGMA wal_checkpoint(TRUNCATE);"); err != nil {
+		slog.Warn("wal checkpoint", "error", err)
+	}
+	if err := s.DB.Close(); err != nil {
+		return fmt.Errorf("close db: %w", err)
+	}
+	slog.Info("database closed")
+	return nil
+}
+
+// loadArticleSettings reads the article retention and tracking settings
+// from the environment.
+func (s *Server) loadArticleSettings() {
+	// Parse purge days setting (default 30 days, 0 to disable)
+	s.PurgeDays = 30
+	if envPurge := os.Getenv("GORSS_PURGE_DAYS"); envPurge != "" {
+		if parsed, err := strconv.Atoi(envPurge); err == nil {
+			s.PurgeDays = parsed
+		}
+	}
+
+	// Parse empty-feed warning threshold (default 5 refreshes, 0 to disable)
+	s.EmptyFeedWarnAfter = 5
+	if envEmpty := os.Getenv("GORSS_EMPTY_FEED_WARN_AFTER"); envEmpty != "" {
+		if parsed, err := strconv.Atoi(envEmpty); err == nil && parsed >= 0 {
+			s.EmptyFeedWarnAfter = parsed
+		}
+	}
+
+	// Parse dead-feed threshold for unresolvable hosts (default 5, 0 to disable)
+	s.DNSFailDisableAfter = 5
+	if envDNS := os.Getenv("GORSS_DNS_FAIL_DISABLE_AFTER"); envDNS != "" {
+		if parsed, err := strconv.Atoi(envDNS); err == nil && parsed >= 0 {
+			s.DNSFailDisableAfter = parsed
+		}
+	}
+
+	// Parse read propagation window (default off)
+	if envProp := os.Getenv("GORSS_READ_PROPAGATION_WINDOW"); envProp != "" {
+		if parsed, err := time.ParseDuration(envProp); err == nil && parsed >= 0 {
+			s.ReadPropagationWindow = parsed
+		} else {
+			slog.Warn("invalid GORSS_READ_PROPAGATION_WINDOW, disabling", "value", envProp)
+		}
+	}
+
+	// Parse fresh window for the "fresh" count (default 24 hours, 0 to disable)
+	s.FreshHours = 24
+	if envFresh := os.Getenv("GORSS_FRESH_HOURS"); envFresh != "" {
+		if parsed, err := strconv.Atoi(envFresh); err == nil && parsed >= 0 {
+			s.FreshHours = parsed
+		}
+	}
+}
+
+// readOnlyMiddleware rejects API requests that would change data when
+// GORSS_READONLY=1, so a public demo can be browsed but not modified. Fever
+// clients POST even to read, so only their mark requests count as writes.
+func (s *Server) readOnlyMiddleware(next http.Handler) http.Handler {
+	if !s.ReadOnly {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isWriteRequest(r) {
+			jsonError(w, "read-only mode", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// countsCacheMiddleware drops the user's cached counts after any write
+// request, so badges reflect marking read, starring or subscribing at once.
+// Fever requests authenticate their own user by API key rather than through
+// the request context, so HandleFever invalidates for them.
+func (s *Server) countsCacheMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if isWriteRequest(r) && !isFeverPath(r.URL.Path) {
+			s.countsCache.invalidate(s.userFromContext(r))
+		}
+	})
+}
+
+// isWriteRequest reports whether r is an API call that may modify data.
+func isWriteRequest(r *http.Request) bool {
+	if isFeverPath(r.URL.Path) {
+		return r.FormValue("mark") != ""
+	}
+	if !strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/api/opml/preview" {
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
 }
 
 func cspMiddleware(next http.Handler) http.Handler {
@@ -240,7 +617,9 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) { return w.gz.Write(b)
 
 func gzipMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		// Proxied media is already compressed, and byte ranges must refer to
+		// the body as the upstream server sent it
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || strings.HasSuffix(r.URL.Path, "/media") {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -257,22 +636,8 @@ func gzipMiddleware(next http.Handler) http.Handler {
 
 // HandleRoot serves the unified responsive application page
 func (s *Server) HandleRoot(w http.ResponseWriter, r *http.Request) {
-	userID := strings.TrimSpace(r.Header.Get("X-ExeDev-UserID"))
-	userEmail := strings.TrimSpace(r.Header.Get("X-ExeDev-Email"))
-	now := time.Now()
-
-	if userID == "" {
-		userID = "anonymous"
-	}
-
-	// Ensure user exists
+	userID := s.userFromContext(r)
 	q := dbgen.New(s.DB)
-	_ = q.UpsertUser(r.Context(), dbgen.UpsertUserParams{
-		ID:        userID,
-		Email:     &userEmail,
-		CreatedAt: now,
-		LastSeen:  now,
-	})
 
 	// Get counts
 	unreadCount, _ := q.GetUnreadCount(r.Context(), userID)
@@ -288,13 +653,14 @@ func (s *Server) HandleRoot(w http.ResponseWriter, r *http.Request) {
 	data := map[string]any{
 		"Version":      s.Version,
 		"Hostname":     s.Hostname,
-		"UserEmail":    userEmail,
+		"UserEmail":    getUserEmail(r),
 		"UserID":       userID,
 		"UnreadCount":  unreadCount,
 		"StarredCount": starredCount,
 		"Feeds":        feeds,
 		"Categories":   categories,
 		"AuthMode":     string(authMode),
+		"DefaultTheme": s.DefaultTheme,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -303,6 +669,37 @@ func (s *Server) HandleRoot(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleManifest serves the web app manifest for add-to-homescreen installs
+func (s *Server) HandleManifest(w http.ResponseWriter, r *http.Request) {
+	manifest := map[string]any{
+		"name":             "GoRSS",
+		"short_name":       "GoRSS",
+		"description":      "Self-hosted RSS/Atom feed reader",
+		"start_url":        "/",
+		"scope":            "/",
+		"display":          "standalone",
+		"background_color": "#f5f5f5",
+		"theme_color":      "#1a73e8",
+		"icons": []map[string]string{
+			{"src": "/static/favicon-180.png", "sizes": "180x180", "type": "image/png"},
+			{"src": "/static/favicon.svg", "sizes": "any", "type": "image/svg+xml"},
+		},
+	}
+	w.Header().Set("Content-Type", "application/manifest+json")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	_ = json.NewEncoder(w).Encode(manifest)
+}
+
+// HandleServiceWorker serves the service worker script at the root so its
+// scope covers the whole app. It must not be cached long-term, otherwise
+// browsers keep running a stale worker after upgrades.
+func (s *Server) HandleServiceWorker(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Service-Worker-Allowed", "/")
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeFile(w, r, filepath.Join(s.StaticDir, "sw.js"))
+}
+
 func (s *Server) renderTemplate(w http.ResponseWriter, name string, data any) error {
 	tmpl, ok := s.templates[name]
 	if !ok {
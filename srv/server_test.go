@@ -1,17 +1,28 @@
 package srv
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"net/http/httptest"
+	"net/url"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/johnwmail/gorss/db"
 	"github.com/johnwmail/gorss/db/dbgen"
 )
 
@@ -100,6 +111,93 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+func TestStaleBackupWarning(t *testing.T) {
+	s := newTestServer(t)
+	dir := t.TempDir()
+
+	health := func() map[string]string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleHealth(w, httptest.NewRequest("GET", "/health", nil))
+		assertStatus(t, w, 200)
+		var m map[string]string
+		decodeJSON(t, w, &m)
+		return m
+	}
+
+	// A fresh install with no backups yet isn't flagged
+	s.checkBackupAge(dir, 24*time.Hour)
+	if m := health(); m["status"] != "ok" {
+		t.Fatalf("empty backup dir: health = %v", m)
+	}
+
+	path, err := db.Backup(s.DB, dir)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	old := filepath.Join(dir, "gorss-"+time.Now().AddDate(0, 0, -10).Format("2006-01-02-150405")+".db")
+	if err := os.Rename(path, old); err != nil {
+		t.Fatal(err)
+	}
+
+	s.checkBackupAge(dir, 7*24*time.Hour)
+	if m := health(); m["status"] != "ok" {
+		t.Errorf("10-day-old backup with weekly interval: health = %v", m)
+	}
+
+	s.checkBackupAge(dir, 24*time.Hour)
+	if m := health(); m["status"] != "degraded" || m["backup"] != "stale" {
+		t.Errorf("10-day-old backup with daily interval: health = %v, want degraded", m)
+	}
+
+	// The next successful backup clears it
+	s.runBackup(dir, 7)
+	if m := health(); m["status"] != "ok" {
+		t.Errorf("after backup: health = %v", m)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.sqlite3")
+	s, err := New(dbPath, "test-host", "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	seedFeed(t, s, "kept", nil, 2)
+
+	// Shutdown waits for running background jobs before closing the DB
+	release := make(chan struct{})
+	var jobDone atomic.Bool
+	s.jobs.Go(func() {
+		<-release
+		jobDone.Store(true)
+	})
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+	if err := s.shutdown(&http.Server{}); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if !jobDone.Load() {
+		t.Error("shutdown returned before the background job finished")
+	}
+	if err := s.DB.Ping(); err == nil {
+		t.Error("database still open after shutdown")
+	}
+
+	// The WAL was checkpointed into the main file
+	if info, err := os.Stat(dbPath + "-wal"); err == nil && info.Size() > 0 {
+		t.Errorf("WAL has %d bytes after shutdown, want 0", info.Size())
+	}
+	reopened, err := db.Open(dbPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close() //nolint:errcheck
+	var n int
+	if err := reopened.QueryRow("SELECT COUNT(*) FROM articles").Scan(&n); err != nil || n != 2 {
+		t.Errorf("articles after reopen = %d (%v), want 2", n, err)
+	}
+}
+
 func TestRootPage(t *testing.T) {
 	s := newTestServer(t)
 
@@ -120,6 +218,85 @@ func TestRootPage(t *testing.T) {
 	})
 }
 
+func TestDefaultTheme(t *testing.T) {
+	t.Setenv("GORSS_DEFAULT_THEME", "dark")
+	s := newTestServer(t)
+
+	w := httptest.NewRecorder()
+	s.HandleRoot(w, authReq("GET", "/", ""))
+	assertStatus(t, w, 200)
+	if !strings.Contains(w.Body.String(), `data-default-theme="dark"`) {
+		t.Error("app page lacks the default theme")
+	}
+
+	w = httptest.NewRecorder()
+	s.HandleLogin(w, httptest.NewRequest("GET", "/login", nil))
+	assertStatus(t, w, 200)
+	if body := w.Body.String(); !strings.Contains(body, `data-default-theme="dark" data-theme="dark"`) {
+		t.Error("login page lacks the default theme")
+	}
+
+	t.Setenv("GORSS_DEFAULT_THEME", "sepia")
+	if got := defaultThemeFromEnv(); got != "auto" {
+		t.Errorf("invalid theme: got %q, want auto", got)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	s := newTestServer(t)
+	w := httptest.NewRecorder()
+	s.HandleManifest(w, httptest.NewRequest("GET", "/manifest.webmanifest", nil))
+	assertStatus(t, w, 200)
+	if ct := w.Header().Get("Content-Type"); ct != "application/manifest+json" {
+		t.Errorf("Content-Type = %q, want application/manifest+json", ct)
+	}
+	var m map[string]any
+	decodeJSON(t, w, &m)
+	if m["start_url"] != "/" || m["display"] != "standalone" {
+		t.Errorf("unexpected manifest: %v", m)
+	}
+}
+
+func TestServiceWorker(t *testing.T) {
+	s := newTestServer(t)
+	w := httptest.NewRecorder()
+	s.HandleServiceWorker(w, httptest.NewRequest("GET", "/sw.js", nil))
+	assertStatus(t, w, 200)
+	if got := w.Header().Get("Service-Worker-Allowed"); got != "/" {
+		t.Errorf("Service-Worker-Allowed = %q, want /", got)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+		t.Errorf("Content-Type = %q, want text/javascript", ct)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", cc)
+	}
+	if !strings.Contains(w.Body.String(), "addEventListener") {
+		t.Error("expected service worker script body")
+	}
+}
+
+func TestCheckAssetDirs(t *testing.T) {
+	s := newTestServer(t)
+	if err := checkAssetDirs(s.TemplatesDir, s.StaticDir); err != nil {
+		t.Fatalf("bundled asset dirs: %v", err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "templates")
+	err := checkAssetDirs(missing, s.StaticDir)
+	if err == nil || !strings.Contains(err.Error(), "templates directory") || !strings.Contains(err.Error(), missing) {
+		t.Errorf("missing templates dir: err = %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "static")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkAssetDirs(s.TemplatesDir, file); err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Errorf("static path is a file: err = %v", err)
+	}
+}
+
 // --------------- Categories ---------------
 
 func TestCategories(t *testing.T) {
@@ -172,6 +349,58 @@ func TestCategories(t *testing.T) {
 			t.Errorf("expected 1 category, got %d", len(cats))
 		}
 	})
+
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	cats, _ := q.GetCategories(ctx, "testuser")
+	catStr := fmt.Sprint(cats[0].ID)
+	send := func(method, body, userID string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := authReq(method, "/api/categories/"+catStr, body)
+		r.Header.Set("X-ExeDev-UserID", userID)
+		r.SetPathValue("id", catStr)
+		if method == "PUT" {
+			s.HandleUpdateCategory(w, r)
+		} else {
+			s.HandleDeleteCategory(w, r)
+		}
+		return w
+	}
+
+	t.Run("rename", func(t *testing.T) {
+		assertStatus(t, send("PUT", `{"title":" Science "}`, "testuser"), 200)
+		cat, _ := q.GetCategory(ctx, dbgen.GetCategoryParams{ID: cats[0].ID, UserID: "testuser"})
+		if cat.Title != "Science" {
+			t.Errorf("title = %q, want Science", cat.Title)
+		}
+	})
+
+	t.Run("rename empty title", func(t *testing.T) {
+		assertStatus(t, send("PUT", `{"title":"  "}`, "testuser"), 400)
+	})
+
+	t.Run("other user's category", func(t *testing.T) {
+		assertStatus(t, send("PUT", `{"title":"Mine"}`, "someone-else"), 404)
+		assertStatus(t, send("DELETE", "", "someone-else"), 404)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		feed := seedFeed(t, s, "filed", &cats[0].ID, 0)
+		assertStatus(t, send("DELETE", "", "testuser"), 200)
+		if _, err := q.GetCategory(ctx, dbgen.GetCategoryParams{ID: cats[0].ID, UserID: "testuser"}); err == nil {
+			t.Error("category still exists after delete")
+		}
+		// The feed is kept, uncategorized
+		got, err := q.GetFeed(ctx, dbgen.GetFeedParams{ID: feed.ID, UserID: "testuser"})
+		if err != nil {
+			t.Fatalf("feed deleted with its category: %v", err)
+		}
+		if got.CategoryID != nil {
+			t.Errorf("category_id = %d after delete, want nil", *got.CategoryID)
+		}
+		assertStatus(t, send("DELETE", "", "testuser"), 404)
+	})
 }
 
 // --------------- Feeds ---------------
@@ -230,7 +459,113 @@ func TestFeeds(t *testing.T) {
 	})
 }
 
+func TestSubscribeArticleCount(t *testing.T) {
+	old := time.Now().AddDate(0, 0, -60).Format(time.RFC1123Z)
+	recent := time.Now().Add(-time.Hour).Format(time.RFC1123Z)
+	feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>F</title>
+<item><guid>a</guid><title>A</title><pubDate>%[1]s</pubDate></item>
+<item><guid>a</guid><title>A again</title><pubDate>%[1]s</pubDate></item>
+<item><guid>b</guid><title>B</title><pubDate>%[1]s</pubDate></item>
+<item><guid>old</guid><title>Old</title><pubDate>%[2]s</pubDate></item>
+</channel></rss>`, recent, old)
+	}))
+	defer feedSrv.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	s.PurgeDays = 30
+
+	w := httptest.NewRecorder()
+	s.HandleSubscribe(w, authReq("POST", "/api/feeds", `{"url":"`+feedSrv.URL+`"}`))
+	assertStatus(t, w, 200)
+	var resp struct {
+		ID       int64  `json:"id"`
+		Title    string `json:"title"`
+		Articles int    `json:"articles"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.ID == 0 || resp.Title != "F" {
+		t.Errorf("feed fields missing from response: %+v", resp)
+	}
+	// The old item is filtered and the repeated GUID counts once
+	if resp.Articles != 2 {
+		t.Errorf("articles = %d, want 2", resp.Articles)
+	}
+}
+
+func TestPurgeFeedArticles(t *testing.T) {
+	s := newTestServer(t)
+	feed := seedFeed(t, s, "noisy", nil, 3)
+	other := seedFeed(t, s, "calm", nil, 2)
+	ctx := context.Background()
+	fidStr := fmt.Sprint(feed.ID)
+	_, _ = s.DB.ExecContext(ctx, "UPDATE feeds SET etag = 'x' WHERE id = ?", feed.ID)
+
+	list, _ := queryArticles(ctx, s.DB, "testuser", articleQueryOpts{FeedID: &feed.ID, Limit: 10})
+	for i, handle := range []http.HandlerFunc{s.HandleStar, s.HandleMarkRead} {
+		id := fmt.Sprint(list[i].ID)
+		r := authReq("POST", "/api/articles/"+id, "")
+		r.SetPathValue("id", id)
+		handle(httptest.NewRecorder(), r)
+	}
+
+	purge := func(query string) int64 {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := authReq("DELETE", "/api/feeds/"+fidStr+"/articles"+query, "")
+		r.SetPathValue("id", fidStr)
+		s.HandlePurgeFeedArticles(w, r)
+		assertStatus(t, w, 200)
+		var resp struct {
+			Deleted int64 `json:"deleted"`
+		}
+		decodeJSON(t, w, &resp)
+		return resp.Deleted
+	}
+	count := func(query string, args ...any) int {
+		t.Helper()
+		var n int
+		if err := s.DB.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if n := purge("?keep_starred=1"); n != 2 {
+		t.Errorf("keep_starred deleted %d, want 2", n)
+	}
+	if n := count("SELECT COUNT(*) FROM articles WHERE feed_id = ?", feed.ID); n != 1 {
+		t.Errorf("%d articles left, want the starred one", n)
+	}
+	if n := count("SELECT COUNT(*) FROM article_states WHERE is_read = 1"); n != 0 {
+		t.Errorf("%d read states left for purged articles", n)
+	}
+
+	if n := purge(""); n != 1 {
+		t.Errorf("second purge deleted %d, want 1", n)
+	}
+	if n := count("SELECT COUNT(*) FROM article_states"); n != 0 {
+		t.Errorf("%d article states left, want 0", n)
+	}
+	if n := count("SELECT COUNT(*) FROM articles WHERE feed_id = ?", other.ID); n != 2 {
+		t.Errorf("other feed has %d articles, want 2", n)
+	}
+	f, err := dbgen.New(s.DB).GetFeed(ctx, dbgen.GetFeedParams{ID: feed.ID, UserID: "testuser"})
+	if err != nil {
+		t.Fatalf("feed was removed: %v", err)
+	}
+	if f.Etag != "" {
+		t.Errorf("etag = %q, want cleared", f.Etag)
+	}
 
+	w := httptest.NewRecorder()
+	r := authReq("DELETE", "/api/feeds/9999/articles", "")
+	r.SetPathValue("id", "9999")
+	s.HandlePurgeFeedArticles(w, r)
+	assertStatus(t, w, 404)
+}
 
 // --------------- Articles & State ---------------
 
@@ -402,6 +737,175 @@ func TestArticles(t *testing.T) {
 	})
 }
 
+func TestReadView(t *testing.T) {
+	s := newTestServer(t)
+	feed := seedFeed(t, s, "read-view", nil, 3)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	var ids []int64
+	rows, err := s.DB.Query("SELECT id FROM articles WHERE feed_id = ? ORDER BY id", feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id int64
+		_ = rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+
+	// Read the first article before the second; the third stays unread
+	earlier := time.Now().Add(-time.Hour)
+	later := time.Now()
+	_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: ids[0], ReadAt: &earlier})
+	_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: ids[1], ReadAt: &later})
+
+	w := httptest.NewRecorder()
+	s.HandleGetArticles(w, authReq("GET", "/api/articles?view=read", ""))
+	assertStatus(t, w, 200)
+	var articles []struct {
+		ID     int64      `json:"id"`
+		IsRead int64      `json:"is_read"`
+		ReadAt *time.Time `json:"read_at"`
+	}
+	decodeJSON(t, w, &articles)
+	if len(articles) != 2 {
+		t.Fatalf("got %d read articles, want 2", len(articles))
+	}
+	if articles[0].ID != ids[1] || articles[1].ID != ids[0] {
+		t.Errorf("order = [%d %d], want most recently read first [%d %d]", articles[0].ID, articles[1].ID, ids[1], ids[0])
+	}
+	for _, a := range articles {
+		if a.IsRead != 1 || a.ReadAt == nil {
+			t.Errorf("article %d: is_read = %d, read_at = %v", a.ID, a.IsRead, a.ReadAt)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	s.HandleGetCounts(w, authReq("GET", "/api/counts", ""))
+	var counts map[string]any
+	decodeJSON(t, w, &counts)
+	if counts["read"] != float64(2) {
+		t.Errorf("read count = %v, want 2", counts["read"])
+	}
+}
+
+func TestReadHistory(t *testing.T) {
+	s := newTestServer(t)
+	feed := seedFeed(t, s, "history", nil, 4)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	var ids []int64
+	rows, err := s.DB.Query("SELECT id FROM articles WHERE feed_id = ? ORDER BY id", feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id int64
+		_ = rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+
+	// Read out of id order; the last article stays unread
+	base := time.Now().UTC().Add(-3 * time.Hour)
+	readAt := map[int64]time.Time{ids[0]: base.Add(2 * time.Hour), ids[1]: base, ids[2]: base.Add(time.Hour)}
+	for id, at := range readAt {
+		_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: id, ReadAt: &at})
+	}
+
+	history := func(target string) []articleSummary {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleReadHistory(w, authReq("GET", target, ""))
+		assertStatus(t, w, 200)
+		var got []articleSummary
+		decodeJSON(t, w, &got)
+		return got
+	}
+	idsOf := func(arts []articleSummary) []int64 {
+		out := []int64{}
+		for _, a := range arts {
+			out = append(out, a.ID)
+		}
+		return out
+	}
+
+	all := history("/api/history")
+	if want := []int64{ids[0], ids[2], ids[1]}; !reflect.DeepEqual(idsOf(all), want) {
+		t.Fatalf("order = %v, want most recently read first %v", idsOf(all), want)
+	}
+	for _, a := range all {
+		if a.ReadAt == nil || !a.ReadAt.Equal(readAt[a.ID]) {
+			t.Errorf("article %d: read_at = %v, want %v", a.ID, a.ReadAt, readAt[a.ID])
+		}
+	}
+
+	since := url.QueryEscape(base.Add(30 * time.Minute).Format(time.RFC3339Nano))
+	if got := idsOf(history("/api/history?since=" + since)); !reflect.DeepEqual(got, []int64{ids[0], ids[2]}) {
+		t.Errorf("since: got %v", got)
+	}
+
+	// Page with the last article's read_at and id as the cursor
+	page1 := history("/api/history?limit=2")
+	last := page1[len(page1)-1]
+	page2 := history(fmt.Sprintf("/api/history?limit=2&before=%s&before_id=%d",
+		url.QueryEscape(last.ReadAt.Format(time.RFC3339Nano)), last.ID))
+	if got := idsOf(page2); !reflect.DeepEqual(got, []int64{ids[1]}) {
+		t.Errorf("second page = %v, want [%d]", got, ids[1])
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleReadHistory(w, authReq("GET", "/api/history?since=yesterday", ""))
+	assertStatus(t, w, 400)
+
+	// Other users' reads are not included
+	r := httptest.NewRequest("GET", "/api/history", nil)
+	r.Header.Set("X-ExeDev-UserID", "someone-else")
+	w = httptest.NewRecorder()
+	s.HandleReadHistory(w, r)
+	assertStatus(t, w, 200)
+	if strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("other user history = %s, want []", w.Body.String())
+	}
+}
+
+func TestCategoryUnreadCounts(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	seedFeed(t, s, "loose", nil, 1)
+	news, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "News"})
+	done, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "Done"})
+	f1 := seedFeed(t, s, "n1", &news.ID, 2)
+	seedFeed(t, s, "n2", &news.ID, 3)
+	caughtUp := seedFeed(t, s, "d1", &done.ID, 1)
+
+	now := time.Now()
+	for _, feedID := range []int64{f1.ID, caughtUp.ID} {
+		arts, _ := q.GetArticlesByFeed(ctx, dbgen.GetArticlesByFeedParams{UserID: "testuser", ID: feedID, UserID_2: "testuser", Limit: 10})
+		_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: arts[0].ID, ReadAt: &now})
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleGetCounts(w, authReq("GET", "/api/counts", ""))
+	assertStatus(t, w, 200)
+	var counts struct {
+		Unread     int64            `json:"unread"`
+		Categories map[string]int64 `json:"categories"`
+	}
+	decodeJSON(t, w, &counts)
+	want := map[string]int64{fmt.Sprint(news.ID): 4, "0": 1}
+	if !reflect.DeepEqual(counts.Categories, want) {
+		t.Errorf("categories = %v, want %v", counts.Categories, want)
+	}
+	if counts.Unread != 5 {
+		t.Errorf("unread = %d, want 5", counts.Unread)
+	}
+}
+
 // --------------- Sort Order ---------------
 
 func TestArticleSortOrder(t *testing.T) {
@@ -496,6 +1000,39 @@ func TestArticleSortOrder(t *testing.T) {
 		}
 	})
 
+	t.Run("sort=unread_first", func(t *testing.T) {
+		list, _ := queryArticles(ctx, s.DB, "testuser", articleQueryOpts{FeedID: &feed.ID, Limit: 10})
+		newest := fmt.Sprint(list[0].ID)
+		w := httptest.NewRecorder()
+		r := authReq("POST", "/api/articles/"+newest+"/read", "")
+		r.SetPathValue("id", newest)
+		s.HandleMarkRead(w, r)
+		assertStatus(t, w, 200)
+
+		w = httptest.NewRecorder()
+		s.HandleGetArticles(w, authReq("GET", fmt.Sprintf("/api/articles?feed_id=%d&sort=unread_first", feed.ID), ""))
+		assertStatus(t, w, 200)
+		var got []artSummary
+		decodeJSON(t, w, &got)
+		var order []string
+		for _, a := range got {
+			order = append(order, a.Title)
+		}
+		if want := []string{"Middle", "Oldest", "Newest"}; !reflect.DeepEqual(order, want) {
+			t.Errorf("order = %v, want %v", order, want)
+		}
+
+		// Cursor params are ignored; the second page comes from the offset
+		w = httptest.NewRecorder()
+		s.HandleGetArticles(w, authReq("GET", fmt.Sprintf("/api/articles?feed_id=%d&sort=unread_first&limit=2&offset=2&before=%s&before_id=%d",
+			feed.ID, now.Format(time.RFC3339Nano), list[2].ID), ""))
+		got = nil
+		decodeJSON(t, w, &got)
+		if len(got) != 1 || got[0].Title != "Newest" {
+			t.Errorf("second page = %+v, want [Newest]", got)
+		}
+	})
+
 	t.Run("sort=oldest with category", func(t *testing.T) {
 		cat, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "SortCat"})
 		feed2, _ := q.CreateFeed(ctx, dbgen.CreateFeedParams{
@@ -523,719 +1060,3938 @@ func TestArticleSortOrder(t *testing.T) {
 	})
 }
 
-// --------------- Mark All / Feed Read ---------------
-
-func TestMarkAllRead(t *testing.T) {
+func TestArticlePageFormat(t *testing.T) {
 	s := newTestServer(t)
-	seedFeed(t, s, "f1", nil, 3)
-	seedFeed(t, s, "f2", nil, 2)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	seedFeed(t, s, "paged", nil, 5)
+	var ids []int64
+	rows, _ := s.DB.Query("SELECT id FROM articles ORDER BY id")
+	for rows.Next() {
+		var id int64
+		_ = rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+	for i, id := range ids[:3] {
+		at := time.Now().Add(-time.Duration(i) * time.Minute)
+		_ = q.SetArticleStarred(ctx, dbgen.SetArticleStarredParams{UserID: "testuser", ArticleID: id, StarredAt: &at})
+	}
+	// Starred without a starred_at: no sort key, so paged by id alone
+	if _, err := s.DB.Exec("INSERT INTO article_states (user_id, article_id, is_starred) VALUES ('testuser', ?, 1)", ids[3]); err != nil {
+		t.Fatal(err)
+	}
 
-	// Before: 5 unread
-	w := httptest.NewRecorder()
-	s.HandleGetArticles(w, authReq("GET", "/api/articles?view=unread", ""))
-	var before []json.RawMessage
-	decodeJSON(t, w, &before)
-	if len(before) != 5 {
-		t.Fatalf("expected 5 unread, got %d", len(before))
+	type page struct {
+		Articles []struct {
+			ID int64 `json:"id"`
+		} `json:"articles"`
+		NextBefore   *time.Time `json:"next_before"`
+		NextBeforeID *int64     `json:"next_before_id"`
+		NextAfter    *time.Time `json:"next_after"`
+		NextAfterID  *int64     `json:"next_after_id"`
+		HasMore      bool       `json:"has_more"`
+	}
+	get := func(url string) page {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleGetArticles(w, authReq("GET", url, ""))
+		assertStatus(t, w, 200)
+		var p page
+		decodeJSON(t, w, &p)
+		return p
+	}
+	// walk pages two at a time, following the returned cursor
+	walk := func(base string) []int64 {
+		t.Helper()
+		var got []int64
+		url := base
+		for range 10 {
+			p := get(url)
+			for _, a := range p.Articles {
+				got = append(got, a.ID)
+			}
+			if !p.HasMore {
+				if p.NextBefore != nil || p.NextAfter != nil {
+					t.Errorf("last page has a cursor: %+v", p)
+				}
+				return got
+			}
+			switch {
+			case p.NextBeforeID != nil:
+				url = fmt.Sprintf("%s&before_id=%d", base, *p.NextBeforeID)
+				if p.NextBefore != nil {
+					url += "&before=" + p.NextBefore.Format(time.RFC3339Nano)
+				}
+			case p.NextAfterID != nil:
+				url = fmt.Sprintf("%s&after_id=%d", base, *p.NextAfterID)
+				if p.NextAfter != nil {
+					url += "&after=" + p.NextAfter.Format(time.RFC3339Nano)
+				}
+			default:
+				t.Fatalf("page with has_more lacks a cursor: %+v", p)
+			}
+		}
+		t.Fatal("pagination did not end")
+		return nil
 	}
 
-	// Mark all read
-	w = httptest.NewRecorder()
-	s.HandleMarkAllRead(w, authReq("POST", "/api/mark-all-read", ""))
-	assertStatus(t, w, 200)
+	reversed := slices.Clone(ids)
+	slices.Reverse(reversed)
+	if got := walk("/api/articles?format=page&limit=2"); !slices.Equal(got, reversed) {
+		t.Errorf("newest walk = %v, want %v", got, reversed)
+	}
+	if got := walk("/api/articles?format=page&limit=2&sort=oldest"); !slices.Equal(got, ids) {
+		t.Errorf("oldest walk = %v, want %v", got, ids)
+	}
+	if got := walk("/api/articles?format=page&limit=1&view=starred"); !slices.Equal(got, ids[:4]) {
+		t.Errorf("starred walk = %v, want %v", got, ids[:4])
+	}
+	starredOldest := []int64{ids[3], ids[2], ids[1], ids[0]}
+	if got := walk("/api/articles?format=page&limit=1&view=starred&sort=oldest"); !slices.Equal(got, starredOldest) {
+		t.Errorf("starred oldest walk = %v, want %v", got, starredOldest)
+	}
 
-	// After: 0 unread
-	w = httptest.NewRecorder()
-	s.HandleGetArticles(w, authReq("GET", "/api/articles?view=unread", ""))
-	var after []json.RawMessage
-	decodeJSON(t, w, &after)
-	if len(after) != 0 {
-		t.Errorf("expected 0 unread after mark-all-read, got %d", len(after))
+	// Without format=page the response stays a bare array
+	w := httptest.NewRecorder()
+	s.HandleGetArticles(w, authReq("GET", "/api/articles?limit=2", ""))
+	var bare []struct {
+		ID int64 `json:"id"`
+	}
+	decodeJSON(t, w, &bare)
+	if len(bare) != 2 {
+		t.Errorf("bare response has %d articles, want 2", len(bare))
 	}
 }
 
-func TestMarkFeedRead(t *testing.T) {
+func TestArticleCursorPaginationUndated(t *testing.T) {
 	s := newTestServer(t)
-	f1 := seedFeed(t, s, "f1", nil, 3)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	feed := seedFeed(t, s, "mixed", nil, 0)
+
+	// Interleave dated and undated articles
+	now := time.Now()
+	for i := range 6 {
+		var pub *time.Time
+		if i%2 == 0 {
+			p := now.Add(-time.Duration(i) * time.Hour)
+			pub = &p
+		}
+		_, _ = q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
+			FeedID: feed.ID, Guid: fmt.Sprintf("mixed-%d", i),
+			Url: fmt.Sprintf("http://example.com/mixed-%d", i), Title: fmt.Sprintf("Mixed %d", i),
+			PublishedAt: pub,
+		})
+	}
+
+	type artSummary struct {
+		ID          int64      `json:"id"`
+		PublishedAt *time.Time `json:"published_at"`
+		CreatedAt   time.Time  `json:"created_at"`
+	}
+
+	list := func(url string) []artSummary {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleGetArticles(w, authReq("GET", url, ""))
+		assertStatus(t, w, 200)
+		var page []artSummary
+		decodeJSON(t, w, &page)
+		return page
+	}
+
+	for _, sort := range []string{"newest", "oldest"} {
+		t.Run(sort, func(t *testing.T) {
+			full := list("/api/articles?limit=100&sort=" + sort)
+			if len(full) != 6 {
+				t.Fatalf("got %d articles, want 6", len(full))
+			}
+			if again := list("/api/articles?limit=100&sort=" + sort); fmt.Sprint(again) != fmt.Sprint(full) {
+				t.Fatalf("order changed between requests:\n%v\n%v", full, again)
+			}
+
+			// Walk the same list two at a time using the cursor from the last item
+			var walked []artSummary
+			url := "/api/articles?limit=2&sort=" + sort
+			for range 4 {
+				page := list(url)
+				if len(page) == 0 {
+					break
+				}
+				walked = append(walked, page...)
+				last := page[len(page)-1]
+				key := last.CreatedAt
+				if last.PublishedAt != nil {
+					key = *last.PublishedAt
+				}
+				cursor := "before"
+				if sort == "oldest" {
+					cursor = "after"
+				}
+				url = fmt.Sprintf("/api/articles?limit=2&sort=%s&%s=%s&%s_id=%d",
+					sort, cursor, key.Format(time.RFC3339Nano), cursor, last.ID)
+			}
+			if len(walked) != len(full) {
+				t.Fatalf("paginated %d articles, want %d", len(walked), len(full))
+			}
+			for i := range full {
+				if walked[i].ID != full[i].ID {
+					t.Errorf("position %d: got article %d, want %d", i, walked[i].ID, full[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestOldestUnread(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	feed := seedFeed(t, s, "oldest", nil, 0)
+	other := seedFeed(t, s, "other", nil, 0)
+
+	now := time.Now()
+	var ids []int64
+	for i, title := range []string{"Oldest", "Middle", "Newest"} {
+		pub := now.Add(-time.Duration(3-i) * time.Hour)
+		a, _ := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
+			FeedID: feed.ID, Guid: title, Url: "http://example.com/" + title,
+			Title: title, Content: "<p>" + title + "</p>", PublishedAt: &pub,
+		})
+		ids = append(ids, a.ID)
+	}
+	older := now.Add(-48 * time.Hour)
+	_, _ = q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
+		FeedID: other.ID, Guid: "elsewhere", Url: "http://example.com/elsewhere",
+		Title: "Elsewhere", PublishedAt: &older,
+	})
+	_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: ids[0], ReadAt: &now})
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.HandleOldestUnread(w, authReq("GET", url, ""))
+		return w
+	}
+
+	w := get("/api/articles/oldest-unread")
+	assertStatus(t, w, 200)
+	var art struct {
+		Title   string `json:"title"`
+		Content string `json:"content"`
+	}
+	decodeJSON(t, w, &art)
+	if art.Title != "Elsewhere" {
+		t.Errorf("oldest unread = %q, want Elsewhere", art.Title)
+	}
+
+	w = get(fmt.Sprintf("/api/articles/oldest-unread?feed_id=%d", feed.ID))
+	assertStatus(t, w, 200)
+	decodeJSON(t, w, &art)
+	if art.Title != "Middle" || art.Content != "<p>Middle</p>" {
+		t.Errorf("oldest unread in feed = %q (%q), want Middle with content", art.Title, art.Content)
+	}
+
+	assertStatus(t, get("/api/articles/oldest-unread?feed_id=bad"), 400)
+
+	// Nothing unread left
+	_, _ = s.markAllRead(ctx, "testuser")
+	assertStatus(t, get("/api/articles/oldest-unread"), 404)
+}
+
+func TestSearchArticles(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	a := seedFeed(t, s, "search-a", nil, 0)
+	b := seedFeed(t, s, "search-b", nil, 0)
+
+	now := time.Now()
+	add := func(feedID int64, guid, title, content string) {
+		t.Helper()
+		if _, err := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
+			FeedID: feedID, Guid: guid, Url: "http://example.com/" + guid,
+			Title: title, Content: content, PublishedAt: &now,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(a.ID, "passing", "Weekly links", "<p>Lots of topics, and one mention of SQLite tuning.</p>")
+	add(b.ID, "focused", "SQLite tuning", "<p>SQLite tuning: indexes, WAL and more SQLite.</p>")
+	add(a.ID, "unrelated", "Gardening", "<p>Tomatoes.</p>")
+
+	search := func(query string) []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleSearchArticles(w, authReq("GET", "/api/search?"+query, ""))
+		assertStatus(t, w, 200)
+		var got []articleSummary
+		decodeJSON(t, w, &got)
+		var titles []string
+		for _, a := range got {
+			titles = append(titles, a.Title)
+		}
+		return titles
+	}
+
+	// Best match first; every word must match, in any field
+	if got, want := search("q=sqlite+tuning"), []string{"SQLite tuning", "Weekly links"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search = %v, want %v", got, want)
+	}
+	if got := search("q=tuning+tomatoes"); len(got) != 0 {
+		t.Errorf("search for words in different articles = %v, want none", got)
+	}
+	if got, want := search(fmt.Sprintf("q=sqlite&feed_id=%d", a.ID)), []string{"Weekly links"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search in feed = %v, want %v", got, want)
+	}
+	if got, want := search("q=sqlite&limit=1&offset=1"), []string{"Weekly links"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second page = %v, want %v", got, want)
+	}
+	// FTS syntax in the query is taken literally
+	if got := search("q=%22tomatoes+OR"); len(got) != 0 {
+		t.Errorf("search with stray quote = %v, want none", got)
+	}
+
+	// The index follows updates
+	add(a.ID, "unrelated", "Gardening", "<p>Tomatoes, tuned.</p>")
+	if got, want := search("q=tuned"), []string{"Gardening"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search after update = %v, want %v", got, want)
+	}
+	if got := search("q=missing+q"); len(got) != 0 {
+		t.Errorf("unmatched search = %v, want none", got)
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleSearchArticles(w, authReq("GET", "/api/search?q=+", ""))
+	assertStatus(t, w, http.StatusBadRequest)
+
+	// Compressed content is indexed as text, on refresh and on reprocessing
+	s.CompressContent = true
+	s.ExcerptLen = 0
+	long := "<p>" + strings.Repeat("Seville oranges make marmalade. ", 20) + "</p>"
+	s.storeItems(ctx, q, &a, []FeedItem{{GUID: "jam", Title: "Preserves", URL: "http://example.com/jam", Content: long}})
+	if got, want := search("q=marmalade"), []string{"Preserves"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search in compressed content = %v, want %v", got, want)
+	}
+	if _, err := s.DB.Exec(`UPDATE articles_fts SET title = 'Preserves', content = '', summary = ''
+		WHERE rowid = (SELECT id FROM articles WHERE guid = 'jam')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := s.reprocessBatch(ctx, "testuser", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := search("q=marmalade"), []string{"Preserves"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search after reprocessing = %v, want %v", got, want)
+	}
+}
+
+func TestRecentArticles(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	a := seedFeed(t, s, "recent-a", nil, 0)
+	b := seedFeed(t, s, "recent-b", nil, 0)
+
+	// Interleave publish dates across the two feeds
+	now := time.Now()
+	var ids []int64
+	for i, feedID := range []int64{a.ID, b.ID, a.ID, b.ID} {
+		pub := now.Add(-time.Duration(i) * time.Hour)
+		title := fmt.Sprintf("Item %d", i)
+		art, _ := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
+			FeedID: feedID, Guid: title, Url: "http://example.com/" + title,
+			Title: title, Content: "<p>" + title + "</p>", PublishedAt: &pub,
+		})
+		ids = append(ids, art.ID)
+	}
+	// Read state doesn't matter
+	_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: ids[0], ReadAt: &now})
+
+	type recent struct {
+		Title   string `json:"title"`
+		FeedID  int64  `json:"feed_id"`
+		Content string `json:"content"`
+	}
+	get := func(url string) []recent {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleRecentArticles(w, authReq("GET", url, ""))
+		assertStatus(t, w, 200)
+		var got []recent
+		decodeJSON(t, w, &got)
+		return got
+	}
+
+	got := get("/api/articles/recent?limit=3&include_content=1")
+	want := []recent{
+		{"Item 0", a.ID, "<p>Item 0</p>"},
+		{"Item 1", b.ID, "<p>Item 1</p>"},
+		{"Item 2", a.ID, "<p>Item 2</p>"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("recent = %+v, want %+v", got, want)
+	}
+
+	// Content is left out unless asked for
+	got = get("/api/articles/recent")
+	if len(got) != 4 || got[0].Content != "" {
+		t.Errorf("recent without content = %+v, want 4 articles without content", got)
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleRecentArticles(w, authReq("GET", "/api/articles/recent?limit=0", ""))
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
+func TestHideArticle(t *testing.T) {
+	s := newTestServer(t)
+	feed := seedFeed(t, s, "hide", nil, 3)
+	var ids []int64
+	rows, err := s.DB.Query("SELECT id FROM articles WHERE feed_id = ? ORDER BY id", feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id int64
+		_ = rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+
+	post := func(action string, id int64) {
+		t.Helper()
+		idStr := fmt.Sprint(id)
+		r := authReq("POST", "/api/articles/"+idStr+"/"+action, "")
+		r.SetPathValue("id", idStr)
+		w := httptest.NewRecorder()
+		if action == "hide" {
+			s.HandleHide(w, r)
+		} else {
+			s.HandleUnhide(w, r)
+		}
+		assertStatus(t, w, 200)
+	}
+	list := func(query string) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleGetArticles(w, authReq("GET", "/api/articles"+query, ""))
+		assertStatus(t, w, 200)
+		var got []articleSummary
+		decodeJSON(t, w, &got)
+		return len(got)
+	}
+	unread := func() (total, perFeed int64) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleGetCounts(w, authReq("GET", "/api/counts", ""))
+		assertStatus(t, w, 200)
+		var c struct {
+			Unread int64           `json:"unread"`
+			Feeds  map[int64]int64 `json:"feeds"`
+		}
+		decodeJSON(t, w, &c)
+		return c.Unread, c.Feeds[feed.ID]
+	}
+
+	post("hide", ids[0])
+
+	if n := list(""); n != 2 {
+		t.Errorf("all view lists %d articles, want 2", n)
+	}
+	if n := list("?view=unread"); n != 2 {
+		t.Errorf("unread view lists %d articles, want 2", n)
+	}
+	if n := list("?include_hidden=1"); n != 3 {
+		t.Errorf("include_hidden lists %d articles, want 3", n)
+	}
+	if total, perFeed := unread(); total != 2 || perFeed != 2 {
+		t.Errorf("unread = %d, feed unread = %d; want 2 and 2", total, perFeed)
+	}
+
+	// Hiding doesn't mark read, and survives marking the feed's category read
+	var isRead int64
+	_ = s.DB.QueryRow("SELECT is_read FROM article_states WHERE article_id = ?", ids[0]).Scan(&isRead)
+	if isRead != 0 {
+		t.Error("hidden article was marked read")
+	}
+	if _, err := s.markCategoryRead(context.Background(), "testuser", 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := list(""); n != 2 {
+		t.Errorf("after category mark-read all view lists %d articles, want 2", n)
+	}
+
+	post("unhide", ids[0])
+	if n := list(""); n != 3 {
+		t.Errorf("after unhide all view lists %d articles, want 3", n)
+	}
+}
+
+func TestMarkReadPropagation(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	f1 := seedFeed(t, s, "wire", nil, 0)
+	f2 := seedFeed(t, s, "aggregator", nil, 0)
+
+	add := func(feedID int64, guid, url string) int64 {
+		a, err := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{FeedID: feedID, Guid: guid, Url: url, Title: guid})
+		if err != nil {
+			t.Fatalf("UpsertArticle: %v", err)
+		}
+		return a.ID
+	}
+	isRead := func(id int64) bool {
+		a, _ := q.GetArticle(ctx, dbgen.GetArticleParams{UserID: "testuser", ID: id, UserID_2: "testuser"})
+		return a.IsRead == 1
+	}
+	markRead := func(id int64) {
+		w := httptest.NewRecorder()
+		r := authReq("POST", fmt.Sprintf("/api/articles/%d/read", id), "")
+		r.SetPathValue("id", fmt.Sprint(id))
+		s.HandleMarkRead(w, r)
+		assertStatus(t, w, 200)
+	}
+
+	// Off by default
+	a1 := add(f1.ID, "a1", "https://news.example.com/story")
+	a2 := add(f2.ID, "a2", "http://www.news.example.com/story/?utm_source=agg#top")
+	markRead(a1)
+	if isRead(a2) {
+		t.Fatal("propagation should be off by default")
+	}
+
+	s.ReadPropagationWindow = 24 * time.Hour
+	b1 := add(f1.ID, "b1", "https://news.example.com/other")
+	b2 := add(f2.ID, "b2", "https://news.example.com/other?utm_medium=rss")
+	unrelated := add(f2.ID, "c", "https://news.example.com/other?page=2")
+	markRead(b2)
+	if !isRead(b1) {
+		t.Error("same-URL article in another feed should be marked read")
+	}
+	if isRead(unrelated) {
+		t.Error("article with a different URL should stay unread")
+	}
+}
+
+func TestDedupByURL(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	wire := seedFeed(t, s, "wire", nil, 0)
+	agg := seedFeed(t, s, "aggregator", nil, 0)
+
+	add := func(feedID int64, guid, url string, age time.Duration) int64 {
+		t.Helper()
+		published := time.Now().Add(-age)
+		a, err := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{FeedID: feedID, Guid: guid, Url: url, Title: guid, PublishedAt: &published})
+		if err != nil {
+			t.Fatalf("UpsertArticle: %v", err)
+		}
+		return a.ID
+	}
+	original := add(wire.ID, "orig", "https://news.example.com/story", 3*time.Hour)
+	repost := add(agg.ID, "repost", "https://www.news.example.com/story/?utm_source=agg&utm_medium=rss", time.Hour)
+	other := add(agg.ID, "other", "https://news.example.com/story?page=2", 2*time.Hour)
+	noURL := add(agg.ID, "nourl", "", 30*time.Minute)
+
+	list := func() map[int64]int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleGetArticles(w, authReq("GET", "/api/articles", ""))
+		assertStatus(t, w, 200)
+		var got []articleSummary
+		decodeJSON(t, w, &got)
+		ids := make(map[int64]int)
+		for _, a := range got {
+			ids[a.ID] = a.DuplicateCount
+		}
+		return ids
+	}
+
+	// Off by default
+	if got := list(); len(got) != 4 {
+		t.Fatalf("without dedup: %d articles, want 4", len(got))
+	}
+
+	s.DedupByURL = dedupKeepOldest
+	want := map[int64]int{original: 1, other: 0, noURL: 0}
+	if got := list(); !reflect.DeepEqual(got, want) {
+		t.Errorf("oldest: articles = %v, want %v", got, want)
+	}
+
+	s.DedupByURL = dedupKeepNewest
+	want = map[int64]int{repost: 1, other: 0, noURL: 0}
+	if got := list(); !reflect.DeepEqual(got, want) {
+		t.Errorf("newest: articles = %v, want %v", got, want)
+	}
+
+	// Grouping happens before paging, so a group split across pages lists
+	// once and has_more doesn't count the folded articles
+	s.DedupByURL = dedupKeepOldest
+	var paged []int64
+	url := "/api/articles?format=page&limit=2"
+	for range 3 {
+		w := httptest.NewRecorder()
+		s.HandleGetArticles(w, authReq("GET", url, ""))
+		assertStatus(t, w, 200)
+		var page articlePage
+		decodeJSON(t, w, &page)
+		for _, a := range page.Articles {
+			paged = append(paged, a.ID)
+			if a.ID == original && a.DuplicateCount != 1 {
+				t.Errorf("paged original duplicate_count = %d, want 1", a.DuplicateCount)
+			}
+		}
+		if !page.HasMore {
+			break
+		}
+		url = fmt.Sprintf("/api/articles?format=page&limit=2&before=%s&before_id=%d", page.NextBefore.Format(time.RFC3339Nano), *page.NextBeforeID)
+	}
+	if want := []int64{noURL, other, original}; !slices.Equal(paged, want) {
+		t.Errorf("paged oldest: articles = %v, want %v", paged, want)
+	}
+
+	// Stored articles are untouched
+	var n int
+	_ = s.DB.QueryRow("SELECT COUNT(*) FROM articles").Scan(&n)
+	if n != 4 {
+		t.Errorf("articles stored = %d, want 4", n)
+	}
+
+	t.Setenv("GORSS_DEDUP_BY_URL", "Newest")
+	if v := dedupByURLFromEnv(); v != dedupKeepNewest {
+		t.Errorf("dedupByURLFromEnv = %q, want newest", v)
+	}
+	t.Setenv("GORSS_DEDUP_BY_URL", "bogus")
+	if v := dedupByURLFromEnv(); v != "" {
+		t.Errorf("invalid GORSS_DEDUP_BY_URL = %q, want off", v)
+	}
+}
+
+// --------------- Mark All / Feed Read ---------------
+
+func TestMarkAllRead(t *testing.T) {
+	s := newTestServer(t)
+	seedFeed(t, s, "f1", nil, 3)
 	seedFeed(t, s, "f2", nil, 2)
 
+	// Before: 5 unread
 	w := httptest.NewRecorder()
-	r := authReq("POST", "/api/feeds/"+fmt.Sprint(f1.ID)+"/mark-read", "")
-	r.SetPathValue("id", fmt.Sprint(f1.ID))
-	s.HandleMarkFeedRead(w, r)
+	s.HandleGetArticles(w, authReq("GET", "/api/articles?view=unread", ""))
+	var before []json.RawMessage
+	decodeJSON(t, w, &before)
+	if len(before) != 5 {
+		t.Fatalf("expected 5 unread, got %d", len(before))
+	}
+
+	// Mark all read
+	w = httptest.NewRecorder()
+	s.HandleMarkAllRead(w, authReq("POST", "/api/mark-all-read", ""))
+	assertStatus(t, w, 200)
+	var resp struct {
+		Marked int64 `json:"marked"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Marked != 5 {
+		t.Errorf("marked = %d, want 5", resp.Marked)
+	}
+
+	// After: 0 unread
+	w = httptest.NewRecorder()
+	s.HandleGetArticles(w, authReq("GET", "/api/articles?view=unread", ""))
+	var after []json.RawMessage
+	decodeJSON(t, w, &after)
+	if len(after) != 0 {
+		t.Errorf("expected 0 unread after mark-all-read, got %d", len(after))
+	}
+
+	// Repeating only counts articles that were still unread
+	w = httptest.NewRecorder()
+	s.HandleMarkAllRead(w, authReq("POST", "/api/mark-all-read", ""))
+	decodeJSON(t, w, &resp)
+	if resp.Marked != 0 {
+		t.Errorf("marked on repeat = %d, want 0", resp.Marked)
+	}
+}
+
+func TestMarkFeedsReadBatch(t *testing.T) {
+	s := newTestServer(t)
+	f1 := seedFeed(t, s, "f1", nil, 3)
+	f2 := seedFeed(t, s, "f2", nil, 2)
+	f3 := seedFeed(t, s, "f3", nil, 4)
+
+	// A feed owned by someone else
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	now := time.Now()
+	_ = q.UpsertUser(ctx, dbgen.UpsertUserParams{ID: "other", CreatedAt: now, LastSeen: now})
+	foreign, err := q.CreateFeed(ctx, dbgen.CreateFeedParams{UserID: "other", Url: "http://example.com/foreign", Title: "foreign"})
+	if err != nil {
+		t.Fatalf("CreateFeed: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"feed_ids":[%d,%d,%d]}`, f1.ID, f2.ID, foreign.ID)
+	w := httptest.NewRecorder()
+	s.HandleMarkFeedsReadBatch(w, authReq("POST", "/api/feeds/mark-read-batch", body))
 	assertStatus(t, w, 200)
+	var resp struct {
+		Marked  int            `json:"marked"`
+		Feeds   map[string]int `json:"feeds"`
+		Skipped []int64        `json:"skipped"`
+		Undo    string         `json:"undo_token"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Marked != 5 || resp.Feeds[fmt.Sprint(f1.ID)] != 3 || resp.Feeds[fmt.Sprint(f2.ID)] != 2 {
+		t.Errorf("marked = %d, feeds = %v; want 5, {f1:3 f2:2}", resp.Marked, resp.Feeds)
+	}
+	if len(resp.Skipped) != 1 || resp.Skipped[0] != foreign.ID {
+		t.Errorf("skipped = %v, want [%d]", resp.Skipped, foreign.ID)
+	}
+	if resp.Undo == "" {
+		t.Error("expected undo token")
+	}
+
+	// Untouched feed is still unread
+	w = httptest.NewRecorder()
+	s.HandleGetCounts(w, authReq("GET", "/api/counts", ""))
+	var counts struct {
+		Unread int64 `json:"unread"`
+	}
+	decodeJSON(t, w, &counts)
+	if counts.Unread != 4 {
+		t.Errorf("unread = %d, want 4 (feed %d untouched)", counts.Unread, f3.ID)
+	}
+
+	w = httptest.NewRecorder()
+	s.HandleMarkFeedsReadBatch(w, authReq("POST", "/api/feeds/mark-read-batch", `{"feed_ids":[]}`))
+	assertStatus(t, w, 400)
+}
+
+func TestCatchUp(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	f1 := seedFeed(t, s, "f1", nil, 0)
+	f2 := seedFeed(t, s, "f2", nil, 0)
+
+	// Articles published an hour apart; higher i is newer
+	base := time.Now().Add(-24 * time.Hour).UTC()
+	ids := make(map[int]int64)
+	for i := range 6 {
+		feedID := f1.ID
+		if i%2 == 1 {
+			feedID = f2.ID
+		}
+		pub := base.Add(time.Duration(i) * time.Hour)
+		a, err := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
+			FeedID: feedID, Guid: fmt.Sprint("catch-", i), Title: fmt.Sprint("Article ", i), PublishedAt: &pub,
+		})
+		if err != nil {
+			t.Fatalf("UpsertArticle: %v", err)
+		}
+		ids[i] = a.ID
+	}
+	isRead := func(id int64) bool {
+		a, err := q.GetArticle(ctx, dbgen.GetArticleParams{UserID: "testuser", ID: id, UserID_2: "testuser"})
+		if err != nil {
+			t.Fatalf("GetArticle: %v", err)
+		}
+		return a.IsRead == 1
+	}
+	catchUp := func(query string) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleCatchUp(w, authReq("POST", "/api/catch-up"+query, ""))
+		assertStatus(t, w, 200)
+		var resp struct {
+			Marked int `json:"marked"`
+		}
+		decodeJSON(t, w, &resp)
+		return resp.Marked
+	}
+
+	// Scoped to f1 (articles 0, 2, 4): keep the newest one
+	if n := catchUp("?keep=1&feed_id=" + fmt.Sprint(f1.ID)); n != 2 {
+		t.Errorf("feed-scoped marked = %d, want 2", n)
+	}
+	for i, want := range map[int]bool{0: true, 2: true, 4: false, 1: false} {
+		if got := isRead(ids[i]); got != want {
+			t.Errorf("after feed catch-up: article %d read = %v, want %v", i, got, want)
+		}
+	}
+
+	// Unscoped: unread are 1, 3, 4, 5; keep the newest two
+	if n := catchUp("?keep=2"); n != 2 {
+		t.Errorf("marked = %d, want 2", n)
+	}
+	for i, want := range map[int]bool{1: true, 3: true, 4: false, 5: false} {
+		if got := isRead(ids[i]); got != want {
+			t.Errorf("after catch-up: article %d read = %v, want %v", i, got, want)
+		}
+	}
+
+	// Fewer unread than keep marks nothing
+	if n := catchUp(""); n != 0 {
+		t.Errorf("default keep marked = %d, want 0", n)
+	}
+
+	for _, query := range []string{"?keep=-1", "?keep=x", "?feed_id=x", "?category_id=x"} {
+		w := httptest.NewRecorder()
+		s.HandleCatchUp(w, authReq("POST", "/api/catch-up"+query, ""))
+		assertStatus(t, w, 400)
+	}
+}
+
+func TestMarkAllUnread(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	other := seedFeed(t, s, "other", nil, 2)
+	cat, err := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "Reread"})
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	f1 := seedFeed(t, s, "f1", &cat.ID, 3)
+	seedFeed(t, s, "f2", &cat.ID, 1)
+
+	articles, _ := q.GetArticlesByFeed(ctx, dbgen.GetArticlesByFeedParams{UserID: "testuser", ID: f1.ID, UserID_2: "testuser", Limit: 10})
+	now := time.Now()
+	starredID := articles[0].ID
+	_ = q.SetArticleStarred(ctx, dbgen.SetArticleStarredParams{UserID: "testuser", ArticleID: starredID, StarredAt: &now})
+
+	unread := func() int64 {
+		t.Helper()
+		n, err := q.GetUnreadCount(ctx, "testuser")
+		if err != nil {
+			t.Fatalf("GetUnreadCount: %v", err)
+		}
+		return n
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleMarkAllRead(w, authReq("POST", "/api/articles/mark-all-read", ""))
+	assertStatus(t, w, 200)
+	if n := unread(); n != 0 {
+		t.Fatalf("unread after mark-all-read = %d, want 0", n)
+	}
+
+	w = httptest.NewRecorder()
+	s.HandleMarkAllUnread(w, authReq("POST", "/api/mark-all-unread?category_id="+fmt.Sprint(cat.ID), ""))
+	assertStatus(t, w, 200)
+	var resp struct {
+		Marked int `json:"marked"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Marked != 4 {
+		t.Errorf("marked = %d, want 4", resp.Marked)
+	}
+	if n := unread(); n != 4 {
+		t.Errorf("unread after category unread = %d, want 4 (other feed stays read)", n)
+	}
+	a, _ := q.GetArticle(ctx, dbgen.GetArticleParams{UserID: "testuser", ID: starredID, UserID_2: "testuser"})
+	if a.IsStarred != 1 || a.IsRead != 0 {
+		t.Errorf("starred article: starred=%d read=%d, want 1/0", a.IsStarred, a.IsRead)
+	}
+
+	// Feed scope
+	w = httptest.NewRecorder()
+	s.HandleMarkAllUnread(w, authReq("POST", "/api/mark-all-unread?feed_id="+fmt.Sprint(other.ID), ""))
+	assertStatus(t, w, 200)
+	if n := unread(); n != 6 {
+		t.Errorf("unread after feed unread = %d, want 6", n)
+	}
+
+	for _, query := range []string{"", "?feed_id=x", "?category_id=x"} {
+		w := httptest.NewRecorder()
+		s.HandleMarkAllUnread(w, authReq("POST", "/api/mark-all-unread"+query, ""))
+		assertStatus(t, w, 400)
+	}
+}
+
+func TestMarkFeedRead(t *testing.T) {
+	s := newTestServer(t)
+	f1 := seedFeed(t, s, "f1", nil, 3)
+	seedFeed(t, s, "f2", nil, 2)
+
+	w := httptest.NewRecorder()
+	r := authReq("POST", "/api/feeds/"+fmt.Sprint(f1.ID)+"/mark-read", "")
+	r.SetPathValue("id", fmt.Sprint(f1.ID))
+	s.HandleMarkFeedRead(w, r)
+	assertStatus(t, w, 200)
+
+	var resp struct {
+		Marked int64 `json:"marked"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Marked != 3 {
+		t.Errorf("marked = %d, want 3", resp.Marked)
+	}
+
+	// Only f2's articles remain unread
+	w = httptest.NewRecorder()
+	s.HandleGetArticles(w, authReq("GET", "/api/articles?view=unread", ""))
+	var list []json.RawMessage
+	decodeJSON(t, w, &list)
+	if len(list) != 2 {
+		t.Errorf("expected 2 unread, got %d", len(list))
+	}
+}
+
+func TestMarkFeedReadInvalidID(t *testing.T) {
+	s := newTestServer(t)
+	w := httptest.NewRecorder()
+	r := authReq("POST", "/api/feeds/bad/mark-read", "")
+	r.SetPathValue("id", "bad")
+	s.HandleMarkFeedRead(w, r)
+	assertStatus(t, w, 400)
+}
+
+func TestUndoMarkAllRead(t *testing.T) {
+	s := newTestServer(t)
+	feed := seedFeed(t, s, "undo", nil, 3)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	// One article was already read before the bulk operation
+	arts, _ := q.GetArticlesByFeed(ctx, dbgen.GetArticlesByFeedParams{
+		UserID: "testuser", ID: feed.ID, UserID_2: "testuser", Limit: 100,
+	})
+	now := time.Now()
+	_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: arts[0].ID, ReadAt: &now})
+
+	w := httptest.NewRecorder()
+	s.HandleMarkAllRead(w, authReq("POST", "/api/articles/mark-all-read", ""))
+	assertStatus(t, w, 200)
+	var resp struct {
+		Marked    int64  `json:"marked"`
+		UndoToken string `json:"undo_token"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Marked != 2 || resp.UndoToken == "" {
+		t.Fatalf("marked = %d, token = %q; want 2 and a token", resp.Marked, resp.UndoToken)
+	}
+
+	undo := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := authReq("POST", "/api/undo/"+token, "")
+		r.SetPathValue("token", token)
+		s.HandleUndoRead(w, r)
+		return w
+	}
+
+	w = undo(resp.UndoToken)
+	assertStatus(t, w, 200)
+	var undone struct {
+		Restored int64 `json:"restored"`
+	}
+	decodeJSON(t, w, &undone)
+	if undone.Restored != 2 {
+		t.Errorf("restored = %d, want 2", undone.Restored)
+	}
+
+	// The previously-read article stays read
+	unread, _ := q.GetUnreadCount(ctx, "testuser")
+	if unread != 2 {
+		t.Errorf("unread = %d, want 2", unread)
+	}
+
+	t.Run("token is single use", func(t *testing.T) {
+		assertStatus(t, undo(resp.UndoToken), 404)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		assertStatus(t, undo("nope"), 404)
+	})
+
+	t.Run("other user cannot undo", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.HandleMarkAllRead(w, authReq("POST", "/api/articles/mark-all-read", ""))
+		decodeJSON(t, w, &resp)
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/undo/"+resp.UndoToken, nil)
+		r.Header.Set("X-ExeDev-UserID", "someone-else")
+		r.SetPathValue("token", resp.UndoToken)
+		s.HandleUndoRead(w, r)
+		assertStatus(t, w, 404)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		_, _ = s.DB.Exec(`UPDATE read_undo SET created_at = datetime('now', '-1 hour') WHERE token = ?`, resp.UndoToken)
+		assertStatus(t, undo(resp.UndoToken), 404)
+	})
+}
+
+// --------------- Counts ---------------
+
+func TestCounts(t *testing.T) {
+	s := newTestServer(t)
+	feed := seedFeed(t, s, "counted", nil, 4)
+
+	w := httptest.NewRecorder()
+	s.HandleGetCounts(w, authReq("GET", "/api/counts", ""))
+	assertStatus(t, w, 200)
+
+	var counts struct {
+		Total   int64            `json:"total"`
+		Unread  int64            `json:"unread"`
+		Starred int64            `json:"starred"`
+		Feeds   map[string]int64 `json:"feeds"`
+	}
+	decodeJSON(t, w, &counts)
+	if counts.Total != 4 {
+		t.Errorf("total = %d, want 4", counts.Total)
+	}
+	if counts.Unread != 4 {
+		t.Errorf("unread = %d, want 4", counts.Unread)
+	}
+	if counts.Starred != 0 {
+		t.Errorf("starred = %d, want 0", counts.Starred)
+	}
+	fidStr := fmt.Sprint(feed.ID)
+	if counts.Feeds[fidStr] != 4 {
+		t.Errorf("feed count = %d, want 4", counts.Feeds[fidStr])
+	}
+}
+
+func TestStreamLimit(t *testing.T) {
+	s := newTestServer(t)
+	s.streams = newStreamLimiter(3, 2)
+
+	started := make(chan struct{})
+	ts := httptest.NewServer(s.limitStream(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	// open starts a stream for user, returning the status and a func that
+	// disconnects it.
+	open := func(user string) (int, func()) {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+		req.Header.Set("X-ExeDev-UserID", user)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			cancel()
+			t.Fatalf("request: %v", err)
+		}
+		if resp.StatusCode == http.StatusOK {
+			<-started
+		}
+		return resp.StatusCode, func() { cancel(); _ = resp.Body.Close() }
+	}
+	active := func() int {
+		s.streams.mu.Lock()
+		defer s.streams.mu.Unlock()
+		return s.streams.total
+	}
+
+	mustOpen := func(code int) {
+		t.Helper()
+		if code != http.StatusOK {
+			t.Fatalf("stream: status %d, want 200", code)
+		}
+	}
+
+	code, closeA1 := open("alice")
+	mustOpen(code)
+	code, closeA2 := open("alice")
+	mustOpen(code)
+	code, closeA3 := open("alice")
+	closeA3()
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("third stream for one user: status %d, want 503", code)
+	}
+
+	code, closeB1 := open("bob")
+	mustOpen(code)
+	code, closeB2 := open("bob")
+	closeB2()
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("stream over overall cap: status %d, want 503", code)
+	}
+
+	// Disconnecting frees the slot.
+	closeA1()
+	deadline := time.Now().Add(2 * time.Second)
+	for active() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("active streams = %d after disconnect, want 2", active())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	code, closeB3 := open("bob")
+	mustOpen(code)
+
+	closeA2()
+	closeB1()
+	closeB3()
+}
+
+func TestCountsCache(t *testing.T) {
+	s := newTestServer(t)
+	s.countsCache = newCountsCache(time.Minute)
+	seedFeed(t, s, "cached", nil, 4)
+	var ids []int64
+	rows, err := s.DB.Query("SELECT id FROM articles ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id int64
+		_ = rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/counts", s.HandleGetCounts)
+	mux.HandleFunc("POST /api/articles/{id}/read", s.HandleMarkRead)
+	handler := s.countsCacheMiddleware(mux)
+
+	unread := func() int64 {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, authReq("GET", "/api/counts", ""))
+		assertStatus(t, w, 200)
+		var c struct {
+			Unread int64 `json:"unread"`
+		}
+		decodeJSON(t, w, &c)
+		return c.Unread
+	}
+
+	if got := unread(); got != 4 {
+		t.Fatalf("unread = %d, want 4", got)
+	}
+
+	// A change behind the API's back isn't seen while the entry is cached
+	if _, err := s.DB.Exec("INSERT INTO article_states (user_id, article_id, is_read) VALUES ('testuser', ?, 1)", ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got := unread(); got != 4 {
+		t.Errorf("second call: unread = %d, want cached 4", got)
+	}
+
+	// Marking read through the API invalidates the entry
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, authReq("POST", fmt.Sprintf("/api/articles/%d/read", ids[1]), ""))
+	assertStatus(t, w, 200)
+	if got := unread(); got != 2 {
+		t.Errorf("after mark-read: unread = %d, want 2", got)
+	}
+
+	// A Fever mark carries no session user, so the handler invalidates the
+	// API key's owner itself
+	mux.HandleFunc("/fever/", s.HandleFever)
+	if err := s.setFeverKeys(context.Background(), "testuser", "pw", []string{"testuser"}); err != nil {
+		t.Fatalf("setFeverKeys: %v", err)
+	}
+	r := httptest.NewRequest("POST", "/fever/?api", strings.NewReader(fmt.Sprintf("api_key=%s&mark=item&as=read&id=%d", feverAPIKey("testuser", "pw"), ids[2])))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assertStatus(t, w, 200)
+	if got := unread(); got != 1 {
+		t.Errorf("after Fever mark: unread = %d, want 1", got)
+	}
+}
+
+func TestFreshCount(t *testing.T) {
+	s := newTestServer(t)
+	s.FreshHours = 6
+	feed := seedFeed(t, s, "fresh", nil, 0)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	add := func(guid string, pub *time.Time) int64 {
+		a, err := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
+			FeedID: feed.ID, Guid: guid, Url: "http://example.com/" + guid, Title: guid, PublishedAt: pub,
+		})
+		if err != nil {
+			t.Fatalf("UpsertArticle: %v", err)
+		}
+		return a.ID
+	}
+	recent := now.Add(-time.Hour)
+	stale := now.Add(-48 * time.Hour)
+	add("recent", &recent)
+	add("stale", &stale)
+	add("undated", nil) // falls back to created_at (now)
+	readID := add("recent-read", &recent)
+	_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: readID, ReadAt: &now})
+
+	counts := func() (unread, fresh int64) {
+		w := httptest.NewRecorder()
+		s.HandleGetCounts(w, authReq("GET", "/api/counts", ""))
+		assertStatus(t, w, 200)
+		var c struct {
+			Unread int64 `json:"unread"`
+			Fresh  int64 `json:"fresh"`
+		}
+		decodeJSON(t, w, &c)
+		return c.Unread, c.Fresh
+	}
+
+	if unread, fresh := counts(); unread != 3 || fresh != 2 {
+		t.Errorf("unread = %d, fresh = %d; want 3 and 2", unread, fresh)
+	}
+
+	s.FreshHours = 0
+	if _, fresh := counts(); fresh != 0 {
+		t.Errorf("fresh = %d with window disabled, want 0", fresh)
+	}
+}
+
+// --------------- Category-filtered Articles ---------------
+
+func TestArticlesByCategory(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	// Ensure user exists
+	email := "test@example.com"
+	now := time.Now()
+	_ = q.UpsertUser(ctx, dbgen.UpsertUserParams{
+		ID: "testuser", Email: &email, CreatedAt: now, LastSeen: now,
+	})
+
+	cat, err := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "Go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	catID := cat.ID
+
+	seedFeed(t, s, "go-blog", &catID, 3)
+	seedFeed(t, s, "uncategorized", nil, 2) // no category
+
+	t.Run("by category", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.HandleGetArticles(w, authReq("GET", "/api/articles?category_id="+fmt.Sprint(catID), ""))
+		assertStatus(t, w, 200)
+		var list []json.RawMessage
+		decodeJSON(t, w, &list)
+		if len(list) != 3 {
+			t.Errorf("got %d, want 3", len(list))
+		}
+	})
+
+	t.Run("by category unread", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.HandleGetArticles(w, authReq("GET", "/api/articles?category_id="+fmt.Sprint(catID)+"&view=unread", ""))
+		assertStatus(t, w, 200)
+		var list []json.RawMessage
+		decodeJSON(t, w, &list)
+		if len(list) != 3 {
+			t.Errorf("got %d, want 3", len(list))
+		}
+	})
+
+	t.Run("uncategorized (catId=0)", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.HandleGetArticles(w, authReq("GET", "/api/articles?category_id=0", ""))
+		assertStatus(t, w, 200)
+		var list []json.RawMessage
+		decodeJSON(t, w, &list)
+		if len(list) != 2 {
+			t.Errorf("got %d uncategorized, want 2", len(list))
+		}
+	})
+
+	t.Run("uncategorized unread (catId=0)", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.HandleGetArticles(w, authReq("GET", "/api/articles?category_id=0&view=unread", ""))
+		assertStatus(t, w, 200)
+		var list []json.RawMessage
+		decodeJSON(t, w, &list)
+		if len(list) != 2 {
+			t.Errorf("got %d, want 2", len(list))
+		}
+	})
+}
+
+// --------------- Refresh ---------------
+
+func TestRefresh(t *testing.T) {
+	s := newTestServer(t)
+	w := httptest.NewRecorder()
+	s.HandleRefresh(w, authReq("POST", "/api/feeds/refresh", ""))
+	s.jobs.Wait()
+	assertStatus(t, w, 200)
+	var m map[string]string
+	decodeJSON(t, w, &m)
+	if m["status"] != "refreshing" {
+		t.Errorf("status = %q, want refreshing", m["status"])
+	}
+
+	// A manual refresh stops at shutdown like the scheduled ones
+	hits := 0
+	feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+	defer feedSrv.Close()
+	q := dbgen.New(s.DB)
+	feed := seedFeed(t, s, "manual", nil, 0)
+	_ = q.UpdateFeedDetails(context.Background(), dbgen.UpdateFeedDetailsParams{Title: "manual", Url: feedSrv.URL, ID: feed.ID, UserID: "testuser"})
+	s.fetcher.AllowPrivateURLs = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.jobsCtx = ctx
+	s.HandleRefresh(httptest.NewRecorder(), authReq("POST", "/api/feeds/refresh", ""))
+	s.jobs.Wait()
+	if hits != 0 {
+		t.Errorf("refresh fetched %d times after shutdown began, want 0", hits)
+	}
+}
+
+// --------------- Reorder ---------------
+
+func TestReorderCategories(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	email := "test@example.com"
+	now := time.Now()
+	_ = q.UpsertUser(ctx, dbgen.UpsertUserParams{
+		ID: "testuser", Email: &email, CreatedAt: now, LastSeen: now,
+	})
+	c1, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "A"})
+	c2, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "B"})
+
+	body := fmt.Sprintf(`[{"id":%d,"order":2},{"id":%d,"order":1}]`, c1.ID, c2.ID)
+	w := httptest.NewRecorder()
+	s.HandleReorderCategories(w, authReq("POST", "/api/categories/reorder", body))
+	assertStatus(t, w, 200)
+}
+
+func TestReorderCategoriesBadJSON(t *testing.T) {
+	s := newTestServer(t)
+	w := httptest.NewRecorder()
+	s.HandleReorderCategories(w, authReq("POST", "/api/categories/reorder", "not json"))
+	assertStatus(t, w, 400)
+}
+
+func TestReorderFeeds(t *testing.T) {
+	s := newTestServer(t)
+	f := seedFeed(t, s, "rf", nil, 0)
+	body := fmt.Sprintf(`[{"id":%d,"order":1}]`, f.ID)
+	w := httptest.NewRecorder()
+	s.HandleReorderFeeds(w, authReq("POST", "/api/feeds/reorder", body))
+	assertStatus(t, w, 200)
+}
+
+func TestReorderFeedsBadJSON(t *testing.T) {
+	s := newTestServer(t)
+	w := httptest.NewRecorder()
+	s.HandleReorderFeeds(w, authReq("POST", "/api/feeds/reorder", "bad"))
+	assertStatus(t, w, 400)
+}
+
+func TestMoveFeed(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	a := seedFeed(t, s, "a", nil, 0)
+	b := seedFeed(t, s, "b", nil, 0)
+	c := seedFeed(t, s, "c", nil, 0)
+	cat, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "Cat"})
+	d := seedFeed(t, s, "d", &cat.ID, 0)
+
+	type orderResp struct {
+		Order []struct {
+			ID         int64  `json:"id"`
+			Order      int64  `json:"order"`
+			CategoryID *int64 `json:"category_id"`
+		} `json:"order"`
+	}
+	move := func(id int64, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := authReq("POST", fmt.Sprintf("/api/feeds/%d/move", id), body)
+		r.SetPathValue("id", fmt.Sprint(id))
+		s.HandleMoveFeed(w, r)
+		return w
+	}
+	ids := func(resp orderResp) []int64 {
+		var out []int64
+		for _, e := range resp.Order {
+			out = append(out, e.ID)
+		}
+		return out
+	}
+
+	t.Run("after_id", func(t *testing.T) {
+		w := move(a.ID, fmt.Sprintf(`{"after_id":%d}`, c.ID))
+		assertStatus(t, w, 200)
+		var resp orderResp
+		decodeJSON(t, w, &resp)
+		if got, want := fmt.Sprint(ids(resp)), fmt.Sprint([]int64{b.ID, c.ID, a.ID}); got != want {
+			t.Errorf("order = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("position into category", func(t *testing.T) {
+		w := move(b.ID, fmt.Sprintf(`{"position":0,"category_id":%d}`, cat.ID))
+		assertStatus(t, w, 200)
+		var resp orderResp
+		decodeJSON(t, w, &resp)
+		if got, want := fmt.Sprint(ids(resp)), fmt.Sprint([]int64{b.ID, d.ID}); got != want {
+			t.Errorf("order = %s, want %s", got, want)
+		}
+		feeds, _ := q.GetFeedsOrdered(ctx, "testuser")
+		for _, f := range feeds {
+			if f.ID == b.ID && (f.CategoryID == nil || *f.CategoryID != cat.ID || f.SortOrder != 0) {
+				t.Errorf("feed b = category %v order %d, want %d/0", f.CategoryID, f.SortOrder, cat.ID)
+			}
+		}
+	})
+
+	t.Run("position clamped", func(t *testing.T) {
+		w := move(c.ID, `{"position":99,"category_id":0}`)
+		assertStatus(t, w, 200)
+		var resp orderResp
+		decodeJSON(t, w, &resp)
+		if got, want := fmt.Sprint(ids(resp)), fmt.Sprint([]int64{a.ID, c.ID}); got != want {
+			t.Errorf("order = %s, want %s", got, want)
+		}
+	})
+
+	assertStatus(t, move(a.ID, `{}`), 400)
+	assertStatus(t, move(a.ID, `{"after_id":999999}`), 400)
+	assertStatus(t, move(a.ID, `{"position":0,"category_id":999999}`), 400)
+	assertStatus(t, move(999999, `{"position":0}`), 404)
+}
+
+func TestMoveFeedToCategory(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	x := seedFeed(t, s, "x", nil, 0)
+	cat, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "Cat"})
+	a := seedFeed(t, s, "a", &cat.ID, 0)
+	b := seedFeed(t, s, "b", &cat.ID, 0)
+	c := seedFeed(t, s, "c", &cat.ID, 0)
+
+	move := func(id int64, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := authReq("PUT", fmt.Sprintf("/api/feeds/%d/move-to-category", id), body)
+		r.SetPathValue("id", fmt.Sprint(id))
+		s.HandleMoveFeedToCategory(w, r)
+		return w
+	}
+
+	w := move(x.ID, fmt.Sprintf(`{"category_id":%d,"position":1}`, cat.ID))
+	assertStatus(t, w, 200)
+	var resp struct {
+		Order []struct {
+			ID         int64  `json:"id"`
+			Order      int64  `json:"order"`
+			CategoryID *int64 `json:"category_id"`
+		} `json:"order"`
+	}
+	decodeJSON(t, w, &resp)
+	want := []int64{a.ID, x.ID, b.ID, c.ID}
+	if len(resp.Order) != len(want) {
+		t.Fatalf("order = %+v, want ids %v", resp.Order, want)
+	}
+	for i, e := range resp.Order {
+		if e.ID != want[i] || e.Order != int64(i) || e.CategoryID == nil || *e.CategoryID != cat.ID {
+			t.Errorf("order[%d] = %+v, want id %d order %d in category %d", i, e, want[i], i, cat.ID)
+		}
+	}
+
+	// The stored sort orders match the response
+	feeds, _ := q.GetFeedsOrdered(ctx, "testuser")
+	for _, f := range feeds {
+		for i, id := range want {
+			if f.ID == id && (f.SortOrder != int64(i) || f.CategoryID == nil || *f.CategoryID != cat.ID) {
+				t.Errorf("feed %d = category %v order %d, want %d/%d", f.ID, f.CategoryID, f.SortOrder, cat.ID, i)
+			}
+		}
+	}
+
+	assertStatus(t, move(x.ID, `{"position":0}`), 400)
+	assertStatus(t, move(x.ID, fmt.Sprintf(`{"category_id":%d}`, cat.ID)), 400)
+	assertStatus(t, move(x.ID, `{"category_id":999999,"position":0}`), 400)
+	assertStatus(t, move(999999, `{"category_id":0,"position":0}`), 404)
+}
+
+func TestMoveCategory(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	seedFeed(t, s, "user", nil, 0)
+	var cats []dbgen.Category
+	for _, title := range []string{"A", "B", "C"} {
+		c, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: title})
+		cats = append(cats, c)
+	}
+
+	w := httptest.NewRecorder()
+	r := authReq("POST", "/api/categories/x/move", fmt.Sprintf(`{"after_id":%d}`, cats[1].ID))
+	r.SetPathValue("id", fmt.Sprint(cats[0].ID))
+	s.HandleMoveCategory(w, r)
+	assertStatus(t, w, 200)
+
+	ordered, _ := q.GetCategoriesOrdered(ctx, "testuser")
+	var titles []string
+	for _, c := range ordered {
+		titles = append(titles, c.Title)
+	}
+	if got := strings.Join(titles, ","); got != "B,A,C" {
+		t.Errorf("order = %s, want B,A,C", got)
+	}
+
+	w = httptest.NewRecorder()
+	r = authReq("POST", "/api/categories/x/move", `{"position":0}`)
+	r.SetPathValue("id", "999999")
+	s.HandleMoveCategory(w, r)
+	assertStatus(t, w, 404)
+}
+
+// --------------- OPML ---------------
+
+func TestOPMLParseAndGenerate(t *testing.T) {
+	opmlXML := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>Test</title></head>
+  <body>
+    <outline text="Tech" title="Tech">
+      <outline type="rss" text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog"/>
+    </outline>
+    <outline type="rss" text="HN" xmlUrl="https://hnrss.org/newest"/>
+  </body>
+</opml>`
+
+	t.Run("parse", func(t *testing.T) {
+		feeds, err := ParseOPML(strings.NewReader(opmlXML))
+		if err != nil {
+			t.Fatalf("ParseOPML: %v", err)
+		}
+		if len(feeds) != 2 {
+			t.Fatalf("got %d feeds, want 2", len(feeds))
+		}
+		if feeds[0].Category != "Tech" {
+			t.Errorf("feed[0].Category = %q, want Tech", feeds[0].Category)
+		}
+		if feeds[0].URL != "https://go.dev/blog/feed.atom" {
+			t.Errorf("feed[0].URL = %q", feeds[0].URL)
+		}
+		if feeds[1].Category != "" {
+			t.Errorf("feed[1].Category = %q, want empty", feeds[1].Category)
+		}
+	})
+
+	t.Run("parse invalid xml", func(t *testing.T) {
+		_, err := ParseOPML(strings.NewReader("not xml"))
+		if err == nil {
+			t.Error("expected error for invalid XML")
+		}
+	})
+
+	t.Run("generate", func(t *testing.T) {
+		exports := []FeedExport{
+			{URL: "https://go.dev/feed", Title: "Go Blog", SiteURL: "https://go.dev", Category: "Tech"},
+			{URL: "https://hn.com/rss", Title: "HN", SiteURL: "https://hn.com", Category: ""},
+		}
+		data, err := GenerateOPML("Test Export", exports)
+		if err != nil {
+			t.Fatalf("GenerateOPML: %v", err)
+		}
+		xml := string(data)
+		if !strings.Contains(xml, "go.dev/feed") {
+			t.Error("missing go.dev/feed in output")
+		}
+		if !strings.Contains(xml, "Tech") {
+			t.Error("missing Tech category")
+		}
+		if !strings.Contains(xml, "hn.com/rss") {
+			t.Error("missing hn.com/rss")
+		}
+	})
+
+	t.Run("generate empty", func(t *testing.T) {
+		data, err := GenerateOPML("Empty", nil)
+		if err != nil {
+			t.Fatalf("GenerateOPML: %v", err)
+		}
+		if !strings.Contains(string(data), "Empty") {
+			t.Error("missing title")
+		}
+	})
+
+	t.Run("roundtrip", func(t *testing.T) {
+		exports := []FeedExport{
+			{URL: "https://a.com/rss", Title: "A", Category: "Cat1"},
+			{URL: "https://b.com/rss", Title: "B", Category: "Cat1"},
+			{URL: "https://c.com/rss", Title: "C", Category: ""},
+		}
+		data, _ := GenerateOPML("RT", exports)
+		parsed, err := ParseOPML(strings.NewReader(string(data)))
+		if err != nil {
+			t.Fatalf("roundtrip parse: %v", err)
+		}
+		if len(parsed) != 3 {
+			t.Errorf("roundtrip got %d feeds, want 3", len(parsed))
+		}
+	})
+}
+
+func TestExportOPML(t *testing.T) {
+	s := newTestServer(t)
+	seedFeed(t, s, "export-feed", nil, 1)
+	w := httptest.NewRecorder()
+	s.HandleExportOPML(w, authReq("GET", "/api/opml/export", ""))
+	assertStatus(t, w, 200)
+	if ct := w.Header().Get("Content-Type"); ct != "application/xml" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "export-feed") {
+		t.Error("OPML should contain feed title")
+	}
+}
+
+func TestExportOPMLCategory(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	seedFeed(t, s, "loose-feed", nil, 0)
+	cat, _ := q.CreateCategory(context.Background(), dbgen.CreateCategoryParams{UserID: "testuser", Title: "Tech"})
+	seedFeed(t, s, "tech-feed", &cat.ID, 0)
+
+	export := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.HandleExportOPML(w, authReq("GET", "/api/opml/export"+query, ""))
+		return w
+	}
+
+	t.Run("single category", func(t *testing.T) {
+		w := export(fmt.Sprintf("?category_id=%d", cat.ID))
+		assertStatus(t, w, 200)
+		feeds, err := ParseOPML(w.Body)
+		if err != nil {
+			t.Fatalf("ParseOPML: %v", err)
+		}
+		if len(feeds) != 1 || feeds[0].Title != "tech-feed" || feeds[0].Category != "Tech" {
+			t.Errorf("got %+v, want only tech-feed in Tech", feeds)
+		}
+	})
+
+	t.Run("uncategorized", func(t *testing.T) {
+		w := export("?category_id=0")
+		assertStatus(t, w, 200)
+		feeds, _ := ParseOPML(w.Body)
+		if len(feeds) != 1 || feeds[0].Title != "loose-feed" {
+			t.Errorf("got %+v, want only loose-feed", feeds)
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		feeds, _ := ParseOPML(export("").Body)
+		if len(feeds) != 2 {
+			t.Errorf("got %d feeds, want 2", len(feeds))
+		}
+	})
+
+	assertStatus(t, export("?category_id=abc"), 400)
+	assertStatus(t, export("?category_id=999999"), 404)
+}
+
+// --------------- Auth / Sessions ---------------
+
+func TestAuthSessions(t *testing.T) {
+	t.Run("create and validate", func(t *testing.T) {
+		sid := createSession()
+		if sid == "" {
+			t.Fatal("empty session id")
+		}
+		if !validateSession(sid) {
+			t.Error("session should be valid")
+		}
+	})
+
+	t.Run("invalid session", func(t *testing.T) {
+		if validateSession("nonexistent") {
+			t.Error("nonexistent session should be invalid")
+		}
+	})
+
+	t.Run("delete session", func(t *testing.T) {
+		sid := createSession()
+		deleteSession(sid)
+		if validateSession(sid) {
+			t.Error("deleted session should be invalid")
+		}
+	})
+
+	t.Run("cleanup expired", func(t *testing.T) {
+		// Manually insert an expired session
+		sessionsLock.Lock()
+		sessions["expired-test"] = time.Now().Add(-time.Hour)
+		sessionsLock.Unlock()
+
+		cleanupSessions()
+		if validateSession("expired-test") {
+			t.Error("expired session should be cleaned up")
+		}
+	})
+
+	t.Run("cap evicts soonest-expiring", func(t *testing.T) {
+		t.Setenv("GORSS_MAX_SESSIONS", "5")
+		sessionsLock.Lock()
+		saved := sessions
+		sessions = make(map[string]time.Time)
+		sessionsLock.Unlock()
+		t.Cleanup(func() {
+			sessionsLock.Lock()
+			sessions = saved
+			sessionsLock.Unlock()
+		})
+
+		var ids []string
+		for range 20 {
+			ids = append(ids, createSession())
+			time.Sleep(time.Millisecond) // distinct expiry times
+		}
+		sessionsLock.RLock()
+		n := len(sessions)
+		sessionsLock.RUnlock()
+		if n != 5 {
+			t.Errorf("stored sessions = %d, want 5", n)
+		}
+		if validateSession(ids[0]) {
+			t.Error("oldest session should have been evicted")
+		}
+		for _, id := range ids[15:] {
+			if !validateSession(id) {
+				t.Error("newest sessions should be kept")
+			}
+		}
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gorss.yaml")
+	config := `# test config
+port: 9090
+db_path: /tmp/from-file.db
+purge_days: 10
+refresh_interval: 15m
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore the environment afterwards; GORSS_PURGE_DAYS is set and must win
+	for _, name := range []string{"GORSS_PORT", "GORSS_DB_PATH", "GORSS_REFRESH_INTERVAL", "GORSS_BACKUP_KEEP"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("GORSS_PURGE_DAYS", "5")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		t.Fatalf("ApplyEnv: %v", err)
+	}
+	for name, want := range map[string]string{
+		"GORSS_PORT":             "9090",
+		"GORSS_DB_PATH":          "/tmp/from-file.db",
+		"GORSS_REFRESH_INTERVAL": "15m",
+		"GORSS_PURGE_DAYS":       "5", // environment overrides the file
+		"GORSS_BACKUP_KEEP":      "",  // unset in both keeps the built-in default
+	} {
+		if got := os.Getenv(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	t.Run("unknown key", func(t *testing.T) {
+		bad := filepath.Join(t.TempDir(), "bad.yaml")
+		_ = os.WriteFile(bad, []byte("prot: 9090\n"), 0o644)
+		if _, err := LoadConfig(bad); err == nil {
+			t.Error("expected error for unknown key")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := LoadConfig(filepath.Join(t.TempDir(), "nope.yaml")); err == nil {
+			t.Error("expected error for missing file")
+		}
+	})
+}
+
+func TestAuthMode(t *testing.T) {
+	t.Run("default none", func(t *testing.T) {
+		t.Setenv("GORSS_AUTH_MODE", "")
+		if GetAuthMode() != AuthModeNone {
+			t.Errorf("got %v, want none", GetAuthMode())
+		}
+	})
+	t.Run("password", func(t *testing.T) {
+		t.Setenv("GORSS_AUTH_MODE", "password")
+		if GetAuthMode() != AuthModePassword {
+			t.Errorf("got %v", GetAuthMode())
+		}
+	})
+	t.Run("proxy", func(t *testing.T) {
+		t.Setenv("GORSS_AUTH_MODE", "proxy")
+		if GetAuthMode() != AuthModeProxy {
+			t.Errorf("got %v", GetAuthMode())
+		}
+	})
+}
+
+func TestAuthMiddlewareUserContext(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("GORSS_AUTH_MODE", "proxy")
+
+	var got string
+	var inContext bool
+	handler := s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, inContext = r.Context().Value(userIDKey{}).(string)
+	}))
+
+	r := httptest.NewRequest("GET", "/api/feeds", nil)
+	r.Header.Set("X-ExeDev-UserID", "ctxuser")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if !inContext || got != "ctxuser" {
+		t.Fatalf("context user = %q (present %v), want ctxuser", got, inContext)
+	}
+	if _, err := dbgen.New(s.DB).GetUser(context.Background(), "ctxuser"); err != nil {
+		t.Errorf("user not upserted by middleware: %v", err)
+	}
+
+	// Without the middleware, handlers still resolve the user (anonymous fallback)
+	if id := s.userFromContext(httptest.NewRequest("GET", "/", nil)); id != "anonymous" {
+		t.Errorf("fallback user = %q, want anonymous", id)
+	}
+}
+
+func TestAdminGroup(t *testing.T) {
+	t.Setenv("GORSS_AUTH_MODE", "proxy")
+	t.Setenv("GORSS_ADMIN_GROUP", "rss-admins")
+	s := newTestServer(t)
+	if s.AdminGroup != "rss-admins" {
+		t.Fatalf("AdminGroup = %q, want rss-admins", s.AdminGroup)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/admin/integrity-check", s.HandleIntegrityCheck)
+	mux.HandleFunc("GET /api/feeds", s.HandleGetFeeds)
+	handler := s.AuthMiddleware(s.adminMiddleware(mux))
+
+	get := func(path, groups string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("X-ExeDev-UserID", "proxyuser")
+		if groups != "" {
+			r.Header.Set("X-ExeDev-Groups", groups)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	assertStatus(t, get("/api/admin/integrity-check", "readers, rss-admins"), 200)
+	assertStatus(t, get("/api/admin/integrity-check", "readers"), http.StatusForbidden)
+	assertStatus(t, get("/api/admin/integrity-check", "rss-admins-extra"), http.StatusForbidden)
+	assertStatus(t, get("/api/admin/integrity-check", ""), http.StatusForbidden)
+	// Other endpoints don't need the group
+	assertStatus(t, get("/api/feeds", ""), 200)
+
+	// Without GORSS_ADMIN_GROUP nobody is allowed
+	s.AdminGroup = ""
+	handler = s.AuthMiddleware(s.adminMiddleware(mux))
+	assertStatus(t, get("/api/admin/integrity-check", "rss-admins"), http.StatusForbidden)
+
+	// Single-user modes are always allowed, and never read the groups header
+	t.Setenv("GORSS_AUTH_MODE", "none")
+	s.AdminGroup = "rss-admins"
+	handler = s.AuthMiddleware(s.adminMiddleware(mux))
+	assertStatus(t, get("/api/admin/integrity-check", ""), 200)
+	r := authReq("GET", "/", "")
+	r.Header.Set("X-ExeDev-Groups", "rss-admins")
+	if groups := getUserGroups(r); groups != nil {
+		t.Errorf("groups outside proxy mode = %v, want none", groups)
+	}
+}
+
+func TestAdminHandlersFailClosed(t *testing.T) {
+	t.Setenv("GORSS_AUTH_MODE", "proxy")
+	s := newTestServer(t)
+	handlers := map[string]http.HandlerFunc{
+		"GET /api/admin/backup/download": s.HandleDownloadBackup,
+		"GET /api/admin/integrity-check": s.HandleIntegrityCheck,
+		"POST /api/admin/repair":         s.HandleRepair,
+		"POST /api/admin/migrate-user":   s.HandleMigrateUser,
+	}
+	for route, h := range handlers {
+		method, path, _ := strings.Cut(route, " ")
+		// Called directly, without adminMiddleware in front
+		w := httptest.NewRecorder()
+		h(w, authReq(method, path, ""))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s without GORSS_ADMIN_GROUP: status %d, want 403", route, w.Code)
+		}
+	}
+}
+
+func TestDefaultUser(t *testing.T) {
+	t.Setenv("GORSS_DEFAULT_USER", "household")
+	s := newTestServer(t)
+	var got string
+	handler := s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = s.userFromContext(r)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/feeds", nil))
+	if got != "household" {
+		t.Fatalf("user = %q, want household", got)
+	}
+	if _, err := dbgen.New(s.DB).GetUser(context.Background(), "household"); err != nil {
+		t.Errorf("default user not created: %v", err)
+	}
+
+	t.Run("reject without user ID", func(t *testing.T) {
+		s.RequireUserID = true
+		// Only proxy mode has user IDs to require
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/feeds", nil))
+		assertStatus(t, w, 200)
+
+		t.Setenv("GORSS_AUTH_MODE", "proxy")
+		handler := s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = s.userFromContext(r)
+		}))
+		got = ""
+		r := httptest.NewRequest("GET", "/api/feeds", nil)
+		r.Header.Set("X-ExeDev-UserID", " ")
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assertStatus(t, w, http.StatusUnauthorized)
+		if got != "" {
+			t.Errorf("handler ran as %q", got)
+		}
+
+		// Requests carrying a user ID and public paths still get through
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, authReq("GET", "/api/feeds", ""))
+		assertStatus(t, w, 200)
+		if got != "testuser" {
+			t.Errorf("user = %q, want testuser", got)
+		}
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+		assertStatus(t, w, 200)
+	})
+}
+
+func TestEnsureUserDebounced(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	q := dbgen.New(s.DB)
+
+	req := func(email string) *http.Request {
+		r := authReq("GET", "/api/feeds", "")
+		r.Header.Set("X-ExeDev-Email", email)
+		return r
+	}
+	// Mark the stored row so any further write is detectable
+	sentinel := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	stamp := func() {
+		if _, err := s.DB.Exec(`UPDATE users SET last_seen = ? WHERE id = 'testuser'`, sentinel); err != nil {
+			t.Fatalf("stamp: %v", err)
+		}
+	}
+	lastSeen := func() time.Time {
+		u, err := q.GetUser(ctx, "testuser")
+		if err != nil {
+			t.Fatalf("GetUser: %v", err)
+		}
+		return u.LastSeen
+	}
+
+	if _, err := s.ensureUser(req("a@example.com")); err != nil {
+		t.Fatalf("ensureUser: %v", err)
+	}
+	stamp()
+	for range 5 {
+		_, _ = s.ensureUser(req("a@example.com"))
+	}
+	if !lastSeen().Equal(sentinel) {
+		t.Error("repeated requests within the interval should not write the user")
+	}
+
+	// A changed email is written immediately
+	_, _ = s.ensureUser(req("b@example.com"))
+	if lastSeen().Equal(sentinel) {
+		t.Error("email change should write the user")
+	}
+
+	// Once the interval has passed, last_seen is refreshed
+	stamp()
+	s.usersSeen.interval = time.Nanosecond
+	_, _ = s.ensureUser(req("b@example.com"))
+	if lastSeen().Equal(sentinel) {
+		t.Error("expired entry should write the user")
+	}
+}
+
+func TestLoginLogout(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("GORSS_AUTH_MODE", "password")
+	t.Setenv("GORSS_PASSWORD", "secret123")
+
+	t.Run("login page GET", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.HandleLogin(w, httptest.NewRequest("GET", "/login", nil))
+		assertStatus(t, w, 200)
+		if !strings.Contains(w.Body.String(), "password") {
+			t.Error("login page should contain password field")
+		}
+	})
+
+	t.Run("login wrong password", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/login", strings.NewReader("password=wrong"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.HandleLogin(w, r)
+		assertStatus(t, w, 200) // re-renders login page
+		if !strings.Contains(w.Body.String(), "Invalid") {
+			t.Error("should show invalid password error")
+		}
+	})
+
+	t.Run("login correct password", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/login", strings.NewReader("password=secret123"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.HandleLogin(w, r)
+		if w.Code != http.StatusFound {
+			t.Errorf("expected 302 redirect, got %d", w.Code)
+		}
+		cookie := w.Header().Get("Set-Cookie")
+		if !strings.Contains(cookie, "gorss_session") {
+			t.Error("expected session cookie")
+		}
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/logout", nil)
+		r.AddCookie(&http.Cookie{Name: "gorss_session", Value: createSession()})
+		w := httptest.NewRecorder()
+		s.HandleLogout(w, r)
+		if w.Code != http.StatusFound {
+			t.Errorf("expected 302, got %d", w.Code)
+		}
+	})
+}
+
+func TestCookieConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg := GetCookieConfig()
+		if cfg.Name != "gorss_session" || cfg.SameSite != http.SameSiteLaxMode || cfg.Secure != nil {
+			t.Errorf("got %+v", cfg)
+		}
+	})
+	t.Run("invalid samesite falls back to lax", func(t *testing.T) {
+		t.Setenv("GORSS_COOKIE_SAMESITE", "sideways")
+		if cfg := GetCookieConfig(); cfg.SameSite != http.SameSiteLaxMode {
+			t.Errorf("SameSite = %v, want lax", cfg.SameSite)
+		}
+	})
+	t.Run("none forces secure", func(t *testing.T) {
+		t.Setenv("GORSS_COOKIE_SAMESITE", "None")
+		t.Setenv("GORSS_COOKIE_SECURE", "false")
+		if !GetCookieConfig().secure(httptest.NewRequest("GET", "/", nil)) {
+			t.Error("SameSite=None cookie should be Secure")
+		}
+	})
+
+	s := newTestServer(t)
+	t.Setenv("GORSS_AUTH_MODE", "password")
+	t.Setenv("GORSS_PASSWORD", "secret123")
+	t.Setenv("GORSS_COOKIE_NAME", "myrss")
+	t.Setenv("GORSS_COOKIE_SAMESITE", "strict")
+	t.Setenv("GORSS_COOKIE_SECURE", "true")
+
+	r := httptest.NewRequest("POST", "/login", strings.NewReader("password=secret123"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	s.HandleLogin(w, r)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	c := cookies[0]
+	if c.Name != "myrss" || c.SameSite != http.SameSiteStrictMode || !c.Secure {
+		t.Errorf("cookie = %+v, want myrss/strict/secure", c)
+	}
+
+	// The middleware accepts the session under the configured name
+	handler := s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	r = httptest.NewRequest("GET", "/api/feeds", nil)
+	r.AddCookie(c)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assertStatus(t, w, 200)
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	s := newTestServer(t)
+	s.ReadOnly = true
+	handler := s.readOnlyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method, target, body string
+		want                 int
+	}{
+		{"GET", "/api/feeds", "", 200},
+		{"GET", "/api/articles?unread=1", "", 200},
+		{"GET", "/", "", 200},
+		{"POST", "/login", "password=x", 200},
+		{"POST", "/api/feeds", `{"url":"http://example.com/feed"}`, 403},
+		{"PUT", "/api/feeds/1", `{"title":"x"}`, 403},
+		{"DELETE", "/api/feeds/1", "", 403},
+		{"POST", "/api/articles/1/read", "", 403},
+		{"POST", "/fever/?api", "api_key=k&items", 200},
+		{"POST", "/fever/?api", "api_key=k&mark=item&as=read&id=1", 403},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if strings.Contains(tt.body, "=") {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.target, w.Code, tt.want)
+		}
+		if tt.want == 403 && !strings.Contains(w.Body.String(), "read-only mode") {
+			t.Errorf("%s %s: body = %q", tt.method, tt.target, w.Body.String())
+		}
+	}
+
+	// Off by default: writes pass through
+	s.ReadOnly = false
+	w := httptest.NewRecorder()
+	s.readOnlyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(w, httptest.NewRequest("POST", "/api/feeds", nil))
+	assertStatus(t, w, 200)
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(prev)
+
+	handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logFrom(r.Context()).Info("handled")
+	}))
+	serve := func(incoming string) string {
+		logs.Reset()
+		r := httptest.NewRequest("GET", "/api/feeds", nil)
+		if incoming != "" {
+			r.Header.Set("X-Request-ID", incoming)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		id := w.Header().Get("X-Request-ID")
+		if id == "" {
+			t.Fatalf("no X-Request-ID in response for incoming %q", incoming)
+		}
+		if !strings.Contains(logs.String(), "request_id="+id) {
+			t.Errorf("handler log %q lacks request_id=%s", logs.String(), id)
+		}
+		return id
+	}
+
+	if id := serve("abc-123"); id != "abc-123" {
+		t.Errorf("incoming ID not propagated: got %q", id)
+	}
+	first, second := serve(""), serve("")
+	if first == second {
+		t.Errorf("generated IDs repeat: %q", first)
+	}
+	for _, bad := range []string{"has space", "x\"y", strings.Repeat("a", 65)} {
+		if id := serve(bad); id == bad {
+			t.Errorf("unsafe incoming ID %q was echoed", bad)
+		}
+	}
 
-	// Only f2's articles remain unread
-	w = httptest.NewRecorder()
-	s.HandleGetArticles(w, authReq("GET", "/api/articles?view=unread", ""))
-	var list []json.RawMessage
-	decodeJSON(t, w, &list)
-	if len(list) != 2 {
-		t.Errorf("expected 2 unread, got %d", len(list))
+	// Background jobs get their own IDs
+	logs.Reset()
+	logFrom(withJobID(context.Background(), "refresh")).Info("cycle")
+	if !strings.Contains(logs.String(), "job=refresh") || !strings.Contains(logs.String(), "job_id=") {
+		t.Errorf("job log %q lacks job ID", logs.String())
 	}
 }
 
-func TestMarkFeedReadInvalidID(t *testing.T) {
+// --------------- Get Single Article ---------------
+
+func TestGetArticle(t *testing.T) {
 	s := newTestServer(t)
-	w := httptest.NewRecorder()
-	r := authReq("POST", "/api/feeds/bad/mark-read", "")
-	r.SetPathValue("id", "bad")
-	s.HandleMarkFeedRead(w, r)
-	assertStatus(t, w, 400)
+	feed := seedFeed(t, s, "content-feed", nil, 2)
+
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	arts, _ := q.GetArticlesByFeed(ctx, dbgen.GetArticlesByFeedParams{
+		UserID: "testuser", ID: feed.ID, UserID_2: "testuser", Limit: 10,
+	})
+	if len(arts) == 0 {
+		t.Fatal("expected articles")
+	}
+	id := fmt.Sprint(arts[0].ID)
+
+	t.Run("success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := authReq("GET", "/api/articles/"+id, "")
+		r.SetPathValue("id", id)
+		s.HandleGetArticle(w, r)
+		assertStatus(t, w, 200)
+		var a map[string]any
+		decodeJSON(t, w, &a)
+		if a["content"] == nil {
+			t.Error("expected content field in single article response")
+		}
+		if a["summary"] == nil {
+			t.Error("expected summary field")
+		}
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := authReq("GET", "/api/articles/abc", "")
+		r.SetPathValue("id", "abc")
+		s.HandleGetArticle(w, r)
+		assertStatus(t, w, 400)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := authReq("GET", "/api/articles/999999", "")
+		r.SetPathValue("id", "999999")
+		s.HandleGetArticle(w, r)
+		assertStatus(t, w, 404)
+	})
+
+	t.Run("mark read", func(t *testing.T) {
+		get := func(query string) map[string]any {
+			w := httptest.NewRecorder()
+			r := authReq("GET", "/api/articles/"+id+query, "")
+			r.SetPathValue("id", id)
+			s.HandleGetArticle(w, r)
+			assertStatus(t, w, 200)
+			var a map[string]any
+			decodeJSON(t, w, &a)
+			return a
+		}
+		readAt := func() string {
+			var v string
+			if err := s.DB.QueryRow(`SELECT read_at FROM article_states WHERE user_id = 'testuser' AND article_id = ?`, arts[0].ID).Scan(&v); err != nil {
+				t.Fatalf("read_at: %v", err)
+			}
+			return v
+		}
+
+		if a := get(""); a["is_read"] != float64(0) {
+			t.Fatalf("plain GET should not mark read, is_read = %v", a["is_read"])
+		}
+		if a := get("?mark_read=true"); a["is_read"] != float64(1) {
+			t.Fatalf("is_read = %v, want 1", a["is_read"])
+		}
+		first := readAt()
+		time.Sleep(10 * time.Millisecond)
+		if a := get("?mark_read=true"); a["is_read"] != float64(1) {
+			t.Fatalf("is_read = %v, want 1", a["is_read"])
+		}
+		if got := readAt(); got != first {
+			t.Errorf("read_at changed on repeat: %q -> %q", first, got)
+		}
+	})
+
+	t.Run("read=1", func(t *testing.T) {
+		other := fmt.Sprint(arts[1].ID)
+		get := func(query string) {
+			w := httptest.NewRecorder()
+			r := authReq("GET", "/api/articles/"+other+query, "")
+			r.SetPathValue("id", other)
+			s.HandleGetArticle(w, r)
+			assertStatus(t, w, 200)
+		}
+		isRead := func() int {
+			var n int
+			_ = s.DB.QueryRow(`SELECT COALESCE(MAX(is_read), 0) FROM article_states WHERE user_id = 'testuser' AND article_id = ?`, arts[1].ID).Scan(&n)
+			return n
+		}
+
+		get("")
+		if isRead() != 0 {
+			t.Fatal("plain GET marked the article read")
+		}
+		get("?read=1")
+		if isRead() != 1 {
+			t.Fatal("GET ?read=1 did not mark the article read")
+		}
+	})
 }
 
-// --------------- Counts ---------------
+// --------------- Article List Strips Content ---------------
 
-func TestCounts(t *testing.T) {
+func TestArticleListStripsContent(t *testing.T) {
 	s := newTestServer(t)
-	feed := seedFeed(t, s, "counted", nil, 4)
+	seedFeed(t, s, "strip-test", nil, 1)
 
 	w := httptest.NewRecorder()
-	s.HandleGetCounts(w, authReq("GET", "/api/counts", ""))
+	s.HandleGetArticles(w, authReq("GET", "/api/articles", ""))
 	assertStatus(t, w, 200)
 
-	var counts struct {
-		Total   int64            `json:"total"`
-		Unread  int64            `json:"unread"`
-		Starred int64            `json:"starred"`
-		Feeds   map[string]int64 `json:"feeds"`
+	var list []map[string]any
+	decodeJSON(t, w, &list)
+	if len(list) == 0 {
+		t.Fatal("expected articles")
 	}
-	decodeJSON(t, w, &counts)
-	if counts.Total != 4 {
-		t.Errorf("total = %d, want 4", counts.Total)
+	// List endpoint should NOT include content or summary
+	if _, ok := list[0]["content"]; ok {
+		t.Error("list should not contain content field")
 	}
-	if counts.Unread != 4 {
-		t.Errorf("unread = %d, want 4", counts.Unread)
+	if _, ok := list[0]["summary"]; ok {
+		t.Error("list should not contain summary field")
 	}
-	if counts.Starred != 0 {
-		t.Errorf("starred = %d, want 0", counts.Starred)
+	// But should have metadata
+	if _, ok := list[0]["title"]; !ok {
+		t.Error("list should contain title")
 	}
-	fidStr := fmt.Sprint(feed.ID)
-	if counts.Feeds[fidStr] != 4 {
-		t.Errorf("feed count = %d, want 4", counts.Feeds[fidStr])
+	if _, ok := list[0]["feed_title"]; !ok {
+		t.Error("list should contain feed_title")
 	}
 }
 
-// --------------- Category-filtered Articles ---------------
+// --------------- Batch Mark Read ---------------
 
-func TestArticlesByCategory(t *testing.T) {
+func TestMarkReadBatch(t *testing.T) {
 	s := newTestServer(t)
+	feed := seedFeed(t, s, "batch-feed", nil, 5)
+
 	q := dbgen.New(s.DB)
 	ctx := context.Background()
+	arts, _ := q.GetArticlesByFeed(ctx, dbgen.GetArticlesByFeedParams{
+		UserID: "testuser", ID: feed.ID, UserID_2: "testuser", Limit: 10,
+	})
 
-	// Ensure user exists
+	t.Run("batch mark 3 as read", func(t *testing.T) {
+		ids := []int64{arts[0].ID, arts[1].ID, arts[2].ID}
+		body := fmt.Sprintf(`{"ids":[%d,%d,%d]}`, ids[0], ids[1], ids[2])
+		w := httptest.NewRecorder()
+		s.HandleMarkReadBatch(w, authReq("POST", "/api/articles/mark-read-batch", body))
+		assertStatus(t, w, 200)
+
+		// Verify only 2 remain unread
+		w2 := httptest.NewRecorder()
+		s.HandleGetArticles(w2, authReq("GET", "/api/articles?view=unread", ""))
+		var list []json.RawMessage
+		decodeJSON(t, w2, &list)
+		if len(list) != 2 {
+			t.Errorf("got %d unread, want 2", len(list))
+		}
+	})
+
+	t.Run("empty ids", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.HandleMarkReadBatch(w, authReq("POST", "/api/articles/mark-read-batch", `{"ids":[]}`))
+		assertStatus(t, w, 400)
+	})
+
+	t.Run("bad json", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.HandleMarkReadBatch(w, authReq("POST", "/api/articles/mark-read-batch", `not json`))
+		assertStatus(t, w, 400)
+	})
+}
+
+// --------------- Mark All Read by Category ---------------
+
+func TestMarkAllReadByCategory(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
 	email := "test@example.com"
 	now := time.Now()
 	_ = q.UpsertUser(ctx, dbgen.UpsertUserParams{
 		ID: "testuser", Email: &email, CreatedAt: now, LastSeen: now,
 	})
+	cat, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "CatA"})
+	catID := cat.ID
+	seedFeed(t, s, "cat-feed", &catID, 3)
+	seedFeed(t, s, "other-feed", nil, 2)
 
-	cat, err := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "Go"})
-	if err != nil {
-		t.Fatal(err)
+	w := httptest.NewRecorder()
+	s.HandleMarkAllRead(w, authReq("POST", "/api/articles/mark-all-read?category_id="+fmt.Sprint(catID), ""))
+	assertStatus(t, w, 200)
+	var resp struct {
+		Marked int64 `json:"marked"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Marked != 3 {
+		t.Errorf("marked = %d, want 3", resp.Marked)
 	}
-	catID := cat.ID
 
-	seedFeed(t, s, "go-blog", &catID, 3)
-	seedFeed(t, s, "uncategorized", nil, 2) // no category
+	// Category articles should be read, other feed still unread
+	w2 := httptest.NewRecorder()
+	s.HandleGetArticles(w2, authReq("GET", "/api/articles?view=unread", ""))
+	var list []json.RawMessage
+	decodeJSON(t, w2, &list)
+	if len(list) != 2 {
+		t.Errorf("expected 2 unread (from other-feed), got %d", len(list))
+	}
+}
 
-	t.Run("by category", func(t *testing.T) {
+// --------------- Theme Support ---------------
+
+func TestThemeSupport(t *testing.T) {
+	s := newTestServer(t)
+
+	t.Run("CSS contains dark theme variables", func(t *testing.T) {
+		css, err := os.ReadFile(filepath.Join(s.StaticDir, "app.css"))
+		if err != nil {
+			t.Fatalf("read CSS: %v", err)
+		}
+		body := string(css)
+		if !strings.Contains(body, `[data-theme="dark"]`) {
+			t.Error("CSS should contain dark theme selector")
+		}
+		if !strings.Contains(body, "prefers-color-scheme: dark") {
+			t.Error("CSS should contain prefers-color-scheme media query")
+		}
+		if !strings.Contains(body, ".theme-toggle") {
+			t.Error("CSS should contain theme-toggle class")
+		}
+	})
+
+	t.Run("JS contains theme management", func(t *testing.T) {
+		js, err := os.ReadFile(filepath.Join(s.StaticDir, "app.js"))
+		if err != nil {
+			t.Fatalf("read JS: %v", err)
+		}
+		body := string(js)
+		if !strings.Contains(body, "gorss-theme-mode") {
+			t.Error("JS should contain theme localStorage key")
+		}
+		if !strings.Contains(body, "resolveTheme") {
+			t.Error("JS should contain resolveTheme function")
+		}
+		if !strings.Contains(body, "cycleTheme") {
+			t.Error("JS should contain cycleTheme function")
+		}
+		if !strings.Contains(body, "prefers-color-scheme") {
+			t.Error("JS should check prefers-color-scheme for auto mode")
+		}
+	})
+
+	t.Run("HTML contains theme toggle button", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		s.HandleGetArticles(w, authReq("GET", "/api/articles?category_id="+fmt.Sprint(catID), ""))
+		r := authReq("GET", "/", "")
+		s.HandleRoot(w, r)
 		assertStatus(t, w, 200)
-		var list []json.RawMessage
-		decodeJSON(t, w, &list)
-		if len(list) != 3 {
-			t.Errorf("got %d, want 3", len(list))
+		body := w.Body.String()
+		if !strings.Contains(body, "btn-theme") {
+			t.Error("HTML should contain theme toggle button")
+		}
+	})
+}
+
+// --------------- Update Feed ---------------
+
+func TestUpdateFeed(t *testing.T) {
+	s := newTestServer(t)
+	feed := seedFeed(t, s, "update-test", nil, 2)
+	fidStr := fmt.Sprint(feed.ID)
+
+	t.Run("rename feed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := authReq("PUT", "/api/feeds/"+fidStr, `{"title":"New Name"}`)
+		r.SetPathValue("id", fidStr)
+		s.HandleUpdateFeed(w, r)
+		assertStatus(t, w, 200)
+
+		// Verify the feed was actually renamed in the DB
+		q := dbgen.New(s.DB)
+		updated, err := q.GetFeed(context.Background(), dbgen.GetFeedParams{ID: feed.ID, UserID: "testuser"})
+		if err != nil {
+			t.Fatalf("get feed: %v", err)
+		}
+		if updated.Title != "New Name" {
+			t.Errorf("title = %q, want New Name", updated.Title)
 		}
 	})
 
-	t.Run("by category unread", func(t *testing.T) {
+	t.Run("empty title keeps current", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		s.HandleGetArticles(w, authReq("GET", "/api/articles?category_id="+fmt.Sprint(catID)+"&view=unread", ""))
-		assertStatus(t, w, 200)
-		var list []json.RawMessage
-		decodeJSON(t, w, &list)
-		if len(list) != 3 {
-			t.Errorf("got %d, want 3", len(list))
+		r := authReq("PUT", "/api/feeds/"+fidStr, `{"title":""}`)
+		r.SetPathValue("id", fidStr)
+		s.HandleUpdateFeed(w, r)
+		assertStatus(t, w, 200) // empty title defaults to current
+
+		q := dbgen.New(s.DB)
+		updated, err := q.GetFeed(context.Background(), dbgen.GetFeedParams{ID: feed.ID, UserID: "testuser"})
+		if err != nil {
+			t.Fatalf("get feed: %v", err)
+		}
+		if updated.Title != "New Name" { // kept from previous test
+			t.Errorf("title = %q, want New Name (unchanged)", updated.Title)
 		}
 	})
 
-	t.Run("uncategorized (catId=0)", func(t *testing.T) {
+	t.Run("auto read after days", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		s.HandleGetArticles(w, authReq("GET", "/api/articles?category_id=0", ""))
+		r := authReq("PUT", "/api/feeds/"+fidStr, `{"auto_read_after_days":7}`)
+		r.SetPathValue("id", fidStr)
+		s.HandleUpdateFeed(w, r)
 		assertStatus(t, w, 200)
-		var list []json.RawMessage
-		decodeJSON(t, w, &list)
-		if len(list) != 2 {
-			t.Errorf("got %d uncategorized, want 2", len(list))
+
+		q := dbgen.New(s.DB)
+		updated, _ := q.GetFeed(context.Background(), dbgen.GetFeedParams{ID: feed.ID, UserID: "testuser"})
+		if updated.AutoReadAfterDays != 7 {
+			t.Errorf("auto_read_after_days = %d, want 7", updated.AutoReadAfterDays)
 		}
+
+		w = httptest.NewRecorder()
+		r = authReq("PUT", "/api/feeds/"+fidStr, `{"auto_read_after_days":-1}`)
+		r.SetPathValue("id", fidStr)
+		s.HandleUpdateFeed(w, r)
+		assertStatus(t, w, 400)
 	})
 
-	t.Run("uncategorized unread (catId=0)", func(t *testing.T) {
+	t.Run("invalid id", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		s.HandleGetArticles(w, authReq("GET", "/api/articles?category_id=0&view=unread", ""))
-		assertStatus(t, w, 200)
-		var list []json.RawMessage
-		decodeJSON(t, w, &list)
-		if len(list) != 2 {
-			t.Errorf("got %d, want 2", len(list))
-		}
+		r := authReq("PUT", "/api/feeds/abc", `{"title":"X"}`)
+		r.SetPathValue("id", "abc")
+		s.HandleUpdateFeed(w, r)
+		assertStatus(t, w, 400)
 	})
-}
 
-// --------------- Refresh ---------------
+	t.Run("bad json", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := authReq("PUT", "/api/feeds/"+fidStr, "not json")
+		r.SetPathValue("id", fidStr)
+		s.HandleUpdateFeed(w, r)
+		assertStatus(t, w, 400)
+	})
 
-func TestRefresh(t *testing.T) {
-	s := newTestServer(t)
-	w := httptest.NewRecorder()
-	s.HandleRefresh(w, authReq("POST", "/api/feeds/refresh", ""))
-	assertStatus(t, w, 200)
-	var m map[string]string
-	decodeJSON(t, w, &m)
-	if m["status"] != "refreshing" {
-		t.Errorf("status = %q, want refreshing", m["status"])
-	}
+	t.Run("not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := authReq("PUT", "/api/feeds/999999", `{"title":"X"}`)
+		r.SetPathValue("id", "999999")
+		s.HandleUpdateFeed(w, r)
+		assertStatus(t, w, 404)
+	})
 }
 
-// --------------- Reorder ---------------
+// --------------- Import OPML ---------------
 
-func TestReorderCategories(t *testing.T) {
+func TestExportOPMLState(t *testing.T) {
 	s := newTestServer(t)
 	q := dbgen.New(s.DB)
 	ctx := context.Background()
-	email := "test@example.com"
-	now := time.Now()
-	_ = q.UpsertUser(ctx, dbgen.UpsertUserParams{
-		ID: "testuser", Email: &email, CreatedAt: now, LastSeen: now,
-	})
-	c1, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "A"})
-	c2, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "B"})
+	seedFeed(t, s, "state", nil, 3)
+	articleID := func(s *Server, guid string) int64 {
+		t.Helper()
+		var id int64
+		if err := s.DB.QueryRow("SELECT id FROM articles WHERE guid = ?", guid).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	readAt := time.Now()
+	starredAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: articleID(s, "state-a"), ReadAt: &readAt})
+	_ = q.SetArticleStarred(ctx, dbgen.SetArticleStarredParams{UserID: "testuser", ArticleID: articleID(s, "state-aa"), StarredAt: &starredAt})
 
-	body := fmt.Sprintf(`[{"id":%d,"order":2},{"id":%d,"order":1}]`, c1.ID, c2.ID)
-	w := httptest.NewRecorder()
-	s.HandleReorderCategories(w, authReq("POST", "/api/categories/reorder", body))
-	assertStatus(t, w, 200)
-}
+	export := func(query string) string {
+		w := httptest.NewRecorder()
+		s.HandleExportOPML(w, authReq("GET", "/api/opml/export"+query, ""))
+		assertStatus(t, w, 200)
+		return w.Body.String()
+	}
 
-func TestReorderCategoriesBadJSON(t *testing.T) {
-	s := newTestServer(t)
-	w := httptest.NewRecorder()
-	s.HandleReorderCategories(w, authReq("POST", "/api/categories/reorder", "not json"))
-	assertStatus(t, w, 400)
-}
+	if body := export(""); strings.Contains(body, "opml-state") {
+		t.Errorf("plain export carries reading state:\n%s", body)
+	}
 
-func TestReorderFeeds(t *testing.T) {
-	s := newTestServer(t)
-	f := seedFeed(t, s, "rf", nil, 0)
-	body := fmt.Sprintf(`[{"id":%d,"order":1}]`, f.ID)
-	w := httptest.NewRecorder()
-	s.HandleReorderFeeds(w, authReq("POST", "/api/feeds/reorder", body))
-	assertStatus(t, w, 200)
+	feeds, err := ParseOPML(strings.NewReader(export("?state=1")))
+	if err != nil {
+		t.Fatalf("ParseOPML: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].State == nil {
+		t.Fatalf("feeds = %+v, want one with state", feeds)
+	}
+	want := OPMLState{Read: 1, Unread: 2, Starred: []OPMLStarred{{
+		GUID: "state-aa", URL: "http://example.com/state-aa", Title: "Article state-aa", StarredAt: "2026-01-02T03:04:05Z",
+	}}}
+	if !reflect.DeepEqual(*feeds[0].State, want) {
+		t.Errorf("state = %+v, want %+v", *feeds[0].State, want)
+	}
+
+	// Importing the export elsewhere stars the same article again
+	feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>State</title>
+<item><guid>state-a</guid><title>A</title></item>
+<item><guid>state-aa</guid><title>AA</title></item>
+</channel></rss>`)
+	}))
+	defer feedSrv.Close()
+	other := newTestServer(t)
+	other.fetcher.AllowPrivateURLs = true
+	seedFeed(t, other, "existing", nil, 0)
+	imp := feeds[0]
+	imp.URL = feedSrv.URL
+	if res := other.importSingleFeed(ctx, "testuser", imp, nil); res.Reason != importCreated {
+		t.Fatalf("import = %+v", res)
+	}
+	var starred int64
+	var at time.Time
+	if err := other.DB.QueryRow("SELECT is_starred, starred_at FROM article_states WHERE article_id = ?", articleID(other, "state-aa")).Scan(&starred, &at); err != nil {
+		t.Fatalf("starred state not restored: %v", err)
+	}
+	if starred != 1 || !at.Equal(starredAt) {
+		t.Errorf("restored starred = %d at %v, want 1 at %v", starred, at, starredAt)
+	}
 }
 
-func TestReorderFeedsBadJSON(t *testing.T) {
+func TestImportOPML(t *testing.T) {
 	s := newTestServer(t)
-	w := httptest.NewRecorder()
-	s.HandleReorderFeeds(w, authReq("POST", "/api/feeds/reorder", "bad"))
-	assertStatus(t, w, 400)
-}
 
-// --------------- OPML ---------------
+	t.Run("bad request no file", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := authReq("POST", "/api/opml/import", "")
+		s.HandleImportOPML(w, r)
+		assertStatus(t, w, 400)
+	})
 
-func TestOPMLParseAndGenerate(t *testing.T) {
-	opmlXML := `<?xml version="1.0" encoding="UTF-8"?>
-<opml version="2.0">
-  <head><title>Test</title></head>
-  <body>
-    <outline text="Tech" title="Tech">
-      <outline type="rss" text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog"/>
-    </outline>
-    <outline type="rss" text="HN" xmlUrl="https://hnrss.org/newest"/>
-  </body>
-</opml>`
+	t.Run("multiple files merged", func(t *testing.T) {
+		feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>F</title></channel></rss>`)
+		}))
+		defer feedSrv.Close()
+		s.fetcher.AllowPrivateURLs = true
+
+		opml := func(paths ...string) string {
+			var b strings.Builder
+			b.WriteString(`<?xml version="1.0"?><opml version="2.0"><body>`)
+			for _, p := range paths {
+				fmt.Fprintf(&b, `<outline type="rss" text="%s" xmlUrl="%s/%s"/>`, p, feedSrv.URL, p)
+			}
+			b.WriteString(`</body></opml>`)
+			return b.String()
+		}
 
-	t.Run("parse", func(t *testing.T) {
-		feeds, err := ParseOPML(strings.NewReader(opmlXML))
-		if err != nil {
-			t.Fatalf("ParseOPML: %v", err)
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for name, content := range map[string]string{
+			"a.opml":   opml("one", "two"),
+			"b.opml":   opml("two", "three"),
+			"bad.opml": "not xml <",
+		} {
+			fw, _ := mw.CreateFormFile("file[]", name)
+			_, _ = fw.Write([]byte(content))
 		}
-		if len(feeds) != 2 {
-			t.Fatalf("got %d feeds, want 2", len(feeds))
+		_ = mw.Close()
+
+		r := httptest.NewRequest("POST", "/api/opml/import", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.Header.Set("X-ExeDev-UserID", "testuser")
+		w := httptest.NewRecorder()
+		s.HandleImportOPML(w, r)
+		assertStatus(t, w, 200)
+
+		var resp struct {
+			Imported int `json:"imported"`
+			Total    int `json:"total"`
+			Files    []struct {
+				Name  string `json:"name"`
+				Feeds int    `json:"feeds"`
+				Error string `json:"error"`
+			} `json:"files"`
 		}
-		if feeds[0].Category != "Tech" {
-			t.Errorf("feed[0].Category = %q, want Tech", feeds[0].Category)
+		decodeJSON(t, w, &resp)
+		if resp.Total != 3 || resp.Imported != 3 {
+			t.Errorf("total = %d, imported = %d; want 3 and 3", resp.Total, resp.Imported)
 		}
-		if feeds[0].URL != "https://go.dev/blog/feed.atom" {
-			t.Errorf("feed[0].URL = %q", feeds[0].URL)
+		if len(resp.Files) != 3 {
+			t.Fatalf("got %d file reports, want 3", len(resp.Files))
 		}
-		if feeds[1].Category != "" {
-			t.Errorf("feed[1].Category = %q, want empty", feeds[1].Category)
+		for _, f := range resp.Files {
+			if (f.Name == "bad.opml") != (f.Error != "") {
+				t.Errorf("file %s: error = %q", f.Name, f.Error)
+			}
+			if f.Error == "" && f.Feeds != 2 {
+				t.Errorf("file %s: feeds = %d, want 2", f.Name, f.Feeds)
+			}
 		}
 	})
 
-	t.Run("parse invalid xml", func(t *testing.T) {
-		_, err := ParseOPML(strings.NewReader("not xml"))
-		if err == nil {
-			t.Error("expected error for invalid XML")
+	t.Run("concurrent fetches", func(t *testing.T) {
+		var inFlight, peak atomic.Int32
+		feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			time.Sleep(30 * time.Millisecond)
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>C</title><item><guid>x</guid><title>X</title></item></channel></rss>`)
+		}))
+		defer feedSrv.Close()
+		s.fetcher.AllowPrivateURLs = true
+
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0"?><opml version="2.0"><body>`)
+		for _, cat := range []string{"Pool A", "Pool B"} {
+			fmt.Fprintf(&b, `<outline text="%s">`, cat)
+			for i := range 10 {
+				fmt.Fprintf(&b, `<outline type="rss" text="c%d" xmlUrl="%s/%s/%d"/>`, i, feedSrv.URL, cat[len(cat)-1:], i)
+			}
+			b.WriteString(`</outline>`)
+		}
+		b.WriteString(`</body></opml>`)
+
+		importOPML := func() (imported, skipped, total int) {
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			fw, _ := mw.CreateFormFile("file", "pool.opml")
+			_, _ = fw.Write([]byte(b.String()))
+			_ = mw.Close()
+			r := httptest.NewRequest("POST", "/api/opml/import", &body)
+			r.Header.Set("Content-Type", mw.FormDataContentType())
+			r.Header.Set("X-ExeDev-UserID", "testuser")
+			w := httptest.NewRecorder()
+			s.HandleImportOPML(w, r)
+			assertStatus(t, w, 200)
+			var resp struct{ Imported, Skipped, Total int }
+			decodeJSON(t, w, &resp)
+			return resp.Imported, resp.Skipped, resp.Total
+		}
+
+		if imported, skipped, total := importOPML(); imported != 20 || skipped != 0 || total != 20 {
+			t.Errorf("imported/skipped/total = %d/%d/%d, want 20/0/20", imported, skipped, total)
+		}
+		if p := peak.Load(); p < 2 || p > importWorkers {
+			t.Errorf("peak concurrent fetches = %d, want 2..%d", p, importWorkers)
+		}
+		var inCats int
+		_ = s.DB.QueryRow(`SELECT COUNT(*) FROM feeds f JOIN categories c ON c.id = f.category_id
+			WHERE f.user_id = 'testuser' AND c.title IN ('Pool A', 'Pool B')`).Scan(&inCats)
+		if inCats != 20 {
+			t.Errorf("%d feeds in their categories, want 20", inCats)
+		}
+		if imported, skipped, _ := importOPML(); imported != 0 || skipped != 20 {
+			t.Errorf("re-import: imported/skipped = %d/%d, want 0/20", imported, skipped)
 		}
 	})
 
-	t.Run("generate", func(t *testing.T) {
-		exports := []FeedExport{
-			{URL: "https://go.dev/feed", Title: "Go Blog", SiteURL: "https://go.dev", Category: "Tech"},
-			{URL: "https://hn.com/rss", Title: "HN", SiteURL: "https://hn.com", Category: ""},
+	t.Run("selected urls", func(t *testing.T) {
+		feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>F</title></channel></rss>`)
+		}))
+		defer feedSrv.Close()
+		s.fetcher.AllowPrivateURLs = true
+
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0"?><opml version="2.0"><body>`)
+		for _, p := range []string{"pick-a", "leave", "pick-b"} {
+			fmt.Fprintf(&b, `<outline type="rss" text="%s" xmlUrl="%s/%s"/>`, p, feedSrv.URL, p)
 		}
-		data, err := GenerateOPML("Test Export", exports)
-		if err != nil {
-			t.Fatalf("GenerateOPML: %v", err)
+		b.WriteString(`</body></opml>`)
+
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "feeds.opml")
+		_, _ = fw.Write([]byte(b.String()))
+		_ = mw.WriteField("urls", feedSrv.URL+"/pick-a")
+		_ = mw.WriteField("urls", feedSrv.URL+"/pick-b")
+		_ = mw.WriteField("urls", feedSrv.URL+"/not-in-opml")
+		_ = mw.Close()
+		r := httptest.NewRequest("POST", "/api/opml/import", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.Header.Set("X-ExeDev-UserID", "testuser")
+		w := httptest.NewRecorder()
+		s.HandleImportOPML(w, r)
+		assertStatus(t, w, 200)
+
+		var resp struct {
+			Imported   int `json:"imported"`
+			Total      int `json:"total"`
+			Unselected int `json:"unselected"`
 		}
-		xml := string(data)
-		if !strings.Contains(xml, "go.dev/feed") {
-			t.Error("missing go.dev/feed in output")
+		decodeJSON(t, w, &resp)
+		if resp.Imported != 2 || resp.Total != 2 || resp.Unselected != 1 {
+			t.Errorf("imported = %d, total = %d, unselected = %d; want 2, 2, 1", resp.Imported, resp.Total, resp.Unselected)
 		}
-		if !strings.Contains(xml, "Tech") {
-			t.Error("missing Tech category")
+		q := dbgen.New(s.DB)
+		for path, want := range map[string]bool{"pick-a": true, "pick-b": true, "leave": false} {
+			_, err := q.GetFeedByURL(context.Background(), dbgen.GetFeedByURLParams{UserID: "testuser", Url: feedSrv.URL + "/" + path})
+			if got := err == nil; got != want {
+				t.Errorf("%s subscribed = %v, want %v", path, got, want)
+			}
 		}
-		if !strings.Contains(xml, "hn.com/rss") {
-			t.Error("missing hn.com/rss")
+	})
+
+	t.Run("skip reasons", func(t *testing.T) {
+		feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/broken" {
+				http.Error(w, "gone", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>F</title></channel></rss>`)
+		}))
+		defer feedSrv.Close()
+		s.fetcher.AllowPrivateURLs = true
+
+		type importResp struct {
+			Imported int            `json:"imported"`
+			Skipped  int            `json:"skipped"`
+			Reasons  map[string]int `json:"reasons"`
+			Failed   []struct {
+				URL    string `json:"url"`
+				Reason string `json:"reason"`
+				Error  string `json:"error"`
+			} `json:"failed"`
+		}
+		doImport := func(paths ...string) importResp {
+			t.Helper()
+			var b strings.Builder
+			b.WriteString(`<?xml version="1.0"?><opml version="2.0"><body>`)
+			for _, p := range paths {
+				fmt.Fprintf(&b, `<outline type="rss" text="%s" xmlUrl="%s/%s"/>`, p, feedSrv.URL, p)
+			}
+			b.WriteString(`</body></opml>`)
+
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			fw, _ := mw.CreateFormFile("file", "feeds.opml")
+			_, _ = fw.Write([]byte(b.String()))
+			_ = mw.Close()
+			r := httptest.NewRequest("POST", "/api/opml/import", &body)
+			r.Header.Set("Content-Type", mw.FormDataContentType())
+			r.Header.Set("X-ExeDev-UserID", "testuser")
+			w := httptest.NewRecorder()
+			s.HandleImportOPML(w, r)
+			assertStatus(t, w, 200)
+			var resp importResp
+			decodeJSON(t, w, &resp)
+			return resp
+		}
+
+		doImport("existing")
+		resp := doImport("existing", "fresh", "broken")
+		if resp.Imported != 1 || resp.Skipped != 2 {
+			t.Errorf("imported = %d, skipped = %d; want 1 and 2", resp.Imported, resp.Skipped)
+		}
+		want := map[string]int{"created": 1, "duplicate": 1, "fetch_failed": 1}
+		if !reflect.DeepEqual(resp.Reasons, want) {
+			t.Errorf("reasons = %v, want %v", resp.Reasons, want)
+		}
+		if len(resp.Failed) != 1 || resp.Failed[0].URL != feedSrv.URL+"/broken" ||
+			resp.Failed[0].Reason != "fetch_failed" || resp.Failed[0].Error == "" {
+			t.Errorf("failed = %+v, want the broken feed with an error", resp.Failed)
+		}
+
+		// A category that no longer exists makes the insert fail
+		res := s.importSingleFeed(context.Background(), "testuser",
+			FeedImport{URL: feedSrv.URL + "/orphan", Category: "Gone"}, map[string]int64{"Gone": 999999})
+		if res.Reason != importCreateFailed || res.Error == "" {
+			t.Errorf("orphan import = %+v, want create_failed", res)
 		}
 	})
+}
 
-	t.Run("generate empty", func(t *testing.T) {
-		data, err := GenerateOPML("Empty", nil)
-		if err != nil {
-			t.Fatalf("GenerateOPML: %v", err)
+func TestImportOPMLLimits(t *testing.T) {
+	s := newTestServer(t)
+	upload := func(feeds int) *httptest.ResponseRecorder {
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0"?><opml version="2.0"><body>`)
+		for i := range feeds {
+			fmt.Fprintf(&b, `<outline type="rss" text="feed %d" xmlUrl="https://example.com/feed/%d"/>`, i, i)
 		}
-		if !strings.Contains(string(data), "Empty") {
-			t.Error("missing title")
+		b.WriteString(`</body></opml>`)
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "feeds.opml")
+		_, _ = fw.Write([]byte(b.String()))
+		_ = mw.Close()
+
+		r := httptest.NewRequest("POST", "/api/opml/import", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.Header.Set("X-ExeDev-UserID", "testuser")
+		w := httptest.NewRecorder()
+		s.HandleImportOPML(w, r)
+		return w
+	}
+
+	t.Run("oversized upload", func(t *testing.T) {
+		s.MaxImportBytes = 1 << 20
+		w := upload(30000) // about 2 MB
+		assertStatus(t, w, http.StatusRequestEntityTooLarge)
+		if !strings.Contains(w.Body.String(), "1 MB") {
+			t.Errorf("body = %s, want the size limit", w.Body.String())
 		}
 	})
 
-	t.Run("roundtrip", func(t *testing.T) {
-		exports := []FeedExport{
-			{URL: "https://a.com/rss", Title: "A", Category: "Cat1"},
-			{URL: "https://b.com/rss", Title: "B", Category: "Cat1"},
-			{URL: "https://c.com/rss", Title: "C", Category: ""},
+	t.Run("too many feeds", func(t *testing.T) {
+		s.MaxImportBytes = defaultMaxImportMB << 20
+		s.MaxImportFeeds = 3
+		w := upload(5)
+		assertStatus(t, w, http.StatusRequestEntityTooLarge)
+		if !strings.Contains(w.Body.String(), "5 feeds") {
+			t.Errorf("body = %s, want the feed count", w.Body.String())
 		}
-		data, _ := GenerateOPML("RT", exports)
-		parsed, err := ParseOPML(strings.NewReader(string(data)))
-		if err != nil {
-			t.Fatalf("roundtrip parse: %v", err)
+		feeds, _ := dbgen.New(s.DB).GetFeeds(context.Background(), "testuser")
+		if len(feeds) != 0 {
+			t.Errorf("%d feeds created by a refused import", len(feeds))
 		}
-		if len(parsed) != 3 {
-			t.Errorf("roundtrip got %d feeds, want 3", len(parsed))
+	})
+}
+
+func TestPreviewOPML(t *testing.T) {
+	s := newTestServer(t)
+	seedFeed(t, s, "have", nil, 0)
+
+	const opml = `<?xml version="1.0"?><opml version="2.0"><body>
+<outline type="rss" text="Loose" xmlUrl="http://example.com/loose"/>
+<outline text="News">
+  <outline type="rss" text="Have" xmlUrl="http://example.com/have"/>
+  <outline type="rss" text="New" xmlUrl="http://example.com/new"/>
+</outline>
+</body></opml>`
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "feeds.opml")
+	_, _ = fw.Write([]byte(opml))
+	_ = mw.Close()
+	r := httptest.NewRequest("POST", "/api/opml/preview", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	r.Header.Set("X-ExeDev-UserID", "testuser")
+	w := httptest.NewRecorder()
+	s.HandlePreviewOPML(w, r)
+	assertStatus(t, w, 200)
+
+	var resp struct {
+		Total             int                   `json:"total"`
+		AlreadySubscribed int                   `json:"already_subscribed"`
+		Categories        []opmlPreviewCategory `json:"categories"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Total != 3 || resp.AlreadySubscribed != 1 {
+		t.Errorf("total = %d, already_subscribed = %d; want 3 and 1", resp.Total, resp.AlreadySubscribed)
+	}
+	want := []opmlPreviewCategory{
+		{Name: "", Feeds: []opmlPreviewFeed{{URL: "http://example.com/loose", Title: "Loose"}}},
+		{Name: "News", Feeds: []opmlPreviewFeed{
+			{URL: "http://example.com/have", Title: "Have", AlreadySubscribed: true},
+			{URL: "http://example.com/new", Title: "New"},
+		}},
+	}
+	if !reflect.DeepEqual(resp.Categories, want) {
+		t.Errorf("categories = %+v, want %+v", resp.Categories, want)
+	}
+
+	// Nothing was created
+	var feeds int
+	_ = s.DB.QueryRow("SELECT COUNT(*) FROM feeds").Scan(&feeds)
+	if feeds != 1 {
+		t.Errorf("preview left %d feeds, want 1", feeds)
+	}
+	if isWriteRequest(r) {
+		t.Error("preview should not count as a write (read-only mode)")
+	}
+}
+
+func TestBulkSubscribe(t *testing.T) {
+	feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			http.Error(w, "gone", http.StatusGone)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Upstream</title></channel></rss>`)
+	}))
+	defer feedSrv.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	seedFeed(t, s, "existing", nil, 0)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	_, _ = q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "News"})
+
+	body := fmt.Sprintf(`[
+		{"url": %[1]q, "category": "News", "title": "My Title"},
+		{"url": %[2]q, "category": "Tech"},
+		{"url": %[1]q, "category": "Tech"},
+		{"url": "http://example.com/existing"},
+		{"url": %[3]q},
+		{"url": " "}
+	]`, feedSrv.URL+"/a", feedSrv.URL+"/b", feedSrv.URL+"/broken")
+	w := httptest.NewRecorder()
+	s.HandleBulkSubscribe(w, authReq("POST", "/api/feeds/bulk-subscribe", body))
+	assertStatus(t, w, 200)
+
+	var results []importResult
+	decodeJSON(t, w, &results)
+	want := []importReason{importCreated, importCreated, importDuplicate, importDuplicate, importFetchFailed, importInvalid}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, r := range results {
+		if r.Reason != want[i] {
+			t.Errorf("entry %d (%s): reason = %s, want %s", i, r.URL, r.Reason, want[i])
 		}
+	}
+
+	// Existing categories are reused, missing ones created
+	cats, _ := q.GetCategories(ctx, "testuser")
+	catIDs := map[string]int64{}
+	for _, c := range cats {
+		catIDs[c.Title] = c.ID
+	}
+	if len(cats) != 2 || catIDs["Tech"] == 0 {
+		t.Fatalf("categories = %+v, want News and Tech", cats)
+	}
+
+	a, err := q.GetFeed(ctx, dbgen.GetFeedParams{ID: results[0].FeedID, UserID: "testuser"})
+	if err != nil {
+		t.Fatalf("get feed a: %v", err)
+	}
+	if a.Title != "My Title" || a.CategoryID == nil || *a.CategoryID != catIDs["News"] {
+		t.Errorf("feed a = %q in %v, want My Title in News", a.Title, a.CategoryID)
+	}
+	b, _ := q.GetFeed(ctx, dbgen.GetFeedParams{ID: results[1].FeedID, UserID: "testuser"})
+	if b.Title != "Upstream" || b.CategoryID == nil || *b.CategoryID != catIDs["Tech"] {
+		t.Errorf("feed b = %q in %v, want Upstream in Tech", b.Title, b.CategoryID)
+	}
+
+	t.Run("bad body", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.HandleBulkSubscribe(w, authReq("POST", "/api/feeds/bulk-subscribe", `{"url":"x"}`))
+		assertStatus(t, w, 400)
 	})
 }
 
-func TestExportOPML(t *testing.T) {
+// --------------- Reprocess Content ---------------
+
+func TestReprocessContent(t *testing.T) {
 	s := newTestServer(t)
-	seedFeed(t, s, "export-feed", nil, 1)
+	s.ExcerptLen = 0 // seeded articles have no summary; see TestGeneratedExcerpt
+	feed := seedFeed(t, s, "reprocess", nil, 2)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+
+	// Content stored before the current processing pipeline existed
+	stale, err := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
+		FeedID: feed.ID, Guid: "stale", Url: "http://example.com/stale",
+		Title: "  Stale  ", Content: "\n<p>old</p>\n", Summary: " sum ",
+	})
+	if err != nil {
+		t.Fatalf("UpsertArticle: %v", err)
+	}
+
 	w := httptest.NewRecorder()
-	s.HandleExportOPML(w, authReq("GET", "/api/opml/export", ""))
+	s.HandleReprocessContent(w, authReq("POST", "/api/admin/reprocess-content", ""))
 	assertStatus(t, w, 200)
-	if ct := w.Header().Get("Content-Type"); ct != "application/xml" {
-		t.Errorf("Content-Type = %q", ct)
+
+	var resp struct {
+		Total     int64 `json:"total"`
+		Processed int   `json:"processed"`
+		Updated   int   `json:"updated"`
 	}
-	if !strings.Contains(w.Body.String(), "export-feed") {
-		t.Error("OPML should contain feed title")
+	decodeJSON(t, w, &resp)
+	if resp.Total != 3 || resp.Processed != 3 || resp.Updated != 1 {
+		t.Errorf("got %+v, want total=3 processed=3 updated=1", resp)
 	}
-}
 
-// --------------- Auth / Sessions ---------------
+	art, err := q.GetArticle(ctx, dbgen.GetArticleParams{UserID: "testuser", ID: stale.ID, UserID_2: "testuser"})
+	if err != nil {
+		t.Fatalf("GetArticle: %v", err)
+	}
+	if art.Title != "Stale" || art.Content != "<p>old</p>" || art.Summary != "sum" {
+		t.Errorf("article not reprocessed: %q %q %q", art.Title, art.Content, art.Summary)
+	}
+}
 
-func TestAuthSessions(t *testing.T) {
-	t.Run("create and validate", func(t *testing.T) {
-		sid := createSession()
-		if sid == "" {
-			t.Fatal("empty session id")
-		}
-		if !validateSession(sid) {
-			t.Error("session should be valid")
-		}
-	})
+// --------------- Backup Download ---------------
 
-	t.Run("invalid session", func(t *testing.T) {
-		if validateSession("nonexistent") {
-			t.Error("nonexistent session should be invalid")
-		}
-	})
+func TestDownloadBackup(t *testing.T) {
+	s := newTestServer(t)
+	seedFeed(t, s, "backed-up", nil, 2)
 
-	t.Run("delete session", func(t *testing.T) {
-		sid := createSession()
-		deleteSession(sid)
-		if validateSession(sid) {
-			t.Error("deleted session should be invalid")
-		}
-	})
+	w := httptest.NewRecorder()
+	s.HandleDownloadBackup(w, authReq("GET", "/api/admin/backup/download", ""))
+	assertStatus(t, w, 200)
+	cd := w.Header().Get("Content-Disposition")
+	if !strings.HasPrefix(cd, `attachment; filename="gorss-`) || !strings.HasSuffix(cd, `.db"`) {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	if w.Header().Get("Content-Length") != fmt.Sprint(w.Body.Len()) {
+		t.Errorf("Content-Length = %s, body is %d bytes", w.Header().Get("Content-Length"), w.Body.Len())
+	}
 
-	t.Run("cleanup expired", func(t *testing.T) {
-		// Manually insert an expired session
-		sessionsLock.Lock()
-		sessions["expired-test"] = time.Now().Add(-time.Hour)
-		sessionsLock.Unlock()
+	// The snapshot is a working database with the seeded articles
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+	if err := os.WriteFile(path, w.Body.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := db.Open(path)
+	if err != nil {
+		t.Fatalf("open snapshot: %v", err)
+	}
+	defer snap.Close() //nolint:errcheck
+	var n int
+	if err := snap.QueryRow("SELECT COUNT(*) FROM articles").Scan(&n); err != nil || n != 2 {
+		t.Errorf("snapshot articles = %d (err %v), want 2", n, err)
+	}
 
-		cleanupSessions()
-		if validateSession("expired-test") {
-			t.Error("expired session should be cleaned up")
-		}
-	})
+	// Only one snapshot at a time
+	s.backupMu.Lock()
+	w = httptest.NewRecorder()
+	s.HandleDownloadBackup(w, authReq("GET", "/api/admin/backup/download", ""))
+	s.backupMu.Unlock()
+	assertStatus(t, w, 409)
 }
 
-func TestAuthMode(t *testing.T) {
-	t.Run("default none", func(t *testing.T) {
-		t.Setenv("GORSS_AUTH_MODE", "")
-		if GetAuthMode() != AuthModeNone {
-			t.Errorf("got %v, want none", GetAuthMode())
-		}
-	})
-	t.Run("password", func(t *testing.T) {
-		t.Setenv("GORSS_AUTH_MODE", "password")
-		if GetAuthMode() != AuthModePassword {
-			t.Errorf("got %v", GetAuthMode())
-		}
-	})
-	t.Run("proxy", func(t *testing.T) {
-		t.Setenv("GORSS_AUTH_MODE", "proxy")
-		if GetAuthMode() != AuthModeProxy {
-			t.Errorf("got %v", GetAuthMode())
+// --------------- Integrity Check ---------------
+
+func TestIntegrityCheck(t *testing.T) {
+	s := newTestServer(t)
+	seedFeed(t, s, "intact", nil, 2)
+
+	check := func(query string) (status string, problems []string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.HandleIntegrityCheck(w, authReq("GET", "/api/admin/integrity-check"+query, ""))
+		assertStatus(t, w, 200)
+		var resp struct {
+			Status   string   `json:"status"`
+			Problems []string `json:"problems"`
 		}
-	})
+		decodeJSON(t, w, &resp)
+		return resp.Status, resp.Problems
+	}
+
+	if status, problems := check("?foreign_keys=1"); status != "ok" || len(problems) != 0 {
+		t.Fatalf("healthy db: status = %q, problems = %v", status, problems)
+	}
+
+	// A dangling article state, written on a connection without FK enforcement
+	ctx := context.Background()
+	conn, err := s.DB.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = conn.ExecContext(ctx, "PRAGMA foreign_keys=OFF")
+	if _, err := conn.ExecContext(ctx, "INSERT INTO article_states (user_id, article_id, is_read) VALUES ('testuser', 9999, 1)"); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+
+	if status, _ := check(""); status != "ok" {
+		t.Errorf("integrity_check alone: status = %q, want ok", status)
+	}
+	status, problems := check("?foreign_keys=1")
+	if status != "problems" || len(problems) != 1 || !strings.Contains(problems[0], "article_states") {
+		t.Errorf("with foreign_keys: status = %q, problems = %v", status, problems)
+	}
 }
 
-func TestLoginLogout(t *testing.T) {
+func TestRepair(t *testing.T) {
 	s := newTestServer(t)
-	t.Setenv("GORSS_AUTH_MODE", "password")
-	t.Setenv("GORSS_PASSWORD", "secret123")
+	feed := seedFeed(t, s, "kept", nil, 2)
+	ctx := context.Background()
 
-	t.Run("login page GET", func(t *testing.T) {
+	var keptID int64
+	if err := s.DB.QueryRow("SELECT id FROM articles WHERE feed_id = ? LIMIT 1", feed.ID).Scan(&keptID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Orphans, written on a connection without FK enforcement: an article of
+	// a deleted feed (with a state of its own) and a state of a missing article
+	conn, err := s.DB.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = conn.ExecContext(ctx, "PRAGMA foreign_keys=OFF")
+	for _, stmt := range []string{
+		"INSERT INTO articles (id, feed_id, guid) VALUES (5000, 999, 'orphan')",
+		"INSERT INTO article_states (user_id, article_id, is_read) VALUES ('testuser', 5000, 1)",
+		"INSERT INTO article_states (user_id, article_id, is_read) VALUES ('testuser', 9999, 1)",
+		fmt.Sprintf("INSERT INTO article_states (user_id, article_id, is_starred) VALUES ('testuser', %d, 1)", keptID),
+	} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	_ = conn.Close()
+
+	repair := func() map[string]int64 {
+		t.Helper()
 		w := httptest.NewRecorder()
-		s.HandleLogin(w, httptest.NewRequest("GET", "/login", nil))
+		s.HandleRepair(w, authReq("POST", "/api/admin/repair", ""))
 		assertStatus(t, w, 200)
-		if !strings.Contains(w.Body.String(), "password") {
-			t.Error("login page should contain password field")
-		}
+		var removed map[string]int64
+		decodeJSON(t, w, &removed)
+		return removed
+	}
+
+	want := map[string]int64{"articles": 1, "article_states": 2}
+	if got := repair(); !reflect.DeepEqual(got, want) {
+		t.Errorf("removed = %v, want %v", got, want)
+	}
+	var articles, states int
+	_ = s.DB.QueryRow("SELECT COUNT(*) FROM articles").Scan(&articles)
+	_ = s.DB.QueryRow("SELECT COUNT(*) FROM article_states").Scan(&states)
+	if articles != 2 || states != 1 {
+		t.Errorf("after repair: %d articles, %d states; want 2 and 1", articles, states)
+	}
+
+	want = map[string]int64{"articles": 0, "article_states": 0}
+	if got := repair(); !reflect.DeepEqual(got, want) {
+		t.Errorf("second repair removed = %v, want %v", got, want)
+	}
+}
+
+// --------------- Raw Feed ---------------
+
+func TestGetFeedRaw(t *testing.T) {
+	const body = `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"><title>Odd</title><bogus/></feed>`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		fmt.Fprint(w, body)
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	feed := seedFeed(t, s, "raw", nil, 1)
+	_ = q.UpdateFeedDetails(context.Background(), dbgen.UpdateFeedDetailsParams{
+		Title: "raw", Url: upstream.URL, ID: feed.ID, UserID: "testuser",
 	})
+	id := fmt.Sprint(feed.ID)
 
-	t.Run("login wrong password", func(t *testing.T) {
-		r := httptest.NewRequest("POST", "/login", strings.NewReader("password=wrong"))
-		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	get := func(userID string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api/feeds/"+id+"/raw", nil)
+		r.Header.Set("X-ExeDev-UserID", userID)
+		r.SetPathValue("id", id)
 		w := httptest.NewRecorder()
-		s.HandleLogin(w, r)
-		assertStatus(t, w, 200) // re-renders login page
-		if !strings.Contains(w.Body.String(), "Invalid") {
-			t.Error("should show invalid password error")
-		}
+		s.HandleGetFeedRaw(w, r)
+		return w
+	}
+
+	t.Run("private address blocked", func(t *testing.T) {
+		assertStatus(t, get("testuser"), http.StatusBadGateway)
 	})
 
-	t.Run("login correct password", func(t *testing.T) {
-		r := httptest.NewRequest("POST", "/login", strings.NewReader("password=secret123"))
-		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-		w := httptest.NewRecorder()
-		s.HandleLogin(w, r)
-		if w.Code != http.StatusFound {
-			t.Errorf("expected 302 redirect, got %d", w.Code)
+	s.fetcher.AllowPrivateURLs = true
+
+	t.Run("returns body as-is", func(t *testing.T) {
+		w := get("testuser")
+		assertStatus(t, w, 200)
+		if w.Body.String() != body {
+			t.Errorf("body = %q", w.Body.String())
 		}
-		cookie := w.Header().Get("Set-Cookie")
-		if !strings.Contains(cookie, "gorss_session") {
-			t.Error("expected session cookie")
+		if ct := w.Header().Get("Content-Type"); ct != "application/atom+xml; charset=utf-8" {
+			t.Errorf("content type = %q", ct)
+		}
+		if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+			t.Error("missing nosniff header")
 		}
 	})
 
-	t.Run("logout", func(t *testing.T) {
-		r := httptest.NewRequest("POST", "/logout", nil)
-		r.AddCookie(&http.Cookie{Name: "gorss_session", Value: createSession()})
-		w := httptest.NewRecorder()
-		s.HandleLogout(w, r)
-		if w.Code != http.StatusFound {
-			t.Errorf("expected 302, got %d", w.Code)
+	t.Run("other user", func(t *testing.T) {
+		assertStatus(t, get("someone-else"), 404)
+	})
+
+	t.Run("does not store", func(t *testing.T) {
+		arts, _ := q.GetArticlesByFeed(context.Background(), dbgen.GetArticlesByFeedParams{
+			UserID: "testuser", ID: feed.ID, UserID_2: "testuser", Limit: 10,
+		})
+		if len(arts) != 1 {
+			t.Errorf("got %d articles, want 1", len(arts))
 		}
 	})
 }
 
-// --------------- Get Single Article ---------------
+// --------------- Feed Content ---------------
 
-func TestGetArticle(t *testing.T) {
+func TestFeedContent(t *testing.T) {
 	s := newTestServer(t)
-	feed := seedFeed(t, s, "content-feed", nil, 2)
-
 	q := dbgen.New(s.DB)
 	ctx := context.Background()
-	arts, _ := q.GetArticlesByFeed(ctx, dbgen.GetArticlesByFeedParams{
-		UserID: "testuser", ID: feed.ID, UserID_2: "testuser", Limit: 10,
-	})
-	if len(arts) == 0 {
-		t.Fatal("expected articles")
-	}
-	id := fmt.Sprint(arts[0].ID)
+	feed := seedFeed(t, s, "offline", nil, 3)
+	fid := fmt.Sprint(feed.ID)
 
-	t.Run("success", func(t *testing.T) {
+	// Two articles last changed long ago
+	_, _ = s.DB.ExecContext(ctx, `UPDATE articles SET updated_at = '2020-01-01 00:00:00'
+		WHERE feed_id = ? AND guid IN ('offline-a', 'offline-aa')`, feed.ID)
+
+	get := func(query string) []feedContentItem {
+		t.Helper()
 		w := httptest.NewRecorder()
-		r := authReq("GET", "/api/articles/"+id, "")
-		r.SetPathValue("id", id)
-		s.HandleGetArticle(w, r)
+		r := authReq("GET", "/api/feeds/"+fid+"/content"+query, "")
+		r.SetPathValue("id", fid)
+		s.HandleGetFeedContent(w, r)
 		assertStatus(t, w, 200)
-		var a map[string]any
-		decodeJSON(t, w, &a)
-		if a["content"] == nil {
-			t.Error("expected content field in single article response")
+		var items []feedContentItem
+		decodeJSON(t, w, &items)
+		return items
+	}
+
+	if items := get(""); len(items) != 3 || items[0].Content == "" || items[0].UpdatedAt == nil {
+		t.Fatalf("all content = %+v, want 3 articles with content and updated_at", items)
+	}
+	since := "?since=2021-01-01T00:00:00Z"
+	items := get(since)
+	if len(items) != 1 || items[0].GUID != "offline-aaa" {
+		t.Fatalf("since = %+v, want only offline-aaa", items)
+	}
+
+	// Re-storing unchanged content keeps updated_at; a content change bumps it
+	upsert := func(content string) {
+		_, err := q.UpsertArticle(ctx, dbgen.UpsertArticleParams{
+			FeedID: feed.ID, Guid: "offline-a", Url: "http://example.com/offline-a",
+			Title: "Article offline-a", Content: content,
+		})
+		if err != nil {
+			t.Fatalf("UpsertArticle: %v", err)
 		}
-		if a["summary"] == nil {
-			t.Error("expected summary field")
+	}
+	upsert("<p>content</p>")
+	if n := len(get(since)); n != 1 {
+		t.Errorf("after unchanged upsert: %d articles, want 1", n)
+	}
+	upsert("<p>edited</p>")
+	if n := len(get(since)); n != 2 {
+		t.Errorf("after edit: %d articles, want 2", n)
+	}
+
+	// Unread only
+	_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: items[0].ID})
+	if n := len(get("?unread=1")); n != 2 {
+		t.Errorf("unread = %d articles, want 2", n)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for path, want := range map[string]int{
+			"/api/feeds/" + fid + "/content?since=yesterday": 400,
+			"/api/feeds/abc/content":                         400,
+			"/api/feeds/999999/content":                      404,
+		} {
+			w := httptest.NewRecorder()
+			r := authReq("GET", path, "")
+			r.SetPathValue("id", strings.Split(path, "/")[3])
+			s.HandleGetFeedContent(w, r)
+			assertStatus(t, w, want)
 		}
 	})
+}
 
-	t.Run("invalid id", func(t *testing.T) {
-		w := httptest.NewRecorder()
-		r := authReq("GET", "/api/articles/abc", "")
-		r.SetPathValue("id", "abc")
-		s.HandleGetArticle(w, r)
-		assertStatus(t, w, 400)
-	})
+func TestGetStaleFeeds(t *testing.T) {
+	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	seedFeed(t, s, "fresh", nil, 2)
+	quiet := seedFeed(t, s, "quiet", nil, 2)
+	broken := seedFeed(t, s, "broken", nil, 1)
+	seedFeed(t, s, "empty", nil, 0)
+
+	// Age quiet's articles (one 100 days old, its newest 95) and broken's (200)
+	age := func(guid string, days int) {
+		t.Helper()
+		old := time.Now().AddDate(0, 0, -days).UTC()
+		if _, err := s.DB.ExecContext(ctx, "UPDATE articles SET published_at = ? WHERE guid = ?", old, guid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	age("quiet-a", 100)
+	age("quiet-aa", 95)
+	age("broken-a", 200)
+	errStr := "fetch feed: 404"
+	_ = q.UpdateFeedMeta(ctx, dbgen.UpdateFeedMetaParams{ID: broken.ID, Title: "broken", LastError: &errStr, ErrorCount: 4})
 
-	t.Run("not found", func(t *testing.T) {
+	// Someone else's stale feed stays out of it
+	now := time.Now()
+	_ = q.UpsertUser(ctx, dbgen.UpsertUserParams{ID: "other", CreatedAt: now, LastSeen: now})
+	if _, err := q.CreateFeed(ctx, dbgen.CreateFeedParams{UserID: "other", Url: "http://example.com/foreign", Title: "foreign"}); err != nil {
+		t.Fatalf("CreateFeed: %v", err)
+	}
+
+	get := func(query string) []staleFeed {
+		t.Helper()
 		w := httptest.NewRecorder()
-		r := authReq("GET", "/api/articles/999999", "")
-		r.SetPathValue("id", "999999")
-		s.HandleGetArticle(w, r)
-		assertStatus(t, w, 404)
-	})
+		s.HandleGetStaleFeeds(w, authReq("GET", "/api/feeds/stale"+query, ""))
+		assertStatus(t, w, 200)
+		var feeds []staleFeed
+		decodeJSON(t, w, &feeds)
+		return feeds
+	}
+
+	feeds := get("")
+	var titles []string
+	for _, f := range feeds {
+		titles = append(titles, f.Title+":"+f.Status)
+	}
+	want := []string{"empty:no_new_articles", "broken:failing", "quiet:no_new_articles"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Fatalf("stale feeds = %v, want %v", titles, want)
+	}
+	if feeds[0].NewestArticle != nil {
+		t.Errorf("empty feed newest = %v, want nil", feeds[0].NewestArticle)
+	}
+	if n := feeds[2].NewestArticle; n == nil || time.Since(*n) > 96*24*time.Hour {
+		t.Errorf("quiet newest = %v, want about 95 days ago", n)
+	}
+	if feeds[2].ID != quiet.ID || feeds[1].LastError == nil || *feeds[1].LastError != errStr {
+		t.Errorf("unexpected feed details: %+v", feeds)
+	}
+
+	if feeds := get("?days=150"); len(feeds) != 2 || feeds[1].Title != "broken" {
+		t.Errorf("days=150 returned %+v, want empty and broken", feeds)
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleGetStaleFeeds(w, authReq("GET", "/api/feeds/stale?days=0", ""))
+	assertStatus(t, w, 400)
 }
 
-// --------------- Article List Strips Content ---------------
+// --------------- Atom Republishing ---------------
 
-func TestArticleListStripsContent(t *testing.T) {
+func TestFeedAtom(t *testing.T) {
 	s := newTestServer(t)
-	seedFeed(t, s, "strip-test", nil, 1)
+	feed := seedFeed(t, s, "atom-src", nil, 2)
+	id := fmt.Sprint(feed.ID)
 
-	w := httptest.NewRecorder()
-	s.HandleGetArticles(w, authReq("GET", "/api/articles", ""))
-	assertStatus(t, w, 200)
+	getToken := func(method, userID string) string {
+		t.Helper()
+		r := httptest.NewRequest(method, "/api/feed-token", nil)
+		r.Header.Set("X-ExeDev-UserID", userID)
+		w := httptest.NewRecorder()
+		if method == "POST" {
+			s.HandleRotateFeedToken(w, r)
+		} else {
+			s.HandleGetFeedToken(w, r)
+		}
+		assertStatus(t, w, 200)
+		var resp struct {
+			Token string `json:"token"`
+		}
+		decodeJSON(t, w, &resp)
+		return resp.Token
+	}
+	token := getToken("GET", "testuser")
+	if token == "" || getToken("GET", "testuser") != token {
+		t.Fatal("feed token should be created once and then reused")
+	}
 
-	var list []map[string]any
-	decodeJSON(t, w, &list)
-	if len(list) == 0 {
-		t.Fatal("expected articles")
+	// Goes through the middleware without a session cookie
+	t.Setenv("GORSS_AUTH_MODE", "password")
+	t.Setenv("GORSS_PASSWORD", "secret")
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/feeds/{id}/atom", s.HandleFeedAtom)
+	handler := s.AuthMiddleware(mux)
+	atom := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/feeds/"+id+"/atom?token="+token, nil))
+		return w
 	}
-	// List endpoint should NOT include content or summary
-	if _, ok := list[0]["content"]; ok {
-		t.Error("list should not contain content field")
+
+	w := atom(token)
+	assertStatus(t, w, 200)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Errorf("Content-Type = %q", ct)
 	}
-	if _, ok := list[0]["summary"]; ok {
-		t.Error("list should not contain summary field")
+	var doc AtomFeed
+	if err := xml.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid Atom: %v", err)
 	}
-	// But should have metadata
-	if _, ok := list[0]["title"]; !ok {
-		t.Error("list should contain title")
+	if doc.Title != "atom-src" || len(doc.Entries) != 2 {
+		t.Errorf("title = %q, entries = %d; want atom-src and 2", doc.Title, len(doc.Entries))
 	}
-	if _, ok := list[0]["feed_title"]; !ok {
-		t.Error("list should contain feed_title")
+	for _, e := range doc.Entries {
+		if e.ID == "" || e.Updated == "" || e.Content == nil {
+			t.Errorf("incomplete entry: %+v", e)
+		}
 	}
+
+	assertStatus(t, atom(""), 401)
+	assertStatus(t, atom("bogus"), 401)
+
+	// Another user's token can't read this feed
+	assertStatus(t, atom(getToken("GET", "other")), 404)
+
+	// Rotating invalidates the old token
+	rotated := getToken("POST", "testuser")
+	assertStatus(t, atom(token), 401)
+	assertStatus(t, atom(rotated), 200)
 }
 
-// --------------- Batch Mark Read ---------------
+// --------------- Refresh Intervals ---------------
 
-func TestMarkReadBatch(t *testing.T) {
+func TestRefreshIntervalPrecedence(t *testing.T) {
 	s := newTestServer(t)
-	feed := seedFeed(t, s, "batch-feed", nil, 5)
-
+	s.RefreshInterval = time.Hour
 	q := dbgen.New(s.DB)
 	ctx := context.Background()
-	arts, _ := q.GetArticlesByFeed(ctx, dbgen.GetArticlesByFeedParams{
-		UserID: "testuser", ID: feed.ID, UserID_2: "testuser", Limit: 10,
-	})
 
-	t.Run("batch mark 3 as read", func(t *testing.T) {
-		ids := []int64{arts[0].ID, arts[1].ID, arts[2].ID}
-		body := fmt.Sprintf(`{"ids":[%d,%d,%d]}`, ids[0], ids[1], ids[2])
+	plain := seedFeed(t, s, "plain", nil, 0) // also creates testuser
+	cat, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "Slow"})
+	inCat := seedFeed(t, s, "in-cat", &cat.ID, 0)
+	override := seedFeed(t, s, "override", &cat.ID, 0)
+
+	catStr := fmt.Sprint(cat.ID)
+	w := httptest.NewRecorder()
+	r := authReq("PUT", "/api/categories/"+catStr, `{"refresh_interval_minutes":240}`)
+	r.SetPathValue("id", catStr)
+	s.HandleUpdateCategory(w, r)
+	assertStatus(t, w, 200)
+
+	fidStr := fmt.Sprint(override.ID)
+	w = httptest.NewRecorder()
+	r = authReq("PUT", "/api/feeds/"+fidStr, `{"refresh_interval_minutes":15}`)
+	r.SetPathValue("id", fidStr)
+	s.HandleUpdateFeed(w, r)
+	assertStatus(t, w, 200)
+
+	effective := func() map[int64]int64 {
+		t.Helper()
 		w := httptest.NewRecorder()
-		s.HandleMarkReadBatch(w, authReq("POST", "/api/articles/mark-read-batch", body))
+		s.HandleGetFeeds(w, authReq("GET", "/api/feeds", ""))
 		assertStatus(t, w, 200)
+		var feeds []struct {
+			ID       int64 `json:"id"`
+			Interval int64 `json:"effective_refresh_interval_minutes"`
+		}
+		decodeJSON(t, w, &feeds)
+		got := make(map[int64]int64)
+		for _, f := range feeds {
+			got[f.ID] = f.Interval
+		}
+		return got
+	}
 
-		// Verify only 2 remain unread
-		w2 := httptest.NewRecorder()
-		s.HandleGetArticles(w2, authReq("GET", "/api/articles?view=unread", ""))
-		var list []json.RawMessage
-		decodeJSON(t, w2, &list)
-		if len(list) != 2 {
-			t.Errorf("got %d unread, want 2", len(list))
+	got := effective()
+	for id, want := range map[int64]int64{plain.ID: 60, inCat.ID: 240, override.ID: 15} {
+		if got[id] != want {
+			t.Errorf("feed %d effective interval = %d, want %d", id, got[id], want)
+		}
+	}
+
+	// Clearing the feed override falls back to the category interval
+	w = httptest.NewRecorder()
+	r = authReq("PUT", "/api/feeds/"+fidStr, `{"refresh_interval_minutes":0}`)
+	r.SetPathValue("id", fidStr)
+	s.HandleUpdateFeed(w, r)
+	assertStatus(t, w, 200)
+	if got := effective()[override.ID]; got != 240 {
+		t.Errorf("after clearing override: interval = %d, want 240", got)
+	}
+
+	t.Run("due check", func(t *testing.T) {
+		now := time.Now()
+		recent := now.Add(-30 * time.Minute)
+		feed := dbgen.Feed{LastUpdated: &recent}
+		if feedDue(&feed, time.Hour, now) {
+			t.Error("feed updated 30m ago should not be due with a 1h interval")
+		}
+		if !feedDue(&feed, 15*time.Minute, now) {
+			t.Error("feed updated 30m ago should be due with a 15m interval")
+		}
+		if !feedDue(&dbgen.Feed{}, time.Hour, now) {
+			t.Error("never-updated feed should be due")
 		}
 	})
 
-	t.Run("empty ids", func(t *testing.T) {
+	t.Run("negative rejected", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		s.HandleMarkReadBatch(w, authReq("POST", "/api/articles/mark-read-batch", `{"ids":[]}`))
+		r := authReq("PUT", "/api/categories/"+catStr, `{"refresh_interval_minutes":-1}`)
+		r.SetPathValue("id", catStr)
+		s.HandleUpdateCategory(w, r)
 		assertStatus(t, w, 400)
 	})
 
-	t.Run("bad json", func(t *testing.T) {
+	t.Run("unknown category", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		s.HandleMarkReadBatch(w, authReq("POST", "/api/articles/mark-read-batch", `not json`))
-		assertStatus(t, w, 400)
+		r := authReq("PUT", "/api/categories/99999", `{"title":"x"}`)
+		r.SetPathValue("id", "99999")
+		s.HandleUpdateCategory(w, r)
+		assertStatus(t, w, 404)
 	})
 }
 
-// --------------- Mark All Read by Category ---------------
+// --------------- Feed Discovery ---------------
+
+func TestDiscoverFeeds(t *testing.T) {
+	var gotQuery, gotAuth string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery, gotAuth = r.URL.Query().Get("q"), r.Header.Get("Authorization")
+		if gotQuery == "fail" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"results":[{"url":"https://go.dev/blog/feed.atom","title":"Go Blog"},{"url":"javascript:alert(1)","title":"bad"}]}`)
+	}))
+	defer backend.Close()
 
-func TestMarkAllReadByCategory(t *testing.T) {
 	s := newTestServer(t)
-	q := dbgen.New(s.DB)
-	ctx := context.Background()
-	email := "test@example.com"
-	now := time.Now()
-	_ = q.UpsertUser(ctx, dbgen.UpsertUserParams{
-		ID: "testuser", Email: &email, CreatedAt: now, LastSeen: now,
-	})
-	cat, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "CatA"})
-	catID := cat.ID
-	seedFeed(t, s, "cat-feed", &catID, 3)
-	seedFeed(t, s, "other-feed", nil, 2)
+	discover := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.HandleDiscoverFeeds(w, authReq("GET", "/api/feeds/discover"+query, ""))
+		return w
+	}
 
-	w := httptest.NewRecorder()
-	s.HandleMarkAllRead(w, authReq("POST", "/api/articles/mark-all-read?category_id="+fmt.Sprint(catID), ""))
+	// No backend configured
+	w := discover("?q=golang")
 	assertStatus(t, w, 200)
+	if w.Body.String() != "[]\n" {
+		t.Errorf("without backend: %s, want []", w.Body.String())
+	}
 
-	// Category articles should be read, other feed still unread
-	w2 := httptest.NewRecorder()
-	s.HandleGetArticles(w2, authReq("GET", "/api/articles?view=unread", ""))
-	var list []json.RawMessage
-	decodeJSON(t, w2, &list)
-	if len(list) != 2 {
-		t.Errorf("expected 2 unread (from other-feed), got %d", len(list))
+	s.DiscoveryURL = backend.URL + "/search"
+	s.DiscoveryKey = "secret"
+	w = discover("?q=go+blog")
+	assertStatus(t, w, 200)
+	var feeds []DiscoveredFeed
+	decodeJSON(t, w, &feeds)
+	if len(feeds) != 1 || feeds[0].URL != "https://go.dev/blog/feed.atom" || feeds[0].Title != "Go Blog" {
+		t.Errorf("feeds = %+v, want just the Go Blog", feeds)
 	}
+	if gotQuery != "go blog" || gotAuth != "Bearer secret" {
+		t.Errorf("backend got q=%q auth=%q", gotQuery, gotAuth)
+	}
+
+	// {q} placeholder
+	s.DiscoveryURL = backend.URL + "/search?q={q}&lang=en"
+	assertStatus(t, discover("?q=a%26b"), 200)
+	if gotQuery != "a&b" {
+		t.Errorf("placeholder: backend got q=%q, want a&b", gotQuery)
+	}
+
+	assertStatus(t, discover("?q=fail"), 502)
+	assertStatus(t, discover(""), 400)
 }
 
-// --------------- Theme Support ---------------
+// --------------- Fever API ---------------
 
-func TestThemeSupport(t *testing.T) {
+func TestFever(t *testing.T) {
 	s := newTestServer(t)
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	seedFeed(t, s, "plain", nil, 1)
+	cat, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "News"})
+	feed := seedFeed(t, s, "fever", &cat.ID, 3)
+	if err := q.SetFeedToken(ctx, dbgen.SetFeedTokenParams{UserID: "testuser", Token: "tok"}); err != nil {
+		t.Fatalf("SetFeedToken: %v", err)
+	}
+	setPassword := func() string {
+		t.Helper()
+		r := authReq("POST", "/api/fever-password", "")
+		r.Header.Set("X-ExeDev-Email", "test@example.com")
+		w := httptest.NewRecorder()
+		s.HandleSetFeverPassword(w, r)
+		assertStatus(t, w, 200)
+		var body struct {
+			Usernames []string `json:"usernames"`
+			Password  string   `json:"password"`
+		}
+		decodeJSON(t, w, &body)
+		if !slices.Equal(body.Usernames, []string{"testuser", "test@example.com"}) || body.Password == "" {
+			t.Fatalf("fever password = %+v", body)
+		}
+		return body.Password
+	}
+	apiKey := feverAPIKey("testuser", setPassword())
+
+	type feverResp struct {
+		Auth       int    `json:"auth"`
+		APIVersion int    `json:"api_version"`
+		TotalItems int    `json:"total_items"`
+		UnreadIDs  string `json:"unread_item_ids"`
+		SavedIDs   string `json:"saved_item_ids"`
+		Groups     []struct {
+			ID    int64  `json:"id"`
+			Title string `json:"title"`
+		} `json:"groups"`
+		FeedsGroups []struct {
+			GroupID int64  `json:"group_id"`
+			FeedIDs string `json:"feed_ids"`
+		} `json:"feeds_groups"`
+		Feeds []struct {
+			ID int64 `json:"id"`
+		} `json:"feeds"`
+		Items []struct {
+			ID     int64  `json:"id"`
+			FeedID int64  `json:"feed_id"`
+			HTML   string `json:"html"`
+			IsRead int    `json:"is_read"`
+		} `json:"items"`
+	}
+	fever := func(query, form string, wantStatus int) feverResp {
+		t.Helper()
+		r := httptest.NewRequest("POST", "/fever/?api"+query, strings.NewReader(form))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.HandleFever(w, r)
+		assertStatus(t, w, wantStatus)
+		var resp feverResp
+		if wantStatus == 200 {
+			decodeJSON(t, w, &resp)
+		}
+		return resp
+	}
+	key := "api_key=" + apiKey
 
-	t.Run("CSS contains dark theme variables", func(t *testing.T) {
-		css, err := os.ReadFile(filepath.Join(s.StaticDir, "app.css"))
-		if err != nil {
-			t.Fatalf("read CSS: %v", err)
+	t.Run("auth", func(t *testing.T) {
+		if resp := fever("", "api_key=wrong", 200); resp.Auth != 0 || resp.APIVersion != 3 {
+			t.Errorf("bad key: auth = %d, version = %d", resp.Auth, resp.APIVersion)
 		}
-		body := string(css)
-		if !strings.Contains(body, `[data-theme="dark"]`) {
-			t.Error("CSS should contain dark theme selector")
+		if resp := fever("", key, 200); resp.Auth != 1 {
+			t.Errorf("good key: auth = %d, want 1", resp.Auth)
 		}
-		if !strings.Contains(body, "prefers-color-scheme: dark") {
-			t.Error("CSS should contain prefers-color-scheme media query")
+		// The feed token, which leaks via ?token= URLs, is not a Fever password
+		sum := md5.Sum([]byte("testuser:tok"))
+		if resp := fever("", "api_key="+hex.EncodeToString(sum[:]), 200); resp.Auth != 0 {
+			t.Error("feed token accepted as Fever password")
 		}
-		if !strings.Contains(body, ".theme-toggle") {
-			t.Error("CSS should contain theme-toggle class")
+	})
+
+	t.Run("groups and feeds", func(t *testing.T) {
+		resp := fever("&groups&feeds", key, 200)
+		if len(resp.Groups) != 1 || resp.Groups[0].Title != "News" || len(resp.Feeds) != 2 {
+			t.Errorf("groups = %+v, feeds = %+v", resp.Groups, resp.Feeds)
+		}
+		if len(resp.FeedsGroups) != 1 || resp.FeedsGroups[0].FeedIDs != fmt.Sprint(feed.ID) {
+			t.Errorf("feeds_groups = %+v, want the fever feed in News", resp.FeedsGroups)
 		}
 	})
 
-	t.Run("JS contains theme management", func(t *testing.T) {
-		js, err := os.ReadFile(filepath.Join(s.StaticDir, "app.js"))
-		if err != nil {
-			t.Fatalf("read JS: %v", err)
+	var ids []int64
+	t.Run("items", func(t *testing.T) {
+		resp := fever("&items", key, 200)
+		if len(resp.Items) != 4 || resp.TotalItems != 4 {
+			t.Fatalf("items = %d, total = %d; want 4 and 4", len(resp.Items), resp.TotalItems)
 		}
-		body := string(js)
-		if !strings.Contains(body, "gorss-theme-mode") {
-			t.Error("JS should contain theme localStorage key")
+		for _, it := range resp.Items {
+			if it.FeedID == feed.ID {
+				ids = append(ids, it.ID)
+			}
+			if it.HTML == "" {
+				t.Errorf("item %d has no html", it.ID)
+			}
 		}
-		if !strings.Contains(body, "resolveTheme") {
-			t.Error("JS should contain resolveTheme function")
+		if resp := fever("&items&since_id="+fmt.Sprint(ids[0]), key, 200); len(resp.Items) != 2 {
+			t.Errorf("since_id: %d items, want 2", len(resp.Items))
 		}
-		if !strings.Contains(body, "cycleTheme") {
-			t.Error("JS should contain cycleTheme function")
+		if resp := fever("&items&max_id="+fmt.Sprint(ids[2]), key, 200); len(resp.Items) != 3 || resp.Items[0].ID != ids[1] {
+			t.Errorf("max_id: %+v, want 3 items newest first", resp.Items)
 		}
-		if !strings.Contains(body, "prefers-color-scheme") {
-			t.Error("JS should check prefers-color-scheme for auto mode")
+		if resp := fever("&items&with_ids="+fmt.Sprintf("%d,%d", ids[0], ids[2]), key, 200); len(resp.Items) != 2 {
+			t.Errorf("with_ids: %d items, want 2", len(resp.Items))
 		}
 	})
 
-	t.Run("HTML contains theme toggle button", func(t *testing.T) {
-		w := httptest.NewRecorder()
-		r := authReq("GET", "/", "")
-		s.HandleRoot(w, r)
-		assertStatus(t, w, 200)
-		body := w.Body.String()
-		if !strings.Contains(body, "btn-theme") {
-			t.Error("HTML should contain theme toggle button")
+	t.Run("mark item", func(t *testing.T) {
+		resp := fever("", fmt.Sprintf("%s&mark=item&as=read&id=%d", key, ids[0]), 200)
+		if strings.Contains(","+resp.UnreadIDs+",", fmt.Sprintf(",%d,", ids[0])) {
+			t.Errorf("unread_item_ids = %q still contains %d", resp.UnreadIDs, ids[0])
 		}
-	})
-}
-
-// --------------- Update Feed ---------------
-
-func TestUpdateFeed(t *testing.T) {
-	s := newTestServer(t)
-	feed := seedFeed(t, s, "update-test", nil, 2)
-	fidStr := fmt.Sprint(feed.ID)
-
-	t.Run("rename feed", func(t *testing.T) {
-		w := httptest.NewRecorder()
-		r := authReq("PUT", "/api/feeds/"+fidStr, `{"title":"New Name"}`)
-		r.SetPathValue("id", fidStr)
-		s.HandleUpdateFeed(w, r)
-		assertStatus(t, w, 200)
-
-		// Verify the feed was actually renamed in the DB
-		q := dbgen.New(s.DB)
-		updated, err := q.GetFeed(context.Background(), dbgen.GetFeedParams{ID: feed.ID, UserID: "testuser"})
-		if err != nil {
-			t.Fatalf("get feed: %v", err)
+		resp = fever("", fmt.Sprintf("%s&mark=item&as=saved&id=%d", key, ids[1]), 200)
+		if resp.SavedIDs != fmt.Sprint(ids[1]) {
+			t.Errorf("saved_item_ids = %q, want %d", resp.SavedIDs, ids[1])
 		}
-		if updated.Title != "New Name" {
-			t.Errorf("title = %q, want New Name", updated.Title)
+		fever("", fmt.Sprintf("%s&mark=item&as=unread&id=%d", key, ids[0]), 200)
+		if resp := fever("&items&with_ids="+fmt.Sprint(ids[0]), key, 200); resp.Items[0].IsRead != 0 {
+			t.Error("item still read after mark unread")
 		}
+		fever("", key+"&mark=item&as=read&id=999999", 400)
+		fever("", key+"&mark=item&as=bogus&id="+fmt.Sprint(ids[0]), 400)
 	})
 
-	t.Run("empty title keeps current", func(t *testing.T) {
-		w := httptest.NewRecorder()
-		r := authReq("PUT", "/api/feeds/"+fidStr, `{"title":""}`)
-		r.SetPathValue("id", fidStr)
-		s.HandleUpdateFeed(w, r)
-		assertStatus(t, w, 200) // empty title defaults to current
-
-		q := dbgen.New(s.DB)
-		updated, err := q.GetFeed(context.Background(), dbgen.GetFeedParams{ID: feed.ID, UserID: "testuser"})
-		if err != nil {
-			t.Fatalf("get feed: %v", err)
+	t.Run("mark feed and group", func(t *testing.T) {
+		before := fmt.Sprint(time.Now().Add(time.Minute).Unix())
+		fever("", fmt.Sprintf("%s&mark=feed&as=read&id=%d&before=%s", key, feed.ID, before), 200)
+		resp := fever("&unread_item_ids", key, 200)
+		if strings.Count(resp.UnreadIDs, ",") != 0 || resp.UnreadIDs == "" {
+			t.Errorf("after feed read: unread_item_ids = %q, want just the plain feed's article", resp.UnreadIDs)
 		}
-		if updated.Title != "New Name" { // kept from previous test
-			t.Errorf("title = %q, want New Name (unchanged)", updated.Title)
+		// Group 0 is everything; before in the past marks nothing
+		fever("", key+"&mark=group&as=read&id=0&before=1", 200)
+		if resp := fever("&unread_item_ids", key, 200); resp.UnreadIDs == "" {
+			t.Error("before=1 marked articles read")
+		}
+		fever("", key+"&mark=group&as=read&id=0&before="+before, 200)
+		if resp := fever("&unread_item_ids", key, 200); resp.UnreadIDs != "" {
+			t.Errorf("after group 0 read: unread_item_ids = %q, want empty", resp.UnreadIDs)
 		}
 	})
 
-	t.Run("invalid id", func(t *testing.T) {
+	t.Run("not an api request", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		r := authReq("PUT", "/api/feeds/abc", `{"title":"X"}`)
-		r.SetPathValue("id", "abc")
-		s.HandleUpdateFeed(w, r)
+		s.HandleFever(w, httptest.NewRequest("GET", "/fever/", nil))
 		assertStatus(t, w, 400)
 	})
 
-	t.Run("bad json", func(t *testing.T) {
+	t.Run("new and revoked password", func(t *testing.T) {
+		password := setPassword()
+		if resp := fever("", key, 200); resp.Auth != 0 {
+			t.Error("old password still accepted")
+		}
+		byEmail := "api_key=" + feverAPIKey("test@example.com", password)
+		if resp := fever("", byEmail, 200); resp.Auth != 1 {
+			t.Errorf("email login: auth = %d, want 1", resp.Auth)
+		}
 		w := httptest.NewRecorder()
-		r := authReq("PUT", "/api/feeds/"+fidStr, "not json")
-		r.SetPathValue("id", fidStr)
-		s.HandleUpdateFeed(w, r)
-		assertStatus(t, w, 400)
+		s.HandleDeleteFeverPassword(w, authReq("DELETE", "/api/fever-password", ""))
+		assertStatus(t, w, 200)
+		if resp := fever("", byEmail, 200); resp.Auth != 0 {
+			t.Error("revoked password still accepted")
+		}
 	})
+}
 
-	t.Run("not found", func(t *testing.T) {
+// --------------- Preferences ---------------
+
+func TestDefaultCategory(t *testing.T) {
+	feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>F</title></channel></rss>`)
+	}))
+	defer feedSrv.Close()
+
+	s := newTestServer(t)
+	s.fetcher.AllowPrivateURLs = true
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	seedFeed(t, s, "seed", nil, 0)
+	cat, err := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "Inbox"})
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	other, _ := q.CreateCategory(ctx, dbgen.CreateCategoryParams{UserID: "testuser", Title: "Other"})
+
+	subscribe := func(body string) dbgen.Feed {
+		t.Helper()
 		w := httptest.NewRecorder()
-		r := authReq("PUT", "/api/feeds/999999", `{"title":"X"}`)
-		r.SetPathValue("id", "999999")
-		s.HandleUpdateFeed(w, r)
-		assertStatus(t, w, 404)
-	})
+		s.HandleSubscribe(w, authReq("POST", "/api/feeds", body))
+		assertStatus(t, w, 200)
+		var feed dbgen.Feed
+		decodeJSON(t, w, &feed)
+		return feed
+	}
+	setDefault := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.HandleUpdatePrefs(w, authReq("PUT", "/api/prefs", body))
+		return w
+	}
+
+	// No preference: uncategorized
+	if f := subscribe(`{"url":"` + feedSrv.URL + `/a"}`); f.CategoryID != nil {
+		t.Errorf("without default: category_id = %v, want nil", *f.CategoryID)
+	}
+
+	assertStatus(t, setDefault(fmt.Sprintf(`{"default_category_id":%d}`, cat.ID)), 200)
+	w := httptest.NewRecorder()
+	s.HandleGetPrefs(w, authReq("GET", "/api/prefs", ""))
+	var prefs dbgen.UserPref
+	decodeJSON(t, w, &prefs)
+	if prefs.DefaultCategoryID == nil || *prefs.DefaultCategoryID != cat.ID {
+		t.Fatalf("prefs = %+v, want default_category_id %d", prefs, cat.ID)
+	}
+
+	if f := subscribe(`{"url":"` + feedSrv.URL + `/b"}`); f.CategoryID == nil || *f.CategoryID != cat.ID {
+		t.Errorf("with default: category_id = %v, want %d", f.CategoryID, cat.ID)
+	}
+	// An explicit category wins
+	if f := subscribe(fmt.Sprintf(`{"url":"%s/c","category_id":%d}`, feedSrv.URL, other.ID)); f.CategoryID == nil || *f.CategoryID != other.ID {
+		t.Errorf("explicit category: category_id = %v, want %d", f.CategoryID, other.ID)
+	}
+
+	// Deleting the default category falls back to uncategorized
+	_ = q.DeleteCategory(ctx, dbgen.DeleteCategoryParams{ID: cat.ID, UserID: "testuser"})
+	if f := subscribe(`{"url":"` + feedSrv.URL + `/d"}`); f.CategoryID != nil {
+		t.Errorf("after deleting default: category_id = %v, want nil", *f.CategoryID)
+	}
+
+	// Categories that don't exist are rejected
+	assertStatus(t, setDefault(`{"default_category_id":999999}`), 400)
+	assertStatus(t, setDefault(`bad`), 400)
+	assertStatus(t, setDefault(`{"default_category_id":null}`), 200)
 }
 
-// --------------- Import OPML ---------------
+// --------------- Digest ---------------
 
-func TestImportOPML(t *testing.T) {
+func TestDigest(t *testing.T) {
 	s := newTestServer(t)
+	f1 := seedFeed(t, s, "digest-a", nil, 4)
+	f2 := seedFeed(t, s, "digest-b", nil, 2)
+	seedFeed(t, s, "digest-empty", nil, 0)
 
-	t.Run("bad request no file", func(t *testing.T) {
+	// Read one of digest-b's articles
+	q := dbgen.New(s.DB)
+	ctx := context.Background()
+	now := time.Now()
+	var readID int64
+	if err := s.DB.QueryRow("SELECT id FROM articles WHERE feed_id = ? LIMIT 1", f2.ID).Scan(&readID); err != nil {
+		t.Fatal(err)
+	}
+	_ = q.SetArticleRead(ctx, dbgen.SetArticleReadParams{UserID: "testuser", ArticleID: readID, ReadAt: &now})
+
+	digest := func(query string) []digestGroup {
+		t.Helper()
 		w := httptest.NewRecorder()
-		r := authReq("POST", "/api/opml/import", "")
-		s.HandleImportOPML(w, r)
+		s.HandleDigest(w, authReq("GET", "/api/digest"+query, ""))
+		assertStatus(t, w, 200)
+		var groups []digestGroup
+		decodeJSON(t, w, &groups)
+		return groups
+	}
+
+	groups := digest("?view=unread&per_feed=3")
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2 (empty feed omitted)", len(groups))
+	}
+	want := map[int64]struct{ articles, unread int }{f1.ID: {3, 4}, f2.ID: {1, 1}}
+	for _, g := range groups {
+		w := want[g.Feed.ID]
+		if len(g.Articles) != w.articles || g.UnreadCount != int64(w.unread) {
+			t.Errorf("feed %q: %d articles, unread %d; want %d, %d", g.Feed.Title, len(g.Articles), g.UnreadCount, w.articles, w.unread)
+		}
+		for _, a := range g.Articles {
+			if a.FeedID != g.Feed.ID || a.IsRead != 0 || a.FeedTitle != g.Feed.Title {
+				t.Errorf("unexpected article in %q: %+v", g.Feed.Title, a)
+			}
+		}
+	}
+
+	// view=all includes the read article
+	for _, g := range digest("?view=all") {
+		if g.Feed.ID == f2.ID && len(g.Articles) != 2 {
+			t.Errorf("view=all: feed b has %d articles, want 2", len(g.Articles))
+		}
+	}
+
+	for _, query := range []string{"?view=bogus", "?per_feed=0", "?per_feed=x"} {
+		w := httptest.NewRecorder()
+		s.HandleDigest(w, authReq("GET", "/api/digest"+query, ""))
 		assertStatus(t, w, 400)
-	})
+	}
 }
@@ -25,11 +25,12 @@
   }
 
   // ── Theme Management ──────────────────────────────────────────────────
-  // Modes: 'auto' (time-based), 'light', 'dark'
+  // Modes: 'auto' (time-based), 'light', 'dark'. Without a saved choice
+  // the server's GORSS_DEFAULT_THEME applies.
   const THEME_KEY = 'gorss-theme-mode';
 
   function getThemeMode() {
-    return localStorage.getItem(THEME_KEY) || 'auto';
+    return localStorage.getItem(THEME_KEY) || document.documentElement.dataset.defaultTheme || 'auto';
   }
 
   function resolveTheme(mode) {
@@ -186,6 +187,7 @@
       setupKeyboardNav();
       console.log('Keyboard nav set up');
       setupTitleEdit();
+      registerServiceWorker();
       await reloadData();
       console.log('GoRSS init complete');
     } catch (e) {
@@ -193,6 +195,13 @@
     }
   }
 
+  // Register the service worker for PWA install / offline app shell
+  function registerServiceWorker() {
+    if (!('serviceWorker' in navigator)) return;
+    navigator.serviceWorker.register('/sw.js', { scope: '/' })
+      .catch(e => console.warn('Service worker registration failed:', e));
+  }
+
   // Reload feeds, counts, and articles (used by init and bfcache/tab restore)
   async function reloadData() {
     const [feedsOk] = await Promise.allSettled([
@@ -421,6 +430,27 @@
     titleEl.textContent = title;
     titleEl.classList.toggle('editable', editable);
     titleEl.title = editable ? 'Click to rename feed' : '';
+    updateFeedLogo(currentFeedId ? feeds.find(f => f.id == currentFeedId) : null);
+  }
+
+  // Show the feed's declared logo in the header, else its site's favicon
+  function updateFeedLogo(feed) {
+    const logoEl = document.getElementById('feed-logo');
+    let src = '';
+    if (feed) {
+      src = feed.logo_url;
+      if (!src) {
+        try { src = new URL('/favicon.ico', feed.site_url || feed.url).href; } catch { src = ''; }
+      }
+    }
+    if (!src) {
+      logoEl.hidden = true;
+      logoEl.removeAttribute('src');
+      return;
+    }
+    logoEl.onerror = () => { logoEl.hidden = true; };
+    logoEl.onload = () => { logoEl.hidden = false; };
+    if (logoEl.getAttribute('src') !== src) logoEl.src = src;
   }
 
   // Inline feed title editing
@@ -694,12 +724,15 @@
 
   function feedItemHtml(f) {
     const hasError = f.error_count > 0;
-    const errorClass = hasError ? 'feed-error' : '';
-    const errorTitle = hasError ? `title="Error: ${escapeHtml(f.last_error || 'Unknown error')}"` : '';
+    const hasWarning = !hasError && !!f.last_warning;
+    const errorClass = hasError ? 'feed-error' : (hasWarning ? 'feed-warning' : '');
+    const errorTitle = f.dead ? `title="Dead (host does not resolve), not refreshed: ${escapeHtml(f.last_error || '')}"`
+      : hasError ? `title="Error: ${escapeHtml(f.last_error || 'Unknown error')}"`
+      : (hasWarning ? `title="Warning: ${escapeHtml(f.last_warning)}"` : '');
     
     return `<div class="nav-item-wrapper" data-feed-id="${f.id}">
       <a href="#" class="nav-item ${errorClass}" data-feed-id="${f.id}" draggable="true" data-drag-feed="${f.id}" ${errorTitle}>
-        <span class="icon">${hasError ? '⚠️' : '📡'}</span>
+        <span class="icon">${f.dead ? '🪦' : (hasError ? '⚠️' : (hasWarning ? '💤' : '📡'))}</span>
         <span class="label">${escapeHtml(f.title || f.url)}</span>
         <span class="count" data-feed-count="${f.id}">0</span>
       </a>
@@ -790,12 +823,14 @@
       let cursor = null;
       if (articles.length > 0) {
         const last = articles[articles.length - 1];
-        if (last.published_at) {
+        // Undated articles are ordered by ingestion time on the server
+        const sortKey = last.published_at || last.created_at;
+        if (sortKey) {
           const sortOrder = getSortOrder();
           if (sortOrder === 'oldest') {
-            cursor = { after: last.published_at, after_id: last.id };
+            cursor = { after: sortKey, after_id: last.id };
           } else {
-            cursor = { before: last.published_at, before_id: last.id };
+            cursor = { before: sortKey, before_id: last.id };
           }
         }
       }
@@ -1171,7 +1206,7 @@
         // Update category counts
         feedsList.querySelectorAll('.feed-category').forEach(catEl => {
           const catId = parseInt(catEl.querySelector('.category-header')?.dataset.catId || '0');
-          const total = catTotals.get(catId) || 0;
+          const total = data.categories ? (data.categories[String(catId)] || 0) : (catTotals.get(catId) || 0);
           const countEl = document.querySelector(`[data-cat-count="${catId}"]`);
           if (countEl) countEl.textContent = total;
         });
@@ -1258,8 +1293,8 @@
   async function handleImport(e) {
     e.preventDefault();
     const form = e.target;
-    const file = form.file.files[0];
-    if (!file) return;
+    const files = form.file.files;
+    if (!files.length) return;
 
     const btn = form.querySelector('button[type="submit"]');
     const result = document.getElementById('import-result');
@@ -1269,7 +1304,7 @@
 
     try {
       const formData = new FormData();
-      formData.append('file', file);
+      for (const file of files) formData.append('file[]', file);
 
       const res = await fetch('/api/opml/import', {
         method: 'POST',
@@ -1301,7 +1336,7 @@
     if (fab) fab.textContent = '⏳';
 
     try {
-      await fetch('/api/feeds/refresh', { method: 'POST' });
+      await fetch('/api/feeds/refresh?force=1', { method: 'POST' });
       await loadArticles();
       await updateCounts();
     } catch (e) {
@@ -0,0 +1,45 @@
+// GoRSS service worker — caches the app shell so the UI opens offline.
+// API responses are never cached; articles always come from the network.
+const CACHE = 'gorss-shell-v1';
+const SHELL = [
+  '/',
+  '/static/app.css',
+  '/static/app.js',
+  '/static/purify.min.js',
+  '/static/favicon.svg',
+  '/static/favicon-180.png',
+];
+
+self.addEventListener('install', (event) => {
+  event.waitUntil(
+    caches.open(CACHE).then((cache) => cache.addAll(SHELL)).then(() => self.skipWaiting())
+  );
+});
+
+self.addEventListener('activate', (event) => {
+  event.waitUntil(
+    caches.keys()
+      .then((keys) => Promise.all(keys.filter((k) => k !== CACHE).map((k) => caches.delete(k))))
+      .then(() => self.clients.claim())
+  );
+});
+
+self.addEventListener('fetch', (event) => {
+  const req = event.request;
+  if (req.method !== 'GET') return;
+  const url = new URL(req.url);
+  if (url.origin !== self.location.origin || url.pathname.startsWith('/api/')) return;
+
+  // Network first, fall back to the cached shell when offline
+  event.respondWith(
+    fetch(req)
+      .then((res) => {
+        if (res.ok && (req.mode === 'navigate' || url.pathname.startsWith('/static/'))) {
+          const copy = res.clone();
+          caches.open(CACHE).then((cache) => cache.put(req.mode === 'navigate' ? '/' : url.pathname, copy));
+        }
+        return res;
+      })
+      .catch(() => caches.match(req.mode === 'navigate' ? '/' : url.pathname))
+  );
+});
@@ -0,0 +1,75 @@
+package srv
+
+import (
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"net/http"
+)
+
+// requestIDHeader carries the correlation ID in both directions.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds incoming IDs, which end up in every log line.
+const maxRequestIDLen = 64
+
+type loggerKey struct{}
+
+// requestIDMiddleware tags each request with a correlation ID, taken from
+// X-Request-ID when the client (or a proxy) sent a usable one. The ID is
+// echoed in the response and attached to the request's logger.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+		w.Header().Set(requestIDHeader, id)
+		logger := slog.Default().With("request_id", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), loggerKey{}, logger)))
+	})
+}
+
+// validRequestID reports whether id is short and made of characters that
+// are safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// withJobID returns a context whose logger tags lines with a fresh ID for
+// one run of a background job, e.g. a refresh cycle.
+func withJobID(ctx context.Context, job string) context.Context {
+	logger := slog.Default().With("job", job, "job_id", rand.Text())
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// jobContext returns a context for background work started by r. It
+// outlives the response but is cancelled at shutdown like the other jobs,
+// and keeps r's logger so the job's logs carry the request ID.
+func (s *Server) jobContext(r *http.Request) context.Context {
+	ctx := s.jobsCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, loggerKey{}, logFrom(r.Context()))
+}
+
+// logFrom returns the logger attached to ctx by requestIDMiddleware or
+// withJobID, or the default logger.
+func logFrom(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,85 @@
+package srv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/johnwmail/gorss/db/dbgen"
+)
+
+// webhookTimeout bounds each webhook delivery attempt.
+const webhookTimeout = 5 * time.Second
+
+// webhookRetryDelay is the pause before the single retry of a failed
+// delivery.
+const webhookRetryDelay = time.Second
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// webhookPayload is the JSON body POSTed to GORSS_WEBHOOK_URL.
+type webhookPayload struct {
+	FeedID    int64            `json:"feed_id"`
+	FeedTitle string           `json:"feed_title"`
+	FeedURL   string           `json:"feed_url"`
+	Articles  []webhookArticle `json:"articles"`
+}
+
+type webhookArticle struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// notifyNewArticles sends a feed's newly inserted articles to the webhook,
+// if one is configured. Delivery runs in the background so a slow endpoint
+// never holds up the refresh loop.
+func (s *Server) notifyNewArticles(ctx context.Context, feed *dbgen.Feed, title string, items []FeedItem) {
+	if s.WebhookURL == "" || len(items) == 0 {
+		return
+	}
+	payload := webhookPayload{FeedID: feed.ID, FeedTitle: title, FeedURL: feed.Url}
+	for _, item := range items {
+		payload.Articles = append(payload.Articles, webhookArticle{Title: item.Title, URL: item.URL})
+	}
+	ctx = context.WithoutCancel(ctx)
+	s.jobs.Go(func() {
+		attempts, err := Retry(ctx, 2, webhookRetryDelay, func() error {
+			return s.sendWebhook(ctx, &payload)
+		})
+		if err != nil {
+			logFrom(ctx).Warn("webhook delivery failed", "feed_id", feed.ID, "articles", len(items), "attempts", attempts, "error", err)
+			return
+		}
+		logFrom(ctx).Debug("webhook delivered", "feed_id", feed.ID, "articles", len(items), "attempts", attempts)
+	})
+}
+
+// sendWebhook POSTs payload once. Network errors and 5xx responses are
+// returned as transient, so Retry tries again.
+func (s *Server) sendWebhook(ctx context.Context, payload *webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.fetcher.UserAgent)
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return &httpStatusError{StatusCode: resp.StatusCode}
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}